@@ -79,7 +79,7 @@ func NormalChat(messages []ChatCompletionMessage) ChatCompletionMessage {
 		agentMessages[i] = agent.Message{Role: m.Role, Content: m.Content}
 	}
 
-	client, err := agent.NewAnthropicLLMClient(nil)
+	client, err := agent.NewLLMClient(nil)
 	if err != nil {
 		log.Println("Failed to create LLM client:", err)
 		return ChatCompletionMessage{}
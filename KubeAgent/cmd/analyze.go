@@ -1,7 +1,6 @@
 package cmd
 
 import (
-	"bufio"
 	"context"
 	"fmt"
 
@@ -9,6 +8,7 @@ import (
 	"github.com/spf13/cobra"
 
 	"kubeagent/pkg/agent"
+	"kubeagent/pkg/agent/harness"
 	"kubeagent/pkg/agent/specialists"
 	"kubeagent/pkg/k8s"
 	pkgtools "kubeagent/pkg/tools"
@@ -22,11 +22,16 @@ var analyzeCmd = &cobra.Command{
 		logger := agent.NewSimpleLogger("KubeAgent")
 		stateStore := agent.NewMemoryStateStore()
 
-		llmClient, err := agent.NewAnthropicLLMClient(nil)
+		llmClient, err := newLLMClient(logger)
 		if err != nil {
 			fmt.Printf("Failed to initialize LLM client: %v\n", err)
 			return
 		}
+		prices, err := loadPriceTable()
+		if err != nil {
+			fmt.Printf("Failed to load price table: %v\n", err)
+			return
+		}
 
 		k8sClient, err := k8s.NewClient()
 		if err != nil {
@@ -43,39 +48,77 @@ var analyzeCmd = &cobra.Command{
 		diagnostician.AddTool(pkgtools.NewListTool(k8sClient))
 		diagnostician.AddTool(pkgtools.NewKubeTool())
 		coordinator.RegisterAgent(diagnostician)
+		if err := applyAgentModels(coordinator, diagnostician); err != nil {
+			fmt.Println(err)
+			return
+		}
+		if err := applySchedulerFlags(coordinator); err != nil {
+			fmt.Println(err)
+			return
+		}
 
-		scanner := bufio.NewScanner(cmd.InOrStdin())
+		session := newSession(llmClient)
+		lines := harness.NewLineReader(cmd.InOrStdin())
 		fmt.Println("Hi, I am KubeAgent (Analyze mode). Describe the issue you want to diagnose. (Input 'exit' to quit):")
+		fmt.Println(sessionHelp)
 		for {
-			fmt.Print(">>> ")
-			if !scanner.Scan() {
-				break
-			}
-			input := scanner.Text()
-			if input == "exit" {
-				fmt.Println("Goodbye!")
-				return
+			// --plan-in already holds the work; there is nothing to ask.
+			var input string
+			if planInFile == "" {
+				fmt.Print(">>> ")
+				line, err := lines.ReadLine(context.Background())
+				if err != nil {
+					break
+				}
+				input = line
+				if input == "exit" {
+					fmt.Println("Goodbye!")
+					return
+				}
+				if sessionCommand(session, input, cmd.OutOrStdout()) {
+					continue
+				}
 			}
 
+			// Ctrl-C cancels this request only; at the prompt it exits.
+			reqCtx, stop := interruptible()
 			ctx := agent.NewAgentContext(
-				context.Background(),
+				reqCtx,
 				uuid.New().String(),
 				"cli-user",
 				uuid.New().String(),
 			)
+			attachStream(ctx, cmd.OutOrStdout())
+			ctx.SetSession(session)
+			ledger := newUsageLedger(prices)
+			ctx.SetUsageLedger(ledger)
 			request := &agent.Request{
 				ID:    uuid.New().String(),
 				User:  "cli-user",
 				Input: input,
 			}
 
-			plan, err := coordinator.Plan(ctx, request)
+			plan, err := planRequest(ctx, coordinator, request, lines, cmd.OutOrStdout())
 			if err != nil {
+				session.Record(ctx, request, nil, nil)
+				stop()
 				fmt.Printf("Planning failed: %v\n", err)
+				if planInFile != "" {
+					return
+				}
+				continue
+			}
+			if plan == nil {
+				stop()
+				if planInFile != "" || planOutFile != "" {
+					return
+				}
 				continue
 			}
 
 			response, err := coordinator.ExecutePlan(ctx, plan)
+			session.Record(ctx, request, plan, response)
+			stop()
 			if err != nil {
 				fmt.Printf("Execution failed: %v\n", err)
 				continue
@@ -89,11 +132,21 @@ var analyzeCmd = &cobra.Command{
 					fmt.Println(" -", e)
 				}
 			}
+			printUsage(cmd.OutOrStdout(), ledger)
 			fmt.Println()
+			if planInFile != "" {
+				return
+			}
 		}
 	},
 }
 
 func init() {
+	addLLMCassetteFlag(analyzeCmd)
+	addLLMRoutingFlags(analyzeCmd)
+	addNoStreamFlag(analyzeCmd)
+	addPlanReviewFlags(analyzeCmd)
+	addSchedulerFlags(analyzeCmd)
+	addUsageFlags(analyzeCmd)
 	rootCmd.AddCommand(analyzeCmd)
 }
@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"kubeagent/pkg/agent/harness"
+)
+
+// Approval flags, shared like llmCassette: one command per process.
+var (
+	approvalTimeout time.Duration
+	autoApproveRisk string
+)
+
+// addApprovalFlags registers --approval-timeout and --auto-approve on cmd.
+func addApprovalFlags(cmd *cobra.Command) {
+	cmd.Flags().DurationVar(&approvalTimeout, "approval-timeout", harness.DefaultApprovalTimeout,
+		"Reject an approval nobody answered within this long")
+	cmd.Flags().StringVar(&autoApproveRisk, "auto-approve", "",
+		"Answer approvals by policy instead of asking: approve up to this risk (low, medium, high) and reject the rest")
+}
+
+// newApprovalGate wraps the command's own approver, or the risk policy
+// when --auto-approve is set, in the timeout and audit rules. A nil
+// approver without --auto-approve yields a nil gate: nobody to ask.
+func newApprovalGate(approver harness.Approver, auditor harness.AuditLogger) (*harness.ApprovalGate, error) {
+	maxRisk, err := autoApprovePolicy()
+	if err != nil {
+		return nil, err
+	}
+	if maxRisk != "" {
+		approver = harness.NewAutoApprover(maxRisk)
+	}
+	if approver == nil {
+		return nil, nil
+	}
+	return harness.NewApprovalGate(approver).
+		WithTimeout(approvalTimeout).
+		WithAuditor(auditor), nil
+}
+
+// autoApprovePolicy is the risk --auto-approve approves up to, empty
+// when it is not set.
+func autoApprovePolicy() (harness.Risk, error) {
+	switch maxRisk := harness.Risk(autoApproveRisk); maxRisk {
+	case "", harness.RiskLow, harness.RiskMedium, harness.RiskHigh:
+		return maxRisk, nil
+	}
+	return "", fmt.Errorf("--auto-approve must be low, medium or high, got %q", autoApproveRisk)
+}
+
+// approvalLabel describes who answers approvals, for the banners.
+func approvalLabel(fallback string) string {
+	if autoApproveRisk != "" {
+		return fmt.Sprintf("auto-policy (approve up to %s risk), timeout %s", autoApproveRisk, approvalTimeout)
+	}
+	return fmt.Sprintf("%s, timeout %s", fallback, approvalTimeout)
+}
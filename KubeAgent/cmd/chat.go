@@ -1,14 +1,15 @@
 package cmd
 
 import (
-	"bufio"
 	"context"
 	"fmt"
+	"os"
 
 	"github.com/google/uuid"
 	"github.com/spf13/cobra"
 
 	"kubeagent/pkg/agent"
+	"kubeagent/pkg/agent/harness"
 	"kubeagent/pkg/agent/specialists"
 	"kubeagent/pkg/k8s"
 	pkgtools "kubeagent/pkg/tools"
@@ -22,11 +23,16 @@ var chatCmd = &cobra.Command{
 		logger := agent.NewSimpleLogger("KubeAgent")
 		stateStore := agent.NewMemoryStateStore()
 
-		llmClient, err := agent.NewAnthropicLLMClient(nil)
+		llmClient, err := newLLMClient(logger)
 		if err != nil {
 			fmt.Printf("Failed to initialize LLM client: %v\n", err)
 			return
 		}
+		prices, err := loadPriceTable()
+		if err != nil {
+			fmt.Printf("Failed to load price table: %v\n", err)
+			return
+		}
 
 		k8sClient, err := k8s.NewClient()
 		if err != nil {
@@ -44,43 +50,93 @@ var chatCmd = &cobra.Command{
 
 		// RemediatorAgent handles write operations (create, delete) with human confirmation
 		remediator := specialists.NewRemediatorAgent(llmClient, logger)
-		remediator.AddTool(pkgtools.NewHumanTool())
-		remediator.AddTool(pkgtools.NewCreateTool(k8sClient))
+		// Shared preview board: dry-run diffs from the write tools are shown
+		// in the HumanTool prompt and must be approved before the real write.
+		// The terminal approver reads stdin through the chat loop's reader,
+		// so the two never compete for a line.
+		lines := harness.NewLineReader(cmd.InOrStdin())
+		approvals, err := newApprovalGate(harness.NewTerminalApprover(lines, os.Stdout), nil)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		previews := pkgtools.NewChangePreviews()
+		remediator.AddTool(pkgtools.NewHumanTool().WithChangePreviews(previews).WithApprover(approvals))
+		remediator.AddTool(pkgtools.NewPatchTool(k8sClient).WithChangePreviews(previews))
+		remediator.AddTool(pkgtools.NewApplyTool(k8sClient).WithChangePreviews(previews))
+		remediator.AddTool(pkgtools.NewCreateTool(k8sClient).WithChangePreviews(previews))
 		remediator.AddTool(pkgtools.NewDeleteTool(k8sClient))
 		coordinator.RegisterAgent(remediator)
+		if err := applyAgentModels(coordinator, diagnostician, remediator); err != nil {
+			fmt.Println(err)
+			return
+		}
+		if err := applySchedulerFlags(coordinator); err != nil {
+			fmt.Println(err)
+			return
+		}
 
-		scanner := bufio.NewScanner(cmd.InOrStdin())
+		session := newSession(llmClient)
 		fmt.Println("Hi, I am KubeAgent (Chat mode). How can I help you manage your Kubernetes resources? (Input 'exit' to quit):")
+		fmt.Println(sessionHelp)
 		for {
-			fmt.Print(">>> ")
-			if !scanner.Scan() {
-				break
-			}
-			input := scanner.Text()
-			if input == "exit" {
-				fmt.Println("Goodbye!")
-				return
+			// --plan-in already holds the work; there is nothing to ask.
+			var input string
+			if planInFile == "" {
+				fmt.Print(">>> ")
+				line, err := lines.ReadLine(context.Background())
+				if err != nil {
+					break
+				}
+				input = line
+				if input == "exit" {
+					fmt.Println("Goodbye!")
+					return
+				}
+				if sessionCommand(session, input, cmd.OutOrStdout()) {
+					continue
+				}
 			}
 
+			// Ctrl-C cancels this request only; at the prompt it exits.
+			reqCtx, stop := interruptible()
 			ctx := agent.NewAgentContext(
-				context.Background(),
+				reqCtx,
 				uuid.New().String(),
 				"cli-user",
 				uuid.New().String(),
 			)
+			attachStream(ctx, cmd.OutOrStdout())
+			ctx.SetSession(session)
+			ledger := newUsageLedger(prices)
+			ctx.SetUsageLedger(ledger)
 			request := &agent.Request{
 				ID:    uuid.New().String(),
 				User:  "cli-user",
 				Input: input,
 			}
 
-			plan, err := coordinator.Plan(ctx, request)
+			plan, err := planRequest(ctx, coordinator, request, lines, cmd.OutOrStdout())
 			if err != nil {
+				session.Record(ctx, request, nil, nil)
+				stop()
 				fmt.Printf("Planning failed: %v\n", err)
+				if planInFile != "" {
+					return
+				}
+				continue
+			}
+			if plan == nil {
+				stop()
+				if planInFile != "" || planOutFile != "" {
+					return
+				}
 				continue
 			}
 
 			response, err := coordinator.ExecutePlan(ctx, plan)
+			session.Record(ctx, request, plan, response)
+			stop()
 			if err != nil {
 				fmt.Printf("Execution failed: %v\n", err)
 				continue
@@ -94,11 +150,22 @@ var chatCmd = &cobra.Command{
 					fmt.Println(" -", e)
 				}
 			}
+			printUsage(cmd.OutOrStdout(), ledger)
 			fmt.Println()
+			if planInFile != "" {
+				return
+			}
 		}
 	},
 }
 
 func init() {
+	addLLMCassetteFlag(chatCmd)
+	addLLMRoutingFlags(chatCmd)
+	addNoStreamFlag(chatCmd)
+	addPlanReviewFlags(chatCmd)
+	addSchedulerFlags(chatCmd)
+	addUsageFlags(chatCmd)
+	addApprovalFlags(chatCmd)
 	rootCmd.AddCommand(chatCmd)
 }
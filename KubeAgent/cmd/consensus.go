@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/spf13/cobra"
+
+	"kubeagent/pkg/agent"
+	"kubeagent/pkg/agent/harness"
+)
+
+// Consensus flags, shared like llmCassette: one command per process.
+var (
+	consensusReviewers map[string]string
+	consensusQuorum    int
+	consensusVerbs     []string
+)
+
+// addConsensusFlags registers --consensus-reviewer, --consensus-quorum
+// and --consensus-verbs on cmd.
+func addConsensusFlags(cmd *cobra.Command) {
+	cmd.Flags().StringToStringVar(&consensusReviewers, "consensus-reviewer", nil,
+		"Reviewers that must approve destructive writes, as name=[provider:]model (empty model = primary LLM); a skill named review-<name> replaces the default review prompt")
+	cmd.Flags().IntVar(&consensusQuorum, "consensus-quorum", 0,
+		"Approvals a reviewed write needs (0 = majority of --consensus-reviewer)")
+	cmd.Flags().StringSliceVar(&consensusVerbs, "consensus-verbs", []string{"delete"},
+		"Write verbs put to the reviewers (delete, patch, apply, create)")
+}
+
+// newConsensusCheck builds the ConsensusCheck for --consensus-reviewer,
+// or nil when no reviewers were given.
+func newConsensusCheck(llmClient agent.LLMClient, skills *harness.Skills, auditor harness.AuditLogger) (*harness.ConsensusCheck, error) {
+	if len(consensusReviewers) == 0 {
+		return nil, nil
+	}
+	if consensusQuorum > len(consensusReviewers) {
+		return nil, fmt.Errorf("--consensus-quorum %d exceeds the %d reviewer(s)", consensusQuorum, len(consensusReviewers))
+	}
+
+	names := make([]string, 0, len(consensusReviewers))
+	for name := range consensusReviewers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	reviewers := make([]harness.Reviewer, 0, len(names))
+	for _, name := range names {
+		var llmConfig *agent.LLMConfig
+		if spec := consensusReviewers[name]; spec != "" {
+			llmConfig = parseModelSpec(spec)
+		}
+		prompt, ok := skills.Get("review-" + name)
+		if !ok {
+			prompt, _ = skills.Get("review")
+		}
+		reviewers = append(reviewers, agent.NewLLMReviewer(name, llmClient, llmConfig).WithPrompt(prompt))
+	}
+	return harness.NewConsensusCheck(consensusQuorum, reviewers...).
+		WithVerbs(consensusVerbs...).
+		WithAuditor(auditor), nil
+}
@@ -1,7 +1,7 @@
 package cmd
 
 import (
-	"context"
+	"encoding/json"
 	"fmt"
 	"os"
 	"strings"
@@ -11,10 +11,7 @@ import (
 
 	"kubeagent/pkg/agent"
 	"kubeagent/pkg/agent/harness"
-	"kubeagent/pkg/agent/skills"
-	"kubeagent/pkg/agent/specialists"
 	"kubeagent/pkg/k8s"
-	pkgtools "kubeagent/pkg/tools"
 )
 
 // Flags for `kubeagent fix`. Kept package-private; cobra's Run closure
@@ -26,6 +23,10 @@ var (
 	fixAuditFile   string
 	fixProtected   []string
 	fixNoVerify    bool
+	fixStateFile   string
+	fixResume      string
+	fixIterations  int
+	fixReportFile  string
 )
 
 // fixCmd is the closed-loop remediation entry point.
@@ -57,7 +58,19 @@ Examples:
   kubeagent fix --description "the redis pod in cache namespace keeps crashing"
 
   # Dry-run style: skip post-action verification (not recommended for prod):
-  kubeagent fix --pod nginx-1 --no-verify`,
+  kubeagent fix --pod nginx-1 --no-verify
+
+  # Persist plans and tasks so an interrupted run can be resumed:
+  kubeagent fix --pod nginx-1 --state-file ~/.kubeagent/state.db
+  kubeagent plans --state-file ~/.kubeagent/state.db
+  kubeagent fix --state-file ~/.kubeagent/state.db --resume <plan-id>
+
+  # Let two independent models vote before any delete; both must approve:
+  kubeagent fix --pod nginx-1 -n prod --consensus-reviewer safety=anthropic:claude-3-5-sonnet-latest,blast=dashscope:qwen-max --consensus-quorum 2
+
+  # Review the plan now, execute it later (the review can drop tasks and edit inputs):
+  kubeagent fix --pod nginx-1 --plan-out plan.json
+  kubeagent fix --plan-in plan.json --yes`,
 	Run: runFix,
 }
 
@@ -68,24 +81,54 @@ func init() {
 	fixCmd.Flags().StringVar(&fixAuditFile, "audit-file", "", "Path to a JSONL audit log file (also tees to console)")
 	fixCmd.Flags().StringSliceVar(&fixProtected, "protected", []string{"kube-system", "kube-public", "kube-node-lease"}, "Namespaces that must never be mutated")
 	fixCmd.Flags().BoolVar(&fixNoVerify, "no-verify", false, "Skip post-action verification (debug only)")
+	fixCmd.Flags().StringVar(&fixStateFile, "state-file", "", "Path to a durable state file; plans and tasks survive exit and can be resumed")
+	fixCmd.Flags().StringVar(&fixResume, "resume", "", "Resume a saved plan by ID instead of planning a new request (requires --state-file)")
+	fixCmd.Flags().StringVar(&fixReportFile, "report-file", "", "Write the machine-readable final report (JSON) to this file")
+	fixCmd.Flags().IntVar(&fixIterations, "max-iterations", agent.DefaultRemediationIterations, "Re-diagnose and retry a fix that fails verification up to this many times (0 = never)")
 
+	addLLMCassetteFlag(fixCmd)
+	addLLMRoutingFlags(fixCmd)
+	addNoStreamFlag(fixCmd)
+	addPlanReviewFlags(fixCmd)
+	addSchedulerFlags(fixCmd)
+	addConsensusFlags(fixCmd)
+	addUsageFlags(fixCmd)
+	addApprovalFlags(fixCmd)
 	rootCmd.AddCommand(fixCmd)
 }
 
 func runFix(cmd *cobra.Command, args []string) {
-	if fixPod == "" && fixDescription == "" {
-		fmt.Println("Either --pod or --description is required.")
+	if fixResume != "" && fixStateFile == "" {
+		fmt.Println("--resume requires --state-file.")
+		os.Exit(1)
+	}
+	if fixResume != "" && (planInFile != "" || planOutFile != "") {
+		fmt.Println("--resume cannot be combined with --plan-in or --plan-out.")
+		os.Exit(1)
+	}
+	if fixResume == "" && planInFile == "" && fixPod == "" && fixDescription == "" {
+		fmt.Println("Either --pod, --description or --plan-in is required.")
 		os.Exit(1)
 	}
 
 	logger := agent.NewSimpleLogger("KubeAgent")
-	stateStore := agent.NewMemoryStateStore()
+	stateStore, closeStateStore, err := newStateStore(fixStateFile)
+	if err != nil {
+		fmt.Printf("Failed to open state file: %v\n", err)
+		os.Exit(1)
+	}
+	defer closeStateStore()
 
-	llmClient, err := agent.NewAnthropicLLMClient(nil)
+	llmClient, err := newLLMClient(logger)
 	if err != nil {
 		fmt.Printf("Failed to initialize LLM client: %v\n", err)
 		os.Exit(1)
 	}
+	prices, err := loadPriceTable()
+	if err != nil {
+		fmt.Printf("Failed to load price table: %v\n", err)
+		os.Exit(1)
+	}
 
 	k8sClient, err := k8s.NewClient()
 	if err != nil {
@@ -93,17 +136,11 @@ func runFix(cmd *cobra.Command, args []string) {
 		os.Exit(1)
 	}
 
-	// Skills: load embedded markdown prompts, allow runtime override via
-	// SKILLS_DIR (operators can drop a tweaked diagnose.md without
-	// rebuilding).
-	skillRegistry, err := harness.NewSkillsFromFS(skills.FS())
+	skillRegistry, err := loadSkills()
 	if err != nil {
-		fmt.Printf("Failed to load skills: %v\n", err)
+		fmt.Println(err)
 		os.Exit(1)
 	}
-	if dir := os.Getenv("SKILLS_DIR"); dir != "" {
-		skillRegistry = skillRegistry.WithOverrideDir(dir)
-	}
 
 	// Audit sink: ConsoleReporter is always on (operator-facing), JSON
 	// auditor only when --audit-file is set. Tee fans out to both.
@@ -120,6 +157,9 @@ func runFix(cmd *cobra.Command, args []string) {
 		defer f.Close()
 		auditSinks = append(auditSinks, harness.NewJSONLogAuditor(f))
 	}
+	// The trail keeps this run's events for the final report.
+	trail := harness.NewAuditTrail(0)
+	auditSinks = append(auditSinks, trail)
 	auditor := harness.NewTee(auditSinks...)
 
 	// Verifier: nil when --no-verify so the remediator falls back to its
@@ -130,64 +170,73 @@ func runFix(cmd *cobra.Command, args []string) {
 		verifier = harness.NewK8sVerifier(k8sClient)
 	}
 
-	coordinator := agent.NewCoordinator(nil, llmClient, stateStore, logger)
+	coordinator := agent.NewCoordinator(nil, llmClient, stateStore, logger).
+		WithAuditor(auditor).
+		WithAuditTrail(trail).
+		WithRemediationIterations(fixIterations)
 
 	// Diagnostician: read-only tools.
-	diagnostician := specialists.NewDiagnosticianAgent(llmClient, logger).
-		WithSkills(skillRegistry)
-	diagnostician.AddTool(pkgtools.NewLogTool(k8sClient))
-	diagnostician.AddTool(pkgtools.NewEventTool(k8sClient))
-	diagnostician.AddTool(pkgtools.NewListTool(k8sClient))
-	diagnostician.AddTool(pkgtools.NewKubeTool())
+	diagnostician := newDiagnostician(llmClient, logger, k8sClient, skillRegistry)
 	if err := coordinator.RegisterAgent(diagnostician); err != nil {
 		fmt.Printf("Failed to register diagnostician: %v\n", err)
 		os.Exit(1)
 	}
 
-	// Preflight chain: protected namespaces + resource-existence invariants.
-	// Fail-closed so a flaky cluster read never silently allows a write.
-	preflight := harness.NewPreflightChain().
-		Add(harness.NewProtectedNamespaceCheck(fixProtected...)).
-		Add(harness.NewResourceExistsCheck(k8sClient))
-
-	// Remediator: write tools + closed-loop sensors.
-	remediator := specialists.NewRemediatorAgent(llmClient, logger).
-		WithSkills(skillRegistry).
-		WithAuditor(auditor).
-		WithVerifier(verifier)
-	// Remediator tool set is deliberately NARROW:
-	//   - HumanTool     : approvals for dangerous writes
-	//   - CreateTool    : submit full YAML (also the "patch" path)
-	//   - DeleteTool    : delete a resource, letting controllers rebuild
-	//
-	// KubeTool is intentionally NOT registered here. Including it
-	// tempted the LLM to reach for `kubectl patch` — which KubeTool
-	// rejects (read-only whitelist) — causing the tool loop to spin
-	// until iteration cap. Read-only state lookup is Diagnostician's
-	// job; by this point its report is already in `task.Input`.
-	remediator.AddTool(pkgtools.NewHumanTool())
-	remediator.AddTool(pkgtools.NewCreateTool(k8sClient).
-		WithPreflight(preflight).
-		WithAuditor(auditor))
-	remediator.AddTool(pkgtools.NewDeleteTool(k8sClient).
-		WithPreflight(preflight).
-		WithAuditor(auditor))
+	// Remediator: write tools behind preflight, consensus and the
+	// operator's approval.
+	// The approver and the plan review share one reader of stdin.
+	lines := harness.NewLineReader(os.Stdin)
+	approvals, err := newApprovalGate(harness.NewTerminalApprover(lines, os.Stdout), auditor)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	remediator, err := newRemediator(remediatorWiring{
+		llmClient:  llmClient,
+		logger:     logger,
+		k8sClient:  k8sClient,
+		stateStore: stateStore,
+		skillSet:   skillRegistry,
+		auditor:    auditor,
+		verifier:   verifier,
+		protected:  fixProtected,
+		approvals:  approvals,
+	})
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
 	if err := coordinator.RegisterAgent(remediator); err != nil {
 		fmt.Printf("Failed to register remediator: %v\n", err)
 		os.Exit(1)
 	}
+	if err := applyAgentModels(coordinator, diagnostician, remediator); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if err := applySchedulerFlags(coordinator); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
 
 	// Build the user request. We synthesize a description when the
 	// operator only supplied --pod / -n, so the Coordinator's planner
 	// has enough material to decompose the work.
 	input := buildFixDescription(fixPod, fixNamespace, fixDescription)
 
+	// Ctrl-C marks running tasks cancelled and checkpoints the plan, so
+	// with --state-file the run can be continued with --resume.
+	reqCtx, stop := interruptible()
+	defer stop()
 	ctx := agent.NewAgentContext(
-		context.Background(),
+		reqCtx,
 		uuid.New().String(),
 		"cli-user",
 		uuid.New().String(),
 	)
+	attachStream(ctx, os.Stdout)
+	ledger := newUsageLedger(prices)
+	ctx.SetUsageLedger(ledger)
 	request := &agent.Request{
 		ID:    uuid.New().String(),
 		User:  "cli-user",
@@ -197,17 +246,35 @@ func runFix(cmd *cobra.Command, args []string) {
 	fmt.Printf("\n=== kubeagent fix ===\n")
 	fmt.Printf("Target:      %s/%s\n", defaultIfEmpty(fixNamespace, "default"), defaultIfEmpty(fixPod, "(from description)"))
 	fmt.Printf("Verifier:    %s\n", verifierLabel(verifier))
+	fmt.Printf("Approvals:   %s\n", approvalLabel("terminal"))
 	fmt.Printf("Audit file:  %s\n", defaultIfEmpty(fixAuditFile, "(console only)"))
 	fmt.Printf("Protected:   %s\n", strings.Join(fixProtected, ", "))
+	fmt.Printf("State file:  %s\n", defaultIfEmpty(fixStateFile, "(in-memory)"))
 	fmt.Println()
 
-	plan, err := coordinator.Plan(ctx, request)
-	if err != nil {
-		fmt.Printf("Planning failed: %v\n", err)
-		os.Exit(1)
+	var response *agent.Response
+	if fixResume != "" {
+		fmt.Printf("Resuming plan %s\n\n", fixResume)
+		response, err = coordinator.ResumePlan(ctx, fixResume)
+	} else {
+		plan, planErr := planRequest(ctx, coordinator, request, lines, os.Stdout)
+		if planErr != nil {
+			fmt.Printf("Planning failed: %v\n", planErr)
+			os.Exit(1)
+		}
+		if plan == nil {
+			return
+		}
+		if fixStateFile != "" {
+			// Persist the reviewed plan, not the one Plan saved before
+			// the operator's edits, so --resume runs what was approved.
+			if err := stateStore.SavePlan(ctx.Context(), plan); err != nil {
+				fmt.Printf("Failed to save plan: %v\n", err)
+			}
+			fmt.Printf("Plan ID:     %s (resume with --resume %s)\n\n", plan.ID, plan.ID)
+		}
+		response, err = coordinator.ExecutePlan(ctx, plan)
 	}
-
-	response, err := coordinator.ExecutePlan(ctx, plan)
 	if err != nil {
 		fmt.Printf("Execution failed: %v\n", err)
 		// Don't exit non-zero immediately — we still want to print the
@@ -223,7 +290,15 @@ func runFix(cmd *cobra.Command, args []string) {
 				fmt.Println(" -", e)
 			}
 		}
+		if fixReportFile != "" {
+			if err := writeReportFile(fixReportFile, response); err != nil {
+				fmt.Printf("Failed to write report: %v\n", err)
+			} else {
+				fmt.Printf("\nReport written to %s\n", fixReportFile)
+			}
+		}
 	}
+	printUsage(os.Stdout, ledger)
 	fmt.Println()
 }
 
@@ -258,3 +333,16 @@ func verifierLabel(v harness.Verifier) string {
 	}
 	return "K8sVerifier (closed loop)"
 }
+
+// writeReportFile writes the response's final report as indented JSON.
+func writeReportFile(path string, response *agent.Response) error {
+	report, ok := response.Data[agent.FinalReportKey]
+	if !ok {
+		return fmt.Errorf("response has no final report")
+	}
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode report: %w", err)
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
@@ -23,7 +23,7 @@ var kubecheckCmd = &cobra.Command{
 		logger := agent.NewSimpleLogger("KubeAgent")
 		stateStore := agent.NewMemoryStateStore()
 
-		llmClient, err := agent.NewAnthropicLLMClient(nil)
+		llmClient, err := agent.NewLLMClient(nil)
 		if err != nil {
 			fmt.Printf("Failed to initialize LLM client: %v\n", err)
 			return
@@ -52,8 +52,10 @@ var kubecheckCmd = &cobra.Command{
 
 		coordinator.RegisterAgent(diagnostician)
 
+		session := newSession(llmClient)
 		scanner := bufio.NewScanner(cmd.InOrStdin())
 		fmt.Println("Hi, I am KubeAgent (KubeCheck mode). Ask me about your cluster health or Kubernetes best practices. (Input 'exit' to quit):")
+		fmt.Println(sessionHelp)
 		for {
 			fmt.Print(">>> ")
 			if !scanner.Scan() {
@@ -64,6 +66,9 @@ var kubecheckCmd = &cobra.Command{
 				fmt.Println("Goodbye!")
 				return
 			}
+			if sessionCommand(session, input, cmd.OutOrStdout()) {
+				continue
+			}
 
 			ctx := agent.NewAgentContext(
 				context.Background(),
@@ -71,6 +76,7 @@ var kubecheckCmd = &cobra.Command{
 				"cli-user",
 				uuid.New().String(),
 			)
+			ctx.SetSession(session)
 			request := &agent.Request{
 				ID:    uuid.New().String(),
 				User:  "cli-user",
@@ -79,11 +85,13 @@ var kubecheckCmd = &cobra.Command{
 
 			plan, err := coordinator.Plan(ctx, request)
 			if err != nil {
+				session.Record(ctx, request, nil, nil)
 				fmt.Printf("Planning failed: %v\n", err)
 				continue
 			}
 
 			response, err := coordinator.ExecutePlan(ctx, plan)
+			session.Record(ctx, request, plan, response)
 			if err != nil {
 				fmt.Printf("Execution failed: %v\n", err)
 				continue
@@ -0,0 +1,180 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"kubeagent/pkg/agent"
+)
+
+// llmCassette is shared by every command that talks to the LLM; only
+// one command runs per process, so a single package var is enough.
+var llmCassette string
+
+// Routing flags, shared the same way.
+var (
+	llmFallbacks []string
+	agentModels  map[string]string
+)
+
+// addLLMCassetteFlag registers --llm-cassette on cmd.
+func addLLMCassetteFlag(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&llmCassette, "llm-cassette", "",
+		"Record LLM traffic to this file, or replay it if the file already exists (no API key needed on replay)")
+}
+
+// addLLMRoutingFlags registers --llm-fallback and --agent-model on cmd.
+func addLLMRoutingFlags(cmd *cobra.Command) {
+	cmd.Flags().StringSliceVar(&llmFallbacks, "llm-fallback", nil,
+		"Providers to fall back to, in order, when the primary LLM is down (anthropic, minimax, dashscope, openai); each needs its API key set")
+	cmd.Flags().StringToStringVar(&agentModels, "agent-model", nil,
+		"Per-agent model as agent=[provider:]model, e.g. coordinator=claude-3-5-haiku-latest,diagnostician=dashscope:qwen-max")
+}
+
+// newLLMClient builds the LLM client for a command.
+//
+// Without --llm-cassette this is the client for whichever provider
+// agent.NewLLMClient detects. With it, the cassette file decides the
+// mode: if it exists it is replayed, otherwise the real client is
+// wrapped and every call is recorded to it. Delete the file to
+// re-record.
+//
+// Replay uses sequential fallback because the CLI still runs tools
+// against a live cluster: pod logs and event timestamps differ between
+// runs, which changes the request hash even when the conversation is
+// the same.
+func newLLMClient(logger agent.Logger) (agent.LLMClient, error) {
+	if llmCassette == "" {
+		return newProviderClient(logger)
+	}
+
+	_, err := os.Stat(llmCassette)
+	switch {
+	case err == nil:
+		replay, err := agent.NewReplayLLMClient(llmCassette)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Printf("LLM cassette: replaying %s\n", llmCassette)
+		return replay.WithSequentialFallback(), nil
+	case errors.Is(err, fs.ErrNotExist):
+		inner, err := newProviderClient(logger)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Printf("LLM cassette: recording to %s\n", llmCassette)
+		return agent.NewRecordingLLMClient(inner, llmCassette), nil
+	default:
+		return nil, fmt.Errorf("failed to stat cassette %s: %w", llmCassette, err)
+	}
+}
+
+// newProviderClient is the detected provider's client, behind a
+// RouterLLMClient when --llm-fallback, --agent-model or
+// --consensus-reviewer asks for routing.
+func newProviderClient(logger agent.Logger) (agent.LLMClient, error) {
+	if len(llmFallbacks) == 0 && len(routedModelSpecs()) == 0 {
+		return agent.NewLLMClient(nil)
+	}
+
+	primaryConfig := agent.DetectLLMConfig()
+	if primaryConfig == nil {
+		return nil, fmt.Errorf("no API key found for the primary LLM provider")
+	}
+	primary, err := agent.NewLLMBackend(primaryConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	backends := make([]*agent.LLMBackend, 0, len(llmFallbacks))
+	for _, provider := range llmFallbacks {
+		config := agent.LLMConfigFromEnv(provider)
+		if config == nil {
+			return nil, fmt.Errorf("fallback provider %s: unknown provider or API key not set", provider)
+		}
+		if strings.EqualFold(config.Provider, primaryConfig.Provider) {
+			continue
+		}
+		backend, err := agent.NewLLMBackend(config)
+		if err != nil {
+			return nil, err
+		}
+		backends = append(backends, backend)
+	}
+
+	// Per-agent and reviewer providers must be routable even when they
+	// are not listed as fallbacks.
+	for _, spec := range routedModelSpecs() {
+		provider, _, ok := strings.Cut(spec, ":")
+		if !ok || strings.EqualFold(provider, primaryConfig.Provider) || hasBackend(backends, provider) {
+			continue
+		}
+		config := agent.LLMConfigFromEnv(provider)
+		if config == nil {
+			return nil, fmt.Errorf("model %s: unknown provider or API key not set", spec)
+		}
+		backend, err := agent.NewLLMBackend(config)
+		if err != nil {
+			return nil, err
+		}
+		backends = append(backends, backend)
+	}
+
+	return agent.NewRouterLLMClient(logger, primary, backends...), nil
+}
+
+func hasBackend(backends []*agent.LLMBackend, provider string) bool {
+	for _, b := range backends {
+		if strings.EqualFold(b.Name, provider) {
+			return true
+		}
+	}
+	return false
+}
+
+// applyAgentModels copies --agent-model onto the agents' configs; the
+// router reads AgentConfig.LLMConfig on every call.
+func applyAgentModels(agents ...agent.Agent) error {
+	byType := make(map[string]agent.Agent, len(agents))
+	for _, a := range agents {
+		byType[string(a.Type())] = a
+	}
+	for agentType, spec := range agentModels {
+		a, ok := byType[agentType]
+		if !ok {
+			return fmt.Errorf("--agent-model: no %s agent in this command", agentType)
+		}
+		a.Config().LLMConfig = parseModelSpec(spec)
+	}
+	return nil
+}
+
+// parseModelSpec reads [provider:]model; without a provider the model
+// is served by the primary provider.
+func parseModelSpec(spec string) *agent.LLMConfig {
+	provider, model, ok := strings.Cut(spec, ":")
+	if !ok {
+		provider, model = "", spec
+	}
+	return &agent.LLMConfig{Provider: provider, Model: model}
+}
+
+// routedModelSpecs lists every [provider:]model a command routes to:
+// --agent-model and --consensus-reviewer values.
+func routedModelSpecs() []string {
+	specs := make([]string, 0, len(agentModels)+len(consensusReviewers))
+	for _, spec := range agentModels {
+		specs = append(specs, spec)
+	}
+	for _, spec := range consensusReviewers {
+		if spec != "" {
+			specs = append(specs, spec)
+		}
+	}
+	return specs
+}
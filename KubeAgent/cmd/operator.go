@@ -0,0 +1,288 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr/funcr"
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
+	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+
+	"kubeagent/pkg/agent"
+	"kubeagent/pkg/agent/harness"
+	"kubeagent/pkg/api"
+	"kubeagent/pkg/k8s"
+	"kubeagent/pkg/operator"
+	"kubeagent/pkg/operator/v1alpha1"
+)
+
+// Flags for `kubeagent operator`.
+var (
+	operatorNamespaces    []string
+	operatorMetricsAddr   string
+	operatorHealthAddr    string
+	operatorLeaderElect   bool
+	operatorPollInterval  time.Duration
+	operatorMaxConcurrent int
+	operatorProtected     []string
+	operatorAuditFile     string
+	operatorStateFile     string
+)
+
+// operatorCmd runs KubeAgent as a controller: every DiagnosisTask
+// becomes a coordinator request, and its status follows the plan, the
+// approvals and the final report.
+//
+// Requests run on the same engine as `serve`, without the HTTP API.
+// Approvals are answered by annotating the DiagnosisTask. For tasks
+// that do not set approvalRequired the operator approves the plan, and
+// the changes --auto-approve allows.
+var operatorCmd = &cobra.Command{
+	Use:   "operator",
+	Short: "Run the DiagnosisTask controller in the cluster",
+	Long: `Reconcile kubeagent.io/v1alpha1 DiagnosisTasks: diagnose (and, with autoRemediate, fix)
+each task's target and write the plan, root cause, task progress and verification to its status.
+
+Install the CRD first:
+  kubectl apply -f deploy/crd/
+
+Tasks with approvalRequired wait in phase AwaitingApproval until annotated with the
+ID shown in status.pendingApproval:
+  kubectl annotate dt web-1 kubeagent.io/approve=<id> --overwrite
+  kubectl annotate dt web-1 kubeagent.io/reject=<id> kubeagent.io/reason="not now" --overwrite
+
+Without approvalRequired the operator approves the plan, and the changes the remediator
+asks to confirm up to the --auto-approve risk; riskier changes wait for an annotation
+like above.
+
+Requests run in the operator's process: tasks that were running when it restarted
+fail instead of resuming, with RequestLost or, given --state-file, from the plan
+stored there. Finished tasks keep their status either way.
+
+Examples:
+  kubeagent operator --leader-elect --audit-file /var/log/kubeagent/audit.jsonl
+  kubeagent operator -n shop -n staging --poll-interval 10s`,
+	Run: runOperator,
+}
+
+func init() {
+	operatorCmd.Flags().StringSliceVarP(&operatorNamespaces, "namespace", "n", nil, "Namespaces whose DiagnosisTasks are reconciled (repeatable; default all)")
+	operatorCmd.Flags().StringVar(&operatorMetricsAddr, "metrics-addr", "0", "Address of the controller metrics endpoint (0 disables)")
+	operatorCmd.Flags().StringVar(&operatorHealthAddr, "health-addr", ":8081", "Address of the /healthz and /readyz probes")
+	operatorCmd.Flags().BoolVar(&operatorLeaderElect, "leader-elect", false, "Elect a leader so only one replica reconciles")
+	operatorCmd.Flags().DurationVar(&operatorPollInterval, "poll-interval", operator.DefaultPollInterval, "How often a running task's status is refreshed")
+	operatorCmd.Flags().IntVar(&operatorMaxConcurrent, "max-concurrent", api.DefaultMaxConcurrent, "Tasks planned or executed at once; the rest queue")
+	operatorCmd.Flags().StringSliceVar(&operatorProtected, "protected", []string{"kube-system", "kube-public", "kube-node-lease"}, "Namespaces that must never be mutated")
+	operatorCmd.Flags().StringVar(&operatorAuditFile, "audit-file", "", "Path to a JSONL audit log file")
+	operatorCmd.Flags().StringVar(&operatorStateFile, "state-file", "", "Path to a durable state file for plans, tasks and rollback snapshots")
+
+	addLLMCassetteFlag(operatorCmd)
+	addLLMRoutingFlags(operatorCmd)
+	addSchedulerFlags(operatorCmd)
+	addConsensusFlags(operatorCmd)
+	addUsageFlags(operatorCmd)
+	addApprovalFlags(operatorCmd)
+	rootCmd.AddCommand(operatorCmd)
+}
+
+func runOperator(cmd *cobra.Command, args []string) {
+	logger := agent.NewSimpleLogger("KubeAgent")
+	stateStore, closeStateStore, err := newStateStore(operatorStateFile)
+	if err != nil {
+		fmt.Printf("Failed to open state file: %v\n", err)
+		os.Exit(1)
+	}
+	defer closeStateStore()
+
+	llmClient, err := newLLMClient(logger)
+	if err != nil {
+		fmt.Printf("Failed to initialize LLM client: %v\n", err)
+		os.Exit(1)
+	}
+	prices, err := loadPriceTable()
+	if err != nil {
+		fmt.Printf("Failed to load price table: %v\n", err)
+		os.Exit(1)
+	}
+	k8sClient, err := k8s.NewClient()
+	if err != nil {
+		fmt.Printf("Failed to initialize K8s client: %v\n", err)
+		os.Exit(1)
+	}
+	restConfig, err := k8s.RestConfig()
+	if err != nil {
+		fmt.Printf("Failed to load cluster config: %v\n", err)
+		os.Exit(1)
+	}
+	skillRegistry, err := loadSkills()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	// The DiagnosisTask decides whether a plan waits for approval, so
+	// the server never holds one on its own.
+	coordinator := agent.NewCoordinator(nil, llmClient, stateStore, logger)
+	server := api.NewServer(coordinator, logger).
+		WithStateStore(stateStore).
+		WithMaxConcurrent(operatorMaxConcurrent).
+		WithRemediationApproval(false).
+		WithContextSetup(func(ctx *agent.AgentContext) {
+			ctx.SetUsageLedger(newUsageLedger(prices))
+		})
+
+	auditSinks := []harness.AuditLogger{server.Auditor()}
+	if operatorAuditFile != "" {
+		f, err := os.OpenFile(operatorAuditFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			fmt.Printf("Failed to open audit file %q: %v\n", operatorAuditFile, err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		auditSinks = append(auditSinks, harness.NewJSONLogAuditor(f))
+	}
+	trail := harness.NewAuditTrail(0)
+	auditSinks = append(auditSinks, trail)
+	auditor := harness.NewTee(auditSinks...)
+	coordinator.WithAuditor(auditor).WithAuditTrail(trail)
+
+	queue := harness.NewHTTPApprover(stateStore)
+	if expired, err := queue.ExpireStale(context.Background()); err != nil {
+		fmt.Printf("Failed to load pending approvals: %v\n", err)
+		os.Exit(1)
+	} else if expired > 0 {
+		fmt.Printf("Expired %d approval(s) left pending by a previous run\n", expired)
+	}
+	// The DiagnosisTask decides which approvals the --auto-approve
+	// policy may answer, so every approval waits in the queue for the
+	// reconciler or an annotation.
+	autoApprove, err := autoApprovePolicy()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	approvals := harness.NewApprovalGate(queue).
+		WithTimeout(approvalTimeout).
+		WithAuditor(auditor)
+	server.WithApprovals(approvals, queue)
+
+	diagnostician := newDiagnostician(llmClient, logger, k8sClient, skillRegistry)
+	if err := coordinator.RegisterAgent(diagnostician); err != nil {
+		fmt.Printf("Failed to register diagnostician: %v\n", err)
+		os.Exit(1)
+	}
+	remediator, err := newRemediator(remediatorWiring{
+		llmClient:  llmClient,
+		logger:     logger,
+		k8sClient:  k8sClient,
+		stateStore: stateStore,
+		skillSet:   skillRegistry,
+		auditor:    auditor,
+		verifier:   harness.NewK8sVerifier(k8sClient),
+		protected:  operatorProtected,
+		approvals:  approvals,
+	})
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if err := coordinator.RegisterAgent(remediator); err != nil {
+		fmt.Printf("Failed to register remediator: %v\n", err)
+		os.Exit(1)
+	}
+	if err := applyAgentModels(diagnostician, remediator); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if err := applySchedulerFlags(coordinator); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	mgr, err := newOperatorManager(restConfig, logger)
+	if err != nil {
+		fmt.Printf("Failed to create controller manager: %v\n", err)
+		os.Exit(1)
+	}
+	reconciler := operator.NewDiagnosisTaskReconciler(mgr.GetClient(), server, logger).
+		WithPollInterval(operatorPollInterval).
+		WithAutoApprove(autoApprove)
+	server.OnApproval(reconciler.NotifyApproval)
+	if err := reconciler.SetupWithManager(mgr); err != nil {
+		fmt.Printf("Failed to set up the DiagnosisTask controller: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("\n=== kubeagent operator ===\n")
+	fmt.Printf("Namespaces:  %s\n", defaultIfEmpty(strings.Join(operatorNamespaces, ", "), "(all)"))
+	approvalsLabel := "annotate kubeagent.io/approve=<id>"
+	if autoApprove != "" {
+		approvalsLabel += fmt.Sprintf("; auto-policy up to %s risk without approvalRequired", autoApprove)
+	}
+	fmt.Printf("Approvals:   %s, timeout %s\n", approvalsLabel, approvalTimeout)
+	fmt.Printf("Leader:      %v\n", operatorLeaderElect)
+	fmt.Printf("Protected:   %s\n", strings.Join(operatorProtected, ", "))
+	fmt.Printf("State file:  %s\n", defaultIfEmpty(operatorStateFile, "(in-memory)"))
+	fmt.Println()
+
+	ctx, stop := interruptible()
+	defer stop()
+	err = mgr.Start(ctx)
+	// Let cancelled requests checkpoint their plans before exiting.
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	server.Stop(shutdownCtx)
+	if err != nil {
+		fmt.Printf("Operator failed: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// newOperatorManager builds the controller-runtime manager from the
+// operator flags, logging through logger.
+func newOperatorManager(config *rest.Config, logger agent.Logger) (ctrl.Manager, error) {
+	ctrl.SetLogger(funcr.New(func(prefix, args string) {
+		logger.Debug("controller-runtime", map[string]interface{}{"logger": prefix, "message": args})
+	}, funcr.Options{}))
+
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		return nil, err
+	}
+	if err := v1alpha1.AddToScheme(scheme); err != nil {
+		return nil, err
+	}
+	options := ctrl.Options{
+		Scheme:                 scheme,
+		Metrics:                metricsserver.Options{BindAddress: operatorMetricsAddr},
+		HealthProbeBindAddress: operatorHealthAddr,
+		LeaderElection:         operatorLeaderElect,
+		LeaderElectionID:       "kubeagent-operator.kubeagent.io",
+	}
+	if len(operatorNamespaces) > 0 {
+		options.Cache.DefaultNamespaces = make(map[string]cache.Config, len(operatorNamespaces))
+		for _, namespace := range operatorNamespaces {
+			options.Cache.DefaultNamespaces[namespace] = cache.Config{}
+		}
+	}
+	mgr, err := ctrl.NewManager(config, options)
+	if err != nil {
+		return nil, err
+	}
+	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
+		return nil, err
+	}
+	if err := mgr.AddReadyzCheck("readyz", healthz.Ping); err != nil {
+		return nil, err
+	}
+	return mgr, nil
+}
@@ -0,0 +1,328 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"kubeagent/pkg/agent"
+	"kubeagent/pkg/agent/harness"
+)
+
+// Plan review flags, shared like llmCassette: one command per process.
+var (
+	planOutFile string
+	planInFile  string
+	autoApprove bool
+)
+
+// addPlanReviewFlags registers --plan-out, --plan-in and --yes on cmd.
+func addPlanReviewFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&planOutFile, "plan-out", "",
+		"Write the reviewed execution plan to this JSON file instead of executing it")
+	cmd.Flags().StringVar(&planInFile, "plan-in", "",
+		"Execute the plan in this JSON file (from --plan-out) instead of planning the request")
+	cmd.Flags().BoolVarP(&autoApprove, "yes", "y", false,
+		"Execute the plan without the interactive review")
+}
+
+// planRequest produces the plan for request: loaded from --plan-in or
+// made by the coordinator, then reviewed on in/out unless --yes. It
+// returns a nil plan when there is nothing to execute, because the
+// operator aborted or the plan went to --plan-out.
+func planRequest(ctx *agent.AgentContext, coordinator *agent.BaseCoordinator, request *agent.Request, in *harness.LineReader, out io.Writer) (*agent.ExecutionPlan, error) {
+	var plan *agent.ExecutionPlan
+	var err error
+	if planInFile != "" {
+		plan, err = loadPlanFile(planInFile)
+	} else {
+		plan, err = coordinator.Plan(ctx, request)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if !autoApprove {
+		approved, err := reviewPlan(plan, in, out)
+		if err != nil {
+			return nil, err
+		}
+		if !approved {
+			fmt.Fprintln(out, "Plan aborted; nothing was executed.")
+			return nil, nil
+		}
+	}
+
+	if planOutFile != "" {
+		if err := savePlanFile(planOutFile, plan); err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(out, "Plan written to %s; execute it with --plan-in %s\n", planOutFile, planOutFile)
+		return nil, nil
+	}
+	return plan, nil
+}
+
+// loadPlanFile reads a plan written by --plan-out. Only plans that have
+// not started are accepted; an interrupted run is continued with
+// `fix --resume` instead.
+func loadPlanFile(path string) (*agent.ExecutionPlan, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plan: %w", err)
+	}
+	plan := &agent.ExecutionPlan{}
+	if err := json.Unmarshal(data, plan); err != nil {
+		return nil, fmt.Errorf("failed to parse plan %s: %w", path, err)
+	}
+	if len(plan.Tasks) == 0 {
+		return nil, fmt.Errorf("plan %s has no tasks", path)
+	}
+	for _, task := range plan.Tasks {
+		if task.Status != "" && task.Status != agent.TaskStatusPending {
+			return nil, fmt.Errorf("plan %s already ran (task %s is %s)", path, task.ID, task.Status)
+		}
+	}
+	return plan, nil
+}
+
+func savePlanFile(path string, plan *agent.ExecutionPlan) error {
+	data, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode plan: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write plan: %w", err)
+	}
+	return nil
+}
+
+const planReviewHelp = `  a | approve                 execute this plan
+  d | drop <task>             remove a task and every task depending on it
+  e | edit <task> key=value   set an input (value may be JSON; empty value removes the key)
+  s | show                    print the plan again
+  q | abort                   discard the plan`
+
+// reviewPlan shows plan and applies the operator's edits until they
+// approve or abort. Running out of input aborts: an unattended run must
+// opt in with --yes.
+func reviewPlan(plan *agent.ExecutionPlan, in *harness.LineReader, out io.Writer) (bool, error) {
+	renderPlan(out, plan)
+	fmt.Fprintln(out, planReviewHelp)
+
+	var dropped, edited []string
+	for {
+		fmt.Fprint(out, "plan> ")
+		line, err := in.ReadLine(context.Background())
+		if err != nil {
+			fmt.Fprintln(out)
+			if err == io.EOF {
+				return false, nil
+			}
+			return false, err
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch fields[0] {
+		case "a", "approve", "y", "yes":
+			if len(plan.Tasks) == 0 {
+				fmt.Fprintln(out, "The plan has no tasks left; abort instead.")
+				continue
+			}
+			if plan.Metadata == nil {
+				plan.Metadata = map[string]interface{}{}
+			}
+			plan.Metadata["review"] = map[string]interface{}{
+				"approved_at": time.Now(),
+				"dropped":     dropped,
+				"edited":      edited,
+			}
+			plan.UpdatedAt = time.Now()
+			return true, nil
+
+		case "q", "abort", "n", "no":
+			return false, nil
+
+		case "d", "drop":
+			if len(fields) != 2 {
+				fmt.Fprintln(out, "usage: drop <task>")
+				continue
+			}
+			removed, err := plan.DropTask(fields[1])
+			if err != nil {
+				fmt.Fprintln(out, err)
+				continue
+			}
+			dropped = append(dropped, removed...)
+			fmt.Fprintf(out, "Dropped %s\n", strings.Join(removed, ", "))
+
+		case "e", "edit":
+			if len(fields) < 3 {
+				fmt.Fprintln(out, "usage: edit <task> key=value")
+				continue
+			}
+			task := plan.Task(fields[1])
+			if task == nil {
+				fmt.Fprintf(out, "plan has no task %q\n", fields[1])
+				continue
+			}
+			// Values may contain spaces; take everything after the ID.
+			assignment := strings.TrimPrefix(strings.TrimSpace(line), fields[0])
+			assignment = strings.TrimPrefix(strings.TrimSpace(assignment), fields[1])
+			key, value, ok := strings.Cut(assignment, "=")
+			if !ok || strings.TrimSpace(key) == "" {
+				fmt.Fprintln(out, "usage: edit <task> key=value")
+				continue
+			}
+			setTaskInput(task, strings.TrimSpace(key), strings.TrimSpace(value))
+			if len(edited) == 0 || edited[len(edited)-1] != task.ID {
+				edited = append(edited, task.ID)
+			}
+			fmt.Fprintf(out, "%s input: %s\n", task.ID, compactJSON(task.Input))
+
+		case "s", "show":
+			renderPlan(out, plan)
+
+		default:
+			fmt.Fprintln(out, planReviewHelp)
+		}
+	}
+}
+
+// setTaskInput sets key to value, decoded as JSON when it parses so
+// numbers and objects keep their type. An empty value removes key.
+func setTaskInput(task *agent.Task, key, value string) {
+	if value == "" {
+		delete(task.Input, key)
+		return
+	}
+	if task.Input == nil {
+		task.Input = map[string]interface{}{}
+	}
+	var decoded interface{}
+	if err := json.Unmarshal([]byte(value), &decoded); err == nil {
+		task.Input[key] = decoded
+		return
+	}
+	task.Input[key] = value
+}
+
+// renderPlan prints the plan's DAG as stages: every task in a stage
+// depends only on tasks in earlier stages, so a stage's tasks run in
+// parallel.
+func renderPlan(w io.Writer, plan *agent.ExecutionPlan) {
+	fmt.Fprintf(w, "\n========== Execution Plan ==========\n")
+	fmt.Fprintf(w, "Plan %s, %d task(s)\n", plan.ID, len(plan.Tasks))
+
+	stages := planStages(plan.Tasks)
+	for i, stage := range stages {
+		fmt.Fprintf(w, "\nStage %d\n", i+1)
+		for _, task := range stage {
+			agentType := string(task.AssignedAgent)
+			if agentType == "" {
+				agentType = "(by task type)"
+			}
+			fmt.Fprintf(w, "  • %s  [%s → %s]\n", task.ID, task.Type, agentType)
+			fmt.Fprintf(w, "      %s\n", task.Description)
+			if route := plan.Routing(task.ID); route != nil && len(route.Candidates) > 0 {
+				best := route.Candidates[0]
+				fmt.Fprintf(w, "      route: %s (%s)", best.Agent, strings.Join(best.Reasons, "; "))
+				if route.Fallback != "" {
+					fmt.Fprintf(w, ", fallback %s", route.Fallback)
+				}
+				fmt.Fprintln(w)
+			}
+			if len(task.Dependencies) > 0 {
+				fmt.Fprintf(w, "      after: %s\n", strings.Join(task.Dependencies, ", "))
+			}
+			if c := task.Condition; c != nil {
+				if len(c.OnSuccess) > 0 {
+					fmt.Fprintf(w, "      only if succeeded: %s\n", strings.Join(c.OnSuccess, ", "))
+				}
+				if len(c.OnFailure) > 0 {
+					fmt.Fprintf(w, "      only if any failed: %s\n", strings.Join(c.OnFailure, ", "))
+				}
+			}
+			if len(task.Input) > 0 {
+				fmt.Fprintf(w, "      input: %s\n", compactJSON(task.Input))
+			}
+		}
+	}
+	fmt.Fprintln(w)
+}
+
+// planStages groups tasks by dependency depth. Tasks caught in a cycle
+// or depending on a missing task end up in a last stage of their own;
+// ExecutePlan rejects such a plan anyway.
+func planStages(tasks []*agent.Task) [][]*agent.Task {
+	if len(tasks) == 0 {
+		return nil
+	}
+	byID := make(map[string]*agent.Task, len(tasks))
+	for _, task := range tasks {
+		byID[task.ID] = task
+	}
+
+	depth := make(map[string]int, len(tasks))
+	for changed := true; changed; {
+		changed = false
+		for _, task := range tasks {
+			if _, done := depth[task.ID]; done {
+				continue
+			}
+			d, ready := 0, true
+			for _, dep := range task.Dependencies {
+				depDepth, ok := depth[dep]
+				if !ok || byID[dep] == nil {
+					ready = false
+					break
+				}
+				if depDepth+1 > d {
+					d = depDepth + 1
+				}
+			}
+			if ready {
+				depth[task.ID] = d
+				changed = true
+			}
+		}
+	}
+
+	maxDepth := 0
+	for _, d := range depth {
+		if d > maxDepth {
+			maxDepth = d
+		}
+	}
+	stages := make([][]*agent.Task, maxDepth+1)
+	var unresolved []*agent.Task
+	for _, task := range tasks {
+		if d, ok := depth[task.ID]; ok {
+			stages[d] = append(stages[d], task)
+		} else {
+			unresolved = append(unresolved, task)
+		}
+	}
+	if len(unresolved) > 0 {
+		stages = append(stages, unresolved)
+	}
+	return stages
+}
+
+// compactJSON renders v on one line; map keys come out sorted.
+func compactJSON(v map[string]interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
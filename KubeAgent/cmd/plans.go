@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"kubeagent/pkg/agent"
+)
+
+// Flags for `kubeagent plans`.
+var (
+	plansStateFile string
+)
+
+// plansCmd lists what a durable state file (see `fix --state-file`)
+// holds, so an operator can find the plan ID of an interrupted run and
+// hand it to `fix --resume`. Read-only: no LLM, no cluster access.
+var plansCmd = &cobra.Command{
+	Use:   "plans",
+	Short: "List execution plans saved in a state file",
+	Long: `List the execution plans persisted by 'kubeagent fix --state-file', with
+the status of every task. Use the plan ID with 'kubeagent fix --resume' to
+continue a run that was interrupted.
+
+Examples:
+  kubeagent plans --state-file ~/.kubeagent/state.db
+  kubeagent fix --state-file ~/.kubeagent/state.db --resume <plan-id>`,
+	Run: runPlans,
+}
+
+func init() {
+	plansCmd.Flags().StringVar(&plansStateFile, "state-file", "", "Path to the state file written by 'fix --state-file'")
+	plansCmd.MarkFlagRequired("state-file")
+
+	rootCmd.AddCommand(plansCmd)
+}
+
+func runPlans(_ *cobra.Command, _ []string) {
+	store, err := agent.NewBoltStateStore(plansStateFile)
+	if err != nil {
+		fmt.Printf("Failed to open state file: %v\n", err)
+		os.Exit(1)
+	}
+	defer store.Close()
+
+	plans, err := store.ListPlans(context.Background())
+	if err != nil {
+		fmt.Printf("Failed to list plans: %v\n", err)
+		os.Exit(1)
+	}
+	if len(plans) == 0 {
+		fmt.Println("No plans saved.")
+		return
+	}
+
+	for _, plan := range plans {
+		fmt.Printf("%s  %-9s  %s  (%d tasks)\n",
+			plan.ID, plan.Status, plan.CreatedAt.Format(time.RFC3339), len(plan.Tasks))
+		for _, task := range plan.Tasks {
+			line := fmt.Sprintf("    - %-30s %-9s %s", task.ID, task.Status, task.Type)
+			if n := len(task.Attempts); n > 1 {
+				line += fmt.Sprintf(" [%d attempts]", n)
+			}
+			if task.Error != "" {
+				line += "  (" + task.Error + ")"
+			}
+			fmt.Println(line)
+		}
+	}
+}
+
+// newStateStore picks the StateStore for a command: the durable bbolt
+// store when path is set, otherwise the in-memory default. The returned
+// close func is always safe to defer.
+func newStateStore(path string) (agent.StateStore, func(), error) {
+	if path == "" {
+		return agent.NewMemoryStateStore(), func() {}, nil
+	}
+	store, err := agent.NewBoltStateStore(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return store, func() { store.Close() }, nil
+}
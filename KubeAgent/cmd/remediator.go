@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"kubeagent/pkg/agent"
+	"kubeagent/pkg/agent/harness"
+	"kubeagent/pkg/agent/skills"
+	"kubeagent/pkg/agent/specialists"
+	"kubeagent/pkg/k8s"
+	pkgtools "kubeagent/pkg/tools"
+)
+
+// remediatorWiring is what `fix`, `watch` and `serve` share to build a
+// Remediator: the same sensors, preflight chain and write tools.
+type remediatorWiring struct {
+	llmClient  agent.LLMClient
+	logger     agent.Logger
+	k8sClient  *k8s.Client
+	stateStore agent.StateStore
+	skillSet   *harness.Skills
+	auditor    harness.AuditLogger
+	verifier   harness.Verifier
+	protected  []string
+
+	// approvals adds HumanTool so dangerous writes and dry-run diffs
+	// are approved through it: at the terminal, over HTTP or by
+	// policy. Nil means nobody is there to ask: preflight, consensus,
+	// verification and rollback are the only guards.
+	approvals *harness.ApprovalGate
+}
+
+// newRemediator builds the Remediator with its narrow write tool set.
+func newRemediator(w remediatorWiring) (*specialists.RemediatorAgent, error) {
+	// Preflight chain: protected namespaces + resource-existence invariants.
+	// Fail-closed so a flaky cluster read never silently allows a write.
+	preflight := harness.NewPreflightChain().
+		Add(harness.NewProtectedNamespaceCheck(w.protected...)).
+		Add(harness.NewResourceExistsCheck(w.k8sClient))
+
+	// Consensus goes last so reviewers are only asked about writes the
+	// cheap checks already allow.
+	consensus, err := newConsensusCheck(w.llmClient, w.skillSet, w.auditor)
+	if err != nil {
+		return nil, err
+	}
+	if consensus != nil {
+		preflight.Add(consensus)
+	}
+
+	// Remediator: write tools + closed-loop sensors. The snapshotter
+	// is shared with every write tool so a fix that fails verification
+	// is rolled back; snapshots go to the state store (durable with
+	// --state-file) keyed by request ID.
+	snapshots := specialists.NewSnapshotter(w.k8sClient, w.stateStore)
+	remediator := specialists.NewRemediatorAgent(w.llmClient, w.logger).
+		WithSkills(w.skillSet).
+		WithAuditor(w.auditor).
+		WithVerifier(w.verifier).
+		WithSnapshots(snapshots)
+	// Remediator tool set is deliberately NARROW:
+	//   - HumanTool     : approvals for dangerous writes (with approvals only)
+	//   - PatchTool     : change fields of a live object in place
+	//   - ApplyTool     : server-side apply of a full YAML
+	//   - CreateTool    : submit a new object from full YAML
+	//   - DeleteTool    : delete a resource, letting controllers rebuild
+	//
+	// Every Patch/Apply/Create is dry-run first and its diff approved
+	// through HumanTool via the shared preview board.
+	//
+	// KubeTool is intentionally NOT registered here. Including it
+	// tempted the LLM to reach for `kubectl patch` — which KubeTool
+	// rejects (read-only whitelist) — causing the tool loop to spin
+	// until iteration cap. Read-only state lookup is Diagnostician's
+	// job; by this point its report is already in `task.Input`.
+	previews := pkgtools.NewChangePreviews()
+	if w.approvals != nil {
+		remediator.AddTool(pkgtools.NewHumanTool().WithChangePreviews(previews).WithApprover(w.approvals))
+	}
+	remediator.AddTool(pkgtools.NewPatchTool(w.k8sClient).
+		WithChangePreviews(previews).
+		WithPreflight(preflight).
+		WithAuditor(w.auditor).
+		WithSnapshots(snapshots))
+	remediator.AddTool(pkgtools.NewApplyTool(w.k8sClient).
+		WithChangePreviews(previews).
+		WithPreflight(preflight).
+		WithAuditor(w.auditor).
+		WithSnapshots(snapshots))
+	remediator.AddTool(pkgtools.NewCreateTool(w.k8sClient).
+		WithChangePreviews(previews).
+		WithPreflight(preflight).
+		WithAuditor(w.auditor).
+		WithSnapshots(snapshots))
+	remediator.AddTool(pkgtools.NewDeleteTool(w.k8sClient).
+		WithPreflight(preflight).
+		WithAuditor(w.auditor).
+		WithSnapshots(snapshots))
+	return remediator, nil
+}
+
+// newDiagnostician builds the Diagnostician with its read-only tools.
+func newDiagnostician(llmClient agent.LLMClient, logger agent.Logger, k8sClient *k8s.Client, skillSet *harness.Skills) *specialists.DiagnosticianAgent {
+	diagnostician := specialists.NewDiagnosticianAgent(llmClient, logger).
+		WithSkills(skillSet)
+	diagnostician.AddTool(pkgtools.NewLogTool(k8sClient))
+	diagnostician.AddTool(pkgtools.NewEventTool(k8sClient))
+	diagnostician.AddTool(pkgtools.NewListTool(k8sClient))
+	diagnostician.AddTool(pkgtools.NewKubeTool())
+	return diagnostician
+}
+
+// loadSkills loads the embedded skill prompts, overridable at runtime
+// via SKILLS_DIR (operators can drop a tweaked diagnose.md without
+// rebuilding).
+func loadSkills() (*harness.Skills, error) {
+	skillRegistry, err := harness.NewSkillsFromFS(skills.FS())
+	if err != nil {
+		return nil, fmt.Errorf("failed to load skills: %w", err)
+	}
+	if dir := os.Getenv("SKILLS_DIR"); dir != "" {
+		skillRegistry = skillRegistry.WithOverrideDir(dir)
+	}
+	return skillRegistry, nil
+}
@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"kubeagent/pkg/agent"
+)
+
+// Scheduler flags, shared like llmCassette: one command per process.
+var (
+	maxParallel    int
+	agentParallel  map[string]int
+	agentIntervals map[string]string
+)
+
+// addSchedulerFlags registers --max-parallel, --agent-parallel and
+// --agent-interval on cmd.
+func addSchedulerFlags(cmd *cobra.Command) {
+	cmd.Flags().IntVar(&maxParallel, "max-parallel", agent.DefaultMaxConcurrency,
+		"Run at most this many plan tasks at once (0 = no limit)")
+	cmd.Flags().StringToIntVar(&agentParallel, "agent-parallel", nil,
+		"Per-agent cap on tasks running at once as agent=n, e.g. remediator=1")
+	cmd.Flags().StringToStringVar(&agentIntervals, "agent-interval", nil,
+		"Per-agent minimum time between task starts as agent=duration, e.g. diagnostician=2s")
+}
+
+// applySchedulerFlags sets the coordinator's scheduler from the flags.
+// Like --agent-model, naming an agent the command does not run is an
+// error rather than a silently ignored limit.
+func applySchedulerFlags(coordinator *agent.BaseCoordinator) error {
+	config := agent.SchedulerConfig{
+		MaxConcurrency: maxParallel,
+		Agents:         map[agent.AgentType]agent.AgentLimit{},
+	}
+	for name, n := range agentParallel {
+		agentType := agent.AgentType(name)
+		if _, err := coordinator.GetAgent(agentType); err != nil {
+			return fmt.Errorf("--agent-parallel: no %s agent in this command", name)
+		}
+		limit := config.Agents[agentType]
+		limit.MaxConcurrency = n
+		config.Agents[agentType] = limit
+	}
+	for name, spec := range agentIntervals {
+		agentType := agent.AgentType(name)
+		if _, err := coordinator.GetAgent(agentType); err != nil {
+			return fmt.Errorf("--agent-interval: no %s agent in this command", name)
+		}
+		interval, err := time.ParseDuration(spec)
+		if err != nil {
+			return fmt.Errorf("--agent-interval %s: %w", name, err)
+		}
+		limit := config.Agents[agentType]
+		limit.MinInterval = interval
+		config.Agents[agentType] = limit
+	}
+	coordinator.WithScheduler(config)
+	return nil
+}
@@ -0,0 +1,295 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"kubeagent/pkg/agent"
+	"kubeagent/pkg/agent/harness"
+	"kubeagent/pkg/api"
+	"kubeagent/pkg/integrations/dingtalk"
+	"kubeagent/pkg/integrations/slack"
+	"kubeagent/pkg/k8s"
+)
+
+// Flags for `kubeagent serve`.
+var (
+	serveAddr                  string
+	serveToken                 string
+	serveNoAuth                bool
+	serveMaxConcurrent         int
+	serveNoRemediationApproval bool
+	serveProtected             []string
+	serveAuditFile             string
+	serveStateFile             string
+
+	serveSlackSigningSecret    string
+	serveSlackBotToken         string
+	serveSlackApprovalChannel  string
+	serveDingTalkAppSecret     string
+	serveDingTalkWebhook       string
+	serveDingTalkWebhookSecret string
+	serveChatApprovers         []string
+)
+
+// serveCmd hosts the coordinator behind the REST API in pkg/api, so a
+// web UI, a chat bot or curl can submit requests without a terminal.
+//
+// Nobody is at a terminal, so approvals go to the HTTP queue instead: a
+// plan that remediates waits for POST /api/v1/approvals/{id} before it
+// starts, and each HumanTool confirmation inside it waits there too.
+// The queue is persisted in the state store.
+//
+// With a Slack or DingTalk secret configured the same server answers
+// those bots too; see pkg/integrations.
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve the multi-agent coordinator over an HTTP API",
+	Long: `Run an HTTP API for submitting requests and following their plans, tasks and approvals.
+
+Endpoints (all under /api/v1 need "Authorization: Bearer <token>"):
+  POST   /api/v1/requests             submit {"input": "...", "approval_required": false}
+  GET    /api/v1/requests             list requests (?status=)
+  GET    /api/v1/requests/{id}        request status, plan and final response
+  DELETE /api/v1/requests/{id}        cancel a request
+  GET    /api/v1/requests/{id}/events live progress and audit events (Server-Sent Events)
+  GET    /api/v1/plans/{id}           a plan with its tasks' current status
+  GET    /api/v1/tasks                tasks of all requests (?request_id=, ?status=)
+  GET    /api/v1/tasks/{id}           one task (?request_id=)
+  GET    /api/v1/approvals            plans and actions waiting for approval
+  POST   /api/v1/approvals/{id}       decide {"approved": true, "reason": "...", "approver": "alice", "drop": ["task-id"]}
+  GET    /healthz                     liveness, no token
+
+Chat bots (enabled by their secrets; callbacks are signed, not token-authenticated):
+  POST   /integrations/slack/commands       Slack slash command /kubeagent
+  POST   /integrations/slack/interactions   Slack approve/reject buttons
+  POST   /integrations/dingtalk             DingTalk outgoing robot
+  Commands: diagnose <pod> [-n ns], fix <pod> [-n ns], ask <question>, status [id],
+            approve <approval-id|task-id> [reason], reject <approval-id|task-id> [reason]
+
+Examples:
+  export KUBEAGENT_API_TOKEN=$(openssl rand -hex 16)
+  kubeagent serve --addr :8080 --audit-file /var/log/kubeagent/audit.jsonl
+
+  curl -H "Authorization: Bearer $KUBEAGENT_API_TOKEN" \
+    -d '{"input": "why is web-1 in shop crashing"}' localhost:8080/api/v1/requests
+
+  SLACK_SIGNING_SECRET=... SLACK_BOT_TOKEN=xoxb-... kubeagent serve \
+    --slack-approval-channel C0123456 --chat-approvers U0ABCDEF`,
+	Run: runServe,
+}
+
+func init() {
+	serveCmd.Flags().StringVar(&serveAddr, "addr", ":8080", "Address to listen on")
+	serveCmd.Flags().StringVar(&serveToken, "token", "", "Bearer token API callers must send (default $KUBEAGENT_API_TOKEN)")
+	serveCmd.Flags().BoolVar(&serveNoAuth, "no-auth", false, "Serve without a token (local development only)")
+	serveCmd.Flags().IntVar(&serveMaxConcurrent, "max-concurrent", api.DefaultMaxConcurrent, "Requests planned or executed at once; the rest queue")
+	serveCmd.Flags().BoolVar(&serveNoRemediationApproval, "no-remediation-approval", false, "Run plans that remediate without waiting for an approval")
+	serveCmd.Flags().StringSliceVar(&serveProtected, "protected", []string{"kube-system", "kube-public", "kube-node-lease"}, "Namespaces that must never be mutated")
+	serveCmd.Flags().StringVar(&serveAuditFile, "audit-file", "", "Path to a JSONL audit log file")
+	serveCmd.Flags().StringVar(&serveStateFile, "state-file", "", "Path to a durable state file for plans, tasks and rollback snapshots")
+	serveCmd.Flags().StringVar(&serveSlackSigningSecret, "slack-signing-secret", "", "Slack app signing secret; enables the Slack bot (default $SLACK_SIGNING_SECRET)")
+	serveCmd.Flags().StringVar(&serveSlackBotToken, "slack-bot-token", "", "Slack bot token for chat.postMessage (default $SLACK_BOT_TOKEN)")
+	serveCmd.Flags().StringVar(&serveSlackApprovalChannel, "slack-approval-channel", "", "Slack channel ID for approvals of requests not started from Slack")
+	serveCmd.Flags().StringVar(&serveDingTalkAppSecret, "dingtalk-app-secret", "", "DingTalk robot AppSecret; enables the DingTalk bot (default $DINGTALK_APP_SECRET)")
+	serveCmd.Flags().StringVar(&serveDingTalkWebhook, "dingtalk-webhook", "", "DingTalk group robot webhook for approvals and expired sessions (default $DINGTALK_WEBHOOK)")
+	serveCmd.Flags().StringVar(&serveDingTalkWebhookSecret, "dingtalk-webhook-secret", "", "Signing secret of --dingtalk-webhook")
+	serveCmd.Flags().StringSliceVar(&serveChatApprovers, "chat-approvers", nil, "Slack user IDs / DingTalk staff IDs allowed to approve from chat (default: nobody, approve through the API)")
+
+	addLLMCassetteFlag(serveCmd)
+	addLLMRoutingFlags(serveCmd)
+	addSchedulerFlags(serveCmd)
+	addConsensusFlags(serveCmd)
+	addUsageFlags(serveCmd)
+	addApprovalFlags(serveCmd)
+	rootCmd.AddCommand(serveCmd)
+}
+
+func runServe(cmd *cobra.Command, args []string) {
+	token := serveToken
+	if token == "" {
+		token = os.Getenv("KUBEAGENT_API_TOKEN")
+	}
+	if token == "" && !serveNoAuth {
+		fmt.Println("Set --token or KUBEAGENT_API_TOKEN, or pass --no-auth for local development.")
+		os.Exit(1)
+	}
+
+	logger := agent.NewSimpleLogger("KubeAgent")
+	stateStore, closeStateStore, err := newStateStore(serveStateFile)
+	if err != nil {
+		fmt.Printf("Failed to open state file: %v\n", err)
+		os.Exit(1)
+	}
+	defer closeStateStore()
+
+	llmClient, err := newLLMClient(logger)
+	if err != nil {
+		fmt.Printf("Failed to initialize LLM client: %v\n", err)
+		os.Exit(1)
+	}
+	prices, err := loadPriceTable()
+	if err != nil {
+		fmt.Printf("Failed to load price table: %v\n", err)
+		os.Exit(1)
+	}
+	k8sClient, err := k8s.NewClient()
+	if err != nil {
+		fmt.Printf("Failed to initialize K8s client: %v\n", err)
+		os.Exit(1)
+	}
+	skillRegistry, err := loadSkills()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	// The server is built before the sinks so its audit feed can sit
+	// in the tee every agent and tool writes to.
+	coordinator := agent.NewCoordinator(nil, llmClient, stateStore, logger)
+	server := api.NewServer(coordinator, logger).
+		WithStateStore(stateStore).
+		WithToken(token).
+		WithMaxConcurrent(serveMaxConcurrent).
+		WithRemediationApproval(!serveNoRemediationApproval).
+		WithContextSetup(func(ctx *agent.AgentContext) {
+			ctx.SetUsageLedger(newUsageLedger(prices))
+		})
+
+	auditSinks := []harness.AuditLogger{server.Auditor()}
+	if serveAuditFile != "" {
+		f, err := os.OpenFile(serveAuditFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			fmt.Printf("Failed to open audit file %q: %v\n", serveAuditFile, err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		auditSinks = append(auditSinks, harness.NewJSONLogAuditor(f))
+	}
+	trail := harness.NewAuditTrail(0)
+	auditSinks = append(auditSinks, trail)
+	auditor := harness.NewTee(auditSinks...)
+
+	coordinator.WithAuditor(auditor).WithAuditTrail(trail)
+
+	// Approvals a previous process left pending can no longer be acted
+	// on; mark them expired before taking new ones.
+	queue := harness.NewHTTPApprover(stateStore)
+	if expired, err := queue.ExpireStale(context.Background()); err != nil {
+		fmt.Printf("Failed to load pending approvals: %v\n", err)
+		os.Exit(1)
+	} else if expired > 0 {
+		fmt.Printf("Expired %d approval(s) left pending by a previous run\n", expired)
+	}
+	approvals, err := newApprovalGate(queue, auditor)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	server.WithApprovals(approvals, queue)
+	chatBots, err := mountChatBots(server, logger)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	diagnostician := newDiagnostician(llmClient, logger, k8sClient, skillRegistry)
+	if err := coordinator.RegisterAgent(diagnostician); err != nil {
+		fmt.Printf("Failed to register diagnostician: %v\n", err)
+		os.Exit(1)
+	}
+	remediator, err := newRemediator(remediatorWiring{
+		llmClient:  llmClient,
+		logger:     logger,
+		k8sClient:  k8sClient,
+		stateStore: stateStore,
+		skillSet:   skillRegistry,
+		auditor:    auditor,
+		verifier:   harness.NewK8sVerifier(k8sClient),
+		protected:  serveProtected,
+		approvals:  approvals,
+	})
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if err := coordinator.RegisterAgent(remediator); err != nil {
+		fmt.Printf("Failed to register remediator: %v\n", err)
+		os.Exit(1)
+	}
+	if err := applyAgentModels(diagnostician, remediator); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if err := applySchedulerFlags(coordinator); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("\n=== kubeagent serve ===\n")
+	fmt.Printf("Listening:   %s\n", serveAddr)
+	fmt.Printf("Auth:        %s\n", serveAuthLabel(token))
+	fmt.Printf("Plans:       %s\n", serveApprovalLabel(!serveNoRemediationApproval))
+	fmt.Printf("Approvals:   %s\n", approvalLabel("POST /api/v1/approvals/{id}"))
+	fmt.Printf("Chat:        %s\n", defaultIfEmpty(strings.Join(chatBots, ", "), "(none)"))
+	if len(chatBots) > 0 {
+		fmt.Printf("Chat approvers: %s\n", defaultIfEmpty(strings.Join(serveChatApprovers, ", "), "(none, approve through the API)"))
+	}
+	fmt.Printf("Protected:   %s\n", strings.Join(serveProtected, ", "))
+	fmt.Printf("State file:  %s\n", defaultIfEmpty(serveStateFile, "(in-memory)"))
+	fmt.Println()
+
+	ctx, stop := interruptible()
+	defer stop()
+	if err := server.ListenAndServe(ctx, serveAddr); err != nil {
+		fmt.Printf("Server failed: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func serveAuthLabel(token string) string {
+	if token == "" {
+		return "DISABLED (--no-auth)"
+	}
+	return "bearer token"
+}
+
+func serveApprovalLabel(required bool) string {
+	if !required {
+		return "run without approval (--no-remediation-approval)"
+	}
+	return "plans that remediate wait for approval"
+}
+
+// mountChatBots serves the Slack and DingTalk bots whose secrets are
+// configured and posts every new approval to them. It returns the
+// names of the bots it mounted.
+func mountChatBots(server *api.Server, logger agent.Logger) ([]string, error) {
+	var mounted []string
+	signingSecret := defaultIfEmpty(serveSlackSigningSecret, os.Getenv("SLACK_SIGNING_SECRET"))
+	botToken := defaultIfEmpty(serveSlackBotToken, os.Getenv("SLACK_BOT_TOKEN"))
+	if signingSecret != "" || botToken != "" {
+		if signingSecret == "" || botToken == "" {
+			return nil, fmt.Errorf("the Slack bot needs both --slack-signing-secret and --slack-bot-token")
+		}
+		bot := slack.NewBot(server, signingSecret, botToken, logger).
+			WithApprovers(serveChatApprovers...).
+			WithApprovalChannel(serveSlackApprovalChannel)
+		server.WithRoute("/integrations/slack/", bot.Handler("/integrations/slack")).OnApproval(bot.NotifyApproval)
+		mounted = append(mounted, "slack")
+	}
+
+	if appSecret := defaultIfEmpty(serveDingTalkAppSecret, os.Getenv("DINGTALK_APP_SECRET")); appSecret != "" {
+		webhook := defaultIfEmpty(serveDingTalkWebhook, os.Getenv("DINGTALK_WEBHOOK"))
+		bot := dingtalk.NewBot(server, appSecret, logger).
+			WithApprovers(serveChatApprovers...).
+			WithWebhook(webhook, serveDingTalkWebhookSecret)
+		server.WithRoute("/integrations/dingtalk", bot.Handler("/integrations/dingtalk")).OnApproval(bot.NotifyApproval)
+		mounted = append(mounted, "dingtalk")
+	}
+	return mounted, nil
+}
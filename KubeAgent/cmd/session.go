@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"kubeagent/pkg/agent"
+)
+
+const sessionHelp = `Session commands: /history shows this session's requests, /reset forgets them, exit quits.`
+
+// newSession starts the conversation one interactive command carries
+// from request to request. Older turns are summarised by llmClient.
+func newSession(llmClient agent.LLMClient) *agent.Session {
+	return agent.NewSession(uuid.New().String()).WithSummarizer(llmClient)
+}
+
+// sessionCommand handles /reset and /history typed at the prompt and
+// reports whether input was one of them.
+func sessionCommand(session *agent.Session, input string, out io.Writer) bool {
+	switch strings.TrimSpace(input) {
+	case "/reset":
+		session.Reset()
+		fmt.Fprintln(out, "Conversation cleared; the next request starts fresh.")
+	case "/history":
+		printHistory(session, out)
+	case "/help":
+		fmt.Fprintln(out, sessionHelp)
+	default:
+		return false
+	}
+	return true
+}
+
+func printHistory(session *agent.Session, out io.Writer) {
+	history := session.History()
+	if len(history) == 0 {
+		fmt.Fprintln(out, "No requests in this session yet.")
+		return
+	}
+	for i, turn := range history {
+		fmt.Fprintf(out, "%d. [%s] %s\n", i+1, turn.Status, turn.Input)
+	}
+	entities := session.Entities()
+	if len(entities.Namespaces) > 0 {
+		fmt.Fprintf(out, "Namespaces: %s\n", strings.Join(entities.Namespaces, ", "))
+	}
+	if len(entities.Pods) > 0 {
+		fmt.Fprintf(out, "Pods:       %s\n", strings.Join(entities.Pods, ", "))
+	}
+	if len(entities.Resources) > 0 {
+		fmt.Fprintf(out, "Resources:  %s\n", strings.Join(entities.Resources, ", "))
+	}
+}
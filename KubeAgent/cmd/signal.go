@@ -0,0 +1,21 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// interruptible returns a context cancelled by the first Ctrl-C or
+// SIGTERM. The first signal lets the coordinator mark running tasks
+// cancelled and checkpoint the plan; a second one kills the process as
+// usual. Call the returned func once the request is done.
+func interruptible() (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+	return ctx, stop
+}
@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/spf13/cobra"
+
+	"kubeagent/pkg/agent"
+)
+
+// noStream turns off live progress for the interactive commands; like
+// llmCassette it is shared because only one command runs per process.
+var noStream bool
+
+// addNoStreamFlag registers --no-stream on cmd.
+func addNoStreamFlag(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&noStream, "no-stream", false,
+		"Only print the final result, not model output and tool calls as they happen")
+}
+
+// attachStream installs the terminal progress printer on ctx unless
+// --no-stream is set.
+func attachStream(ctx *agent.AgentContext, w io.Writer) {
+	if noStream {
+		return
+	}
+	ctx.SetStreamHandler(newStreamPrinter(w).handle)
+}
+
+// maxStreamLine caps one tool call or tool result line. The full text
+// still goes to the model; the terminal only needs enough to follow.
+const maxStreamLine = 160
+
+// streamPrinter renders stream events as they arrive. Tasks in the same
+// dependency round stream concurrently, so output is serialized and a
+// "[task]" prefix is repeated whenever another task takes over the line.
+type streamPrinter struct {
+	mu       sync.Mutex
+	w        io.Writer
+	lastTask string
+	lastType agent.StreamEventType
+	midLine  bool
+}
+
+func newStreamPrinter(w io.Writer) *streamPrinter {
+	return &streamPrinter{w: w}
+}
+
+func (p *streamPrinter) handle(event agent.StreamEvent) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	switch event.Type {
+	case agent.StreamTaskStarted:
+		p.endLine()
+		fmt.Fprintf(p.w, "\n▶ [%s] %s: %s\n", event.TaskID, event.Agent, event.Text)
+	case agent.StreamTaskFinished:
+		p.endLine()
+		line := fmt.Sprintf("■ [%s] %s", event.TaskID, event.Status)
+		if event.Text != "" {
+			line += ": " + event.Text
+		}
+		fmt.Fprintln(p.w, clip(line))
+	case agent.StreamTextDelta, agent.StreamThinkingDelta:
+		if event.TaskID != p.lastTask || event.Type != p.lastType || !p.midLine {
+			p.endLine()
+			fmt.Fprintf(p.w, "  [%s] ", event.TaskID)
+			if event.Type == agent.StreamThinkingDelta {
+				fmt.Fprint(p.w, "(thinking) ")
+			}
+		}
+		// Keep continuation lines indented under the prefix.
+		fmt.Fprint(p.w, strings.ReplaceAll(event.Text, "\n", "\n    "))
+		p.midLine = true
+	case agent.StreamToolCall:
+		p.endLine()
+		if event.ToolCall != nil {
+			args, _ := json.Marshal(event.ToolCall.Arguments)
+			fmt.Fprintf(p.w, "  [%s] %s\n", event.TaskID, clip("→ "+event.ToolCall.Name+" "+string(args)))
+		}
+	case agent.StreamToolResult:
+		p.endLine()
+		marker := "←"
+		if event.IsError {
+			marker = "✗"
+		}
+		fmt.Fprintf(p.w, "  [%s] %s\n", event.TaskID, clip(marker+" "+firstLine(event.Text)))
+	}
+	p.lastTask = event.TaskID
+	p.lastType = event.Type
+}
+
+// endLine finishes a partially streamed reply before printing anything
+// else.
+func (p *streamPrinter) endLine() {
+	if p.midLine {
+		fmt.Fprintln(p.w)
+		p.midLine = false
+	}
+}
+
+// firstLine returns the first non-empty line of s, noting how many
+// lines were left out.
+func firstLine(s string) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	if len(lines) <= 1 {
+		return lines[0]
+	}
+	return fmt.Sprintf("%s (+%d lines)", lines[0], len(lines)-1)
+}
+
+// clip shortens s to maxStreamLine runes.
+func clip(s string) string {
+	runes := []rune(s)
+	if len(runes) <= maxStreamLine {
+		return s
+	}
+	return string(runes[:maxStreamLine]) + "…"
+}
@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/spf13/cobra"
+
+	"kubeagent/pkg/agent"
+)
+
+// Budget flags are shared like llmCassette: one command per process.
+var (
+	maxRequestTokens int64
+	maxRequestCost   float64
+	priceTableFile   string
+)
+
+// addUsageFlags registers --max-tokens, --max-cost and --price-table on cmd.
+func addUsageFlags(cmd *cobra.Command) {
+	cmd.Flags().Int64Var(&maxRequestTokens, "max-tokens", 0,
+		"Abort a request once its LLM calls have used this many tokens (0 = no limit)")
+	cmd.Flags().Float64Var(&maxRequestCost, "max-cost", 0,
+		"Abort a request once its LLM calls have cost this many USD (0 = no limit)")
+	cmd.Flags().StringVar(&priceTableFile, "price-table", "",
+		`JSON file of per-model prices in USD per million tokens, e.g. {"MiniMax-M2.5": {"input_per_mtok": 0.3, "output_per_mtok": 1.2}}; merged over the built-in Anthropic prices`)
+}
+
+// loadPriceTable resolves --price-table once per command.
+func loadPriceTable() (agent.PriceTable, error) {
+	if priceTableFile == "" {
+		return agent.DefaultPriceTable(), nil
+	}
+	return agent.LoadPriceTable(priceTableFile)
+}
+
+// newUsageLedger starts the accounting for one request. The budget is
+// per request, so interactive commands create one for every input.
+func newUsageLedger(prices agent.PriceTable) *agent.UsageLedger {
+	return agent.NewUsageLedger(prices).WithBudget(agent.UsageBudget{
+		MaxTokens:  maxRequestTokens,
+		MaxCostUSD: maxRequestCost,
+	})
+}
+
+// printUsage writes a short per-agent breakdown of the request's spend.
+func printUsage(w io.Writer, ledger *agent.UsageLedger) {
+	report := ledger.Report()
+	if report.Total.Calls == 0 {
+		return
+	}
+
+	fmt.Fprintf(w, "\nLLM usage: %d call(s), %d in / %d out tokens",
+		report.Total.Calls, report.Total.InputTokens, report.Total.OutputTokens)
+	if cached := report.Total.CacheReadInputTokens + report.Total.CacheCreationInputTokens; cached > 0 {
+		fmt.Fprintf(w, " (+%d cache)", cached)
+	}
+	fmt.Fprintf(w, ", $%.4f\n", report.Total.CostUSD)
+
+	agents := make([]string, 0, len(report.ByAgent))
+	for agentType := range report.ByAgent {
+		agents = append(agents, string(agentType))
+	}
+	sort.Strings(agents)
+	for _, name := range agents {
+		totals := report.ByAgent[agent.AgentType(name)]
+		fmt.Fprintf(w, "  %-14s %3d call(s) %8d tokens  $%.4f\n", name, totals.Calls, totals.TotalTokens(), totals.CostUSD)
+	}
+	if len(report.Unpriced) > 0 {
+		fmt.Fprintf(w, "  no price for %v; add it with --price-table\n", report.Unpriced)
+	}
+}
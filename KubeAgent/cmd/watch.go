@@ -0,0 +1,226 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"kubeagent/pkg/agent"
+	"kubeagent/pkg/agent/harness"
+	"kubeagent/pkg/k8s"
+	"kubeagent/pkg/watch"
+)
+
+// Flags for `kubeagent watch`.
+var (
+	watchNamespaces          []string
+	watchDedupWindow         time.Duration
+	watchMinInterval         time.Duration
+	watchQueueSize           int
+	watchRemediateReasons    []string
+	watchRemediateNamespaces []string
+	watchProtected           []string
+	watchAuditFile           string
+)
+
+// watchCmd turns KubeAgent from something an operator asks into
+// something that notices: informers report failing Pods, Deployments
+// and Jobs, and each new failure is planned and executed like a
+// `fix` request nobody had to type.
+//
+// Remediation is opt-in per reason. Nobody is at the terminal to
+// answer HumanTool, so --remediate-reasons requires --auto-approve: a
+// risk policy answers HumanTool and rejects whatever is above it. The
+// guards that need no human stay on top: protected namespaces,
+// consensus reviewers when configured, closed-loop verification and
+// rollback.
+var watchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "Watch the cluster and diagnose failing workloads as they happen",
+	Long: `Watch Pods, Deployments and Jobs and submit every new failure to the agents.
+
+Detected failures: CrashLoopBackOff, ImagePullBackOff, ErrImagePull,
+CreateContainerConfigError, InvalidImageName, OOMKilled, Evicted,
+FailedScheduling, stalled Deployment rollouts and failed Jobs.
+
+Examples:
+  # Diagnose failures in two namespaces, at most one every two minutes:
+  kubeagent watch -n shop -n payments --min-interval 2m
+
+  # Also fix OOMKilled pods, but only in staging, approving low-risk
+  # changes only (--remediate-reasons requires --auto-approve):
+  kubeagent watch --remediate-reasons OOMKilled --remediate-namespaces staging \
+    --auto-approve low --audit-file /tmp/audit.jsonl`,
+	Run: runWatch,
+}
+
+func init() {
+	watchCmd.Flags().StringSliceVarP(&watchNamespaces, "namespace", "n", nil, "Namespaces to watch (repeatable; default all)")
+	watchCmd.Flags().DurationVar(&watchDedupWindow, "dedup-window", watch.DefaultDedupWindow, "Do not report the same failure of the same object again within this window")
+	watchCmd.Flags().DurationVar(&watchMinInterval, "min-interval", watch.DefaultMinInterval, "Least time between two incidents submitted to the agents")
+	watchCmd.Flags().IntVar(&watchQueueSize, "queue-size", watch.DefaultQueueSize, "Incidents waiting beyond this many are dropped")
+	watchCmd.Flags().StringSliceVar(&watchRemediateReasons, "remediate-reasons", nil, "Failure reasons to remediate, not only diagnose (e.g. OOMKilled; * for all); requires --auto-approve")
+	watchCmd.Flags().StringSliceVar(&watchRemediateNamespaces, "remediate-namespaces", nil, "Namespaces remediation is allowed in (default every watched namespace)")
+	watchCmd.Flags().StringSliceVar(&watchProtected, "protected", []string{"kube-system", "kube-public", "kube-node-lease"}, "Namespaces that must never be mutated")
+	watchCmd.Flags().StringVar(&watchAuditFile, "audit-file", "", "Path to a JSONL audit log file (also tees to console)")
+
+	addLLMCassetteFlag(watchCmd)
+	addLLMRoutingFlags(watchCmd)
+	addSchedulerFlags(watchCmd)
+	addConsensusFlags(watchCmd)
+	addUsageFlags(watchCmd)
+	addApprovalFlags(watchCmd)
+	rootCmd.AddCommand(watchCmd)
+}
+
+func runWatch(cmd *cobra.Command, args []string) {
+	logger := agent.NewSimpleLogger("KubeAgent")
+	stateStore := agent.NewMemoryStateStore()
+
+	llmClient, err := newLLMClient(logger)
+	if err != nil {
+		fmt.Printf("Failed to initialize LLM client: %v\n", err)
+		os.Exit(1)
+	}
+	prices, err := loadPriceTable()
+	if err != nil {
+		fmt.Printf("Failed to load price table: %v\n", err)
+		os.Exit(1)
+	}
+	k8sClient, err := k8s.NewClient()
+	if err != nil {
+		fmt.Printf("Failed to initialize K8s client: %v\n", err)
+		os.Exit(1)
+	}
+	skillRegistry, err := loadSkills()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	auditSinks := []harness.AuditLogger{harness.NewConsoleReporter(os.Stdout)}
+	if watchAuditFile != "" {
+		f, err := os.OpenFile(watchAuditFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			fmt.Printf("Failed to open audit file %q: %v\n", watchAuditFile, err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		auditSinks = append(auditSinks, harness.NewJSONLogAuditor(f))
+	}
+	trail := harness.NewAuditTrail(0)
+	auditSinks = append(auditSinks, trail)
+	auditor := harness.NewTee(auditSinks...)
+
+	coordinator := agent.NewCoordinator(nil, llmClient, stateStore, logger).
+		WithAuditor(auditor).
+		WithAuditTrail(trail)
+
+	diagnostician := newDiagnostician(llmClient, logger, k8sClient, skillRegistry)
+	if err := coordinator.RegisterAgent(diagnostician); err != nil {
+		fmt.Printf("Failed to register diagnostician: %v\n", err)
+		os.Exit(1)
+	}
+	agents := []agent.Agent{diagnostician}
+
+	// Without an escalation policy there is no Remediator at all, so a
+	// diagnose-only watch cannot write to the cluster even if the
+	// planner asks for a fix.
+	escalation := watch.EscalationPolicy{Reasons: watchRemediateReasons, Namespaces: watchRemediateNamespaces}
+	if len(escalation.Reasons) > 0 {
+		// Without a policy nobody would answer HumanTool, and escalated
+		// fixes would write without any approval.
+		if autoApproveRisk == "" {
+			fmt.Println("--remediate-reasons needs --auto-approve low|medium|high: nobody is at the terminal to approve the fixes.")
+			os.Exit(1)
+		}
+		approvals, err := newApprovalGate(nil, auditor)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		remediator, err := newRemediator(remediatorWiring{
+			llmClient:  llmClient,
+			logger:     logger,
+			k8sClient:  k8sClient,
+			stateStore: stateStore,
+			skillSet:   skillRegistry,
+			auditor:    auditor,
+			verifier:   harness.NewK8sVerifier(k8sClient),
+			protected:  watchProtected,
+			approvals:  approvals,
+		})
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		if err := coordinator.RegisterAgent(remediator); err != nil {
+			fmt.Printf("Failed to register remediator: %v\n", err)
+			os.Exit(1)
+		}
+		agents = append(agents, remediator)
+	}
+	if err := applyAgentModels(agents...); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if err := applySchedulerFlags(coordinator); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	// Incidents are handled one at a time, so one variable carries
+	// each incident's ledger from setup to its result.
+	var ledger *agent.UsageLedger
+	handler := watch.NewCoordinatorHandler(coordinator, logger).
+		WithContextSetup(func(ctx *agent.AgentContext) {
+			ledger = newUsageLedger(prices)
+			ctx.SetUsageLedger(ledger)
+		}).
+		WithResultHandler(func(incident watch.Incident, response *agent.Response, err error) {
+			printIncidentResult(incident, response, err)
+			printUsage(os.Stdout, ledger)
+		})
+	watcher := watch.NewWatcher(k8sClient.Clientset(), handler, logger).WithConfig(watch.Config{
+		Namespaces:  watchNamespaces,
+		DedupWindow: watchDedupWindow,
+		MinInterval: watchMinInterval,
+		QueueSize:   watchQueueSize,
+		Escalation:  escalation,
+	})
+
+	fmt.Printf("\n=== kubeagent watch ===\n")
+	fmt.Printf("Namespaces:  %s\n", defaultIfEmpty(strings.Join(watchNamespaces, ", "), "(all)"))
+	fmt.Printf("Remediate:   %s\n", defaultIfEmpty(strings.Join(watchRemediateReasons, ", "), "(diagnose only)"))
+	if autoApproveRisk != "" {
+		fmt.Printf("Approvals:   %s\n", approvalLabel(""))
+	}
+	fmt.Printf("Protected:   %s\n", strings.Join(watchProtected, ", "))
+	fmt.Printf("Audit file:  %s\n", defaultIfEmpty(watchAuditFile, "(console only)"))
+	fmt.Println()
+
+	ctx, stop := interruptible()
+	defer stop()
+	if err := watcher.Run(ctx); err != nil {
+		fmt.Printf("Watch failed: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// printIncidentResult writes one incident's outcome to the terminal.
+func printIncidentResult(incident watch.Incident, response *agent.Response, err error) {
+	fmt.Printf("\n========== %s ==========\n", incident)
+	if err != nil {
+		fmt.Printf("Failed: %v\n", err)
+	}
+	if response == nil {
+		return
+	}
+	fmt.Println(response.Result)
+	for _, e := range response.Errors {
+		fmt.Println(" -", e)
+	}
+}
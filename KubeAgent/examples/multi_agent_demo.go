@@ -21,7 +21,7 @@ func main() {
 	stateStore := agent.NewMemoryStateStore()
 
 	// Initialize LLM client
-	llmClient, err := agent.NewAnthropicLLMClient(nil) // Auto-detects ANTHROPIC_API_KEY or MINIMAX_API_KEY
+	llmClient, err := agent.NewLLMClient(nil) // Auto-detects the provider from ANTHROPIC_API_KEY, MINIMAX_API_KEY, DASHSCOPE_API_KEY or OPENAI_API_KEY
 	if err != nil {
 		log.Fatalf("Failed to create LLM client: %v", err)
 	}
@@ -43,8 +43,11 @@ func main() {
 	diagnostician.AddTool(pkgtools.NewKubeTool())
 
 	remediator := specialists.NewRemediatorAgent(llmClient, logger)
-	remediator.AddTool(pkgtools.NewHumanTool())
-	remediator.AddTool(pkgtools.NewCreateTool(k8sClient))
+	previews := pkgtools.NewChangePreviews()
+	remediator.AddTool(pkgtools.NewHumanTool().WithChangePreviews(previews))
+	remediator.AddTool(pkgtools.NewPatchTool(k8sClient).WithChangePreviews(previews))
+	remediator.AddTool(pkgtools.NewApplyTool(k8sClient).WithChangePreviews(previews))
+	remediator.AddTool(pkgtools.NewCreateTool(k8sClient).WithChangePreviews(previews))
 	remediator.AddTool(pkgtools.NewDeleteTool(k8sClient))
 
 	if err := coordinator.RegisterAgent(diagnostician); err != nil {
@@ -57,7 +60,7 @@ func main() {
 
 	fmt.Println("Coordinator and specialist agents initialized")
 	fmt.Println("Registered agents: Diagnostician (LogTool, EventTool, ListTool, KubeTool)")
-	fmt.Println("                   Remediator    (HumanTool, CreateTool, DeleteTool)")
+	fmt.Println("                   Remediator    (HumanTool, PatchTool, ApplyTool, CreateTool, DeleteTool)")
 	fmt.Println()
 
 	// Example 1: Simple diagnosis task
@@ -0,0 +1,49 @@
+// Package agenttest provides test doubles for code built on the agent
+// package, such as the API server's front ends.
+package agenttest
+
+import (
+	"kubeagent/pkg/agent"
+	"kubeagent/pkg/agent/harness"
+)
+
+// FixingCoordinator plans a diagnosis and, for remediate requests, a
+// fix, and reports the fix as verified without touching a cluster.
+// Methods other than Plan and ExecutePlan are not implemented.
+type FixingCoordinator struct {
+	agent.CoordinatorAgent
+}
+
+// Plan implements agent.CoordinatorAgent.
+func (c *FixingCoordinator) Plan(ctx *agent.AgentContext, request *agent.Request) (*agent.ExecutionPlan, error) {
+	pod, _ := request.Context["pod_name"].(string)
+	tasks := []*agent.Task{{ID: "diag", Type: agent.TaskTypeDiagnose, Description: "diagnose " + pod}}
+	if request.Intent == string(agent.TaskTypeRemediate) {
+		tasks = append(tasks, &agent.Task{ID: "fix", Type: agent.TaskTypeRemediate, Description: "raise the memory limit", Dependencies: []string{"diag"}})
+	}
+	return &agent.ExecutionPlan{ID: "plan-" + request.ID, RequestID: request.ID, Tasks: tasks}, nil
+}
+
+// ExecutePlan implements agent.CoordinatorAgent: every task completes,
+// the diagnosis finds a memory limit too low and the fix raises it.
+func (c *FixingCoordinator) ExecutePlan(ctx *agent.AgentContext, plan *agent.ExecutionPlan) (*agent.Response, error) {
+	report := &agent.FinalReport{}
+	for _, task := range plan.Tasks {
+		task.Status = agent.TaskStatusCompleted
+		tr := agent.TaskReport{ID: task.ID, Type: task.Type, Status: task.Status}
+		switch task.Type {
+		case agent.TaskTypeDiagnose:
+			tr.Output = map[string]interface{}{"root_cause": "container exceeds its 64Mi memory limit"}
+		case agent.TaskTypeRemediate:
+			tr.Output = map[string]interface{}{"actions_taken": []string{"patched deployment web memory limit to 256Mi"}}
+			tr.Verification = &harness.VerificationResult{Status: harness.VerificationPassed, Summary: "pod is Ready"}
+		}
+		report.Tasks = append(report.Tasks, tr)
+	}
+	return &agent.Response{
+		RequestID: ctx.RequestID,
+		Status:    agent.TaskStatusCompleted,
+		Result:    "raised the memory limit",
+		Data:      map[string]interface{}{agent.FinalReportKey: report},
+	}, nil
+}
@@ -2,16 +2,20 @@ package agent
 
 import (
 	"fmt"
+	"sync"
 	"time"
+
+	"kubeagent/pkg/agent/harness"
 )
 
 // BaseAgent provides common functionality for all agents
 type BaseAgent struct {
-	config       *AgentConfig
-	tools        []Tool
-	llmClient    LLMClient
-	logger       Logger
-	metrics      *AgentMetrics
+	config    *AgentConfig
+	tools     []Tool
+	llmClient LLMClient
+	logger    Logger
+	metrics   *AgentMetrics
+	metricsMu sync.Mutex
 }
 
 // NewBaseAgent creates a new base agent
@@ -19,7 +23,7 @@ func NewBaseAgent(config *AgentConfig, llmClient LLMClient, logger Logger) *Base
 	return &BaseAgent{
 		config:    config,
 		tools:     make([]Tool, 0),
-		llmClient: llmClient,
+		llmClient: scopeToAgent(llmClient, config),
 		logger:    logger,
 		metrics: &AgentMetrics{
 			AgentType: config.Type,
@@ -56,6 +60,15 @@ func (b *BaseAgent) GetTools() []Tool {
 	return b.tools
 }
 
+// ToolNames returns the names of the agent's tools, for Capabilities
+func (b *BaseAgent) ToolNames() []string {
+	names := make([]string, len(b.tools))
+	for i, tool := range b.tools {
+		names[i] = tool.Name()
+	}
+	return names
+}
+
 // Execute executes a task (to be overridden by specific agents)
 func (b *BaseAgent) Execute(ctx *AgentContext, task *Task) (*Task, error) {
 	startTime := time.Now()
@@ -85,13 +98,19 @@ func (b *BaseAgent) CanHandle(taskType TaskType) bool {
 	return false
 }
 
-// GetMetrics returns the agent's metrics
+// GetMetrics returns a copy of the agent's metrics
 func (b *BaseAgent) GetMetrics() *AgentMetrics {
-	return b.metrics
+	b.metricsMu.Lock()
+	defer b.metricsMu.Unlock()
+	metrics := *b.metrics
+	return &metrics
 }
 
-// updateMetrics updates agent execution metrics
+// updateMetrics updates agent execution metrics. The scheduler runs
+// several tasks of one agent at once, hence the lock.
 func (b *BaseAgent) updateMetrics(duration time.Duration, success bool) {
+	b.metricsMu.Lock()
+	defer b.metricsMu.Unlock()
 	if success {
 		b.metrics.TasksCompleted++
 	} else {
@@ -110,10 +129,23 @@ func (b *BaseAgent) updateMetrics(duration time.Duration, success bool) {
 func (b *BaseAgent) CallLLM(ctx *AgentContext, systemPrompt, userPrompt string) (string, error) {
 	messages := []Message{
 		{Role: "system", Content: systemPrompt},
-		{Role: "user", Content: userPrompt},
+		{Role: "user", Content: withConversation(ctx, userPrompt)},
 	}
 
-	response, err := b.llmClient.Complete(ctx.Context(), messages)
+	if err := ctx.checkBudget(); err != nil {
+		return "", err
+	}
+
+	var resp *LLMResponse
+	var err error
+	if streaming, ok := b.llmClient.(StreamingLLMClient); ok && ctx.Streaming() {
+		resp, err = streaming.StreamWithTools(ctx.Context(), messages, nil, b.forward(ctx))
+	} else {
+		resp, err = completeText(ctx.Context(), b.llmClient, messages)
+		if err == nil {
+			b.emit(ctx, StreamEvent{Type: StreamTextDelta, Text: resp.Content})
+		}
+	}
 	if err != nil {
 		b.logger.Error("LLM call failed", map[string]interface{}{
 			"agent_type": b.config.Type,
@@ -122,14 +154,21 @@ func (b *BaseAgent) CallLLM(ctx *AgentContext, systemPrompt, userPrompt string)
 		return "", err
 	}
 
-	return response, nil
+	// The answer is already paid for, so an overrun is not an error here;
+	// the next call's budget check stops the request.
+	_ = ctx.recordUsage(b.config.Type, resp.Usage)
+	return resp.Content, nil
 }
 
 // CallLLMWithTools is a helper method for agents to call LLM with tools (single call, no loop)
 func (b *BaseAgent) CallLLMWithTools(ctx *AgentContext, systemPrompt, userPrompt string) (*LLMResponse, error) {
 	messages := []Message{
 		{Role: "system", Content: systemPrompt},
-		{Role: "user", Content: userPrompt},
+		{Role: "user", Content: withConversation(ctx, userPrompt)},
+	}
+
+	if err := ctx.checkBudget(); err != nil {
+		return nil, err
 	}
 
 	response, err := b.llmClient.CompleteWithTools(ctx.Context(), messages, b.tools)
@@ -141,6 +180,7 @@ func (b *BaseAgent) CallLLMWithTools(ctx *AgentContext, systemPrompt, userPrompt
 		return nil, err
 	}
 
+	_ = ctx.recordUsage(b.config.Type, response.Usage)
 	return response, nil
 }
 
@@ -163,19 +203,28 @@ func (b *BaseAgent) RunToolLoop(ctx *AgentContext, systemPrompt, userPrompt stri
 
 	messages := []Message{
 		{Role: "system", Content: systemPrompt},
-		{Role: "user", Content: userPrompt},
+		{Role: "user", Content: withConversation(ctx, userPrompt)},
 	}
 
 	for i := 0; i < maxIterations; i++ {
-		resp, err := b.llmClient.CompleteWithTools(ctx.Context(), messages, b.tools)
+		// Checked before every turn, not only after this agent's own
+		// calls: a task running in parallel may have spent the budget.
+		if err := ctx.checkBudget(); err != nil {
+			return "", err
+		}
+		resp, err := b.completeWithTools(ctx, messages)
 		if err != nil {
 			return "", fmt.Errorf("LLM call failed: %w", err)
 		}
+		budgetErr := ctx.recordUsage(b.config.Type, resp.Usage)
 
 		// No tool calls - return the final text response
 		if len(resp.ToolCalls) == 0 {
 			return resp.Content, nil
 		}
+		if budgetErr != nil {
+			return "", fmt.Errorf("tool loop stopped after %d LLM call(s): %w", i+1, budgetErr)
+		}
 
 		// Add assistant message with tool calls to conversation history
 		messages = append(messages, Message{
@@ -192,7 +241,10 @@ func (b *BaseAgent) RunToolLoop(ctx *AgentContext, systemPrompt, userPrompt stri
 				"tool_id":    tc.ID,
 			})
 
-			result, toolErr := b.executeTool(tc.Name, tc.Arguments)
+			call := tc
+			b.emit(ctx, StreamEvent{Type: StreamToolCall, ToolCall: &call})
+
+			result, toolErr := b.executeTool(ctx, tc.Name, tc.Arguments)
 			toolMsg := Message{
 				Role:       "tool",
 				Content:    result,
@@ -206,6 +258,12 @@ func (b *BaseAgent) RunToolLoop(ctx *AgentContext, systemPrompt, userPrompt stri
 					"error":     toolErr.Error(),
 				})
 			}
+			b.emit(ctx, StreamEvent{
+				Type:     StreamToolResult,
+				ToolCall: &call,
+				Text:     truncateForStream(toolMsg.Content),
+				IsError:  toolMsg.IsError,
+			})
 			messages = append(messages, toolMsg)
 		}
 
@@ -219,10 +277,50 @@ func (b *BaseAgent) RunToolLoop(ctx *AgentContext, systemPrompt, userPrompt stri
 	return "", fmt.Errorf("tool loop: max iterations (%d) reached", maxIterations)
 }
 
+// completeWithTools is one LLM turn of the tool loop. With a stream
+// handler and a streaming-capable client the reply is forwarded as it
+// is generated; otherwise it is emitted in one piece when it arrives,
+// so progress display works with every client.
+func (b *BaseAgent) completeWithTools(ctx *AgentContext, messages []Message) (*LLMResponse, error) {
+	if streaming, ok := b.llmClient.(StreamingLLMClient); ok && ctx.Streaming() {
+		return streaming.StreamWithTools(ctx.Context(), messages, b.tools, b.forward(ctx))
+	}
+
+	resp, err := b.llmClient.CompleteWithTools(ctx.Context(), messages, b.tools)
+	if err == nil {
+		b.emit(ctx, StreamEvent{Type: StreamTextDelta, Text: resp.Content})
+	}
+	return resp, err
+}
+
+// forward adapts ctx's handler for an LLM client: the client only knows
+// the delta, the agent adds who produced it.
+func (b *BaseAgent) forward(ctx *AgentContext) StreamHandler {
+	return func(event StreamEvent) {
+		b.emit(ctx, event)
+	}
+}
+
+// emit tags event with this agent and hands it to ctx. Empty text
+// deltas are dropped so non-streaming tool-call turns stay quiet.
+func (b *BaseAgent) emit(ctx *AgentContext, event StreamEvent) {
+	if event.Type == StreamTextDelta && event.Text == "" {
+		return
+	}
+	if event.Agent == "" {
+		event.Agent = b.config.Type
+	}
+	ctx.Emit(event)
+}
+
 // executeTool finds and executes a tool by name
-func (b *BaseAgent) executeTool(name string, args map[string]interface{}) (string, error) {
+func (b *BaseAgent) executeTool(ctx *AgentContext, name string, args map[string]interface{}) (string, error) {
 	for _, tool := range b.tools {
 		if tool.Name() == name {
+			if ct, ok := tool.(ContextTool); ok {
+				toolCtx := harness.WithRequestScope(ctx.Context(), ctx.RequestID, ctx.taskID)
+				return ct.ExecuteContext(withAgentContext(toolCtx, ctx), args)
+			}
 			return tool.Execute(args)
 		}
 	}
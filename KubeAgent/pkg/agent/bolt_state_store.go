@@ -0,0 +1,282 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"kubeagent/pkg/agent/harness"
+)
+
+// Bucket names for BoltStateStore. One bucket per entity keeps the
+// key space flat (ID -> JSON) and lets each bucket be iterated on its
+// own when inspecting a state file.
+var (
+	boltContextsBucket = []byte("contexts")
+	boltTasksBucket    = []byte("tasks")
+	boltPlansBucket    = []byte("plans")
+	// Snapshot keys are "<requestID>/<bbolt sequence>" so a prefix scan
+	// returns one request's snapshots in the order they were taken.
+	boltSnapshotsBucket = []byte("snapshots")
+	boltApprovalsBucket = []byte("approvals")
+)
+
+// BoltStateStore is a durable StateStore backed by a single bbolt
+// file. Everything the coordinator saves survives process exit, so a
+// `kubeagent fix` run that crashed half-way can be inspected and
+// resumed via BaseCoordinator.ResumePlan.
+//
+// Values are stored as JSON rather than gob so a state file can be
+// dumped with standard tooling and stays readable across refactors of
+// the Go types (unknown fields are simply ignored on load).
+type BoltStateStore struct {
+	db *bolt.DB
+}
+
+// NewBoltStateStore opens (or creates) the state file at path. bbolt
+// takes an exclusive file lock, so a second process pointing at the
+// same file fails after a short timeout instead of blocking forever.
+func NewBoltStateStore(path string) (*BoltStateStore, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: 2 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open state file %s: %w", path, err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{boltContextsBucket, boltTasksBucket, boltPlansBucket, boltSnapshotsBucket, boltApprovalsBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return fmt.Errorf("failed to create bucket %s: %w", name, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &BoltStateStore{db: db}, nil
+}
+
+// Close releases the file lock. Callers should defer it right after
+// NewBoltStateStore.
+func (b *BoltStateStore) Close() error {
+	return b.db.Close()
+}
+
+// SaveContext saves the agent context
+func (b *BoltStateStore) SaveContext(ctx context.Context, agentCtx *AgentContext) error {
+	return b.put(boltContextsBucket, agentCtx.RequestID, agentCtx)
+}
+
+// LoadContext loads the agent context. The returned context is bound
+// to ctx, since the original context.Context cannot be persisted.
+func (b *BoltStateStore) LoadContext(ctx context.Context, requestID string) (*AgentContext, error) {
+	agentCtx := &AgentContext{}
+	found, err := b.get(boltContextsBucket, requestID, agentCtx)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("context not found for request ID: %s", requestID)
+	}
+
+	agentCtx.ctx = ctx
+	if agentCtx.State == nil {
+		agentCtx.State = make(map[string]interface{})
+	}
+	return agentCtx, nil
+}
+
+// SaveTask saves a task
+func (b *BoltStateStore) SaveTask(ctx context.Context, task *Task) error {
+	return b.put(boltTasksBucket, task.ID, task)
+}
+
+// LoadTask loads a task
+func (b *BoltStateStore) LoadTask(ctx context.Context, taskID string) (*Task, error) {
+	task := &Task{}
+	found, err := b.get(boltTasksBucket, taskID, task)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("task not found: %s", taskID)
+	}
+	return task, nil
+}
+
+// SavePlan saves an execution plan
+func (b *BoltStateStore) SavePlan(ctx context.Context, plan *ExecutionPlan) error {
+	return b.put(boltPlansBucket, plan.ID, plan)
+}
+
+// LoadPlan loads an execution plan
+func (b *BoltStateStore) LoadPlan(ctx context.Context, planID string) (*ExecutionPlan, error) {
+	plan := &ExecutionPlan{}
+	found, err := b.get(boltPlansBucket, planID, plan)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("plan not found: %s", planID)
+	}
+	return plan, nil
+}
+
+// UpdateTaskStatus updates a task's status. The read-modify-write runs
+// in a single bbolt transaction so concurrent updates cannot interleave.
+func (b *BoltStateStore) UpdateTaskStatus(ctx context.Context, taskID string, status TaskStatus) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltTasksBucket)
+		data := bucket.Get([]byte(taskID))
+		if data == nil {
+			return fmt.Errorf("task not found: %s", taskID)
+		}
+
+		task := &Task{}
+		if err := json.Unmarshal(data, task); err != nil {
+			return fmt.Errorf("failed to decode task %s: %w", taskID, err)
+		}
+		task.Status = status
+
+		encoded, err := json.Marshal(task)
+		if err != nil {
+			return fmt.Errorf("failed to encode task %s: %w", taskID, err)
+		}
+		return bucket.Put([]byte(taskID), encoded)
+	})
+}
+
+// SaveSnapshot stores a snapshot under its request ID
+func (b *BoltStateStore) SaveSnapshot(ctx context.Context, snapshot *ResourceSnapshot) error {
+	if snapshot.RequestID == "" {
+		return fmt.Errorf("snapshot %s has no request ID", snapshot.ID)
+	}
+
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return fmt.Errorf("failed to encode snapshot %s: %w", snapshot.ID, err)
+	}
+
+	return b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltSnapshotsBucket)
+		seq, err := bucket.NextSequence()
+		if err != nil {
+			return fmt.Errorf("failed to allocate snapshot key: %w", err)
+		}
+		key := fmt.Sprintf("%s/%020d", snapshot.RequestID, seq)
+		return bucket.Put([]byte(key), data)
+	})
+}
+
+// LoadSnapshots returns a request's snapshots in the order taken. A
+// request without snapshots yields an empty list, not an error.
+func (b *BoltStateStore) LoadSnapshots(ctx context.Context, requestID string) ([]*ResourceSnapshot, error) {
+	snapshots := make([]*ResourceSnapshot, 0)
+	prefix := []byte(requestID + "/")
+	err := b.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(boltSnapshotsBucket).Cursor()
+		for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			snapshot := &ResourceSnapshot{}
+			if err := json.Unmarshal(v, snapshot); err != nil {
+				return fmt.Errorf("failed to decode snapshot %s: %w", k, err)
+			}
+			snapshots = append(snapshots, snapshot)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return snapshots, nil
+}
+
+// SaveApproval stores an approval, replacing an earlier record with
+// the same ID
+func (b *BoltStateStore) SaveApproval(ctx context.Context, record *harness.ApprovalRecord) error {
+	return b.put(boltApprovalsBucket, record.ID, record)
+}
+
+// LoadApprovals returns every stored approval, oldest first
+func (b *BoltStateStore) LoadApprovals(ctx context.Context) ([]*harness.ApprovalRecord, error) {
+	records := make([]*harness.ApprovalRecord, 0)
+	err := b.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltApprovalsBucket).ForEach(func(k, v []byte) error {
+			record := &harness.ApprovalRecord{}
+			if err := json.Unmarshal(v, record); err != nil {
+				return fmt.Errorf("failed to decode approval %s: %w", k, err)
+			}
+			records = append(records, record)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	sortApprovals(records)
+	return records, nil
+}
+
+// ListPlans returns every stored plan, newest first. Used by the CLI
+// to show which plans can be resumed.
+func (b *BoltStateStore) ListPlans(ctx context.Context) ([]*ExecutionPlan, error) {
+	plans := make([]*ExecutionPlan, 0)
+	err := b.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltPlansBucket).ForEach(func(k, v []byte) error {
+			plan := &ExecutionPlan{}
+			if err := json.Unmarshal(v, plan); err != nil {
+				return fmt.Errorf("failed to decode plan %s: %w", k, err)
+			}
+			plans = append(plans, plan)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(plans, func(i, j int) bool {
+		return plans[i].CreatedAt.After(plans[j].CreatedAt)
+	})
+	return plans, nil
+}
+
+// put JSON-encodes value and stores it under key in bucket.
+func (b *BoltStateStore) put(bucket []byte, key string, value interface{}) error {
+	if key == "" {
+		return fmt.Errorf("cannot save to %s with empty key", bucket)
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s/%s: %w", bucket, key, err)
+	}
+
+	return b.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucket).Put([]byte(key), data)
+	})
+}
+
+// get decodes the value under key into out. found is false when the
+// key does not exist; that is not an error at this layer so callers can
+// produce the same "not found" messages as MemoryStateStore.
+func (b *BoltStateStore) get(bucket []byte, key string, out interface{}) (found bool, err error) {
+	err = b.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(bucket).Get([]byte(key))
+		if data == nil {
+			return nil
+		}
+		found = true
+		if err := json.Unmarshal(data, out); err != nil {
+			return fmt.Errorf("failed to decode %s/%s: %w", bucket, key, err)
+		}
+		return nil
+	})
+	return found, err
+}
@@ -0,0 +1,220 @@
+package agent
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"kubeagent/pkg/agent/harness"
+)
+
+func newTestBoltStore(t *testing.T) (*BoltStateStore, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "state.db")
+	store, err := NewBoltStateStore(path)
+	if err != nil {
+		t.Fatalf("Failed to open bolt store: %v", err)
+	}
+	return store, path
+}
+
+// TestBoltStateStore_SurvivesReopen is the reason this store exists:
+// whatever was saved must still be there after the process (here: the
+// DB handle) goes away.
+func TestBoltStateStore_SurvivesReopen(t *testing.T) {
+	store, path := newTestBoltStore(t)
+	bg := context.Background()
+
+	agentCtx := NewAgentContext(bg, "req-001", "test-user", "trace-001")
+	agentCtx.SetState("namespace", "demo")
+	if err := store.SaveContext(bg, agentCtx); err != nil {
+		t.Fatalf("Failed to save context: %v", err)
+	}
+
+	plan := &ExecutionPlan{
+		ID:        "plan-001",
+		RequestID: "req-001",
+		Status:    TaskStatusRunning,
+		Tasks: []*Task{
+			{ID: "diagnose-pod", Type: TaskTypeDiagnose, Status: TaskStatusCompleted,
+				Output: map[string]interface{}{"root_cause": "OOMKilled"}},
+			{ID: "fix-pod", Type: TaskTypeRemediate, Status: TaskStatusPending,
+				Dependencies: []string{"diagnose-pod"}},
+		},
+		CreatedAt: time.Now(),
+	}
+	if err := store.SavePlan(bg, plan); err != nil {
+		t.Fatalf("Failed to save plan: %v", err)
+	}
+	if err := store.SaveTask(bg, plan.Tasks[1]); err != nil {
+		t.Fatalf("Failed to save task: %v", err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatalf("Failed to close store: %v", err)
+	}
+
+	reopened, err := NewBoltStateStore(path)
+	if err != nil {
+		t.Fatalf("Failed to reopen bolt store: %v", err)
+	}
+	defer reopened.Close()
+
+	loadedCtx, err := reopened.LoadContext(bg, "req-001")
+	if err != nil {
+		t.Fatalf("Failed to load context: %v", err)
+	}
+	if loadedCtx.Context() == nil {
+		t.Error("Expected loaded context to be bound to a context.Context")
+	}
+	if ns, _ := loadedCtx.GetState("namespace"); ns != "demo" {
+		t.Errorf("Expected state namespace=demo, got %v", ns)
+	}
+
+	loadedPlan, err := reopened.LoadPlan(bg, "plan-001")
+	if err != nil {
+		t.Fatalf("Failed to load plan: %v", err)
+	}
+	if len(loadedPlan.Tasks) != 2 {
+		t.Fatalf("Expected 2 tasks, got %d", len(loadedPlan.Tasks))
+	}
+	if loadedPlan.Tasks[0].Output["root_cause"] != "OOMKilled" {
+		t.Errorf("Expected task output to round-trip, got %v", loadedPlan.Tasks[0].Output)
+	}
+	if deps := loadedPlan.Tasks[1].Dependencies; len(deps) != 1 || deps[0] != "diagnose-pod" {
+		t.Errorf("Expected dependencies to round-trip, got %v", deps)
+	}
+
+	if err := reopened.UpdateTaskStatus(bg, "fix-pod", TaskStatusFailed); err != nil {
+		t.Fatalf("Failed to update task status: %v", err)
+	}
+	task, err := reopened.LoadTask(bg, "fix-pod")
+	if err != nil {
+		t.Fatalf("Failed to load task: %v", err)
+	}
+	if task.Status != TaskStatusFailed {
+		t.Errorf("Expected status failed, got %s", task.Status)
+	}
+}
+
+func TestBoltStateStore_NotFound(t *testing.T) {
+	store, _ := newTestBoltStore(t)
+	defer store.Close()
+	bg := context.Background()
+
+	if _, err := store.LoadPlan(bg, "missing"); err == nil {
+		t.Error("Expected error loading missing plan")
+	}
+	if _, err := store.LoadTask(bg, "missing"); err == nil {
+		t.Error("Expected error loading missing task")
+	}
+	if _, err := store.LoadContext(bg, "missing"); err == nil {
+		t.Error("Expected error loading missing context")
+	}
+	if err := store.UpdateTaskStatus(bg, "missing", TaskStatusCompleted); err == nil {
+		t.Error("Expected error updating missing task")
+	}
+}
+
+func TestBoltStateStore_ListPlansNewestFirst(t *testing.T) {
+	store, _ := newTestBoltStore(t)
+	defer store.Close()
+	bg := context.Background()
+
+	now := time.Now()
+	store.SavePlan(bg, &ExecutionPlan{ID: "older", CreatedAt: now.Add(-time.Hour)})
+	store.SavePlan(bg, &ExecutionPlan{ID: "newer", CreatedAt: now})
+
+	plans, err := store.ListPlans(bg)
+	if err != nil {
+		t.Fatalf("Failed to list plans: %v", err)
+	}
+	if len(plans) != 2 || plans[0].ID != "newer" || plans[1].ID != "older" {
+		t.Errorf("Expected [newer older], got %v", plans)
+	}
+}
+
+// TestBoltStateStore_Snapshots checks snapshots come back per request,
+// in capture order, after a reopen. "req-1" must not pick up "req-10":
+// the key prefix includes the separator.
+func TestBoltStateStore_Snapshots(t *testing.T) {
+	store, path := newTestBoltStore(t)
+	bg := context.Background()
+
+	saves := []*ResourceSnapshot{
+		{ID: "a", RequestID: "req-1", Resource: "Deployment", Name: "web", Object: map[string]interface{}{"kind": "Deployment"}},
+		{ID: "b", RequestID: "req-10", Resource: "Pod", Name: "other"},
+		{ID: "c", RequestID: "req-1", Resource: "Pod", Name: "web-1"},
+	}
+	for _, s := range saves {
+		if err := store.SaveSnapshot(bg, s); err != nil {
+			t.Fatalf("Failed to save snapshot %s: %v", s.ID, err)
+		}
+	}
+	if err := store.SaveSnapshot(bg, &ResourceSnapshot{ID: "d"}); err == nil {
+		t.Error("Expected error saving snapshot without request ID")
+	}
+	store.Close()
+
+	reopened, err := NewBoltStateStore(path)
+	if err != nil {
+		t.Fatalf("Failed to reopen: %v", err)
+	}
+	defer reopened.Close()
+
+	got, err := reopened.LoadSnapshots(bg, "req-1")
+	if err != nil {
+		t.Fatalf("LoadSnapshots failed: %v", err)
+	}
+	if len(got) != 2 || got[0].ID != "a" || got[1].ID != "c" {
+		t.Fatalf("Expected snapshots a, c in order, got %+v", got)
+	}
+	if got[0].Object["kind"] != "Deployment" || got[1].Object != nil {
+		t.Errorf("Snapshot objects not preserved: %+v, %+v", got[0].Object, got[1].Object)
+	}
+
+	none, err := reopened.LoadSnapshots(bg, "req-2")
+	if err != nil || len(none) != 0 {
+		t.Errorf("Expected no snapshots for unknown request, got %v (err %v)", none, err)
+	}
+}
+
+func TestBoltStateStore_Approvals(t *testing.T) {
+	store, path := newTestBoltStore(t)
+	bg := context.Background()
+	now := time.Now()
+
+	first := &harness.ApprovalRecord{ApprovalRequest: harness.ApprovalRequest{ID: "a", Prompt: "patch web?", CreatedAt: now}, Status: harness.ApprovalPending}
+	second := &harness.ApprovalRecord{ApprovalRequest: harness.ApprovalRequest{ID: "b", Prompt: "delete web?", CreatedAt: now.Add(time.Second)}, Status: harness.ApprovalPending}
+	for _, record := range []*harness.ApprovalRecord{second, first} {
+		if err := store.SaveApproval(bg, record); err != nil {
+			t.Fatalf("Failed to save approval %s: %v", record.ID, err)
+		}
+	}
+	first.Status = harness.ApprovalApproved
+	first.Decision = &harness.ApprovalDecision{Approved: true, Approver: "alice"}
+	if err := store.SaveApproval(bg, first); err != nil {
+		t.Fatalf("Failed to update approval: %v", err)
+	}
+	store.Close()
+
+	reopened, err := NewBoltStateStore(path)
+	if err != nil {
+		t.Fatalf("Failed to reopen: %v", err)
+	}
+	defer reopened.Close()
+
+	got, err := reopened.LoadApprovals(bg)
+	if err != nil {
+		t.Fatalf("LoadApprovals failed: %v", err)
+	}
+	if len(got) != 2 || got[0].ID != "a" || got[1].ID != "b" {
+		t.Fatalf("Expected approvals a, b oldest first, got %+v", got)
+	}
+	if got[0].Status != harness.ApprovalApproved || got[0].Decision == nil || got[0].Decision.Approver != "alice" {
+		t.Errorf("Expected the decision to survive, got %+v", got[0])
+	}
+	if got[1].Status != harness.ApprovalPending || got[1].Prompt != "delete web?" {
+		t.Errorf("Unexpected pending record %+v", got[1])
+	}
+}
@@ -0,0 +1,325 @@
+package agent
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Cassette methods. One per LLMClient method so a Complete and a
+// CompleteWithTools call with identical messages never collide.
+const (
+	CassetteMethodComplete          = "complete"
+	CassetteMethodCompleteWithTools = "complete_with_tools"
+)
+
+// Cassette is the on-disk format shared by RecordingLLMClient and
+// ReplayLLMClient: an ordered list of LLM round-trips. It is plain,
+// indented JSON so a recorded demo can be reviewed (and hand-edited)
+// in a pull request.
+type Cassette struct {
+	Interactions []CassetteInteraction `json:"interactions"`
+}
+
+// CassetteInteraction is one recorded LLM call.
+//
+// Hash identifies the request (see RequestHash) and is what replay
+// matches on; Request is stored alongside purely so humans can see what
+// was asked. Error is set instead of Response when the wrapped client
+// failed, so failure paths replay deterministically too.
+type CassetteInteraction struct {
+	Hash     string          `json:"hash"`
+	Method   string          `json:"method"`
+	Request  CassetteRequest `json:"request"`
+	Response *LLMResponse    `json:"response,omitempty"`
+	Error    string          `json:"error,omitempty"`
+}
+
+// CassetteRequest is the human-readable half of an interaction.
+type CassetteRequest struct {
+	Messages []Message `json:"messages"`
+	Tools    []string  `json:"tools,omitempty"`
+}
+
+// RequestHash returns the replay key for a call. Tools contribute only
+// their names: descriptions are prose that gets tweaked often, and
+// re-recording every cassette for a wording change is not worth it.
+// json.Marshal sorts map keys, so tool-call arguments hash stably.
+func RequestHash(method string, messages []Message, tools []Tool) string {
+	data, _ := json.Marshal(struct {
+		Method   string    `json:"method"`
+		Messages []Message `json:"messages"`
+		Tools    []string  `json:"tools,omitempty"`
+	}{method, messages, toolNames(tools)})
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// LoadCassette reads a cassette file.
+func LoadCassette(path string) (*Cassette, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cassette %s: %w", path, err)
+	}
+	cassette := &Cassette{}
+	if err := json.Unmarshal(data, cassette); err != nil {
+		return nil, fmt.Errorf("failed to decode cassette %s: %w", path, err)
+	}
+	return cassette, nil
+}
+
+// save writes the cassette atomically (temp file + rename) so a crash
+// mid-write never leaves a truncated cassette behind.
+func (c *Cassette) save(path string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode cassette: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create cassette temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write cassette: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write cassette: %w", err)
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// RecordingLLMClient wraps any LLMClient and writes every call it
+// forwards to a cassette file. The file is rewritten after each call,
+// so a run that crashes half-way still leaves a usable (partial)
+// cassette.
+type RecordingLLMClient struct {
+	inner    LLMClient
+	path     string
+	mu       sync.Mutex
+	cassette Cassette
+	// parent owns the cassette for clients returned by ForAgent.
+	parent *RecordingLLMClient
+}
+
+// NewRecordingLLMClient starts a fresh cassette at path, overwriting
+// any existing file on the first recorded call.
+func NewRecordingLLMClient(inner LLMClient, path string) *RecordingLLMClient {
+	return &RecordingLLMClient{
+		inner: inner,
+		path:  path,
+	}
+}
+
+// Complete implements LLMClient
+func (r *RecordingLLMClient) Complete(ctx context.Context, messages []Message) (string, error) {
+	content, err := r.inner.Complete(ctx, messages)
+
+	interaction := CassetteInteraction{
+		Hash:    RequestHash(CassetteMethodComplete, messages, nil),
+		Method:  CassetteMethodComplete,
+		Request: CassetteRequest{Messages: messages},
+	}
+	if err != nil {
+		interaction.Error = err.Error()
+	} else {
+		interaction.Response = &LLMResponse{Content: content}
+	}
+
+	if recErr := r.record(interaction); recErr != nil {
+		return content, errors.Join(err, recErr)
+	}
+	return content, err
+}
+
+// CompleteWithTools implements LLMClient
+func (r *RecordingLLMClient) CompleteWithTools(ctx context.Context, messages []Message, tools []Tool) (*LLMResponse, error) {
+	resp, err := r.inner.CompleteWithTools(ctx, messages, tools)
+
+	interaction := CassetteInteraction{
+		Hash:   RequestHash(CassetteMethodCompleteWithTools, messages, tools),
+		Method: CassetteMethodCompleteWithTools,
+		Request: CassetteRequest{
+			Messages: messages,
+			Tools:    toolNames(tools),
+		},
+		Response: resp,
+	}
+	if err != nil {
+		interaction.Error = err.Error()
+		interaction.Response = nil
+	}
+
+	if recErr := r.record(interaction); recErr != nil {
+		return resp, errors.Join(err, recErr)
+	}
+	return resp, err
+}
+
+// ForAgent implements AgentScopedLLMClient so per-agent routing in the
+// wrapped client survives recording. All scoped clients write to the
+// same cassette.
+func (r *RecordingLLMClient) ForAgent(config *AgentConfig) LLMClient {
+	root := r
+	if r.parent != nil {
+		root = r.parent
+	}
+	return &RecordingLLMClient{
+		inner:  scopeToAgent(root.inner, config),
+		path:   root.path,
+		parent: root,
+	}
+}
+
+func (r *RecordingLLMClient) record(interaction CassetteInteraction) error {
+	if r.parent != nil {
+		return r.parent.record(interaction)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Copy messages so later appends by the caller's tool loop don't
+	// alias into the recorded slice.
+	interaction.Request.Messages = append([]Message(nil), interaction.Request.Messages...)
+	r.cassette.Interactions = append(r.cassette.Interactions, interaction)
+
+	if err := r.cassette.save(r.path); err != nil {
+		return fmt.Errorf("cassette: %w", err)
+	}
+	return nil
+}
+
+// ReplayLLMClient serves LLM calls from a cassette instead of the
+// network. Requests are matched by RequestHash; when the same request
+// was recorded several times, the recordings are served in order.
+//
+// By default a request with no recording is an error, which is what
+// tests want. Live demos replay against a real cluster whose logs and
+// timestamps drift between runs, so WithSequentialFallback lets a miss
+// fall back to the next unplayed recording of the same method.
+type ReplayLLMClient struct {
+	mu         sync.Mutex
+	byHash     map[string][]int
+	played     []bool
+	cassette   *Cassette
+	sequential bool
+}
+
+// NewReplayLLMClient loads the cassette at path.
+func NewReplayLLMClient(path string) (*ReplayLLMClient, error) {
+	cassette, err := LoadCassette(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewReplayLLMClientFromCassette(cassette), nil
+}
+
+// NewReplayLLMClientFromCassette replays an in-memory cassette.
+func NewReplayLLMClientFromCassette(cassette *Cassette) *ReplayLLMClient {
+	byHash := make(map[string][]int)
+	for i, interaction := range cassette.Interactions {
+		byHash[interaction.Hash] = append(byHash[interaction.Hash], i)
+	}
+	return &ReplayLLMClient{
+		byHash:   byHash,
+		played:   make([]bool, len(cassette.Interactions)),
+		cassette: cassette,
+	}
+}
+
+// WithSequentialFallback serves the next unplayed recording of the same
+// method when a request hash has no match.
+func (r *ReplayLLMClient) WithSequentialFallback() *ReplayLLMClient {
+	r.sequential = true
+	return r
+}
+
+// Complete implements LLMClient
+func (r *ReplayLLMClient) Complete(ctx context.Context, messages []Message) (string, error) {
+	interaction, err := r.next(CassetteMethodComplete, RequestHash(CassetteMethodComplete, messages, nil))
+	if err != nil {
+		return "", err
+	}
+	if interaction.Error != "" {
+		return "", errors.New(interaction.Error)
+	}
+	if interaction.Response == nil {
+		return "", nil
+	}
+	return interaction.Response.Content, nil
+}
+
+// CompleteWithTools implements LLMClient
+func (r *ReplayLLMClient) CompleteWithTools(ctx context.Context, messages []Message, tools []Tool) (*LLMResponse, error) {
+	interaction, err := r.next(CassetteMethodCompleteWithTools, RequestHash(CassetteMethodCompleteWithTools, messages, tools))
+	if err != nil {
+		return nil, err
+	}
+	if interaction.Error != "" {
+		return nil, errors.New(interaction.Error)
+	}
+	if interaction.Response == nil {
+		return &LLMResponse{}, nil
+	}
+	resp := *interaction.Response
+	return &resp, nil
+}
+
+// Remaining reports how many recordings have not been served yet.
+// Tests use it to assert a run consumed the whole cassette.
+func (r *ReplayLLMClient) Remaining() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	remaining := 0
+	for _, played := range r.played {
+		if !played {
+			remaining++
+		}
+	}
+	return remaining
+}
+
+func (r *ReplayLLMClient) next(method, hash string) (*CassetteInteraction, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, i := range r.byHash[hash] {
+		if !r.played[i] {
+			r.played[i] = true
+			return &r.cassette.Interactions[i], nil
+		}
+	}
+
+	if r.sequential {
+		for i := range r.cassette.Interactions {
+			if !r.played[i] && r.cassette.Interactions[i].Method == method {
+				r.played[i] = true
+				return &r.cassette.Interactions[i], nil
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("cassette: no recorded %s response for request %s", method, hash[:12])
+}
+
+func toolNames(tools []Tool) []string {
+	if len(tools) == 0 {
+		return nil
+	}
+	names := make([]string, len(tools))
+	for i, tool := range tools {
+		names[i] = tool.Name()
+	}
+	return names
+}
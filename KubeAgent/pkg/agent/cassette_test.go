@@ -0,0 +1,164 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// echoTool is a minimal Tool for driving RunToolLoop in cassette tests.
+type echoTool struct{}
+
+func (echoTool) Name() string        { return "echo" }
+func (echoTool) Description() string { return "echoes its input" }
+func (echoTool) ArgsSchema() string  { return `{"type":"object"}` }
+func (echoTool) Execute(params map[string]interface{}) (string, error) {
+	return "echo: " + stringArg(params, "text"), nil
+}
+
+func stringArg(params map[string]interface{}, key string) string {
+	s, _ := params[key].(string)
+	return s
+}
+
+// scriptedToolLoopLLM answers the first CompleteWithTools call with a
+// tool call and the second with a final answer built from the tool
+// result, i.e. one full RunToolLoop round-trip.
+func scriptedToolLoopLLM() *MockLLMClient {
+	return &MockLLMClient{
+		CompleteWithToolsFunc: func(ctx context.Context, messages []Message, tools []Tool) (*LLMResponse, error) {
+			last := messages[len(messages)-1]
+			if last.Role == "tool" {
+				return &LLMResponse{Content: "final: " + last.Content, FinishReason: "end_turn"}, nil
+			}
+			return &LLMResponse{
+				ToolCalls: []ToolCall{{
+					ID:        "call-1",
+					Name:      "echo",
+					Arguments: map[string]interface{}{"text": "hello", "count": 2},
+				}},
+				FinishReason: "tool_use",
+			}, nil
+		},
+	}
+}
+
+func newCassetteTestAgent(llmClient LLMClient) *BaseAgent {
+	agent := NewBaseAgent(&AgentConfig{Type: AgentTypeDiagnostician}, llmClient, NewNoOpLogger())
+	agent.AddTool(echoTool{})
+	return agent
+}
+
+// TestCassette_RecordThenReplayToolLoop records a full tool loop and
+// replays it with no live client at all: the replayed run must produce
+// the same answer and consume every recording, tool calls included.
+func TestCassette_RecordThenReplayToolLoop(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	ctx := NewAgentContext(context.Background(), "req-001", "test-user", "trace-001")
+
+	recorder := NewRecordingLLMClient(scriptedToolLoopLLM(), path)
+	recorded, err := newCassetteTestAgent(recorder).RunToolLoop(ctx, "system", "say hello", 0)
+	if err != nil {
+		t.Fatalf("Recording run failed: %v", err)
+	}
+	if recorded != "final: echo: hello" {
+		t.Fatalf("Unexpected recorded output: %q", recorded)
+	}
+
+	cassette, err := LoadCassette(path)
+	if err != nil {
+		t.Fatalf("Failed to load cassette: %v", err)
+	}
+	if len(cassette.Interactions) != 2 {
+		t.Fatalf("Expected 2 interactions, got %d", len(cassette.Interactions))
+	}
+	first := cassette.Interactions[0]
+	if len(first.Response.ToolCalls) != 1 || first.Response.ToolCalls[0].Name != "echo" {
+		t.Errorf("Tool call not recorded: %+v", first.Response)
+	}
+	if len(first.Request.Tools) != 1 || first.Request.Tools[0] != "echo" {
+		t.Errorf("Tool names not recorded: %v", first.Request.Tools)
+	}
+
+	replay, err := NewReplayLLMClient(path)
+	if err != nil {
+		t.Fatalf("Failed to open replay client: %v", err)
+	}
+	replayed, err := newCassetteTestAgent(replay).RunToolLoop(ctx, "system", "say hello", 0)
+	if err != nil {
+		t.Fatalf("Replay run failed: %v", err)
+	}
+	if replayed != recorded {
+		t.Errorf("Replay diverged: got %q, want %q", replayed, recorded)
+	}
+	if replay.Remaining() != 0 {
+		t.Errorf("Expected cassette to be fully consumed, %d left", replay.Remaining())
+	}
+}
+
+// TestCassette_ReplayMiss checks that strict replay refuses unknown
+// requests, while sequential fallback serves the next recording of the
+// same method.
+func TestCassette_ReplayMiss(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	bg := context.Background()
+
+	recorder := NewRecordingLLMClient(&MockLLMClient{
+		CompleteFunc: func(ctx context.Context, messages []Message) (string, error) {
+			return "recorded answer", nil
+		},
+	}, path)
+	if _, err := recorder.Complete(bg, []Message{{Role: "user", Content: "pod logs at 10:00"}}); err != nil {
+		t.Fatalf("Recording failed: %v", err)
+	}
+
+	drifted := []Message{{Role: "user", Content: "pod logs at 10:05"}}
+
+	strict, err := NewReplayLLMClient(path)
+	if err != nil {
+		t.Fatalf("Failed to open replay client: %v", err)
+	}
+	if _, err := strict.Complete(bg, drifted); err == nil || !strings.Contains(err.Error(), "no recorded") {
+		t.Errorf("Expected a miss error, got %v", err)
+	}
+
+	lenient, err := NewReplayLLMClient(path)
+	if err != nil {
+		t.Fatalf("Failed to open replay client: %v", err)
+	}
+	lenient.WithSequentialFallback()
+	got, err := lenient.Complete(bg, drifted)
+	if err != nil || got != "recorded answer" {
+		t.Errorf("Expected fallback answer, got %q (err %v)", got, err)
+	}
+	if _, err := lenient.CompleteWithTools(bg, drifted, nil); err == nil {
+		t.Error("Fallback must not cross methods")
+	}
+}
+
+// TestCassette_ReplaysErrors ensures a failed upstream call is recorded
+// and comes back as an error on replay, so failure paths are testable.
+func TestCassette_ReplaysErrors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	bg := context.Background()
+	messages := []Message{{Role: "user", Content: "hi"}}
+
+	recorder := NewRecordingLLMClient(&MockLLMClient{
+		CompleteWithToolsFunc: func(ctx context.Context, messages []Message, tools []Tool) (*LLMResponse, error) {
+			return nil, errors.New("rate limited")
+		},
+	}, path)
+	if _, err := recorder.CompleteWithTools(bg, messages, []Tool{echoTool{}}); err == nil {
+		t.Fatal("Expected recorder to pass the error through")
+	}
+
+	replay, err := NewReplayLLMClient(path)
+	if err != nil {
+		t.Fatalf("Failed to open replay client: %v", err)
+	}
+	if _, err := replay.CompleteWithTools(bg, messages, []Tool{echoTool{}}); err == nil || err.Error() != "rate limited" {
+		t.Errorf("Expected recorded error, got %v", err)
+	}
+}
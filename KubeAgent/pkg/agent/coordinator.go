@@ -1,25 +1,35 @@
 package agent
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"strings"
 	"sync"
 	"time"
 
 	"github.com/google/uuid"
+
+	"kubeagent/pkg/agent/harness"
 )
 
 // BaseCoordinator implements the CoordinatorAgent interface
 type BaseCoordinator struct {
-	config         *AgentConfig
-	agents         map[AgentType]Agent
-	agentsMutex    sync.RWMutex
-	llmClient      LLMClient
-	stateStore     StateStore
-	toolRegistry   ToolRegistry
-	logger         Logger
-	metrics        *AgentMetrics
+	config       *AgentConfig
+	agents       map[AgentType]Agent
+	agentsMutex  sync.RWMutex
+	llmClient    LLMClient
+	stateStore   StateStore
+	toolRegistry ToolRegistry
+	logger       Logger
+	metrics      *AgentMetrics
+	metricsMu    sync.Mutex
+	planRepairs  int
+	taskRetry    harness.RetryPolicy
+	slots        *dispatchSlots
+	iterations   int
+	auditor      harness.AuditLogger
+	trail        *harness.AuditTrail
 }
 
 // NewCoordinator creates a new coordinator agent
@@ -35,17 +45,50 @@ func NewCoordinator(config *AgentConfig, llmClient LLMClient, stateStore StateSt
 	}
 
 	return &BaseCoordinator{
-		config:      config,
-		agents:      make(map[AgentType]Agent),
-		llmClient:   llmClient,
-		stateStore:  stateStore,
-		logger:      logger,
+		config:     config,
+		agents:     make(map[AgentType]Agent),
+		llmClient:  scopeToAgent(llmClient, config),
+		stateStore: stateStore,
+		logger:     logger,
 		metrics: &AgentMetrics{
 			AgentType: AgentTypeCoordinator,
 		},
+		planRepairs: DefaultPlanRepairRounds,
+		taskRetry:   harness.DefaultRetryPolicy(),
+		slots:       newDispatchSlots(DefaultSchedulerConfig()),
+		iterations:  DefaultRemediationIterations,
+		auditor:     harness.NoopAuditor{},
 	}
 }
 
+// WithScheduler sets the concurrency and rate limits ExecutePlan runs
+// tasks under. The limits are shared by concurrent ExecutePlan calls,
+// so under serve or the operator they bound the whole process. Set it
+// before running plans.
+func (c *BaseCoordinator) WithScheduler(config SchedulerConfig) *BaseCoordinator {
+	c.slots = newDispatchSlots(config)
+	return c
+}
+
+// WithTaskRetryPolicy sets the backoff between attempts of a failed
+// task. The number of attempts always comes from the agent's
+// AgentConfig.MaxRetries, so MaxAttempts is ignored.
+func (c *BaseCoordinator) WithTaskRetryPolicy(p harness.RetryPolicy) *BaseCoordinator {
+	c.taskRetry = p
+	return c
+}
+
+// WithPlanRepairRounds sets how many repair rounds a rejected task
+// decomposition gets before Plan falls back to a single task. Zero
+// disables repair; negative values are treated as zero.
+func (c *BaseCoordinator) WithPlanRepairRounds(n int) *BaseCoordinator {
+	if n < 0 {
+		n = 0
+	}
+	c.planRepairs = n
+	return c
+}
+
 // Name returns the coordinator's name
 func (c *BaseCoordinator) Name() string {
 	return c.config.Name
@@ -69,6 +112,27 @@ func (c *BaseCoordinator) CanHandle(taskType TaskType) bool {
 
 // Execute executes a single task (usually delegates to specialist)
 func (c *BaseCoordinator) Execute(ctx *AgentContext, task *Task) (*Task, error) {
+	agents, _ := c.route(task)
+	return c.executeRanked(ctx, task, agents, nil)
+}
+
+// executeRanked runs task on agents[0] and, when that fails in a way
+// another agent might not, on the runner-up. Under the scheduler, slots
+// already holds a slot for agents[0] (or the assigned agent when none
+// is eligible): the fallback gives it back and waits for one of its
+// own, so it runs under its own AgentLimit, and whatever slot is held
+// at the end is released on return.
+func (c *BaseCoordinator) executeRanked(ctx *AgentContext, task *Task, agents []Agent, slots *dispatchSlots) (*Task, error) {
+	holding, held := slots != nil, task.AssignedAgent
+	if len(agents) > 0 {
+		held = agents[0].Type()
+	}
+	defer func() {
+		if holding {
+			slots.release(held)
+		}
+	}()
+
 	c.logger.Info("Coordinator executing task", map[string]interface{}{
 		"task_id":   task.ID,
 		"task_type": task.Type,
@@ -89,36 +153,72 @@ func (c *BaseCoordinator) Execute(ctx *AgentContext, task *Task) (*Task, error)
 		}
 	}
 
-	// Find appropriate agent for the task
-	agent, err := c.selectAgentForTask(task)
-	if err != nil {
+	// The agents are ranked for the task; the runner-up is the fallback
+	if len(agents) == 0 {
+		err := fmt.Errorf("no agent available to handle task type: %s", task.Type)
 		task.Status = TaskStatusFailed
 		task.Error = err.Error()
 		completedAt := time.Now()
 		task.CompletedAt = &completedAt
 		return task, err
 	}
-
-	// Execute task with selected agent
-	result, err := agent.Execute(ctx, task)
-	if err != nil {
-		c.logger.Error("Agent execution failed", map[string]interface{}{
+	agent := agents[0]
+
+	// Execute task with selected agent. The context is scoped to the
+	// task so concurrent tasks' stream events can be told apart.
+	taskCtx := ctx.ForTask(task.ID)
+	taskCtx.Emit(StreamEvent{Type: StreamTaskStarted, Agent: agent.Type(), Text: task.Description})
+	result, err := c.runAttempts(taskCtx, agent, task)
+	if err != nil && len(agents) > 1 && shouldFallBack(ctx, task, err) {
+		c.logger.Warn("Agent failed; falling back to next candidate", map[string]interface{}{
 			"task_id":    task.ID,
 			"agent_type": agent.Type(),
+			"fallback":   agents[1].Type(),
 			"error":      err.Error(),
 		})
-		task.Status = TaskStatusFailed
-		task.Error = err.Error()
-	} else {
+		agent = agents[1]
+		var waitErr error
+		if holding {
+			slots.release(held)
+			holding = false
+			if waitErr = slots.wait(ctx.Context(), agent.Type()); waitErr == nil {
+				holding, held = true, agent.Type()
+			}
+		}
+		if err = waitErr; err == nil {
+			taskCtx.Emit(StreamEvent{Type: StreamTaskStarted, Agent: agent.Type(), Text: task.Description})
+			result, err = c.runAttempts(taskCtx, agent, task)
+		}
+	}
+	switch {
+	case err == nil:
 		c.logger.Info("Agent execution completed", map[string]interface{}{
 			"task_id":    task.ID,
 			"agent_type": agent.Type(),
+			"attempts":   len(task.Attempts),
 		})
 		task.Status = TaskStatusCompleted
+	case ctx.Context().Err() != nil:
+		c.logger.Warn("Task cancelled", map[string]interface{}{
+			"task_id":    task.ID,
+			"agent_type": agent.Type(),
+		})
+		task.Status = TaskStatusCancelled
+		task.Error = err.Error()
+	default:
+		c.logger.Error("Agent execution failed", map[string]interface{}{
+			"task_id":    task.ID,
+			"agent_type": agent.Type(),
+			"attempts":   len(task.Attempts),
+			"error":      err.Error(),
+		})
+		task.Status = TaskStatusFailed
+		task.Error = err.Error()
 	}
 
 	completedAt := time.Now()
 	task.CompletedAt = &completedAt
+	taskCtx.Emit(StreamEvent{Type: StreamTaskFinished, Agent: agent.Type(), Status: task.Status, Text: task.Error})
 
 	// Save final task state
 	if c.stateStore != nil {
@@ -130,6 +230,109 @@ func (c *BaseCoordinator) Execute(ctx *AgentContext, task *Task) (*Task, error)
 		}
 	}
 
+	if result == nil {
+		result = task
+	}
+	return result, err
+}
+
+// ErrChangesApplied marks (wrapped) a failure that came after the agent
+// had already changed the cluster. A retry would start the change over
+// on top of the half-applied one, so runAttempts does not retry it.
+var ErrChangesApplied = errors.New("changes were already applied")
+
+// runAttempts runs task on agent under the agent's AgentConfig: each
+// attempt gets Timeout as its deadline, and failed attempts are retried
+// up to MaxRetries times with the coordinator's backoff. Exhausted
+// budgets, cancellation, failed post-action verification, failures
+// after a write (ErrChangesApplied) and errors marked
+// harness.PermanentError are not retried. Every attempt is appended to
+// task.Attempts.
+//
+// The deadline is cooperative: it reaches the agent's LLM calls through
+// the context, but a tool that ignores it runs to completion. Its clock
+// stops while an ApprovalGate waits for a person, so Timeout bounds the
+// agent's work and the gate's own timeout bounds the approval.
+func (c *BaseCoordinator) runAttempts(ctx *AgentContext, agent Agent, task *Task) (*Task, error) {
+	config := agent.Config()
+	policy := c.taskRetry
+	policy.MaxAttempts = 1
+	if config != nil && config.MaxRetries > 0 {
+		policy.MaxAttempts = config.MaxRetries + 1
+	}
+	var timeout time.Duration
+	if config != nil {
+		timeout = config.Timeout
+	}
+
+	var result *Task
+	var lastErr error
+	attempts := 0
+	err := policy.Do(ctx.Context(), func(runCtx context.Context) error {
+		attempts++
+		attemptCtx, cancel := runCtx, context.CancelFunc(func() {})
+		if timeout > 0 {
+			attemptCtx, cancel = harness.WithPausableTimeout(runCtx, timeout)
+		}
+		defer cancel()
+
+		attempt := TaskAttempt{
+			Number:    len(task.Attempts) + 1,
+			Agent:     agent.Type(),
+			StartedAt: time.Now(),
+		}
+		task.Output = nil
+		task.Error = ""
+
+		res, err := agent.Execute(ctx.withContext(attemptCtx), task)
+		if err != nil && runCtx.Err() == nil && errors.Is(attemptCtx.Err(), context.DeadlineExceeded) {
+			err = fmt.Errorf("timed out after %s: %w", timeout, err)
+		}
+
+		attempt.FinishedAt = time.Now()
+		switch {
+		case err == nil:
+			attempt.Status = TaskStatusCompleted
+		case runCtx.Err() != nil:
+			attempt.Status = TaskStatusCancelled
+			attempt.Error = err.Error()
+		default:
+			attempt.Status = TaskStatusFailed
+			attempt.Error = err.Error()
+		}
+		task.Attempts = append(task.Attempts, attempt)
+
+		if err != nil {
+			lastErr = err
+			c.logger.Warn("Task attempt failed", map[string]interface{}{
+				"task_id":      task.ID,
+				"agent_type":   agent.Type(),
+				"attempt":      attempt.Number,
+				"max_attempts": policy.MaxAttempts,
+				"error":        err.Error(),
+			})
+			if errors.Is(err, ErrBudgetExceeded) || errors.Is(err, context.Canceled) || errors.Is(err, ErrChangesApplied) {
+				return harness.PermanentError(err)
+			}
+			// Retrying would re-apply the fix that just failed to
+			// converge; iterateRemediation re-diagnoses instead.
+			if failedVerification(task) != nil {
+				return harness.PermanentError(err)
+			}
+			return err
+		}
+		result = res
+		return nil
+	})
+	// Report the agent's own error rather than the retry loop's wrapper;
+	// a cancellation between attempts has no agent error to report.
+	if err != nil && lastErr != nil && ctx.Context().Err() == nil {
+		if attempts > 1 {
+			err = fmt.Errorf("failed after %d attempts: %w", attempts, lastErr)
+		} else {
+			err = lastErr
+		}
+	}
 	return result, err
 }
 
@@ -188,7 +391,7 @@ func (c *BaseCoordinator) Plan(ctx *AgentContext, request *Request) (*ExecutionP
 	}
 
 	// Decompose into tasks
-	tasks, err := c.decomposeTasks(ctx, request, intent)
+	tasks, decomposition, err := c.decomposeTasks(ctx, request, intent)
 	if err != nil {
 		return nil, fmt.Errorf("failed to decompose tasks: %w", err)
 	}
@@ -204,12 +407,19 @@ func (c *BaseCoordinator) Plan(ctx *AgentContext, request *Request) (*ExecutionP
 		ExecutionMode: executionMode,
 		Status:        TaskStatusPending,
 		Metadata: map[string]interface{}{
-			"intent": intent,
+			"request":       request.Input,
+			"intent":        intent,
+			"decomposition": decomposition,
+			RoutingKey:      c.explainRouting(tasks),
 		},
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
 	}
 
+	if session := ctx.Session(); session != nil {
+		plan.Metadata["session"] = session.ID()
+	}
+
 	// Save plan
 	if c.stateStore != nil {
 		if err := c.stateStore.SavePlan(ctx.Context(), plan); err != nil {
@@ -248,7 +458,13 @@ func (c *BaseCoordinator) ExecutePlan(ctx *AgentContext, plan *ExecutionPlan) (*
 	// - Tasks with conditions: checked before execution, skipped if not met
 	result, err := c.executeDependencyBased(ctx, plan)
 
-	if err != nil {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		plan.Status = TaskStatusCancelled
+		c.logger.Warn("Plan execution cancelled", map[string]interface{}{
+			"plan_id": plan.ID,
+			"error":   err.Error(),
+		})
+	} else if err != nil {
 		plan.Status = TaskStatusFailed
 		c.logger.Error("Plan execution failed", map[string]interface{}{
 			"plan_id": plan.ID,
@@ -263,6 +479,7 @@ func (c *BaseCoordinator) ExecutePlan(ctx *AgentContext, plan *ExecutionPlan) (*
 	}
 
 	plan.UpdatedAt = time.Now()
+	c.checkpointPlan(ctx, plan)
 
 	// Update metrics
 	c.updateMetrics(time.Since(startTime), err == nil)
@@ -270,6 +487,59 @@ func (c *BaseCoordinator) ExecutePlan(ctx *AgentContext, plan *ExecutionPlan) (*
 	return result, err
 }
 
+// ResumePlan reloads a previously saved plan from the StateStore and
+// continues it. Tasks that already reached completed or skipped are
+// kept as-is (their outputs still feed the final summary); tasks left
+// pending, running (the process died mid-task), failed or cancelled
+// are reset to pending and re-run through the normal dependency-based
+// executor.
+func (c *BaseCoordinator) ResumePlan(ctx *AgentContext, planID string) (*Response, error) {
+	if c.stateStore == nil {
+		return nil, fmt.Errorf("cannot resume plan %s: no state store configured", planID)
+	}
+
+	plan, err := c.stateStore.LoadPlan(ctx.Context(), planID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load plan: %w", err)
+	}
+
+	resumed := 0
+	for _, task := range plan.Tasks {
+		switch task.Status {
+		case TaskStatusCompleted, TaskStatusSkipped:
+			continue
+		}
+		task.Status = TaskStatusPending
+		task.Error = ""
+		task.StartedAt = nil
+		task.CompletedAt = nil
+		resumed++
+	}
+
+	c.logger.Info("Resuming execution plan", map[string]interface{}{
+		"plan_id":       plan.ID,
+		"task_count":    len(plan.Tasks),
+		"resumed_tasks": resumed,
+	})
+
+	return c.ExecutePlan(ctx, plan)
+}
+
+// checkpointPlan persists the plan so an interrupted run can be
+// resumed. Serialising the plan reads every task's fields, so while
+// tasks are in flight it must be given a checkpointSnapshot.
+func (c *BaseCoordinator) checkpointPlan(ctx *AgentContext, plan *ExecutionPlan) {
+	if c.stateStore == nil {
+		return
+	}
+	if err := c.stateStore.SavePlan(ctx.Context(), plan); err != nil {
+		c.logger.Warn("Failed to checkpoint execution plan", map[string]interface{}{
+			"plan_id": plan.ID,
+			"error":   err.Error(),
+		})
+	}
+}
+
 // parseIntent uses LLM to parse user intent
 func (c *BaseCoordinator) parseIntent(ctx *AgentContext, request *Request) (string, error) {
 	if request.Intent != "" {
@@ -288,13 +558,14 @@ Classify the intent into one of these categories:
 - query: User wants to get information
 
 Respond with only the intent category (one word).`, request.Input)
+	prompt = withConversation(ctx, prompt)
 
 	messages := []Message{
 		{Role: "system", Content: "You are a Kubernetes operations assistant."},
 		{Role: "user", Content: prompt},
 	}
 
-	response, err := c.llmClient.Complete(ctx.Context(), messages)
+	response, err := c.complete(ctx, messages)
 	if err != nil {
 		return "", fmt.Errorf("LLM call failed: %w", err)
 	}
@@ -302,133 +573,102 @@ Respond with only the intent category (one word).`, request.Input)
 	return response, nil
 }
 
-// decomposeTasks breaks down the request into individual tasks
-func (c *BaseCoordinator) decomposeTasks(ctx *AgentContext, request *Request, intent string) ([]*Task, error) {
+// complete is the coordinator's own LLM call (intent, decomposition,
+// summary), metered like an agent's. A spent budget fails it before
+// the call is made.
+func (c *BaseCoordinator) complete(ctx *AgentContext, messages []Message) (string, error) {
+	if err := ctx.checkBudget(); err != nil {
+		return "", err
+	}
+	resp, err := completeText(ctx.Context(), c.llmClient, messages)
+	if err != nil {
+		return "", err
+	}
+	_ = ctx.recordUsage(c.config.Type, resp.Usage)
+	return resp.Content, nil
+}
+
+// decomposeTasks breaks down the request into individual tasks. A
+// reply that fails validation is sent back with the problems found, up
+// to planRepairs times; after that the request becomes a single task
+// typed with the intent.
+func (c *BaseCoordinator) decomposeTasks(ctx *AgentContext, request *Request, intent string) ([]*Task, *DecompositionReport, error) {
+	agents := c.planningAgents()
 	prompt := fmt.Sprintf(`Break down the following user request into specific tasks.
 
 User Request: %s
 Intent: %s
 
 Available Agent Types:
-- diagnostician: Diagnose pod failures, analyze logs, events, metrics
-- remediator: Generate fixes, create patches, remediate issues
-- security: Audit RBAC, scan images, check compliance
-- cost_optimizer: Analyze resource usage, recommend optimizations
-- knowledge: Search documentation, find best practices
-
-Return a JSON array of tasks with this structure:
-[
-  {
-    "id": "unique_task_id",
-    "type": "diagnose|remediate|audit|optimize|query",
-    "description": "Clear description of the task",
-    "assigned_agent": "agent_type",
-    "input": {
-      "key": "value"
-    },
-    "dependencies": ["task_id1", "task_id2"],
-    "condition": {
-      "on_success": ["task_id"],
-      "on_failure": ["task_id"]
-    }
-  }
-]
+%s
+
+Return a JSON array of tasks that validates against this JSON schema:
+%s
 
 Notes:
-- Each task must have a unique "id" field
+- Each task should have a unique "id" field
 - "dependencies" is an array of task IDs that must complete before this task can start
 - If a task has no dependencies, use an empty array []
 - Dependencies should form a valid directed acyclic graph (DAG) with no cycles
+- "assigned_agent" must be able to handle the task's "type"
 - "condition" is optional and defines when this task should execute:
   - "on_success": only execute if ALL specified tasks completed successfully
   - "on_failure": only execute if ANY specified task failed
   - If both are specified, on_success takes precedence
   - Tasks in condition must also be in dependencies
   - Omit condition for tasks that should always execute when dependencies are met
+- An "input" value may reference a dependency's output as "{{tasks.<id>.output.<key>}}",
+  e.g. "{{tasks.diagnose-pod.output.root_cause}}"; the task must list <id> in dependencies
+- Each task also receives its dependencies' outputs under "upstream_outputs" automatically
 
-Respond with only the JSON array.`, request.Input, intent)
+Respond with only the JSON array.`, request.Input, intent, describeAgents(agents), decompositionSchema(agents))
+	prompt = withConversation(ctx, prompt)
 
 	messages := []Message{
 		{Role: "system", Content: "You are a task decomposition expert for Kubernetes operations."},
 		{Role: "user", Content: prompt},
 	}
 
-	response, err := c.llmClient.Complete(ctx.Context(), messages)
-	if err != nil {
-		return nil, fmt.Errorf("LLM call failed: %w", err)
-	}
-
-	// Parse JSON response
-	var tasksData []struct {
-		ID            string                 `json:"id"`
-		Type          string                 `json:"type"`
-		Description   string                 `json:"description"`
-		AssignedAgent string                 `json:"assigned_agent"`
-		Input         map[string]interface{} `json:"input"`
-		Dependencies  []string               `json:"dependencies"`
-		Condition     *struct {
-			OnSuccess []string `json:"on_success"`
-			OnFailure []string `json:"on_failure"`
-		} `json:"condition"`
-	}
-
-	// Strip markdown code fences that MiniMax might wrap around JSON
-	cleanResponse := strings.TrimSpace(response)
-	cleanResponse = strings.TrimPrefix(cleanResponse, "```json")
-	cleanResponse = strings.TrimPrefix(cleanResponse, "```")
-	cleanResponse = strings.TrimSuffix(cleanResponse, "```")
-	cleanResponse = strings.TrimSpace(cleanResponse)
-
-	if err := json.Unmarshal([]byte(cleanResponse), &tasksData); err != nil {
-		// If JSON parsing fails, create a simple single task
-		c.logger.Warn("Failed to parse LLM response as JSON, creating simple task", map[string]interface{}{
-			"error": err.Error(),
-		})
-
-		return []*Task{
-			{
-				ID:          uuid.New().String(),
-				Type:        TaskType(intent),
-				Description: request.Input,
-				Status:      TaskStatusPending,
-				Input:       request.Context,
-				CreatedAt:   time.Now(),
-			},
-		}, nil
-	}
-
-	// Convert to Task objects
-	tasks := make([]*Task, 0, len(tasksData))
-	for _, td := range tasksData {
-		// Use LLM-provided ID if present, otherwise generate UUID
-		taskID := td.ID
-		if taskID == "" {
-			taskID = uuid.New().String()
-		}
-
-		task := &Task{
-			ID:            taskID,
-			Type:          TaskType(td.Type),
-			Description:   td.Description,
-			Status:        TaskStatusPending,
-			AssignedAgent: AgentType(td.AssignedAgent),
-			Input:         td.Input,
-			Dependencies:  td.Dependencies,
-			CreatedAt:     time.Now(),
-		}
-
-		// Convert condition if present
-		if td.Condition != nil {
-			task.Condition = &TaskCondition{
-				OnSuccess: td.Condition.OnSuccess,
-				OnFailure: td.Condition.OnFailure,
-			}
+	report := &DecompositionReport{}
+	for {
+		response, err := c.complete(ctx, messages)
+		if err != nil {
+			return nil, report, fmt.Errorf("LLM call failed: %w", err)
 		}
+		report.Attempts++
 
-		tasks = append(tasks, task)
+		tasks, problems := c.parseDecomposition(response, agents)
+		if len(problems) == 0 {
+			return tasks, report, nil
+		}
+		report.Rejected = append(report.Rejected, problems)
+		c.logger.Warn("LLM task decomposition rejected", map[string]interface{}{
+			"attempt":  report.Attempts,
+			"problems": problems,
+		})
+		if report.Attempts > c.planRepairs {
+			break
+		}
+		messages = append(messages,
+			Message{Role: "assistant", Content: response},
+			Message{Role: "user", Content: repairPrompt(problems)},
+		)
 	}
 
-	return tasks, nil
+	c.logger.Warn("No valid task decomposition, creating simple task", map[string]interface{}{
+		"attempts": report.Attempts,
+	})
+	report.Fallback = true
+	return []*Task{
+		{
+			ID:          uuid.New().String(),
+			Type:        TaskType(intent),
+			Description: request.Input,
+			Status:      TaskStatusPending,
+			Input:       request.Context,
+			CreatedAt:   time.Now(),
+		},
+	}, report, nil
 }
 
 // determineExecutionMode determines the semantic execution mode for logging/monitoring.
@@ -465,28 +705,13 @@ func (c *BaseCoordinator) determineExecutionMode(tasks []*Task) ExecutionMode {
 	return ExecutionModeSequential
 }
 
-// selectAgentForTask selects the appropriate agent for a task
+// selectAgentForTask selects the agent the router ranks best for a task
 func (c *BaseCoordinator) selectAgentForTask(task *Task) (Agent, error) {
-	// If agent is already assigned, use it
-	if task.AssignedAgent != "" {
-		agent, err := c.GetAgent(task.AssignedAgent)
-		if err == nil {
-			return agent, nil
-		}
-		// Fall through to automatic selection if assigned agent not found
-	}
-
-	// Select agent based on task type
-	c.agentsMutex.RLock()
-	defer c.agentsMutex.RUnlock()
-
-	for _, agent := range c.agents {
-		if agent.CanHandle(task.Type) {
-			return agent, nil
-		}
+	agents, _ := c.route(task)
+	if len(agents) == 0 {
+		return nil, fmt.Errorf("no agent available to handle task type: %s", task.Type)
 	}
-
-	return nil, fmt.Errorf("no agent available to handle task type: %s", task.Type)
+	return agents[0], nil
 }
 
 // validateDependencies validates that task dependencies form a valid DAG
@@ -581,13 +806,23 @@ func (c *BaseCoordinator) checkTaskCondition(task *Task, taskMap map[string]*Tas
 	return true, ""
 }
 
-// executeDependencyBased executes tasks respecting their dependencies and conditions
+// executeDependencyBased executes tasks respecting their dependencies
+// and conditions. A task becomes ready the moment its last dependency
+// finishes, not when a whole round of siblings does, and ready tasks
+// start in the order they became ready as far as the scheduler's limits
+// allow: a task held back by its agent type's limit does not hold back
+// the tasks of other agents queued behind it.
+//
+// Only this goroutine touches the bookkeeping; task goroutines report
+// back on done. The limits are the coordinator's, shared with the
+// other plans running, so a slot another plan frees wakes it too.
 func (c *BaseCoordinator) executeDependencyBased(ctx *AgentContext, plan *ExecutionPlan) (*Response, error) {
 	// Validate dependencies first
 	if err := c.validateDependencies(plan.Tasks); err != nil {
 		return nil, fmt.Errorf("invalid task dependencies: %w", err)
 	}
 
+	started := time.Now()
 	executedAgents := make([]AgentType, 0)
 	errors := make([]string, 0)
 	results := make(map[string]interface{})
@@ -597,88 +832,205 @@ func (c *BaseCoordinator) executeDependencyBased(ctx *AgentContext, plan *Execut
 	taskMap := make(map[string]*Task)
 	for _, task := range plan.Tasks {
 		taskMap[task.ID] = task
-	}
 
-	// Execute tasks in dependency order
-	for len(processed) < len(plan.Tasks) {
-		// Find tasks that are ready to process (all dependencies satisfied)
-		readyTasks := make([]*Task, 0)
-		for _, task := range plan.Tasks {
-			if processed[task.ID] {
-				continue
+		// Tasks that already finished in an earlier run (see ResumePlan)
+		// count as processed up front so they are never re-executed.
+		switch task.Status {
+		case TaskStatusCompleted:
+			processed[task.ID] = true
+			if task.Output != nil {
+				results[task.ID] = task.Output
 			}
+		case TaskStatusSkipped:
+			processed[task.ID] = true
+		}
+	}
 
-			// Check if all dependencies are processed (completed, failed, or skipped)
-			allDepsProcessed := true
-			for _, depID := range task.Dependencies {
-				if !processed[depID] {
-					allDepsProcessed = false
-					break
-				}
+	type finished struct {
+		task   *Task
+		result *Task
+		err    error
+	}
+	done := make(chan finished, len(plan.Tasks))
+	slots := c.slots
+
+	// queue holds prepared tasks waiting for a slot. unfinished keeps a
+	// copy of every task from before it was prepared until it finishes,
+	// for checkpoints taken while it is queued or running.
+	var queue []*Task
+	unfinished := make(map[string]*Task)
+	inFlight := 0
+
+	finish := func(f finished) {
+		inFlight--
+		delete(unfinished, f.task.ID)
+		processed[f.task.ID] = true
+		if f.task.Status == TaskStatusCancelled {
+			errors = append(errors, fmt.Sprintf("Task %s cancelled: %s", f.task.ID, f.err.Error()))
+		} else if f.err != nil {
+			errors = append(errors, fmt.Sprintf("Task %s failed: %s", f.task.ID, f.err.Error()))
+			for _, added := range c.iterateRemediation(ctx, plan, f.task) {
+				taskMap[added.ID] = added
 			}
-
-			if allDepsProcessed {
-				readyTasks = append(readyTasks, task)
+		} else {
+			executedAgents = append(executedAgents, f.task.AssignedAgent)
+			if f.result.Output != nil {
+				results[f.task.ID] = f.result.Output
 			}
 		}
+	}
 
-		if len(readyTasks) == 0 {
-			// No tasks ready - this shouldn't happen if validation passed
-			return nil, fmt.Errorf("no tasks ready to execute, but %d tasks remaining", len(plan.Tasks)-len(processed))
-		}
-
-		// Execute ready tasks in parallel
-		var wg sync.WaitGroup
-		var mu sync.Mutex
-
-		for _, task := range readyTasks {
-			wg.Add(1)
-			go func(t *Task) {
-				defer wg.Done()
-
-				mu.Lock()
-				// Check condition before executing
-				shouldExecute, reason := c.checkTaskCondition(t, taskMap)
-				mu.Unlock()
+	// prepareReady moves tasks whose dependencies have all been
+	// processed into the queue, settling the ones that will not run:
+	// skipped by their condition or failed on an unresolvable input.
+	// Settling a task can make its dependents ready, hence the loop.
+	prepareReady := func() {
+		for settled := true; settled; {
+			settled = false
+			for _, task := range plan.Tasks {
+				if processed[task.ID] || unfinished[task.ID] != nil {
+					continue
+				}
+				allDepsProcessed := true
+				for _, depID := range task.Dependencies {
+					if !processed[depID] {
+						allDepsProcessed = false
+						break
+					}
+				}
+				if !allDepsProcessed {
+					continue
+				}
 
-				if !shouldExecute {
-					// Skip this task
-					mu.Lock()
-					t.Status = TaskStatusSkipped
-					t.Error = reason
-					processed[t.ID] = true
+				if shouldExecute, reason := c.checkTaskCondition(task, taskMap); !shouldExecute {
+					task.Status = TaskStatusSkipped
+					task.Error = reason
+					processed[task.ID] = true
 					now := time.Now()
-					t.CompletedAt = &now
+					task.CompletedAt = &now
 					c.logger.Info("Task skipped due to condition", map[string]interface{}{
-						"task_id": t.ID,
+						"task_id": task.ID,
 						"reason":  reason,
 					})
-					mu.Unlock()
-					return
+					settled = true
+					continue
 				}
 
-				result, err := c.Execute(ctx, t)
-
-				mu.Lock()
-				defer mu.Unlock()
+				saved := *task
+				// Dependencies are processed, so their outputs are final.
+				if inputErr := resolveTaskInputs(task, taskMap); inputErr != nil {
+					task.Status = TaskStatusFailed
+					task.Error = inputErr.Error()
+					processed[task.ID] = true
+					now := time.Now()
+					task.CompletedAt = &now
+					errors = append(errors, fmt.Sprintf("Task %s failed: %s", task.ID, inputErr.Error()))
+					settled = true
+					continue
+				}
+				unfinished[task.ID] = &saved
+				queue = append(queue, task)
+			}
+		}
+	}
 
-				processed[t.ID] = true
-				if err != nil {
-					errors = append(errors, fmt.Sprintf("Task %s failed: %s", t.ID, err.Error()))
-				} else {
-					executedAgents = append(executedAgents, t.AssignedAgent)
-					if result.Output != nil {
-						results[t.ID] = result.Output
-					}
+	// dispatch starts every queued task the limits admit and returns
+	// the earliest time a task held back by an agent's MinInterval may
+	// start (zero if none is).
+	dispatch := func() time.Time {
+		var wake time.Time
+		now := time.Now()
+		waiting := queue[:0]
+		for _, task := range queue {
+			// Ranked here, once: the slot is taken for the agent
+			// that will run, and executeRanked swaps it on fallback.
+			agents, _ := c.route(task)
+			agentType := task.AssignedAgent
+			if len(agents) > 0 {
+				agentType = agents[0].Type()
+			}
+			ok, at := slots.acquire(agentType, now)
+			if !ok {
+				if !at.IsZero() && (wake.IsZero() || at.Before(wake)) {
+					wake = at
 				}
-			}(task)
+				waiting = append(waiting, task)
+				continue
+			}
+			inFlight++
+			go func(t *Task, agents []Agent) {
+				result, err := c.executeRanked(ctx, t, agents, slots)
+				done <- finished{task: t, result: result, err: err}
+			}(task, agents)
+		}
+		queue = waiting
+		return wake
+	}
+
+	for {
+		if cancelErr := ctx.Context().Err(); cancelErr != nil {
+			// In-flight tasks see the same context; wait for them so
+			// their cancelled status lands before the plan is reported.
+			for inFlight > 0 {
+				finish(<-done)
+			}
+			// Queued tasks never started; give them back their
+			// unresolved input so a resumed run resolves it afresh.
+			for _, task := range queue {
+				task.Input = unfinished[task.ID].Input
+			}
+			return c.cancelledResponse(ctx, plan, started, processed, results, errors, executedAgents, cancelErr)
+		}
+
+		prepareReady()
+		if len(processed) == len(plan.Tasks) {
+			break
+		}
+		freed := slots.released()
+		wake := dispatch()
+		if inFlight == 0 && len(queue) == 0 {
+			// No tasks ready - this shouldn't happen if validation passed
+			return nil, fmt.Errorf("no tasks ready to execute, but %d tasks remaining", len(plan.Tasks)-len(processed))
+		}
+
+		// Wait for a task to finish, a slot to free up, a rate-limited
+		// task to become due, or cancellation.
+		var timer *time.Timer
+		var due <-chan time.Time
+		if !wake.IsZero() {
+			timer = time.NewTimer(time.Until(wake))
+			due = timer.C
 		}
+		select {
+		case f := <-done:
+			finish(f)
+			// Checkpoint after every task so a crash loses at most the
+			// tasks that were in flight.
+			c.checkpointPlan(ctx, checkpointSnapshot(plan, unfinished))
+		case <-freed:
+		case <-due:
+		case <-ctx.Context().Done():
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+	}
 
-		wg.Wait()
+	// A cancellation while the last tasks ran leaves nothing unstarted,
+	// but the summary call would fail on the dead context anyway.
+	if cancelErr := ctx.Context().Err(); cancelErr != nil {
+		return c.cancelledResponse(ctx, plan, started, processed, results, errors, executedAgents, cancelErr)
 	}
 
 	// Generate final response
-	finalResult := c.generateFinalResponse(ctx, plan, results)
+	report := c.buildReport(ctx, plan, started)
+	finalResult := c.generateFinalResponse(ctx, report)
+
+	// Added after the summary so the LLM only sees the report.
+	results[FinalReportKey] = report
+	if ledger := ctx.UsageLedger(); ledger != nil {
+		results["usage"] = ledger.Report()
+	}
 
 	response := &Response{
 		RequestID:   plan.RequestID,
@@ -693,39 +1045,81 @@ func (c *BaseCoordinator) executeDependencyBased(ctx *AgentContext, plan *Execut
 	return response, nil
 }
 
-// generateFinalResponse generates the final response using LLM
-func (c *BaseCoordinator) generateFinalResponse(ctx *AgentContext, plan *ExecutionPlan, results map[string]interface{}) string {
-	// Format results for LLM
-	resultsJSON, _ := json.MarshalIndent(results, "", "  ")
+// cancelledResponse ends a plan whose context was cancelled (Ctrl-C or
+// a caller deadline). Tasks that were in flight were already marked
+// cancelled by Execute; the ones that never started are marked too, so
+// ResumePlan picks all of them up. No summary is generated: the LLM
+// call would fail on the cancelled context.
+func (c *BaseCoordinator) cancelledResponse(ctx *AgentContext, plan *ExecutionPlan, started time.Time, processed map[string]bool, results map[string]interface{}, errs []string, executedAgents []AgentType, cancelErr error) (*Response, error) {
+	for _, task := range plan.Tasks {
+		if !processed[task.ID] {
+			task.Status = TaskStatusCancelled
+			task.Error = "not started: " + cancelErr.Error()
+		}
+	}
+	results[FinalReportKey] = c.buildReport(ctx, plan, started)
+	if ledger := ctx.UsageLedger(); ledger != nil {
+		results["usage"] = ledger.Report()
+	}
+
+	return &Response{
+		RequestID:   plan.RequestID,
+		Status:      TaskStatusCancelled,
+		Result:      fmt.Sprintf("Execution cancelled after %d of %d task(s) finished.", countFinished(plan.Tasks), len(plan.Tasks)),
+		Data:        results,
+		Errors:      errs,
+		ExecutedBy:  executedAgents,
+		CompletedAt: time.Now(),
+	}, fmt.Errorf("plan cancelled: %w", cancelErr)
+}
+
+// countFinished counts tasks that completed or were skipped.
+func countFinished(tasks []*Task) int {
+	n := 0
+	for _, task := range tasks {
+		if task.Status == TaskStatusCompleted || task.Status == TaskStatusSkipped {
+			n++
+		}
+	}
+	return n
+}
+
+// generateFinalResponse has the LLM summarise report for the user. If
+// the call fails the report's one-line summary is used instead.
+func (c *BaseCoordinator) generateFinalResponse(ctx *AgentContext, report *FinalReport) string {
+	reportJSON, _ := json.MarshalIndent(report, "", "  ")
 
-	prompt := fmt.Sprintf(`Summarize the following task execution results for the user.
+	prompt := fmt.Sprintf(`Summarize the following execution report for the user.
 
 Original Request: %s
 
-Task Results:
+Execution Report (status %s):
 %s
 
-Provide a clear, concise summary of what was done and any important findings.`,
-		plan.RequestID, string(resultsJSON))
+Provide a clear, concise summary of what was done and any important findings. State plainly which tasks failed or were skipped and why, whether verification confirmed each change, and anything the user still has to do. Do not describe a failed or skipped task as done.`,
+		report.Request, report.Status, string(reportJSON))
 
 	messages := []Message{
-		{Role: "system", Content: "You are a helpful Kubernetes assistant. Summarize task results clearly."},
+		{Role: "system", Content: "You are a helpful Kubernetes assistant. Summarize task results clearly and honestly."},
 		{Role: "user", Content: prompt},
 	}
 
-	response, err := c.llmClient.Complete(ctx.Context(), messages)
+	response, err := c.complete(ctx, messages)
 	if err != nil {
 		c.logger.Warn("Failed to generate final response with LLM", map[string]interface{}{
 			"error": err.Error(),
 		})
-		return fmt.Sprintf("Executed %d tasks. Check detailed results in the data field.", len(plan.Tasks))
+		return report.Summary()
 	}
 
 	return response
 }
 
-// updateMetrics updates agent execution metrics
+// updateMetrics updates agent execution metrics. Plans run
+// concurrently on one coordinator, hence the lock.
 func (c *BaseCoordinator) updateMetrics(duration time.Duration, success bool) {
+	c.metricsMu.Lock()
+	defer c.metricsMu.Unlock()
 	if success {
 		c.metrics.TasksCompleted++
 	} else {
@@ -740,7 +1134,10 @@ func (c *BaseCoordinator) updateMetrics(duration time.Duration, success bool) {
 	c.metrics.LastExecutedAt = time.Now()
 }
 
-// GetMetrics returns the coordinator's metrics
+// GetMetrics returns a copy of the coordinator's metrics
 func (c *BaseCoordinator) GetMetrics() *AgentMetrics {
-	return c.metrics
+	c.metricsMu.Lock()
+	defer c.metricsMu.Unlock()
+	metrics := *c.metrics
+	return &metrics
 }
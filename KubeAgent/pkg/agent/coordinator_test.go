@@ -196,6 +196,7 @@ type MockSpecialistAgent struct {
 	agentType     AgentType
 	canHandleFunc func(TaskType) bool
 	executeFunc   func(*AgentContext, *Task) (*Task, error)
+	config        *AgentConfig
 }
 
 func (m *MockSpecialistAgent) Name() string {
@@ -222,6 +223,9 @@ func (m *MockSpecialistAgent) CanHandle(taskType TaskType) bool {
 }
 
 func (m *MockSpecialistAgent) Config() *AgentConfig {
+	if m.config != nil {
+		return m.config
+	}
 	return &AgentConfig{
 		Name: m.name,
 		Type: m.agentType,
@@ -429,3 +433,89 @@ func TestDependencyBasedExecution(t *testing.T) {
 		t.Errorf("Expected no errors, got: %v", response.Errors)
 	}
 }
+
+func TestResumePlan(t *testing.T) {
+	logger := NewNoOpLogger()
+	stateStore, _ := newTestBoltStore(t)
+	defer stateStore.Close()
+	coordinator := NewCoordinator(nil, &MockLLMClient{}, stateStore, logger)
+
+	executed := []string{}
+	var mu sync.Mutex
+	coordinator.RegisterAgent(&MockSpecialistAgent{
+		name:      "mock-agent",
+		agentType: AgentTypeDiagnostician,
+		canHandleFunc: func(taskType TaskType) bool {
+			return true
+		},
+		executeFunc: func(ctx *AgentContext, task *Task) (*Task, error) {
+			mu.Lock()
+			executed = append(executed, task.ID)
+			mu.Unlock()
+			task.Output = map[string]interface{}{"result": task.ID + " done"}
+			return task, nil
+		},
+	})
+
+	// Simulate a run that died after diagnosis finished and while the
+	// remediation was in flight.
+	plan := &ExecutionPlan{
+		ID:        "plan-resume-001",
+		RequestID: "req-resume-001",
+		Status:    TaskStatusRunning,
+		Tasks: []*Task{
+			{ID: "diagnose", Type: TaskTypeDiagnose, Status: TaskStatusCompleted,
+				Output: map[string]interface{}{"root_cause": "bad image"}},
+			{ID: "skipped", Type: TaskTypeDiagnose, Status: TaskStatusSkipped,
+				Dependencies: []string{"diagnose"}},
+			{ID: "remediate", Type: TaskTypeRemediate, Status: TaskStatusRunning,
+				Dependencies: []string{"diagnose"}, Error: "stale"},
+			{ID: "report", Type: TaskTypeQuery, Status: TaskStatusFailed,
+				Dependencies: []string{"remediate"}},
+		},
+		CreatedAt: time.Now(),
+	}
+	if err := stateStore.SavePlan(context.Background(), plan); err != nil {
+		t.Fatalf("Failed to save plan: %v", err)
+	}
+
+	ctx := NewAgentContext(context.Background(), "req-resume-001", "test-user", "trace-resume-001")
+	response, err := coordinator.ResumePlan(ctx, "plan-resume-001")
+	if err != nil {
+		t.Fatalf("ResumePlan failed: %v", err)
+	}
+
+	if len(executed) != 2 || executed[0] != "remediate" || executed[1] != "report" {
+		t.Errorf("Expected only [remediate report] to re-run, got %v", executed)
+	}
+	if _, ok := response.Data["diagnose"]; !ok {
+		t.Error("Expected output of previously completed task in response data")
+	}
+
+	saved, err := stateStore.LoadPlan(context.Background(), "plan-resume-001")
+	if err != nil {
+		t.Fatalf("Failed to reload plan: %v", err)
+	}
+	if saved.Status != TaskStatusCompleted {
+		t.Errorf("Expected checkpointed plan status completed, got %s", saved.Status)
+	}
+	for _, task := range saved.Tasks {
+		if task.ID == "skipped" {
+			if task.Status != TaskStatusSkipped {
+				t.Errorf("Expected skipped task to stay skipped, got %s", task.Status)
+			}
+			continue
+		}
+		if task.Status != TaskStatusCompleted {
+			t.Errorf("Expected task %s completed after resume, got %s", task.ID, task.Status)
+		}
+	}
+}
+
+func TestResumePlanWithoutStateStore(t *testing.T) {
+	coordinator := NewCoordinator(nil, &MockLLMClient{}, nil, NewNoOpLogger())
+	ctx := NewAgentContext(context.Background(), "req", "test-user", "trace")
+	if _, err := coordinator.ResumePlan(ctx, "any"); err == nil {
+		t.Error("Expected error resuming without a state store")
+	}
+}
@@ -0,0 +1,196 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// UpstreamOutputsKey is the task input key under which the coordinator
+// puts the outputs of a task's completed dependencies, keyed by task ID.
+const UpstreamOutputsKey = "upstream_outputs"
+
+// inputRefPattern matches {{tasks.<id>.output}} and
+// {{tasks.<id>.output.<path>}}, where path is dot-separated map keys or
+// list indexes.
+var inputRefPattern = regexp.MustCompile(`\{\{\s*tasks\.([^.\s{}]+)\.output((?:\.[^.\s{}]+)*)\s*\}\}`)
+
+// resolveTaskInputs prepares task.Input for dispatch. References to
+// dependencies' outputs are replaced by their values: a string that is
+// exactly one reference takes the referenced value with its type, while
+// references inside longer text are interpolated (non-strings as JSON).
+// The completed dependencies' outputs are also put under
+// UpstreamOutputsKey, so agents can build on findings the planner did not
+// wire explicitly.
+//
+// tasks must only contain finished tasks for task's dependencies, which
+// executeDependencyBased guarantees by preparing a task only once all
+// of its dependencies are processed.
+func resolveTaskInputs(task *Task, tasks map[string]*Task) error {
+	isDep := make(map[string]bool, len(task.Dependencies))
+	for _, dep := range task.Dependencies {
+		isDep[dep] = true
+	}
+
+	lookup := func(id, path string) (interface{}, error) {
+		if !isDep[id] {
+			return nil, fmt.Errorf("reference to task %s, which is not a dependency", id)
+		}
+		upstream := tasks[id]
+		if upstream == nil || upstream.Status != TaskStatusCompleted {
+			status := TaskStatus("missing")
+			if upstream != nil {
+				status = upstream.Status
+			}
+			return nil, fmt.Errorf("reference to task %s, which did not complete (%s)", id, status)
+		}
+		return lookupPath(upstream.Output, path)
+	}
+
+	resolved, err := resolveValue(task.Input, lookup)
+	if err != nil {
+		return fmt.Errorf("unresolved input of task %s: %w", task.ID, err)
+	}
+	input, _ := resolved.(map[string]interface{})
+	if input == nil {
+		input = map[string]interface{}{}
+	}
+
+	upstream := make(map[string]interface{})
+	for _, dep := range task.Dependencies {
+		if t := tasks[dep]; t != nil && t.Status == TaskStatusCompleted && t.Output != nil {
+			upstream[dep] = t.Output
+		}
+	}
+	if len(upstream) > 0 {
+		input[UpstreamOutputsKey] = upstream
+	}
+
+	task.Input = input
+	return nil
+}
+
+// resolveValue returns v with every reference in its strings resolved.
+// Maps and lists are copied, never modified in place.
+func resolveValue(v interface{}, lookup func(id, path string) (interface{}, error)) (interface{}, error) {
+	switch v := v.(type) {
+	case string:
+		if m := inputRefPattern.FindStringSubmatchIndex(v); m != nil && m[0] == 0 && m[1] == len(v) {
+			return lookup(v[m[2]:m[3]], strings.TrimPrefix(v[m[4]:m[5]], "."))
+		}
+		var lookupErr error
+		out := inputRefPattern.ReplaceAllStringFunc(v, func(ref string) string {
+			m := inputRefPattern.FindStringSubmatch(ref)
+			value, err := lookup(m[1], strings.TrimPrefix(m[2], "."))
+			if err != nil {
+				if lookupErr == nil {
+					lookupErr = err
+				}
+				return ref
+			}
+			if s, ok := value.(string); ok {
+				return s
+			}
+			data, _ := json.Marshal(value)
+			return string(data)
+		})
+		return out, lookupErr
+
+	case map[string]interface{}:
+		if v == nil {
+			return v, nil
+		}
+		out := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			resolved, err := resolveValue(value, lookup)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", key, err)
+			}
+			out[key] = resolved
+		}
+		return out, nil
+
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, value := range v {
+			resolved, err := resolveValue(value, lookup)
+			if err != nil {
+				return nil, fmt.Errorf("[%d]: %w", i, err)
+			}
+			out[i] = resolved
+		}
+		return out, nil
+	}
+	return v, nil
+}
+
+// lookupPath walks a dot-separated path through maps and lists.
+func lookupPath(output map[string]interface{}, path string) (interface{}, error) {
+	var current interface{} = output
+	if path == "" {
+		return current, nil
+	}
+	walked := "output"
+	for _, key := range strings.Split(path, ".") {
+		switch node := current.(type) {
+		case map[string]interface{}:
+			value, ok := node[key]
+			if !ok {
+				return nil, fmt.Errorf("%s has no key %q (has %s)", walked, key, strings.Join(sortedKeys(node), ", "))
+			}
+			current = value
+		case []interface{}:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(node) {
+				return nil, fmt.Errorf("%s has no index %s (length %d)", walked, key, len(node))
+			}
+			current = node[i]
+		default:
+			return nil, fmt.Errorf("%s is not an object or list", walked)
+		}
+		walked += "." + key
+	}
+	return current, nil
+}
+
+// inputReferences lists the task IDs referenced anywhere in input.
+func inputReferences(input map[string]interface{}) []string {
+	seen := map[string]bool{}
+	var walk func(v interface{})
+	walk = func(v interface{}) {
+		switch v := v.(type) {
+		case string:
+			for _, m := range inputRefPattern.FindAllStringSubmatch(v, -1) {
+				seen[m[1]] = true
+			}
+		case map[string]interface{}:
+			for _, value := range v {
+				walk(value)
+			}
+		case []interface{}:
+			for _, value := range v {
+				walk(value)
+			}
+		}
+	}
+	walk(input)
+
+	ids := make([]string, 0, len(seen))
+	for id := range seen {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,109 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestResolveTaskInputs(t *testing.T) {
+	tasks := map[string]*Task{
+		"diagnose": {ID: "diagnose", Status: TaskStatusCompleted, Output: map[string]interface{}{
+			"root_cause":      "OOMKilled",
+			"confidence":      0.9,
+			"recommendations": []interface{}{"raise memory limit", "check for leaks"},
+		}},
+		"audit": {ID: "audit", Status: TaskStatusFailed},
+	}
+	task := &Task{
+		ID:           "fix",
+		Dependencies: []string{"diagnose"},
+		Input: map[string]interface{}{
+			"root_cause": "{{tasks.diagnose.output.root_cause}}",
+			"confidence": "{{ tasks.diagnose.output.confidence }}",
+			"note":       "first try: {{tasks.diagnose.output.recommendations.0}} ({{tasks.diagnose.output.confidence}})",
+			"nested":     []interface{}{map[string]interface{}{"all": "{{tasks.diagnose.output}}"}},
+		},
+	}
+	template := task.Input
+
+	if err := resolveTaskInputs(task, tasks); err != nil {
+		t.Fatalf("resolveTaskInputs failed: %v", err)
+	}
+	if task.Input["root_cause"] != "OOMKilled" || task.Input["confidence"] != 0.9 {
+		t.Errorf("Expected whole-string references to keep their type, got %+v", task.Input)
+	}
+	if task.Input["note"] != "first try: raise memory limit (0.9)" {
+		t.Errorf("Unexpected interpolation %q", task.Input["note"])
+	}
+	if all := task.Input["nested"].([]interface{})[0].(map[string]interface{})["all"]; all.(map[string]interface{})["root_cause"] != "OOMKilled" {
+		t.Errorf("Expected nested reference to the whole output, got %+v", all)
+	}
+	upstream := task.Input[UpstreamOutputsKey].(map[string]interface{})
+	if len(upstream) != 1 || upstream["diagnose"] == nil {
+		t.Errorf("Expected upstream_outputs for the dependency only, got %+v", upstream)
+	}
+	if template["root_cause"] != "{{tasks.diagnose.output.root_cause}}" {
+		t.Error("Expected the original input map to be left alone")
+	}
+
+	for _, tc := range []struct {
+		ref  string
+		deps []string
+		want string
+	}{
+		{"{{tasks.audit.output.x}}", nil, "not a dependency"},
+		{"{{tasks.audit.output.x}}", []string{"audit"}, "did not complete (failed)"},
+		{"{{tasks.diagnose.output.reason}}", []string{"diagnose"}, `no key "reason" (has confidence, recommendations, root_cause)`},
+		{"{{tasks.diagnose.output.recommendations.5}}", []string{"diagnose"}, "no index 5"},
+	} {
+		bad := &Task{ID: "bad", Dependencies: tc.deps, Input: map[string]interface{}{"x": tc.ref}}
+		err := resolveTaskInputs(bad, tasks)
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: expected error containing %q, got %v", tc.ref, tc.want, err)
+		}
+	}
+}
+
+func TestExecutePlan_PassesOutputsDownstream(t *testing.T) {
+	var seen map[string]interface{}
+	coordinator := NewCoordinator(nil, &MockLLMClient{}, nil, NewNoOpLogger())
+	coordinator.RegisterAgent(&MockSpecialistAgent{
+		name:      "diag",
+		agentType: AgentTypeDiagnostician,
+		executeFunc: func(ctx *AgentContext, task *Task) (*Task, error) {
+			task.Output = map[string]interface{}{"root_cause": "image tag does not exist"}
+			return task, nil
+		},
+	})
+	coordinator.RegisterAgent(&MockSpecialistAgent{
+		name:      "fix",
+		agentType: AgentTypeRemediator,
+		executeFunc: func(ctx *AgentContext, task *Task) (*Task, error) {
+			seen = task.Input
+			return task, nil
+		},
+	})
+
+	plan := &ExecutionPlan{ID: "plan", Tasks: []*Task{
+		{ID: "diagnose-pod", Type: TaskTypeDiagnose, AssignedAgent: AgentTypeDiagnostician},
+		{ID: "fix-pod", Type: TaskTypeRemediate, AssignedAgent: AgentTypeRemediator, Dependencies: []string{"diagnose-pod"},
+			Input: map[string]interface{}{"root_cause": "{{tasks.diagnose-pod.output.root_cause}}"}},
+		{ID: "broken", Type: TaskTypeRemediate, AssignedAgent: AgentTypeRemediator, Dependencies: []string{"diagnose-pod"},
+			Input: map[string]interface{}{"x": "{{tasks.diagnose-pod.output.missing}}"}},
+	}}
+	response, err := coordinator.ExecutePlan(NewAgentContext(context.Background(), "req", "user", "trace"), plan)
+	if err != nil {
+		t.Fatalf("ExecutePlan failed: %v", err)
+	}
+
+	if seen["root_cause"] != "image tag does not exist" || seen[UpstreamOutputsKey] == nil {
+		t.Errorf("Remediator did not receive the diagnosis: %+v", seen)
+	}
+	if broken := plan.Tasks[2]; broken.Status != TaskStatusFailed || len(broken.Attempts) != 0 {
+		t.Errorf("Expected the unresolvable task to fail before dispatch, got %s %+v", broken.Status, broken.Attempts)
+	}
+	if len(response.Errors) != 1 || !strings.Contains(response.Errors[0], `no key "missing"`) {
+		t.Errorf("Unexpected errors %v", response.Errors)
+	}
+}
@@ -0,0 +1,294 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DefaultPlanRepairRounds is how many times the coordinator sends a
+// rejected decomposition back to the LLM before falling back to a
+// single task.
+const DefaultPlanRepairRounds = 2
+
+// taskTypes are the task types a decomposition may use.
+var taskTypes = []TaskType{
+	TaskTypeDiagnose,
+	TaskTypeRemediate,
+	TaskTypeAudit,
+	TaskTypeOptimize,
+	TaskTypeQuery,
+}
+
+// agentDescriptions are the prompt descriptions of the built-in agent
+// types. Other agents are described by their AgentConfig.Description.
+var agentDescriptions = map[AgentType]string{
+	AgentTypeDiagnostician: "Diagnose pod failures, analyze logs, events, metrics",
+	AgentTypeRemediator:    "Generate fixes, create patches, remediate issues",
+	AgentTypeSecurity:      "Audit RBAC, scan images, check compliance",
+	AgentTypeCostOptimizer: "Analyze resource usage, recommend optimizations",
+	AgentTypeKnowledge:     "Search documentation, find best practices",
+}
+
+// DecompositionReport records how a plan's task list was obtained. It
+// is stored in ExecutionPlan.Metadata["decomposition"].
+type DecompositionReport struct {
+	// Attempts counts LLM replies, the first one included.
+	Attempts int `json:"attempts"`
+	// Rejected holds the validation errors of each rejected reply, in
+	// order. The LLM saw each list in the following repair prompt.
+	Rejected [][]string `json:"rejected,omitempty"`
+	// Fallback is set when no reply passed validation and the request
+	// became a single task typed with the intent.
+	Fallback bool `json:"fallback,omitempty"`
+}
+
+// decomposedTask is one task as the LLM writes it.
+type decomposedTask struct {
+	ID            string                 `json:"id"`
+	Type          string                 `json:"type"`
+	Description   string                 `json:"description"`
+	AssignedAgent string                 `json:"assigned_agent"`
+	Input         map[string]interface{} `json:"input"`
+	Dependencies  []string               `json:"dependencies"`
+	Condition     *TaskCondition         `json:"condition"`
+}
+
+// planningAgents snapshots the registered agents by type.
+func (c *BaseCoordinator) planningAgents() map[AgentType]Agent {
+	c.agentsMutex.RLock()
+	defer c.agentsMutex.RUnlock()
+
+	agents := make(map[AgentType]Agent, len(c.agents))
+	for agentType, agent := range c.agents {
+		agents[agentType] = agent
+	}
+	return agents
+}
+
+// describeAgents lists the agents the planner may assign, one per line.
+// With nothing registered (planning-only use) the built-in types are
+// offered.
+func describeAgents(agents map[AgentType]Agent) string {
+	lines := make([]string, 0, len(agentDescriptions))
+	if len(agents) == 0 {
+		for agentType, description := range agentDescriptions {
+			lines = append(lines, fmt.Sprintf("- %s: %s", agentType, description))
+		}
+	}
+	for agentType, agent := range agents {
+		description, ok := agentDescriptions[agentType]
+		if !ok {
+			description = agent.Config().Description
+		}
+		lines = append(lines, fmt.Sprintf("- %s: %s", agentType, description))
+	}
+	sort.Strings(lines)
+	return strings.Join(lines, "\n")
+}
+
+// decompositionSchema is the JSON schema a decomposition must satisfy.
+// The rules it cannot express (references between tasks, no cycles)
+// are spelled out in the prompt notes and checked by
+// validateDecomposition.
+func decompositionSchema(agents map[AgentType]Agent) string {
+	types := make([]string, len(taskTypes))
+	for i, t := range taskTypes {
+		types[i] = string(t)
+	}
+	agentProperty := map[string]interface{}{"type": "string"}
+	if len(agents) > 0 {
+		names := make([]string, 0, len(agents))
+		for agentType := range agents {
+			names = append(names, string(agentType))
+		}
+		sort.Strings(names)
+		agentProperty["enum"] = names
+	}
+	ids := map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}}
+
+	schema := map[string]interface{}{
+		"type":     "array",
+		"minItems": 1,
+		"items": map[string]interface{}{
+			"type":     "object",
+			"required": []string{"type", "description", "assigned_agent"},
+			"properties": map[string]interface{}{
+				"id":             map[string]interface{}{"type": "string", "minLength": 1},
+				"type":           map[string]interface{}{"type": "string", "enum": types},
+				"description":    map[string]interface{}{"type": "string", "minLength": 1},
+				"assigned_agent": agentProperty,
+				"input":          map[string]interface{}{"type": "object"},
+				"dependencies":   ids,
+				"condition": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"on_success": ids,
+						"on_failure": ids,
+					},
+				},
+			},
+		},
+	}
+	data, _ := json.MarshalIndent(schema, "", "  ")
+	return string(data)
+}
+
+// parseDecomposition decodes and validates an LLM decomposition. It
+// returns the tasks, or every problem found so the LLM can fix them
+// all in one repair round.
+func (c *BaseCoordinator) parseDecomposition(response string, agents map[AgentType]Agent) ([]*Task, []string) {
+	// Strip markdown code fences that MiniMax might wrap around JSON
+	cleanResponse := strings.TrimSpace(response)
+	cleanResponse = strings.TrimPrefix(cleanResponse, "```json")
+	cleanResponse = strings.TrimPrefix(cleanResponse, "```")
+	cleanResponse = strings.TrimSuffix(cleanResponse, "```")
+	cleanResponse = strings.TrimSpace(cleanResponse)
+
+	var decomposed []decomposedTask
+	if err := json.Unmarshal([]byte(cleanResponse), &decomposed); err != nil {
+		return nil, []string{fmt.Sprintf("response is not a JSON array of tasks: %v", err)}
+	}
+	if problems := c.validateDecomposition(decomposed, agents); len(problems) > 0 {
+		return nil, problems
+	}
+
+	tasks := make([]*Task, 0, len(decomposed))
+	for _, td := range decomposed {
+		// Use LLM-provided ID if present, otherwise generate UUID
+		taskID := td.ID
+		if taskID == "" {
+			taskID = uuid.New().String()
+		}
+		tasks = append(tasks, &Task{
+			ID:            taskID,
+			Type:          TaskType(td.Type),
+			Description:   td.Description,
+			Status:        TaskStatusPending,
+			AssignedAgent: AgentType(td.AssignedAgent),
+			Input:         td.Input,
+			Dependencies:  td.Dependencies,
+			Condition:     td.Condition,
+			CreatedAt:     time.Now(),
+		})
+	}
+	return tasks, nil
+}
+
+// validateDecomposition checks decomposed against the schema and the
+// rules between tasks. Each problem names the task it is about.
+func (c *BaseCoordinator) validateDecomposition(decomposed []decomposedTask, agents map[AgentType]Agent) []string {
+	if len(decomposed) == 0 {
+		return []string{"the task array is empty; return at least one task"}
+	}
+
+	var problems []string
+	ids := make(map[string]bool, len(decomposed))
+	for _, td := range decomposed {
+		// Tasks nothing refers to may omit the id; they get a UUID.
+		if td.ID == "" {
+			continue
+		}
+		if ids[td.ID] {
+			problems = append(problems, fmt.Sprintf("task %q: id is used by more than one task", td.ID))
+		}
+		ids[td.ID] = true
+	}
+
+	validType := make(map[TaskType]bool, len(taskTypes))
+	for _, t := range taskTypes {
+		validType[t] = true
+	}
+
+	for i, td := range decomposed {
+		name := td.ID
+		if name == "" {
+			name = fmt.Sprintf("#%d", i+1)
+		}
+		taskType := TaskType(td.Type)
+
+		if !validType[taskType] {
+			problems = append(problems, fmt.Sprintf("task %q: type %q is not one of %s", name, td.Type, joinTaskTypes(taskTypes)))
+		}
+		if strings.TrimSpace(td.Description) == "" {
+			problems = append(problems, fmt.Sprintf("task %q: \"description\" is missing", name))
+		}
+		if len(agents) > 0 {
+			agent, ok := agents[AgentType(td.AssignedAgent)]
+			switch {
+			case !ok:
+				problems = append(problems, fmt.Sprintf("task %q: assigned_agent %q is not available; use one of %s", name, td.AssignedAgent, joinAgentTypes(agents)))
+			case validType[taskType] && !agent.CanHandle(taskType):
+				problems = append(problems, fmt.Sprintf("task %q: agent %s cannot handle %s tasks", name, td.AssignedAgent, td.Type))
+			}
+		}
+
+		deps := make(map[string]bool, len(td.Dependencies))
+		for _, dep := range td.Dependencies {
+			deps[dep] = true
+			switch {
+			case dep != "" && dep == td.ID:
+				problems = append(problems, fmt.Sprintf("task %q: depends on itself", name))
+			case !ids[dep]:
+				problems = append(problems, fmt.Sprintf("task %q: dependency %q is not a task id", name, dep))
+			}
+		}
+		for _, ref := range inputReferences(td.Input) {
+			if !deps[ref] {
+				problems = append(problems, fmt.Sprintf("task %q: input refers to the output of %q, which is not in its dependencies", name, ref))
+			}
+		}
+		if td.Condition != nil {
+			for _, ref := range append(append([]string(nil), td.Condition.OnSuccess...), td.Condition.OnFailure...) {
+				if !deps[ref] {
+					problems = append(problems, fmt.Sprintf("task %q: condition refers to %q, which is not in its dependencies", name, ref))
+				}
+			}
+		}
+	}
+	if len(problems) > 0 {
+		return problems
+	}
+
+	// References are sound, so the only thing left for
+	// validateDependencies to find is a cycle.
+	tasks := make([]*Task, 0, len(decomposed))
+	for _, td := range decomposed {
+		if td.ID != "" {
+			tasks = append(tasks, &Task{ID: td.ID, Dependencies: td.Dependencies})
+		}
+	}
+	if err := c.validateDependencies(tasks); err != nil {
+		return []string{err.Error()}
+	}
+	return nil
+}
+
+// repairPrompt asks the LLM to fix its previous reply.
+func repairPrompt(problems []string) string {
+	return fmt.Sprintf(`Your task list was rejected:
+- %s
+
+Fix these problems and respond with only the corrected JSON array.`, strings.Join(problems, "\n- "))
+}
+
+func joinTaskTypes(types []TaskType) string {
+	names := make([]string, len(types))
+	for i, t := range types {
+		names[i] = string(t)
+	}
+	return strings.Join(names, ", ")
+}
+
+func joinAgentTypes(agents map[AgentType]Agent) string {
+	names := make([]string, 0, len(agents))
+	for agentType := range agents {
+		names = append(names, string(agentType))
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,136 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+// scriptedPlanner answers intent with "diagnose" and decomposition
+// requests with replies in order, keeping every decomposition
+// conversation it saw.
+func scriptedPlanner(replies ...string) (*MockLLMClient, *[][]Message) {
+	var seen [][]Message
+	return &MockLLMClient{
+		CompleteFunc: func(ctx context.Context, messages []Message) (string, error) {
+			if contains(messages[len(messages)-1].Content, "primary intent") {
+				return "diagnose", nil
+			}
+			seen = append(seen, messages)
+			return replies[len(seen)-1], nil
+		},
+	}, &seen
+}
+
+func newPlanningCoordinator(llm LLMClient) *BaseCoordinator {
+	coordinator := NewCoordinator(nil, llm, nil, NewNoOpLogger())
+	coordinator.RegisterAgent(&MockSpecialistAgent{
+		name:          "diag",
+		agentType:     AgentTypeDiagnostician,
+		canHandleFunc: func(taskType TaskType) bool { return taskType == TaskTypeDiagnose },
+	})
+	coordinator.RegisterAgent(&MockSpecialistAgent{
+		name:          "fix",
+		agentType:     AgentTypeRemediator,
+		canHandleFunc: func(taskType TaskType) bool { return taskType == TaskTypeRemediate },
+	})
+	return coordinator
+}
+
+func TestPlan_RepairsInvalidDecomposition(t *testing.T) {
+	llm, seen := scriptedPlanner(
+		"```json\n"+`[
+			{"id": "d", "type": "diagnose", "description": "find cause", "assigned_agent": "security"},
+			{"id": "r", "type": "fix", "description": "fix it", "assigned_agent": "remediator", "dependencies": ["x"], "condition": {"on_success": ["d"]}}
+		]`+"\n```",
+		`[
+			{"id": "d", "type": "diagnose", "description": "find cause", "assigned_agent": "diagnostician"},
+			{"id": "r", "type": "remediate", "description": "fix it", "assigned_agent": "remediator", "dependencies": ["d"], "condition": {"on_success": ["d"]}}
+		]`,
+	)
+	coordinator := newPlanningCoordinator(llm)
+
+	ctx := NewAgentContext(context.Background(), "req", "user", "trace")
+	plan, err := coordinator.Plan(ctx, &Request{ID: "req", Input: "web-1 crashes, fix it"})
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+	if len(plan.Tasks) != 2 || plan.Tasks[1].Type != TaskTypeRemediate || plan.Tasks[1].Condition == nil {
+		t.Fatalf("Expected the repaired plan, got %+v", plan.Tasks)
+	}
+
+	if !contains((*seen)[0][1].Content, `"enum": [`) || !contains((*seen)[0][1].Content, "- remediator:") || contains((*seen)[0][1].Content, "- knowledge:") {
+		t.Errorf("Expected the prompt to carry the schema and only registered agents:\n%s", (*seen)[0][1].Content)
+	}
+	repair := (*seen)[1][len((*seen)[1])-1].Content
+	for _, want := range []string{
+		`assigned_agent "security" is not available`,
+		`type "fix" is not one of`,
+		`dependency "x" is not a task id`,
+		`condition refers to "d", which is not in its dependencies`,
+	} {
+		if !strings.Contains(repair, want) {
+			t.Errorf("Expected repair prompt to mention %q:\n%s", want, repair)
+		}
+	}
+
+	report := plan.Metadata["decomposition"].(*DecompositionReport)
+	if report.Attempts != 2 || len(report.Rejected) != 1 || len(report.Rejected[0]) != 4 || report.Fallback {
+		t.Errorf("Unexpected decomposition report %+v", report)
+	}
+}
+
+func TestPlan_FallsBackAfterRepairRounds(t *testing.T) {
+	cycle := `[
+		{"id": "a", "type": "diagnose", "description": "a", "assigned_agent": "diagnostician", "dependencies": ["b"]},
+		{"id": "b", "type": "diagnose", "description": "b", "assigned_agent": "diagnostician", "dependencies": ["a"]}
+	]`
+	llm, seen := scriptedPlanner("not json", cycle, cycle)
+	coordinator := newPlanningCoordinator(llm)
+
+	ctx := NewAgentContext(context.Background(), "req", "user", "trace")
+	plan, err := coordinator.Plan(ctx, &Request{ID: "req", Input: "web-1 crashes"})
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+	if len(*seen) != 1+DefaultPlanRepairRounds {
+		t.Errorf("Expected %d decomposition calls, got %d", 1+DefaultPlanRepairRounds, len(*seen))
+	}
+	if len(plan.Tasks) != 1 || plan.Tasks[0].Type != TaskTypeDiagnose || plan.Tasks[0].Description != "web-1 crashes" {
+		t.Fatalf("Expected the single-task fallback, got %+v", plan.Tasks)
+	}
+
+	report := plan.Metadata["decomposition"].(*DecompositionReport)
+	if !report.Fallback || report.Attempts != 3 || len(report.Rejected) != 3 {
+		t.Fatalf("Unexpected decomposition report %+v", report)
+	}
+	if !contains(report.Rejected[0][0], "not a JSON array") || !contains(report.Rejected[2][0], "circular dependency") {
+		t.Errorf("Unexpected rejection reasons %v", report.Rejected)
+	}
+}
+
+func TestPlan_RepairDisabled(t *testing.T) {
+	llm, seen := scriptedPlanner("[]")
+	coordinator := newPlanningCoordinator(llm).WithPlanRepairRounds(0)
+
+	ctx := NewAgentContext(context.Background(), "req", "user", "trace")
+	plan, err := coordinator.Plan(ctx, &Request{ID: "req", Input: "web-1 crashes"})
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+	if len(*seen) != 1 || !plan.Metadata["decomposition"].(*DecompositionReport).Fallback {
+		t.Errorf("Expected an immediate fallback, got %d calls", len(*seen))
+	}
+}
+
+func TestValidateDecomposition_InputReferences(t *testing.T) {
+	coordinator := newPlanningCoordinator(&MockLLMClient{})
+	problems := coordinator.validateDecomposition([]decomposedTask{
+		{ID: "d", Type: "diagnose", Description: "find cause", AssignedAgent: "diagnostician"},
+		{ID: "r", Type: "remediate", Description: "fix it", AssignedAgent: "remediator",
+			Input: map[string]interface{}{"root_cause": "{{tasks.d.output.root_cause}}"}},
+	}, coordinator.planningAgents())
+	if len(problems) != 1 || !contains(problems[0], `input refers to the output of "d", which is not in its dependencies`) {
+		t.Errorf("Expected the undeclared reference to be rejected, got %v", problems)
+	}
+}
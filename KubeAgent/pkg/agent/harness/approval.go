@@ -0,0 +1,209 @@
+package harness
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DefaultApprovalTimeout is how long an ApprovalGate waits for a
+// decision before rejecting.
+const DefaultApprovalTimeout = 10 * time.Minute
+
+// Risk grades an action waiting for approval, so an AutoApprover can
+// let the harmless ones through.
+type Risk string
+
+const (
+	RiskLow    Risk = "low"
+	RiskMedium Risk = "medium"
+	RiskHigh   Risk = "high"
+)
+
+// rank orders risks; anything unknown counts as high.
+func (r Risk) rank() int {
+	switch r {
+	case RiskLow:
+		return 0
+	case RiskMedium:
+		return 1
+	}
+	return 2
+}
+
+// Approval kinds.
+const (
+	// ApprovalAction is one write a tool is about to make.
+	ApprovalAction = "action"
+	// ApprovalPlan is a whole plan about to run.
+	ApprovalPlan = "plan"
+)
+
+// ApprovalRequest is what an Approver is asked to decide.
+type ApprovalRequest struct {
+	ID        string `json:"id"`
+	Kind      string `json:"kind"`
+	RequestID string `json:"request_id,omitempty"`
+	TaskID    string `json:"task_id,omitempty"`
+	PlanID    string `json:"plan_id,omitempty"`
+	Actor     string `json:"actor"`
+
+	// Prompt says what is about to happen; Diffs, when the action was
+	// dry-run first, show exactly what.
+	Prompt string   `json:"prompt"`
+	Diffs  []string `json:"diffs,omitempty"`
+
+	Target    AuditTarget `json:"target,omitempty"`
+	Risk      Risk        `json:"risk"`
+	CreatedAt time.Time   `json:"created_at"`
+}
+
+// ApprovalDecision is an Approver's answer.
+type ApprovalDecision struct {
+	Approved bool   `json:"approved"`
+	Reason   string `json:"reason,omitempty"`
+	Approver string `json:"approver,omitempty"`
+
+	// Drop, for plan approvals, names tasks to remove (with whatever
+	// depends on them) before the approved plan runs.
+	Drop []string `json:"drop,omitempty"`
+
+	DecidedAt time.Time `json:"decided_at"`
+}
+
+// Approver decides whether an action may go ahead. Approve may block
+// until a person answers; it must return once ctx is done.
+// Implementations are called from parallel tasks and must be safe for
+// concurrent use.
+type Approver interface {
+	Approve(ctx context.Context, req ApprovalRequest) (ApprovalDecision, error)
+}
+
+// ApprovalGate puts the rules every approval follows around an
+// Approver: a request nobody answers in time is rejected, an Approver
+// that fails rejects, and every decision is an AuditDecision event.
+type ApprovalGate struct {
+	approver Approver
+	timeout  time.Duration
+	auditor  AuditLogger
+}
+
+// NewApprovalGate wraps approver with DefaultApprovalTimeout.
+func NewApprovalGate(approver Approver) *ApprovalGate {
+	return &ApprovalGate{approver: approver, timeout: DefaultApprovalTimeout, auditor: NoopAuditor{}}
+}
+
+// WithTimeout replaces the decision deadline. Zero or negative waits
+// as long as the caller's context allows.
+func (g *ApprovalGate) WithTimeout(d time.Duration) *ApprovalGate {
+	g.timeout = d
+	return g
+}
+
+// WithAuditor records every decision. Nil is tolerated.
+func (g *ApprovalGate) WithAuditor(auditor AuditLogger) *ApprovalGate {
+	if auditor == nil {
+		auditor = NoopAuditor{}
+	}
+	g.auditor = auditor
+	return g
+}
+
+// Decide asks the approver and never fails: anything but an explicit
+// approval in time is a rejection with the reason filled in.
+func (g *ApprovalGate) Decide(ctx context.Context, req ApprovalRequest) ApprovalDecision {
+	if req.ID == "" {
+		req.ID = uuid.New().String()
+	}
+	if req.CreatedAt.IsZero() {
+		req.CreatedAt = time.Now()
+	}
+	if req.Risk == "" {
+		req.Risk = RiskHigh
+	}
+
+	// The wait is bounded by the gate's timeout, not by the attempt
+	// deadline of the task asking.
+	defer PauseDeadline(ctx)()
+	waitCtx := ctx
+	if g.timeout > 0 {
+		var cancel context.CancelFunc
+		waitCtx, cancel = context.WithTimeout(ctx, g.timeout)
+		defer cancel()
+	}
+	decision, err := g.approver.Approve(waitCtx, req)
+	switch {
+	case errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil:
+		decision = ApprovalDecision{Reason: fmt.Sprintf("no decision within %s", g.timeout)}
+	case err != nil:
+		decision = ApprovalDecision{Reason: fmt.Sprintf("approval failed: %v", err)}
+	}
+	if decision.DecidedAt.IsZero() {
+		decision.DecidedAt = time.Now()
+	}
+
+	outcome := "rejected"
+	if decision.Approved {
+		outcome = "approved"
+	}
+	_ = g.auditor.Record(ctx, AuditEvent{
+		Kind:      AuditDecision,
+		RequestID: req.RequestID,
+		Actor:     req.Actor,
+		Action:    "approval",
+		Target:    req.Target,
+		Outcome:   outcome,
+		Reason:    decision.Reason,
+		Details: map[string]interface{}{
+			"approval_id": req.ID,
+			"kind":        req.Kind,
+			"task_id":     req.TaskID,
+			"risk":        req.Risk,
+			"prompt":      req.Prompt,
+			"approver":    decision.Approver,
+		},
+	})
+	return decision
+}
+
+// AutoApprover approves requests up to a risk level and rejects the
+// rest, for runs with nobody to ask.
+type AutoApprover struct {
+	maxRisk Risk
+}
+
+// NewAutoApprover approves requests whose risk is at most maxRisk.
+func NewAutoApprover(maxRisk Risk) *AutoApprover {
+	return &AutoApprover{maxRisk: maxRisk}
+}
+
+// Approve implements Approver.
+func (a *AutoApprover) Approve(ctx context.Context, req ApprovalRequest) (ApprovalDecision, error) {
+	if req.Risk.rank() <= a.maxRisk.rank() {
+		return ApprovalDecision{Approved: true, Approver: "auto-policy", Reason: fmt.Sprintf("%s risk is within policy", req.Risk)}, nil
+	}
+	return ApprovalDecision{Approver: "auto-policy", Reason: fmt.Sprintf("%s risk needs a person (policy allows up to %s)", req.Risk, a.maxRisk)}, nil
+}
+
+// requestScopeKey carries the request and task a tool call runs for.
+type requestScopeKey struct{}
+
+type requestScope struct {
+	requestID string
+	taskID    string
+}
+
+// WithRequestScope tags ctx with the request and task a tool call
+// runs for, so approvals and audit events can name them.
+func WithRequestScope(ctx context.Context, requestID, taskID string) context.Context {
+	return context.WithValue(ctx, requestScopeKey{}, requestScope{requestID: requestID, taskID: taskID})
+}
+
+// RequestScope returns the request and task ctx was tagged with.
+func RequestScope(ctx context.Context) (requestID, taskID string) {
+	scope, _ := ctx.Value(requestScopeKey{}).(requestScope)
+	return scope.requestID, scope.taskID
+}
@@ -0,0 +1,251 @@
+package harness
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ApprovalStatus is where an ApprovalRecord stands.
+type ApprovalStatus string
+
+const (
+	ApprovalPending  ApprovalStatus = "pending"
+	ApprovalApproved ApprovalStatus = "approved"
+	ApprovalRejected ApprovalStatus = "rejected"
+	// ApprovalExpired marks a request nobody answered: it timed out, its
+	// caller gave up, or the process restarted while it was pending.
+	ApprovalExpired ApprovalStatus = "expired"
+)
+
+// ApprovalRecord is a request in an HTTPApprover's queue together with
+// its outcome.
+type ApprovalRecord struct {
+	ApprovalRequest
+	Status    ApprovalStatus    `json:"status"`
+	Decision  *ApprovalDecision `json:"decision,omitempty"`
+	UpdatedAt time.Time         `json:"updated_at"`
+}
+
+// ApprovalStore persists the approval queue, so an operator can see
+// what was pending when the process died and what was decided by whom.
+// agent.StateStore implements it.
+type ApprovalStore interface {
+	SaveApproval(ctx context.Context, record *ApprovalRecord) error
+	LoadApprovals(ctx context.Context) ([]*ApprovalRecord, error)
+}
+
+// ErrApprovalNotFound is returned by HTTPApprover.Resolve for unknown
+// or already decided approvals.
+var ErrApprovalNotFound = errors.New("approval not found or already decided")
+
+// HTTPApprover parks each request in a pending queue until someone
+// answers it over HTTP (see Handler) or through Resolve, for front ends
+// where nobody sits at the process's terminal.
+//
+// Only the waiting goroutine lives in memory; every record is written
+// to the store when opened and when closed. A waiter cannot survive a
+// restart, so ExpireStale marks what a previous process left pending.
+type HTTPApprover struct {
+	store     ApprovalStore
+	notifiers []func(ApprovalRecord)
+
+	mu      sync.Mutex
+	pending map[string]*pendingApproval
+}
+
+type pendingApproval struct {
+	record  ApprovalRecord
+	decided chan ApprovalDecision
+}
+
+// NewHTTPApprover creates an approver persisting to store. Nil is
+// tolerated; the queue then lives in memory only.
+func NewHTTPApprover(store ApprovalStore) *HTTPApprover {
+	return &HTTPApprover{store: store, pending: make(map[string]*pendingApproval)}
+}
+
+// WithNotifier calls notify with every approval as it becomes pending,
+// so a chat front end can put it in front of the people who decide.
+// notify runs on the waiting goroutine before the wait starts and must
+// not block for long. Nil is tolerated.
+func (h *HTTPApprover) WithNotifier(notify func(ApprovalRecord)) *HTTPApprover {
+	if notify != nil {
+		h.notifiers = append(h.notifiers, notify)
+	}
+	return h
+}
+
+// Approve implements Approver. It blocks until Resolve is called for
+// req.ID or ctx is done.
+func (h *HTTPApprover) Approve(ctx context.Context, req ApprovalRequest) (ApprovalDecision, error) {
+	p := &pendingApproval{
+		record:  ApprovalRecord{ApprovalRequest: req, Status: ApprovalPending, UpdatedAt: time.Now()},
+		decided: make(chan ApprovalDecision, 1),
+	}
+	h.mu.Lock()
+	if _, exists := h.pending[req.ID]; exists {
+		h.mu.Unlock()
+		return ApprovalDecision{}, fmt.Errorf("approval %s is already pending", req.ID)
+	}
+	h.pending[req.ID] = p
+	h.mu.Unlock()
+	h.save(p.record)
+	for _, notify := range h.notifiers {
+		notify(p.record)
+	}
+
+	select {
+	case decision := <-p.decided:
+		return decision, nil
+	case <-ctx.Done():
+		h.mu.Lock()
+		_, stillPending := h.pending[req.ID]
+		delete(h.pending, req.ID)
+		h.mu.Unlock()
+		if !stillPending {
+			// Resolve won the race; its decision stands.
+			return <-p.decided, nil
+		}
+		record := p.record
+		record.Status = ApprovalExpired
+		record.UpdatedAt = time.Now()
+		h.save(record)
+		return ApprovalDecision{}, ctx.Err()
+	}
+}
+
+// Resolve decides a pending approval. A second decision for the same
+// ID fails with ErrApprovalNotFound.
+func (h *HTTPApprover) Resolve(id string, decision ApprovalDecision) (ApprovalRecord, error) {
+	h.mu.Lock()
+	p, ok := h.pending[id]
+	delete(h.pending, id)
+	h.mu.Unlock()
+	if !ok {
+		return ApprovalRecord{}, ErrApprovalNotFound
+	}
+
+	if decision.DecidedAt.IsZero() {
+		decision.DecidedAt = time.Now()
+	}
+	record := p.record
+	record.Status = ApprovalRejected
+	if decision.Approved {
+		record.Status = ApprovalApproved
+	}
+	record.Decision = &decision
+	record.UpdatedAt = decision.DecidedAt
+	h.save(record)
+	p.decided <- decision
+	return record, nil
+}
+
+// Pending lists the approvals waiting for a decision, oldest first.
+func (h *HTTPApprover) Pending() []ApprovalRecord {
+	h.mu.Lock()
+	records := make([]ApprovalRecord, 0, len(h.pending))
+	for _, p := range h.pending {
+		records = append(records, p.record)
+	}
+	h.mu.Unlock()
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].CreatedAt.Before(records[j].CreatedAt)
+	})
+	return records
+}
+
+// Lookup returns a pending approval.
+func (h *HTTPApprover) Lookup(id string) (ApprovalRecord, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	p, ok := h.pending[id]
+	if !ok {
+		return ApprovalRecord{}, false
+	}
+	return p.record, true
+}
+
+// ExpireStale marks approvals a previous process left pending as
+// expired: their waiters are gone, so nobody could act on a decision.
+// Call it once at startup, before the first Approve.
+func (h *HTTPApprover) ExpireStale(ctx context.Context) (int, error) {
+	if h.store == nil {
+		return 0, nil
+	}
+	records, err := h.store.LoadApprovals(ctx)
+	if err != nil {
+		return 0, err
+	}
+	expired := 0
+	for _, record := range records {
+		if record.Status != ApprovalPending {
+			continue
+		}
+		h.mu.Lock()
+		_, live := h.pending[record.ID]
+		h.mu.Unlock()
+		if live {
+			continue
+		}
+		record.Status = ApprovalExpired
+		record.UpdatedAt = time.Now()
+		if err := h.store.SaveApproval(ctx, record); err != nil {
+			return expired, err
+		}
+		expired++
+	}
+	return expired, nil
+}
+
+// save persists a record. A store failure must not block the decision
+// itself; the audit event still records it.
+func (h *HTTPApprover) save(record ApprovalRecord) {
+	if h.store != nil {
+		_ = h.store.SaveApproval(context.Background(), &record)
+	}
+}
+
+// Handler serves the queue under prefix: GET prefix lists pending
+// approvals, POST prefix/{id} decides one with an ApprovalDecision
+// body. Authentication is the caller's job.
+func (h *HTTPApprover) Handler(prefix string) http.Handler {
+	prefix = strings.TrimSuffix(prefix, "/")
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET "+prefix, func(w http.ResponseWriter, r *http.Request) {
+		writeApprovalJSON(w, http.StatusOK, h.Pending())
+	})
+	mux.HandleFunc("POST "+prefix+"/{id}", func(w http.ResponseWriter, r *http.Request) {
+		var decision ApprovalDecision
+		decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&decision); err != nil {
+			writeApprovalJSON(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("invalid JSON body: %v", err)})
+			return
+		}
+		record, err := h.Resolve(r.PathValue("id"), decision)
+		if err != nil {
+			writeApprovalJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+			return
+		}
+		writeApprovalJSON(w, http.StatusOK, record)
+	})
+	return mux
+}
+
+func writeApprovalJSON(w http.ResponseWriter, status int, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, `{"error":"failed to encode response"}`, http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(append(data, '\n'))
+}
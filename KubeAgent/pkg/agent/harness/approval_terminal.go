@@ -0,0 +1,113 @@
+package harness
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TerminalApprover asks whoever sits at a terminal. Prompts are
+// serialized, so parallel tasks queue up instead of interleaving on the
+// screen, and a prompt nobody answers gives up when ctx is done.
+type TerminalApprover struct {
+	in  *LineReader
+	out io.Writer
+
+	mu sync.Mutex
+}
+
+// NewTerminalApprover reads answers from in and writes prompts to out.
+// Give it the LineReader the rest of the front end reads too: `chat`
+// and the plan review read the same stdin between prompts.
+func NewTerminalApprover(in *LineReader, out io.Writer) *TerminalApprover {
+	return &TerminalApprover{in: in, out: out}
+}
+
+// Approve implements Approver. yes/y approves; anything else rejects.
+func (t *TerminalApprover) Approve(ctx context.Context, req ApprovalRequest) (ApprovalDecision, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, diff := range req.Diffs {
+		fmt.Fprintf(t.out, "\n[Dry-run diff]\n%s", diff)
+	}
+	fmt.Fprintf(t.out, "\n[Human Approval Required] %s\n请输入 yes/y 确认，其他内容取消: ", req.Prompt)
+
+	line, err := t.in.ReadLine(ctx)
+	if err != nil {
+		if ctx.Err() != nil {
+			fmt.Fprintln(t.out)
+			return ApprovalDecision{}, ctx.Err()
+		}
+		return ApprovalDecision{}, fmt.Errorf("failed to read user input: %w", err)
+	}
+	answer := strings.TrimSpace(strings.ToLower(line))
+	approved := answer == "yes" || answer == "y"
+	return ApprovalDecision{Approved: approved, Approver: "terminal", DecidedAt: time.Now()}, nil
+}
+
+// LineReader hands the lines of one input, such as stdin, to everyone
+// who reads it: a REPL, the plan review and a TerminalApprover. Two
+// buffered readers on the same input would each swallow lines meant
+// for the other.
+//
+// Lines are read on demand by one goroutine, never ahead. A blocked
+// Read cannot be cancelled, though, so the line an abandoned ReadLine
+// was waiting for goes to the next call.
+type LineReader struct {
+	mu      sync.Mutex
+	start   sync.Once
+	in      *bufio.Reader
+	wants   chan struct{}
+	lines   chan terminalLine
+	reading bool
+}
+
+type terminalLine struct {
+	text string
+	err  error
+}
+
+// NewLineReader reads lines from in.
+func NewLineReader(in io.Reader) *LineReader {
+	return &LineReader{
+		in:    bufio.NewReader(in),
+		wants: make(chan struct{}),
+		lines: make(chan terminalLine, 1),
+	}
+}
+
+// ReadLine returns the next line without its line ending, or io.EOF
+// once the input ends. It gives up with ctx.Err() when ctx is done.
+func (r *LineReader) ReadLine(ctx context.Context) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.start.Do(func() { go r.readLines() })
+
+	if !r.reading {
+		r.wants <- struct{}{}
+		r.reading = true
+	}
+	select {
+	case line := <-r.lines:
+		r.reading = false
+		if line.err != nil && (line.err != io.EOF || line.text == "") {
+			return "", line.err
+		}
+		return strings.TrimRight(line.text, "\r\n"), nil
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// readLines reads one line per request for the reader's lifetime.
+func (r *LineReader) readLines() {
+	for range r.wants {
+		text, err := r.in.ReadString('\n')
+		r.lines <- terminalLine{text: text, err: err}
+	}
+}
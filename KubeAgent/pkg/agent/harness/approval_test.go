@@ -0,0 +1,290 @@
+package harness
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memoryApprovalStore is an ApprovalStore for tests; the real ones
+// live in package agent.
+type memoryApprovalStore struct {
+	mu      sync.Mutex
+	records map[string]ApprovalRecord
+	order   []string
+}
+
+func newMemoryApprovalStore() *memoryApprovalStore {
+	return &memoryApprovalStore{records: make(map[string]ApprovalRecord)}
+}
+
+func (m *memoryApprovalStore) SaveApproval(_ context.Context, record *ApprovalRecord) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.records[record.ID]; !ok {
+		m.order = append(m.order, record.ID)
+	}
+	m.records[record.ID] = *record
+	return nil
+}
+
+func (m *memoryApprovalStore) LoadApprovals(_ context.Context) ([]*ApprovalRecord, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make([]*ApprovalRecord, 0, len(m.order))
+	for _, id := range m.order {
+		record := m.records[id]
+		out = append(out, &record)
+	}
+	return out, nil
+}
+
+func (m *memoryApprovalStore) status(id string) ApprovalStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.records[id].Status
+}
+
+type approverFunc func(ctx context.Context, req ApprovalRequest) (ApprovalDecision, error)
+
+func (f approverFunc) Approve(ctx context.Context, req ApprovalRequest) (ApprovalDecision, error) {
+	return f(ctx, req)
+}
+
+func waitForPending(t *testing.T, approver *HTTPApprover) ApprovalRecord {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if pending := approver.Pending(); len(pending) > 0 {
+			return pending[0]
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatal("No approval became pending")
+	return ApprovalRecord{}
+}
+
+// TestApprovalGate_TimeoutRejects checks the rule the request is about:
+// an approval nobody answers is a rejection, and it is audited as one.
+func TestApprovalGate_TimeoutRejects(t *testing.T) {
+	trail := NewAuditTrail(0)
+	waits := approverFunc(func(ctx context.Context, req ApprovalRequest) (ApprovalDecision, error) {
+		<-ctx.Done()
+		return ApprovalDecision{}, ctx.Err()
+	})
+	gate := NewApprovalGate(waits).WithTimeout(10 * time.Millisecond).WithAuditor(trail)
+
+	decision := gate.Decide(context.Background(), ApprovalRequest{RequestID: "r1", TaskID: "t1", Actor: "HumanTool", Prompt: "delete web-1?"})
+	if decision.Approved || !strings.Contains(decision.Reason, "no decision within 10ms") {
+		t.Fatalf("Expected a timeout rejection, got %+v", decision)
+	}
+
+	events := trail.Since(time.Time{}, "r1")
+	if len(events) != 1 {
+		t.Fatalf("Expected one audit event, got %+v", events)
+	}
+	event := events[0]
+	if event.Kind != AuditDecision || event.Outcome != "rejected" || event.Reason != decision.Reason {
+		t.Errorf("Unexpected audit event %+v", event)
+	}
+	if event.Details["approval_id"] == "" || event.Details["task_id"] != "t1" || event.Details["risk"] != RiskHigh {
+		t.Errorf("Expected the approval ID, task and default risk in the details, got %+v", event.Details)
+	}
+}
+
+func TestApprovalGate_ApproverErrorRejects(t *testing.T) {
+	broken := approverFunc(func(ctx context.Context, req ApprovalRequest) (ApprovalDecision, error) {
+		return ApprovalDecision{Approved: true}, errors.New("queue unavailable")
+	})
+	decision := NewApprovalGate(broken).Decide(context.Background(), ApprovalRequest{Prompt: "patch?"})
+	if decision.Approved || !strings.Contains(decision.Reason, "queue unavailable") {
+		t.Errorf("Expected a failing approver to reject, got %+v", decision)
+	}
+}
+
+func TestAutoApprover_ApprovesUpToPolicy(t *testing.T) {
+	gate := NewApprovalGate(NewAutoApprover(RiskMedium))
+	cases := []struct {
+		risk Risk
+		want bool
+	}{
+		{RiskLow, true},
+		{RiskMedium, true},
+		{RiskHigh, false},
+		{Risk("unknown"), false},
+	}
+	for _, c := range cases {
+		decision := gate.Decide(context.Background(), ApprovalRequest{Prompt: "change", Risk: c.risk})
+		if decision.Approved != c.want || decision.Approver != "auto-policy" {
+			t.Errorf("Risk %q: expected approved=%v, got %+v", c.risk, c.want, decision)
+		}
+	}
+}
+
+func TestTerminalApprover_ReadsOneLinePerPrompt(t *testing.T) {
+	var out bytes.Buffer
+	approver := NewTerminalApprover(NewLineReader(strings.NewReader("y\nno\n")), &out)
+
+	first, err := approver.Approve(context.Background(), ApprovalRequest{Prompt: "scale web?", Diffs: []string{"~ spec.replicas: 1 -> 3\n"}})
+	if err != nil || !first.Approved {
+		t.Fatalf("Expected approval, got %+v (err %v)", first, err)
+	}
+	if !strings.Contains(out.String(), "[Dry-run diff]\n~ spec.replicas") || !strings.Contains(out.String(), "scale web?") {
+		t.Errorf("Expected the diff above the prompt, got:\n%s", out.String())
+	}
+	second, err := approver.Approve(context.Background(), ApprovalRequest{Prompt: "delete web?"})
+	if err != nil || second.Approved {
+		t.Fatalf("Expected rejection, got %+v (err %v)", second, err)
+	}
+	if _, err := approver.Approve(context.Background(), ApprovalRequest{Prompt: "again?"}); err == nil {
+		t.Error("Expected an error once the input ends")
+	}
+}
+
+// TestTerminalApprover_GivesUpWithContext checks an unanswered prompt
+// does not hold the caller forever, and that the late answer is not
+// lost but goes to the next prompt.
+func TestTerminalApprover_GivesUpWithContext(t *testing.T) {
+	in, typed := io.Pipe()
+	approver := NewTerminalApprover(NewLineReader(in), io.Discard)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := approver.Approve(ctx, ApprovalRequest{Prompt: "delete web?"}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected the deadline to end the prompt, got %v", err)
+	}
+
+	go typed.Write([]byte("yes\n"))
+	decision, err := approver.Approve(context.Background(), ApprovalRequest{Prompt: "patch web?"})
+	if err != nil || !decision.Approved {
+		t.Errorf("Expected the next prompt to get the answer, got %+v (err %v)", decision, err)
+	}
+}
+
+// TestLineReader_SharedWithApprover checks a REPL and an approver on
+// one input each get their own lines, and a prompt that gave up leaves
+// no reader behind: the late answer goes to the REPL's next read.
+func TestLineReader_SharedWithApprover(t *testing.T) {
+	in, typed := io.Pipe()
+	lines := NewLineReader(in)
+	approver := NewTerminalApprover(lines, io.Discard)
+
+	go typed.Write([]byte("fix web-1\n"))
+	if line, err := lines.ReadLine(context.Background()); err != nil || line != "fix web-1" {
+		t.Fatalf("Expected the REPL's line, got %q (err %v)", line, err)
+	}
+	go typed.Write([]byte("y\n"))
+	if decision, err := approver.Approve(context.Background(), ApprovalRequest{Prompt: "patch web?"}); err != nil || !decision.Approved {
+		t.Fatalf("Expected the approver's line, got %+v (err %v)", decision, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := approver.Approve(ctx, ApprovalRequest{Prompt: "delete web?"}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected the deadline to end the prompt, got %v", err)
+	}
+	go typed.Write([]byte("exit\n"))
+	if line, err := lines.ReadLine(context.Background()); err != nil || line != "exit" {
+		t.Errorf("Expected the REPL to read the next line, got %q (err %v)", line, err)
+	}
+	typed.Close()
+	if _, err := lines.ReadLine(context.Background()); !errors.Is(err, io.EOF) {
+		t.Errorf("Expected io.EOF once the input ends, got %v", err)
+	}
+}
+
+func TestHTTPApprover_ResolvesOverHTTP(t *testing.T) {
+	store := newMemoryApprovalStore()
+	approver := NewHTTPApprover(store)
+	server := httptest.NewServer(approver.Handler("/approvals"))
+	defer server.Close()
+
+	result := make(chan ApprovalDecision, 1)
+	go func() {
+		result <- NewApprovalGate(approver).Decide(context.Background(), ApprovalRequest{ID: "a1", Kind: ApprovalAction, Prompt: "patch web?", Risk: RiskMedium})
+	}()
+	pending := waitForPending(t, approver)
+	if pending.ID != "a1" || pending.Status != ApprovalPending || store.status("a1") != ApprovalPending {
+		t.Fatalf("Expected a1 pending and persisted, got %+v (stored %q)", pending, store.status("a1"))
+	}
+
+	resp, err := http.Get(server.URL + "/approvals")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var listed []ApprovalRecord
+	json.NewDecoder(resp.Body).Decode(&listed)
+	resp.Body.Close()
+	if len(listed) != 1 || listed[0].Prompt != "patch web?" {
+		t.Fatalf("Expected the pending approval in the list, got %+v", listed)
+	}
+
+	body := `{"approved": true, "approver": "alice"}`
+	resp, err = http.Post(server.URL+"/approvals/a1", "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", resp.StatusCode)
+	}
+	if decision := <-result; !decision.Approved || decision.Approver != "alice" {
+		t.Errorf("Expected alice's approval, got %+v", decision)
+	}
+	if store.status("a1") != ApprovalApproved {
+		t.Errorf("Expected the stored record approved, got %q", store.status("a1"))
+	}
+
+	resp, _ = http.Post(server.URL+"/approvals/a1", "application/json", strings.NewReader(body))
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected a second decision to be refused, got %d", resp.StatusCode)
+	}
+}
+
+func TestHTTPApprover_TimeoutExpiresRecord(t *testing.T) {
+	store := newMemoryApprovalStore()
+	approver := NewHTTPApprover(store)
+	decision := NewApprovalGate(approver).WithTimeout(10*time.Millisecond).Decide(context.Background(), ApprovalRequest{ID: "a1", Prompt: "delete web?"})
+	if decision.Approved {
+		t.Fatal("Expected an unanswered approval to be rejected")
+	}
+	if store.status("a1") != ApprovalExpired || len(approver.Pending()) != 0 {
+		t.Errorf("Expected a1 expired and out of the queue, got %q", store.status("a1"))
+	}
+	if _, err := approver.Resolve("a1", ApprovalDecision{Approved: true}); !errors.Is(err, ErrApprovalNotFound) {
+		t.Errorf("Expected a late decision to be refused, got %v", err)
+	}
+}
+
+func TestHTTPApprover_ExpireStale(t *testing.T) {
+	store := newMemoryApprovalStore()
+	store.SaveApproval(context.Background(), &ApprovalRecord{ApprovalRequest: ApprovalRequest{ID: "old"}, Status: ApprovalPending})
+	store.SaveApproval(context.Background(), &ApprovalRecord{ApprovalRequest: ApprovalRequest{ID: "done"}, Status: ApprovalApproved})
+
+	expired, err := NewHTTPApprover(store).ExpireStale(context.Background())
+	if err != nil || expired != 1 {
+		t.Fatalf("Expected one stale approval, got %d (err %v)", expired, err)
+	}
+	if store.status("old") != ApprovalExpired || store.status("done") != ApprovalApproved {
+		t.Errorf("Unexpected statuses old=%q done=%q", store.status("old"), store.status("done"))
+	}
+}
+
+func TestRequestScope(t *testing.T) {
+	ctx := WithRequestScope(context.Background(), "r1", "t1")
+	if requestID, taskID := RequestScope(ctx); requestID != "r1" || taskID != "t1" {
+		t.Errorf("Expected r1/t1, got %s/%s", requestID, taskID)
+	}
+	if requestID, taskID := RequestScope(context.Background()); requestID != "" || taskID != "" {
+		t.Errorf("Expected an empty scope, got %s/%s", requestID, taskID)
+	}
+}
@@ -25,7 +25,8 @@ const (
 	AuditVerification AuditEventKind = "verification"
 
 	// AuditDecision is emitted when the agent decides to skip, retry,
-	// or escalate based on harness signals.
+	// or escalate based on harness signals, and for every approval an
+	// ApprovalGate decides.
 	AuditDecision AuditEventKind = "decision"
 )
 
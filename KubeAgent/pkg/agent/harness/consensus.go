@@ -0,0 +1,174 @@
+package harness
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Vote is one reviewer's verdict on a proposed action.
+type Vote struct {
+	Reviewer  string `json:"reviewer"`
+	Approve   bool   `json:"approve"`
+	Reasoning string `json:"reasoning,omitempty"`
+
+	// Error is set when the reviewer could not vote (LLM down,
+	// unparseable reply). Such a vote never approves.
+	Error string `json:"error,omitempty"`
+}
+
+// Reviewer judges a proposed action independently of the agent that
+// proposed it. Implementations typically ask an LLM — a different model
+// or a different prompt than the Remediator's — so one model's blind
+// spot cannot both propose and approve a destructive change.
+type Reviewer interface {
+	// Name identifies the reviewer in votes and audit logs.
+	Name() string
+
+	// Review returns the reviewer's verdict. An error counts as a vote
+	// that does not approve.
+	Review(ctx context.Context, req PreflightRequest) (Vote, error)
+}
+
+// ConsensusCheck is a Guide that lets a high-risk action through only
+// when at least Quorum of its reviewers approve it. Reviewers vote in
+// parallel and never see each other's votes. Every ballot is recorded
+// as one AuditDecision event listing all votes.
+//
+// Only the verbs the check is configured for (delete by default) are
+// put to a vote; everything else is allowed without asking, so the
+// check can sit in the same chain the write tools already share.
+type ConsensusCheck struct {
+	reviewers []Reviewer
+	quorum    int
+	verbs     map[string]bool
+	auditor   AuditLogger
+}
+
+// NewConsensusCheck builds a check that needs quorum approvals from
+// reviewers. A quorum of zero or less means a strict majority. With no
+// reviewers every vote fails, which blocks: a misconfigured consensus
+// must not silently become "allow".
+func NewConsensusCheck(quorum int, reviewers ...Reviewer) *ConsensusCheck {
+	if quorum <= 0 {
+		quorum = len(reviewers)/2 + 1
+	}
+	return &ConsensusCheck{
+		reviewers: reviewers,
+		quorum:    quorum,
+		verbs:     map[string]bool{"delete": true},
+		auditor:   NoopAuditor{},
+	}
+}
+
+// WithVerbs replaces the verbs that need consensus, e.g. "delete",
+// "patch", "apply", "create".
+func (c *ConsensusCheck) WithVerbs(verbs ...string) *ConsensusCheck {
+	c.verbs = make(map[string]bool, len(verbs))
+	for _, verb := range verbs {
+		c.verbs[strings.ToLower(verb)] = true
+	}
+	return c
+}
+
+// WithAuditor installs the sink for ballots. Nil is tolerated and keeps
+// the no-op default.
+func (c *ConsensusCheck) WithAuditor(a AuditLogger) *ConsensusCheck {
+	if a != nil {
+		c.auditor = a
+	}
+	return c
+}
+
+// Quorum returns how many approvals an action needs.
+func (c *ConsensusCheck) Quorum() int { return c.quorum }
+
+// Name implements PreflightCheck.
+func (c *ConsensusCheck) Name() string { return "consensus" }
+
+// Check implements PreflightCheck. Reviewer failures are votes, not
+// check errors, so the chain's FailClosed setting does not apply: a
+// reviewer that cannot answer simply does not approve.
+func (c *ConsensusCheck) Check(ctx context.Context, req PreflightRequest) (*PreflightResult, error) {
+	if !c.verbs[strings.ToLower(req.Verb)] {
+		return &PreflightResult{Decision: PreflightAllow}, nil
+	}
+
+	votes := c.collect(ctx, req)
+	approvals := 0
+	var dissent []string
+	for _, vote := range votes {
+		if vote.Approve {
+			approvals++
+			continue
+		}
+		reason := vote.Reasoning
+		if vote.Error != "" {
+			reason = "no vote: " + vote.Error
+		}
+		dissent = append(dissent, fmt.Sprintf("%s: %s", vote.Reviewer, reason))
+	}
+
+	tally := fmt.Sprintf("%d/%d reviewers approved (quorum %d)", approvals, len(votes), c.quorum)
+	result := &PreflightResult{Decision: PreflightAllow, Reason: tally}
+	outcome := "approved"
+	if approvals < c.quorum {
+		result.Decision = PreflightBlock
+		result.Reason = "consensus not reached: " + tally
+		if len(dissent) > 0 {
+			result.Reason += "; " + strings.Join(dissent, "; ")
+		}
+		outcome = "rejected"
+	} else if len(dissent) > 0 {
+		result.Warnings = append(result.Warnings, dissent...)
+	}
+
+	c.record(ctx, req, outcome, result.Reason, votes, approvals)
+	return result, nil
+}
+
+// collect asks every reviewer in parallel. Votes come back in reviewer
+// order so audit records are stable.
+func (c *ConsensusCheck) collect(ctx context.Context, req PreflightRequest) []Vote {
+	votes := make([]Vote, len(c.reviewers))
+	var wg sync.WaitGroup
+	for i, reviewer := range c.reviewers {
+		wg.Add(1)
+		go func(i int, reviewer Reviewer) {
+			defer wg.Done()
+			vote, err := reviewer.Review(ctx, req)
+			vote.Reviewer = reviewer.Name()
+			if err != nil {
+				vote.Approve = false
+				vote.Error = err.Error()
+			}
+			votes[i] = vote
+		}(i, reviewer)
+	}
+	wg.Wait()
+	return votes
+}
+
+func (c *ConsensusCheck) record(ctx context.Context, req PreflightRequest, outcome, reason string, votes []Vote, approvals int) {
+	// Like the tools, a failing audit sink must not change the verdict.
+	requestID, _ := RequestScope(ctx)
+	_ = c.auditor.Record(ctx, AuditEvent{
+		Kind:      AuditDecision,
+		RequestID: requestID,
+		Actor:     "consensus",
+		Action:    req.Verb,
+		Outcome:   outcome,
+		Reason:    reason,
+		Target: AuditTarget{
+			Kind:      req.ResourceKind,
+			Name:      req.ResourceName,
+			Namespace: req.Namespace,
+		},
+		Details: map[string]interface{}{
+			"quorum":    c.quorum,
+			"approvals": approvals,
+			"votes":     votes,
+		},
+	})
+}
@@ -0,0 +1,97 @@
+package harness
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// stubReviewer votes the same way every time and counts its calls.
+type stubReviewer struct {
+	name    string
+	approve bool
+	err     error
+	calls   int32
+}
+
+func (s *stubReviewer) Name() string { return s.name }
+func (s *stubReviewer) Review(_ context.Context, _ PreflightRequest) (Vote, error) {
+	atomic.AddInt32(&s.calls, 1)
+	return Vote{Approve: s.approve, Reasoning: s.name + " says so"}, s.err
+}
+
+type recordingAuditor struct{ events []AuditEvent }
+
+func (r *recordingAuditor) Record(_ context.Context, e AuditEvent) error {
+	r.events = append(r.events, e)
+	return nil
+}
+
+var deletePod = PreflightRequest{Verb: "delete", ResourceKind: "pod", ResourceName: "web-1", Namespace: "prod"}
+
+// TestConsensusCheck_Quorum: the action passes with enough approvals,
+// and a reviewer that errors counts against it rather than aborting.
+func TestConsensusCheck_Quorum(t *testing.T) {
+	audit := &recordingAuditor{}
+	check := NewConsensusCheck(2,
+		&stubReviewer{name: "safety", approve: true},
+		&stubReviewer{name: "blast-radius", approve: false},
+		&stubReviewer{name: "flaky", err: errors.New("503")},
+	).WithAuditor(audit)
+
+	res, err := check.Check(WithRequestScope(context.Background(), "req-1", "fix"), deletePod)
+	if err != nil {
+		t.Fatalf("Check errored: %v", err)
+	}
+	if res.Decision != PreflightBlock || !strings.Contains(res.Reason, "1/3 reviewers approved (quorum 2)") ||
+		!strings.Contains(res.Reason, "blast-radius: blast-radius says so") || !strings.Contains(res.Reason, "flaky: no vote: 503") {
+		t.Fatalf("Expected a block naming the dissent, got %+v", res)
+	}
+
+	if len(audit.events) != 1 {
+		t.Fatalf("Expected one audit event, got %d", len(audit.events))
+	}
+	event := audit.events[0]
+	votes, _ := event.Details["votes"].([]Vote)
+	if event.Kind != AuditDecision || event.RequestID != "req-1" || event.Outcome != "rejected" || event.Target.Name != "web-1" || len(votes) != 3 || votes[2].Error != "503" {
+		t.Errorf("Unexpected audit event %+v", event)
+	}
+
+	check = NewConsensusCheck(2,
+		&stubReviewer{name: "safety", approve: true},
+		&stubReviewer{name: "blast-radius", approve: true},
+		&stubReviewer{name: "paranoid", approve: false},
+	)
+	res, _ = check.Check(context.Background(), deletePod)
+	if res.Decision != PreflightAllow || len(res.Warnings) != 1 {
+		t.Errorf("Expected an allow carrying the dissent as a warning, got %+v", res)
+	}
+}
+
+// TestConsensusCheck_OnlyConfiguredVerbs: other verbs pass without a
+// vote, so one chain can serve every write tool.
+func TestConsensusCheck_OnlyConfiguredVerbs(t *testing.T) {
+	reviewer := &stubReviewer{name: "safety"}
+	check := NewConsensusCheck(0, reviewer)
+
+	res, _ := check.Check(context.Background(), PreflightRequest{Verb: "patch"})
+	if res.Decision != PreflightAllow || reviewer.calls != 0 {
+		t.Errorf("Expected patch to pass unreviewed, got %+v after %d reviews", res, reviewer.calls)
+	}
+
+	check.WithVerbs("patch")
+	if res, _ := check.Check(context.Background(), PreflightRequest{Verb: "patch"}); res.Decision != PreflightBlock {
+		t.Errorf("Expected patch to need consensus now, got %+v", res)
+	}
+}
+
+// TestConsensusCheck_NoReviewersBlocks: a consensus with nobody to ask
+// must not turn into an allow.
+func TestConsensusCheck_NoReviewersBlocks(t *testing.T) {
+	res, _ := NewConsensusCheck(0).Check(context.Background(), deletePod)
+	if res.Decision != PreflightBlock {
+		t.Errorf("Expected block, got %+v", res)
+	}
+}
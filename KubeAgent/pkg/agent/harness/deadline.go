@@ -0,0 +1,104 @@
+package harness
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// deadlinePauseKey carries the pausable deadline a context runs under.
+type deadlinePauseKey struct{}
+
+// pausableDeadline is a timeout whose clock can be stopped. The
+// coordinator gives every task attempt one, and an ApprovalGate stops
+// it while a person decides: an agent's Timeout bounds the agent's own
+// work, and a 2-minute attempt must not reject every approval that
+// takes longer than that to answer.
+type pausableDeadline struct {
+	context.Context
+	done chan struct{}
+
+	mu        sync.Mutex
+	err       error
+	timer     *time.Timer
+	remaining time.Duration
+	resumedAt time.Time
+	pauses    int
+	stop      func() bool
+}
+
+// WithPausableTimeout is context.WithTimeout with a clock PauseDeadline
+// can stop. The context ends with context.DeadlineExceeded once d of
+// unpaused time has passed, or with the parent's error.
+func WithPausableTimeout(parent context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	c := &pausableDeadline{
+		Context:   parent,
+		done:      make(chan struct{}),
+		remaining: d,
+		resumedAt: time.Now(),
+	}
+	// Both callbacks may fire at once; finish waits for the fields.
+	c.mu.Lock()
+	c.timer = time.AfterFunc(d, func() { c.finish(context.DeadlineExceeded) })
+	c.stop = context.AfterFunc(parent, func() { c.finish(parent.Err()) })
+	c.mu.Unlock()
+	return context.WithValue(c, deadlinePauseKey{}, c), func() { c.finish(context.Canceled) }
+}
+
+// PauseDeadline stops the clock of the pausable deadline ctx runs
+// under until the returned func is called. Without one it does
+// nothing. Pauses nest; the clock restarts when the last one ends.
+func PauseDeadline(ctx context.Context) (resume func()) {
+	c, ok := ctx.Value(deadlinePauseKey{}).(*pausableDeadline)
+	if !ok {
+		return func() {}
+	}
+	c.pause()
+	var once sync.Once
+	return func() { once.Do(c.resume) }
+}
+
+func (c *pausableDeadline) Done() <-chan struct{} { return c.done }
+
+func (c *pausableDeadline) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+func (c *pausableDeadline) pause() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return
+	}
+	if c.pauses == 0 && c.timer.Stop() {
+		c.remaining -= time.Since(c.resumedAt)
+	}
+	c.pauses++
+}
+
+func (c *pausableDeadline) resume() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil || c.pauses == 0 {
+		return
+	}
+	c.pauses--
+	if c.pauses == 0 {
+		c.resumedAt = time.Now()
+		c.timer.Reset(max(c.remaining, 0))
+	}
+}
+
+func (c *pausableDeadline) finish(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return
+	}
+	c.err = err
+	c.timer.Stop()
+	c.stop()
+	close(c.done)
+}
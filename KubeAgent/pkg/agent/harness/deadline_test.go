@@ -0,0 +1,63 @@
+package harness
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestPausableTimeout_ApprovalDoesNotCount checks that an approval
+// slower than the attempt timeout is still answered, and that the
+// clock runs again once it is.
+func TestPausableTimeout_ApprovalDoesNotCount(t *testing.T) {
+	ctx, cancel := WithPausableTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	gate := NewApprovalGate(approverFunc(func(ctx context.Context, req ApprovalRequest) (ApprovalDecision, error) {
+		select {
+		case <-time.After(150 * time.Millisecond):
+			return ApprovalDecision{Approved: true}, nil
+		case <-ctx.Done():
+			return ApprovalDecision{}, ctx.Err()
+		}
+	}))
+	if decision := gate.Decide(ctx, ApprovalRequest{}); !decision.Approved {
+		t.Fatalf("Expected the slow approval to be answered, got %+v", decision)
+	}
+	if ctx.Err() != nil {
+		t.Fatalf("Expected the attempt to survive the wait, got %v", ctx.Err())
+	}
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("Deadline never fired after the approval")
+	}
+	if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		t.Errorf("Expected DeadlineExceeded, got %v", ctx.Err())
+	}
+}
+
+// TestPausableTimeout_FollowsParent checks cancellation still reaches
+// a paused attempt.
+func TestPausableTimeout_FollowsParent(t *testing.T) {
+	parent, cancelParent := context.WithCancel(context.Background())
+	ctx, cancel := WithPausableTimeout(parent, time.Hour)
+	defer cancel()
+	resume := PauseDeadline(ctx)
+	defer resume()
+
+	cancelParent()
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("Parent cancellation did not reach the attempt")
+	}
+	if !errors.Is(ctx.Err(), context.Canceled) {
+		t.Errorf("Expected Canceled, got %v", ctx.Err())
+	}
+	if PauseDeadline(context.Background()) == nil {
+		t.Error("Expected a no-op resume without a pausable deadline")
+	}
+}
@@ -36,7 +36,7 @@ func NewK8sVerifier(client *k8s.Client) *K8sVerifier {
 //   - The resource matches the expected phase (Passed).
 //   - The resource is in a clearly-bad terminal state, e.g.
 //     CrashLoopBackOff (Failed). Terminal-bad reasons are detected by
-//     isTerminalFailure; everything else keeps polling.
+//     IsTerminalFailure; everything else keeps polling.
 //   - SettleTimeout elapses without a verdict (Inconclusive).
 //   - The kind is one the underlying state derivation cannot judge
 //     (Inconclusive immediately).
@@ -100,7 +100,7 @@ func (v *K8sVerifier) Verify(ctx context.Context, target VerificationTarget) (*V
 			if state.Exists {
 				// Hard-fail fast on terminal error reasons so we don't
 				// burn the full timeout on a pod that will never recover.
-				if isTerminalFailure(state.Reason) {
+				if IsTerminalFailure(state.Reason) {
 					return failed(start,
 						fmt.Sprintf("resource %s/%s in terminal failure state: %s",
 							state.Kind, state.Name, state.Reason),
@@ -148,9 +148,9 @@ func matchesExpected(state *k8s.ResourceState, expected string) bool {
 	return false
 }
 
-// isTerminalFailure flags reasons we should never wait out: the pod is
+// IsTerminalFailure flags reasons we should never wait out: the pod is
 // looping or stuck pulling and more time will not help.
-func isTerminalFailure(reason string) bool {
+func IsTerminalFailure(reason string) bool {
 	switch reason {
 	case "CrashLoopBackOff", "ImagePullBackOff", "ErrImagePull",
 		"CreateContainerConfigError", "InvalidImageName":
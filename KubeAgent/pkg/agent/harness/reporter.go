@@ -67,7 +67,8 @@ func (r *ConsoleReporter) Record(_ context.Context, event AuditEvent) error {
 }
 
 // writeHeader renders the leading status line, e.g.
-//   [00:01.42] [SENSOR] verification: passed (resource pod/foo reached Running)
+//
+//	[00:01.42] [SENSOR] verification: passed (resource pod/foo reached Running)
 func (r *ConsoleReporter) writeHeader(event AuditEvent) {
 	elapsed := time.Since(r.startedAt)
 	timestamp := fmt.Sprintf("[%02d:%05.2f]",
@@ -110,7 +111,7 @@ func (r *ConsoleReporter) writeBody(event AuditEvent) {
 	// Surface the small handful of fields that matter for the demo.
 	// We don't dump the whole map — that defeats the purpose of a
 	// human-readable view.
-	keys := []string{"phase", "reason", "ready", "exists", "warnings"}
+	keys := []string{"phase", "reason", "ready", "exists", "warnings", "iteration", "scheduled", "approvals"}
 	for _, k := range keys {
 		if v, ok := event.Details[k]; ok && !isZero(v) {
 			fmt.Fprintf(r.out, "           %s: %v\n", k, v)
@@ -120,10 +121,11 @@ func (r *ConsoleReporter) writeBody(event AuditEvent) {
 
 // tagForKind produces the [TAG] label and a colour code. The
 // vocabulary is intentionally short so the eye can scan a long log:
-//   GUIDE   — a Preflight check fired
-//   ACTION  — the agent did a thing
-//   SENSOR  — a Verifier observed an outcome
-//   DECIDE  — the harness took a control-flow decision
+//
+//	GUIDE   — a Preflight check fired
+//	ACTION  — the agent did a thing
+//	SENSOR  — a Verifier observed an outcome
+//	DECIDE  — the harness took a control-flow decision
 func tagForKind(kind AuditEventKind, outcome string) (string, string) {
 	switch kind {
 	case AuditPreflight:
@@ -146,9 +148,9 @@ func tagForKind(kind AuditEventKind, outcome string) (string, string) {
 // that matches operator intuition during incident response.
 func outcomeColour(outcome string) string {
 	switch strings.ToLower(outcome) {
-	case "success", "passed", "allow":
+	case "success", "passed", "allow", "approved":
 		return colourGreen
-	case "failure", "failed", "block":
+	case "failure", "failed", "block", "rejected":
 		return colourRed
 	case "inconclusive", "warn", "skipped":
 		return colourYellow
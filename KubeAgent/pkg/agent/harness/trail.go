@@ -0,0 +1,65 @@
+package harness
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// DefaultAuditTrailSize is how many events an AuditTrail keeps before
+// dropping the oldest.
+const DefaultAuditTrailSize = 1000
+
+// AuditTrail is an AuditLogger that keeps recent events in memory, so
+// the end-of-request report can quote what the tools and agents
+// recorded without re-reading the durable sink. Tee it next to the
+// console and file sinks; it is not a replacement for either.
+type AuditTrail struct {
+	mu     sync.Mutex
+	events []AuditEvent
+	size   int
+}
+
+// NewAuditTrail keeps the last size events. A size of zero or less
+// means DefaultAuditTrailSize.
+func NewAuditTrail(size int) *AuditTrail {
+	if size <= 0 {
+		size = DefaultAuditTrailSize
+	}
+	return &AuditTrail{size: size}
+}
+
+// Record implements AuditLogger. It stamps the event like
+// JSONLogAuditor so Since can compare timestamps.
+func (t *AuditTrail) Record(_ context.Context, event AuditEvent) error {
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.events = append(t.events, event)
+	if over := len(t.events) - t.size; over > 0 {
+		t.events = append(t.events[:0:0], t.events[over:]...)
+	}
+	return nil
+}
+
+// Since returns the events recorded at or after start for requestID,
+// oldest first. The tools stamp their events with the request they run
+// for, so events of other requests, and unattributed ones, are left
+// out: under serve or the operator many requests share one trail.
+func (t *AuditTrail) Since(start time.Time, requestID string) []AuditEvent {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var out []AuditEvent
+	for _, event := range t.events {
+		if event.Timestamp.Before(start) {
+			continue
+		}
+		if event.RequestID != requestID {
+			continue
+		}
+		out = append(out, event)
+	}
+	return out
+}
@@ -0,0 +1,39 @@
+package harness
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestAuditTrail_FiltersByTimeAndRequest(t *testing.T) {
+	trail := NewAuditTrail(0)
+	ctx := context.Background()
+	old := time.Now().Add(-time.Hour)
+	_ = trail.Record(ctx, AuditEvent{Timestamp: old, Action: "before"})
+
+	start := time.Now()
+	_ = trail.Record(ctx, AuditEvent{RequestID: "r1", Action: "mine"})
+	_ = trail.Record(ctx, AuditEvent{RequestID: "r2", Action: "theirs"})
+	_ = trail.Record(ctx, AuditEvent{Action: "tool"})
+
+	got := trail.Since(start, "r1")
+	if len(got) != 1 || got[0].Action != "mine" {
+		t.Fatalf("Expected only this request's events, got %+v", got)
+	}
+	if got[0].Timestamp.IsZero() {
+		t.Error("Expected Record to stamp the event")
+	}
+}
+
+func TestAuditTrail_DropsOldest(t *testing.T) {
+	trail := NewAuditTrail(3)
+	for i := 0; i < 5; i++ {
+		_ = trail.Record(context.Background(), AuditEvent{Action: fmt.Sprint(i)})
+	}
+	got := trail.Since(time.Time{}, "")
+	if len(got) != 3 || got[0].Action != "2" || got[2].Action != "4" {
+		t.Errorf("Expected the last three events, got %+v", got)
+	}
+}
@@ -83,14 +83,14 @@ func TestIsTerminalFailure(t *testing.T) {
 		"CreateContainerConfigError", "InvalidImageName",
 	}
 	for _, r := range terminal {
-		if !isTerminalFailure(r) {
+		if !IsTerminalFailure(r) {
 			t.Fatalf("%q should be terminal", r)
 		}
 	}
-	if isTerminalFailure("ContainerCreating") {
+	if IsTerminalFailure("ContainerCreating") {
 		t.Fatal("ContainerCreating is transient, not terminal")
 	}
-	if isTerminalFailure("") {
+	if IsTerminalFailure("") {
 		t.Fatal("empty reason is not terminal")
 	}
 }
@@ -2,6 +2,8 @@ package agent
 
 import (
 	"context"
+
+	"kubeagent/pkg/agent/harness"
 )
 
 // Agent is the core interface that all agents must implement
@@ -62,6 +64,16 @@ type Tool interface {
 	Execute(params map[string]interface{}) (string, error)
 }
 
+// ContextTool is a Tool that can wait on something outside the process,
+// such as a human approval. BaseAgent calls ExecuteContext instead of
+// Execute so the wait ends with the request, and tags the context with
+// harness.WithRequestScope so the tool knows which request and task it
+// is acting for.
+type ContextTool interface {
+	Tool
+	ExecuteContext(ctx context.Context, params map[string]interface{}) (string, error)
+}
+
 // ToolRegistry manages available tools
 type ToolRegistry interface {
 	// RegisterTool registers a new tool
@@ -99,6 +111,18 @@ type StateStore interface {
 
 	// UpdateTaskStatus updates a task's status
 	UpdateTaskStatus(ctx context.Context, taskID string, status TaskStatus) error
+
+	// SaveSnapshot stores a pre-change resource snapshot
+	SaveSnapshot(ctx context.Context, snapshot *ResourceSnapshot) error
+
+	// LoadSnapshots returns a request's snapshots in the order taken
+	LoadSnapshots(ctx context.Context, requestID string) ([]*ResourceSnapshot, error)
+
+	// SaveApproval stores an approval request and its outcome
+	SaveApproval(ctx context.Context, record *harness.ApprovalRecord) error
+
+	// LoadApprovals returns every stored approval, oldest first
+	LoadApprovals(ctx context.Context) ([]*harness.ApprovalRecord, error)
 }
 
 // LLMClient represents a client for interacting with LLM
@@ -114,22 +138,24 @@ type LLMClient interface {
 type Message struct {
 	Role       string     `json:"role"`
 	Content    string     `json:"content"`
-	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`    // assistant message with tool calls
+	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`   // assistant message with tool calls
 	ToolCallID string     `json:"tool_call_id,omitempty"` // tool result message
 	IsError    bool       `json:"is_error,omitempty"`     // tool result error flag
 }
 
 // LLMResponse represents a response from LLM
 type LLMResponse struct {
-	Content    string     `json:"content"`
-	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`
-	FinishReason string   `json:"finish_reason"`
+	Content      string     `json:"content"`
+	ToolCalls    []ToolCall `json:"tool_calls,omitempty"`
+	FinishReason string     `json:"finish_reason"`
+	// Usage is nil when the provider (or a test double) does not report it
+	Usage *TokenUsage `json:"usage,omitempty"`
 }
 
 // ToolCall represents a tool call from LLM
 type ToolCall struct {
-	ID       string                 `json:"id"`
-	Name     string                 `json:"name"`
+	ID        string                 `json:"id"`
+	Name      string                 `json:"name"`
 	Arguments map[string]interface{} `json:"arguments"`
 }
 
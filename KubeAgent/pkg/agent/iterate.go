@@ -0,0 +1,276 @@
+package agent
+
+import (
+	"fmt"
+	"strconv"
+
+	"kubeagent/pkg/agent/harness"
+)
+
+// DefaultRemediationIterations is how many times a remediation that
+// failed post-action verification is re-diagnosed and tried again.
+const DefaultRemediationIterations = 2
+
+// PreviousAttemptKey is the task input key under which the coordinator
+// tells a re-diagnosis and the remediation after it what the failed
+// attempt did and what verification observed.
+const PreviousAttemptKey = "previous_attempt"
+
+// WithRemediationIterations sets how many re-diagnose/remediate
+// iterations follow a remediation that failed verification. Zero turns
+// the loop off; negative values are treated as zero.
+func (c *BaseCoordinator) WithRemediationIterations(n int) *BaseCoordinator {
+	if n < 0 {
+		n = 0
+	}
+	c.iterations = n
+	return c
+}
+
+// WithAuditor installs the sink for the coordinator's decisions, such
+// as scheduling another iteration. Nil is tolerated and keeps the no-op
+// default.
+func (c *BaseCoordinator) WithAuditor(a harness.AuditLogger) *BaseCoordinator {
+	if a != nil {
+		c.auditor = a
+	}
+	return c
+}
+
+// iterateRemediation runs after a task failed. If it was a remediation
+// whose verification failed, a re-diagnosis seeded with the attempt and
+// a new remediation after it are added to the plan, and tasks that
+// waited on the failed remediation wait on the new one instead. It
+// returns the added tasks, none once the iteration cap is reached.
+//
+// The re-diagnosis only runs if the failed task failed, so a resumed
+// plan whose original remediation now succeeds skips the iteration.
+func (c *BaseCoordinator) iterateRemediation(ctx *AgentContext, plan *ExecutionPlan, failed *Task) []*Task {
+	if failed.Type != TaskTypeRemediate {
+		return nil
+	}
+	verification := failedVerification(failed)
+	if verification == nil {
+		return nil
+	}
+
+	previous, _ := failed.Input[PreviousAttemptKey].(map[string]interface{})
+	origin, iteration := failed.ID, 1
+	if previous != nil {
+		origin = fmt.Sprint(previous["origin"])
+		iteration = intValue(previous["iteration"]) + 1
+	}
+	diagnoseID := fmt.Sprintf("%s-rediagnose-%d", origin, iteration)
+	remediateID := fmt.Sprintf("%s-remediate-%d", origin, iteration)
+	if plan.Task(diagnoseID) != nil {
+		// Scheduled by the run this one resumes.
+		return nil
+	}
+
+	decision := map[string]interface{}{
+		"task":      failed.ID,
+		"iteration": iteration,
+		"max":       c.iterations,
+	}
+	if iteration > c.iterations {
+		c.auditDecision(ctx, failed, "re-diagnose", "exhausted",
+			fmt.Sprintf("remediation failed verification %d time(s); giving up", iteration), decision)
+		return nil
+	}
+	if _, err := c.GetAgent(AgentTypeDiagnostician); err != nil {
+		c.auditDecision(ctx, failed, "re-diagnose", "skipped", "no diagnostician to re-diagnose with", decision)
+		return nil
+	}
+
+	attempt := map[string]interface{}{
+		"origin":        origin,
+		"iteration":     iteration,
+		"task":          failed.ID,
+		"root_cause":    failed.Input["root_cause"],
+		"actions_taken": failed.Output["actions_taken"],
+		"verification":  verification,
+	}
+	if rollback, ok := failed.Output["rollback"].(map[string]interface{}); ok {
+		attempt["rollback"] = rollback["outcome"]
+	}
+
+	// Both tasks keep the failed attempt's target so the new
+	// remediation is verified against the same resource.
+	target := map[string]interface{}{PreviousAttemptKey: attempt}
+	for _, key := range []string{"pod_name", "namespace", "resource_kind", "resource_name", "expected_phase"} {
+		if v, ok := failed.Input[key]; ok {
+			target[key] = v
+		}
+	}
+	diagnoseInput := make(map[string]interface{}, len(target))
+	remediateInput := make(map[string]interface{}, len(target))
+	for key, v := range target {
+		diagnoseInput[key] = v
+		remediateInput[key] = v
+	}
+
+	remediator := failed.AssignedAgent
+	if remediator == "" {
+		remediator = AgentTypeRemediator
+	}
+	diagnose := &Task{
+		ID:            diagnoseID,
+		Type:          TaskTypeDiagnose,
+		Description:   fmt.Sprintf("Re-diagnose after remediation %s failed verification: %s", failed.ID, verification.Summary),
+		Status:        TaskStatusPending,
+		AssignedAgent: AgentTypeDiagnostician,
+		Input:         diagnoseInput,
+		Dependencies:  []string{failed.ID},
+		Condition:     &TaskCondition{OnFailure: []string{failed.ID}},
+		CreatedAt:     failed.CreatedAt,
+	}
+	remediate := &Task{
+		ID:            remediateID,
+		Type:          TaskTypeRemediate,
+		Description:   failed.Description,
+		Status:        TaskStatusPending,
+		AssignedAgent: remediator,
+		Input:         remediateInput,
+		Dependencies:  []string{diagnoseID},
+		Condition:     &TaskCondition{OnSuccess: []string{diagnoseID}},
+		CreatedAt:     failed.CreatedAt,
+	}
+
+	for _, task := range plan.Tasks {
+		retarget(task, failed.ID, remediateID)
+	}
+	plan.Tasks = append(plan.Tasks, diagnose, remediate)
+
+	decision["scheduled"] = []string{diagnoseID, remediateID}
+	c.auditDecision(ctx, failed, "re-diagnose", "scheduled",
+		"post-action verification failed: "+verification.Summary, decision)
+	c.logger.Info("Remediation failed verification; iterating", map[string]interface{}{
+		"task_id":   failed.ID,
+		"iteration": iteration,
+		"max":       c.iterations,
+	})
+	return []*Task{diagnose, remediate}
+}
+
+// failedVerification returns the verification result a remediation
+// recorded in its output, if verification failed.
+func failedVerification(task *Task) *harness.VerificationResult {
+	if v := taskVerification(task); v != nil && v.Status == harness.VerificationFailed {
+		return v
+	}
+	return nil
+}
+
+// taskVerification returns the verification result a remediation
+// recorded in its output, if any. A plan reloaded from the state store
+// holds it as a plain map.
+func taskVerification(task *Task) *harness.VerificationResult {
+	switch v := task.Output["verification"].(type) {
+	case *harness.VerificationResult:
+		return v
+	case map[string]interface{}:
+		status, _ := v["status"].(string)
+		summary, _ := v["summary"].(string)
+		observations, _ := v["observations"].(map[string]interface{})
+		return &harness.VerificationResult{Status: harness.VerificationStatus(status), Summary: summary, Observations: observations}
+	}
+	return nil
+}
+
+// retarget points task's dependencies, conditions and input references
+// at newID instead of oldID. Conditions and references only name
+// dependencies, so a task not depending on oldID is left alone; that
+// includes every task that is running.
+func retarget(task *Task, oldID, newID string) {
+	depends := false
+	for i, dep := range task.Dependencies {
+		if dep == oldID {
+			task.Dependencies[i] = newID
+			depends = true
+		}
+	}
+	if !depends {
+		return
+	}
+	if c := task.Condition; c != nil {
+		for _, ids := range [][]string{c.OnSuccess, c.OnFailure} {
+			for i, id := range ids {
+				if id == oldID {
+					ids[i] = newID
+				}
+			}
+		}
+	}
+	if input, ok := renameReferences(task.Input, oldID, newID).(map[string]interface{}); ok {
+		task.Input = input
+	}
+}
+
+// renameReferences returns v with {{tasks.oldID.output...}} references
+// pointing at newID. Like resolveValue it copies rather than modifies.
+func renameReferences(v interface{}, oldID, newID string) interface{} {
+	switch v := v.(type) {
+	case string:
+		return inputRefPattern.ReplaceAllStringFunc(v, func(ref string) string {
+			m := inputRefPattern.FindStringSubmatch(ref)
+			if m[1] != oldID {
+				return ref
+			}
+			return "{{tasks." + newID + ".output" + m[2] + "}}"
+		})
+	case map[string]interface{}:
+		if v == nil {
+			return v
+		}
+		out := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			out[key] = renameReferences(value, oldID, newID)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, value := range v {
+			out[i] = renameReferences(value, oldID, newID)
+		}
+		return out
+	}
+	return v
+}
+
+// auditDecision records a coordinator decision about task.
+func (c *BaseCoordinator) auditDecision(ctx *AgentContext, task *Task, action, outcome, reason string, details map[string]interface{}) {
+	event := harness.AuditEvent{
+		Kind:      harness.AuditDecision,
+		RequestID: ctx.RequestID,
+		TraceID:   ctx.TraceID,
+		Actor:     "coordinator",
+		Action:    action,
+		Outcome:   outcome,
+		Reason:    reason,
+		Details:   details,
+	}
+	if name, _ := task.Input["pod_name"].(string); name != "" {
+		namespace, _ := task.Input["namespace"].(string)
+		event.Target = harness.AuditTarget{Kind: "Pod", Name: name, Namespace: namespace}
+	}
+	if err := c.auditor.Record(ctx.Context(), event); err != nil {
+		c.logger.Warn("Failed to record audit event", map[string]interface{}{
+			"action": action,
+			"error":  err.Error(),
+		})
+	}
+}
+
+// intValue reads a count that may have gone through JSON.
+func intValue(v interface{}) int {
+	switch n := v.(type) {
+	case int:
+		return n
+	case float64:
+		return int(n)
+	case string:
+		i, _ := strconv.Atoi(n)
+		return i
+	}
+	return 0
+}
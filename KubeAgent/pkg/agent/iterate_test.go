@@ -0,0 +1,154 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"kubeagent/pkg/agent/harness"
+)
+
+type decisionRecorder struct {
+	mu     sync.Mutex
+	events []harness.AuditEvent
+}
+
+func (r *decisionRecorder) Record(_ context.Context, e harness.AuditEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, e)
+	return nil
+}
+
+// iterationCoordinator has a diagnostician that records the inputs it
+// sees and a remediator whose verification fails until fixesNeeded
+// remediations have run.
+func iterationCoordinator(fixesNeeded int) (*BaseCoordinator, *decisionRecorder, *[]*Task) {
+	var mu sync.Mutex
+	var ran []*Task
+	record := func(task *Task) {
+		mu.Lock()
+		defer mu.Unlock()
+		ran = append(ran, task)
+	}
+	fixes := 0
+
+	audit := &decisionRecorder{}
+	coordinator := NewCoordinator(nil, &MockLLMClient{}, nil, NewNoOpLogger()).
+		WithTaskRetryPolicy(noBackoff).
+		WithAuditor(audit)
+	coordinator.RegisterAgent(&MockSpecialistAgent{
+		name:      "diag",
+		agentType: AgentTypeDiagnostician,
+		executeFunc: func(ctx *AgentContext, task *Task) (*Task, error) {
+			record(task)
+			task.Output = map[string]interface{}{"root_cause": "probe points at the wrong port", "pod_name": "web-1", "namespace": "demo"}
+			return task, nil
+		},
+	})
+	coordinator.RegisterAgent(&MockSpecialistAgent{
+		name:      "fix",
+		agentType: AgentTypeRemediator,
+		config:    &AgentConfig{Name: "fix", Type: AgentTypeRemediator, MaxRetries: 3},
+		executeFunc: func(ctx *AgentContext, task *Task) (*Task, error) {
+			record(task)
+			fixes++
+			if fixes < fixesNeeded {
+				task.Output = map[string]interface{}{
+					"actions_taken": []string{"restarted web-1"},
+					"verification": &harness.VerificationResult{
+						Status:       harness.VerificationFailed,
+						Summary:      "web-1 still CrashLoopBackOff",
+						Observations: map[string]interface{}{"restarts": 7},
+					},
+				}
+				return task, errors.New("verification failed: web-1 still CrashLoopBackOff")
+			}
+			task.Output = map[string]interface{}{"actions_taken": []string{"fixed the probe port"}}
+			return task, nil
+		},
+	})
+	return coordinator, audit, &ran
+}
+
+func iterationPlan() *ExecutionPlan {
+	return &ExecutionPlan{ID: "plan", Tasks: []*Task{
+		{ID: "diagnose", Type: TaskTypeDiagnose, AssignedAgent: AgentTypeDiagnostician,
+			Input: map[string]interface{}{"pod_name": "web-1", "namespace": "demo"}},
+		{ID: "fix", Type: TaskTypeRemediate, AssignedAgent: AgentTypeRemediator, Dependencies: []string{"diagnose"},
+			Input: map[string]interface{}{"root_cause": "{{tasks.diagnose.output.root_cause}}", "pod_name": "web-1", "namespace": "demo"}},
+		{ID: "report", Type: TaskTypeQuery, AssignedAgent: AgentTypeDiagnostician, Dependencies: []string{"fix"},
+			Condition: &TaskCondition{OnSuccess: []string{"fix"}},
+			Input:     map[string]interface{}{"actions": "{{tasks.fix.output.actions_taken}}"}},
+	}}
+}
+
+func TestExecutePlan_RediagnosesAfterFailedVerification(t *testing.T) {
+	coordinator, audit, ran := iterationCoordinator(2)
+	plan := iterationPlan()
+	if _, err := coordinator.ExecutePlan(NewAgentContext(context.Background(), "req", "user", "trace"), plan); err != nil {
+		t.Fatalf("ExecutePlan failed: %v", err)
+	}
+
+	var order []string
+	for _, task := range *ran {
+		order = append(order, task.ID)
+	}
+	want := []string{"diagnose", "fix", "fix-rediagnose-1", "fix-remediate-1", "report"}
+	if len(order) != len(want) {
+		t.Fatalf("Expected %v to run, got %v", want, order)
+	}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("Expected %v to run, got %v", want, order)
+		}
+	}
+
+	fix := plan.Task("fix")
+	if fix.Status != TaskStatusFailed || len(fix.Attempts) != 1 {
+		t.Errorf("Expected one attempt at the failed fix, got %s with %d attempts", fix.Status, len(fix.Attempts))
+	}
+	rediagnose := plan.Task("fix-rediagnose-1")
+	previous, _ := rediagnose.Input[PreviousAttemptKey].(map[string]interface{})
+	if previous["root_cause"] != "probe points at the wrong port" || previous["verification"].(*harness.VerificationResult).Observations["restarts"] != 7 ||
+		rediagnose.Input["pod_name"] != "web-1" {
+		t.Errorf("Expected the re-diagnosis to be seeded with the failed attempt, got %+v", rediagnose.Input)
+	}
+
+	report := plan.Task("report")
+	if report.Status != TaskStatusCompleted || report.Dependencies[0] != "fix-remediate-1" {
+		t.Errorf("Expected the report to follow the second remediation, got %s after %v", report.Status, report.Dependencies)
+	}
+	if actions, _ := report.Input["actions"].([]string); len(actions) != 1 || actions[0] != "fixed the probe port" {
+		t.Errorf("Expected the report to read the second remediation's output, got %+v", report.Input["actions"])
+	}
+
+	if len(audit.events) != 1 || audit.events[0].Kind != harness.AuditDecision || audit.events[0].Outcome != "scheduled" ||
+		audit.events[0].Target.Name != "web-1" {
+		t.Errorf("Expected one scheduled decision, got %+v", audit.events)
+	}
+}
+
+func TestExecutePlan_StopsIteratingAtCap(t *testing.T) {
+	coordinator, audit, ran := iterationCoordinator(10)
+	coordinator.WithRemediationIterations(1)
+	plan := iterationPlan()
+	response, err := coordinator.ExecutePlan(NewAgentContext(context.Background(), "req", "user", "trace"), plan)
+	if err != nil {
+		t.Fatalf("ExecutePlan failed: %v", err)
+	}
+
+	if len(*ran) != 4 || len(plan.Tasks) != 5 {
+		t.Errorf("Expected one iteration, got %d runs and %d tasks", len(*ran), len(plan.Tasks))
+	}
+	if report := plan.Task("report"); report.Status != TaskStatusSkipped {
+		t.Errorf("Expected the report to be skipped, got %s", report.Status)
+	}
+	if len(response.Errors) != 2 {
+		t.Errorf("Expected both remediations in the errors, got %v", response.Errors)
+	}
+	if len(audit.events) != 2 || audit.events[1].Outcome != "exhausted" {
+		t.Errorf("Expected scheduled then exhausted decisions, got %+v", audit.events)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"net/http"
 	"os"
+	"strings"
 
 	"github.com/anthropics/anthropic-sdk-go"
 	"github.com/anthropics/anthropic-sdk-go/option"
@@ -22,7 +23,7 @@ type AnthropicLLMClient struct {
 // This is a workaround for the SDK bug where WithAuthToken stores the token
 // but doesn't actually add it to HTTP request headers.
 type authTransport struct {
-	base         http.RoundTripper
+	base          http.RoundTripper
 	authorization string
 }
 
@@ -31,16 +32,20 @@ func (t *authTransport) RoundTrip(req *http.Request) (*http.Response, error) {
 	req.Header.Set("Authorization", t.authorization)
 	return t.base.RoundTrip(req)
 }
+
 // NewAnthropicLLMClient creates a new Anthropic LLM client
 // If config is nil, auto-detects provider from environment variables:
 //   - ANTHROPIC_API_KEY → Anthropic (Claude)
 //   - MINIMAX_API_KEY   → MiniMax (via Anthropic-compatible API)
 func NewAnthropicLLMClient(config *LLMConfig) (*AnthropicLLMClient, error) {
 	if config == nil {
-		config = detectLLMConfig()
+		config = DetectLLMConfig()
 		if config == nil {
 			return nil, fmt.Errorf("no API key found: set ANTHROPIC_API_KEY or MINIMAX_API_KEY")
 		}
+		if !isAnthropicProvider(config.Provider) {
+			return nil, fmt.Errorf("detected provider %s is not Anthropic-compatible: use NewLLMClient", config.Provider)
+		}
 	}
 
 	opts := []option.RequestOption{}
@@ -53,7 +58,7 @@ func NewAnthropicLLMClient(config *LLMConfig) (*AnthropicLLMClient, error) {
 	// but don't actually add it to HTTP request headers - this is a workaround
 	httpClient := &http.Client{
 		Transport: &authTransport{
-			base:         http.DefaultTransport,
+			base:          http.DefaultTransport,
 			authorization: "Bearer " + config.APIKey,
 		},
 	}
@@ -67,66 +72,197 @@ func NewAnthropicLLMClient(config *LLMConfig) (*AnthropicLLMClient, error) {
 	}, nil
 }
 
-// detectLLMConfig auto-detects LLM provider from environment variables
-func detectLLMConfig() *LLMConfig {
-	if apiKey := os.Getenv("ANTHROPIC_API_KEY"); apiKey != "" {
-		return &LLMConfig{
-			Provider:    "anthropic",
-			Model:       "claude-sonnet-4-20250514",
-			APIKey:      apiKey,
-			Temperature: 0.7,
-			MaxTokens:   2000,
+// NewLLMClient creates the client for config.Provider:
+//   - "anthropic", "minimax" (or empty) → AnthropicLLMClient
+//   - "openai", "dashscope", "qwen", "vllm", "ollama" → OpenAIChatLLMClient
+//
+// A nil config is detected from the environment, see DetectLLMConfig.
+func NewLLMClient(config *LLMConfig) (LLMClient, error) {
+	if config == nil {
+		config = DetectLLMConfig()
+		if config == nil {
+			return nil, fmt.Errorf("no API key found: set ANTHROPIC_API_KEY, MINIMAX_API_KEY, DASHSCOPE_API_KEY or OPENAI_API_KEY")
 		}
 	}
 
-	if apiKey := os.Getenv("MINIMAX_API_KEY"); apiKey != "" {
-		return &LLMConfig{
-			Provider:    "minimax",
-			Model:       "MiniMax-M2.5",
-			APIKey:      apiKey,
-			BaseURL:     "https://api.minimaxi.com/anthropic",
-			Temperature: 0.7,
-			MaxTokens:   2000,
+	switch {
+	case isAnthropicProvider(config.Provider):
+		return NewAnthropicLLMClient(config)
+	case isOpenAIProvider(config.Provider):
+		return NewOpenAIChatLLMClient(config)
+	}
+	return nil, fmt.Errorf("unknown LLM provider %q", config.Provider)
+}
+
+func isAnthropicProvider(provider string) bool {
+	switch strings.ToLower(provider) {
+	case "", "anthropic", "minimax":
+		return true
+	}
+	return false
+}
+
+func isOpenAIProvider(provider string) bool {
+	switch strings.ToLower(provider) {
+	case "openai", "dashscope", "qwen", "vllm", "ollama":
+		return true
+	}
+	return false
+}
+
+// DetectLLMConfig auto-detects LLM provider from environment variables.
+// The first key found wins, in this order:
+//   - ANTHROPIC_API_KEY → Anthropic (Claude)
+//   - MINIMAX_API_KEY   → MiniMax (Anthropic-compatible API)
+//   - DASHSCOPE_API_KEY → DashScope / Qwen (OpenAI-compatible mode)
+//   - OPENAI_API_KEY    → OpenAI, or any compatible server when
+//     OPENAI_BASE_URL is set (vLLM, Ollama; OPENAI_MODEL picks the model)
+func DetectLLMConfig() *LLMConfig {
+	for _, provider := range []string{"anthropic", "minimax", "dashscope", "openai"} {
+		if config := LLMConfigFromEnv(provider); config != nil {
+			return config
 		}
 	}
+	return nil
+}
 
+// LLMConfigFromEnv returns provider's default config with the API key
+// from its environment variable, or nil when the key is not set. It is
+// how fallback providers for RouterLLMClient are named on the CLI.
+func LLMConfigFromEnv(provider string) *LLMConfig {
+	switch strings.ToLower(provider) {
+	case "anthropic":
+		if apiKey := os.Getenv("ANTHROPIC_API_KEY"); apiKey != "" {
+			return &LLMConfig{
+				Provider:    "anthropic",
+				Model:       "claude-sonnet-4-20250514",
+				APIKey:      apiKey,
+				Temperature: 0.7,
+				MaxTokens:   2000,
+			}
+		}
+	case "minimax":
+		if apiKey := os.Getenv("MINIMAX_API_KEY"); apiKey != "" {
+			return &LLMConfig{
+				Provider:    "minimax",
+				Model:       "MiniMax-M2.5",
+				APIKey:      apiKey,
+				BaseURL:     "https://api.minimaxi.com/anthropic",
+				Temperature: 0.7,
+				MaxTokens:   2000,
+			}
+		}
+	case "dashscope", "qwen":
+		if apiKey := os.Getenv("DASHSCOPE_API_KEY"); apiKey != "" {
+			return &LLMConfig{
+				Provider:    "dashscope",
+				Model:       envOr("DASHSCOPE_MODEL", "qwen-plus"),
+				APIKey:      apiKey,
+				BaseURL:     "https://dashscope.aliyuncs.com/compatible-mode/v1",
+				Temperature: 0.7,
+				MaxTokens:   2000,
+			}
+		}
+	case "openai":
+		if apiKey := os.Getenv("OPENAI_API_KEY"); apiKey != "" {
+			return &LLMConfig{
+				Provider:    "openai",
+				Model:       envOr("OPENAI_MODEL", "gpt-4o-mini"),
+				APIKey:      apiKey,
+				BaseURL:     envOr("OPENAI_BASE_URL", DefaultOpenAIBaseURL),
+				Temperature: 0.7,
+				MaxTokens:   2000,
+			}
+		}
+	}
 	return nil
 }
 
+func envOr(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
+
 // Complete sends a prompt and returns the completion
 func (c *AnthropicLLMClient) Complete(ctx context.Context, messages []Message) (string, error) {
-	systemBlocks, anthropicMessages := c.convertMessages(messages)
-
-	params := anthropic.MessageNewParams{
-		Model:     anthropic.Model(c.config.Model),
-		MaxTokens: int64(c.config.MaxTokens),
-		Messages:  anthropicMessages,
-	}
-	if len(systemBlocks) > 0 {
-		params.System = systemBlocks
-	}
-	if c.config.Temperature > 0 {
-		params.Temperature = anthropic.Float(float64(c.config.Temperature))
+	resp, err := c.CompleteWithUsage(ctx, messages)
+	if err != nil {
+		return "", err
 	}
 
-	resp, err := c.client.Messages.New(ctx, params)
+	return resp.Content, nil
+}
+
+// CompleteWithUsage implements UsageLLMClient: Complete, keeping the
+// token counts.
+func (c *AnthropicLLMClient) CompleteWithUsage(ctx context.Context, messages []Message) (*LLMResponse, error) {
+	resp, err := c.client.Messages.New(ctx, c.newParams(messages, nil))
 	if err != nil {
-		return "", fmt.Errorf("LLM API call failed: %w", err)
+		return nil, fmt.Errorf("LLM API call failed: %w", err)
 	}
 
-	return c.extractTextContent(resp), nil
+	return c.toLLMResponse(resp), nil
 }
 
 // CompleteWithTools sends a prompt with available tools
 func (c *AnthropicLLMClient) CompleteWithTools(ctx context.Context, messages []Message, tools []Tool) (*LLMResponse, error) {
+	resp, err := c.client.Messages.New(ctx, c.newParams(messages, tools))
+	if err != nil {
+		return nil, fmt.Errorf("LLM API call failed: %w", err)
+	}
+
+	return c.toLLMResponse(resp), nil
+}
+
+// StreamWithTools implements StreamingLLMClient. Text and thinking
+// deltas are forwarded as they arrive; tool-use input is only complete
+// at the end of its block, so tool calls are reported by the caller from
+// the accumulated response instead.
+func (c *AnthropicLLMClient) StreamWithTools(ctx context.Context, messages []Message, tools []Tool, onEvent StreamHandler) (*LLMResponse, error) {
+	stream := c.client.Messages.NewStreaming(ctx, c.newParams(messages, tools))
+	defer stream.Close()
+
+	message := anthropic.Message{}
+	for stream.Next() {
+		event := stream.Current()
+		if err := message.Accumulate(event); err != nil {
+			return nil, fmt.Errorf("LLM stream failed: %w", err)
+		}
+		if onEvent == nil {
+			continue
+		}
+		delta, ok := event.AsAny().(anthropic.ContentBlockDeltaEvent)
+		if !ok {
+			continue
+		}
+		switch d := delta.Delta.AsAny().(type) {
+		case anthropic.TextDelta:
+			onEvent(StreamEvent{Type: StreamTextDelta, Text: d.Text})
+		case anthropic.ThinkingDelta:
+			onEvent(StreamEvent{Type: StreamThinkingDelta, Text: d.Thinking})
+		}
+	}
+	if err := stream.Err(); err != nil {
+		return nil, fmt.Errorf("LLM API call failed: %w", err)
+	}
+
+	return c.toLLMResponse(&message), nil
+}
+
+// newParams builds the request shared by the blocking and streaming
+// calls. Tools are omitted when empty.
+func (c *AnthropicLLMClient) newParams(messages []Message, tools []Tool) anthropic.MessageNewParams {
 	systemBlocks, anthropicMessages := c.convertMessages(messages)
-	anthropicTools := c.convertTools(tools)
 
 	params := anthropic.MessageNewParams{
 		Model:     anthropic.Model(c.config.Model),
 		MaxTokens: int64(c.config.MaxTokens),
 		Messages:  anthropicMessages,
-		Tools:     anthropicTools,
+	}
+	if len(tools) > 0 {
+		params.Tools = c.convertTools(tools)
 	}
 	if len(systemBlocks) > 0 {
 		params.System = systemBlocks
@@ -134,15 +270,22 @@ func (c *AnthropicLLMClient) CompleteWithTools(ctx context.Context, messages []M
 	if c.config.Temperature > 0 {
 		params.Temperature = anthropic.Float(float64(c.config.Temperature))
 	}
+	return params
+}
 
-	resp, err := c.client.Messages.New(ctx, params)
-	if err != nil {
-		return nil, fmt.Errorf("LLM API call failed: %w", err)
-	}
-
+// toLLMResponse converts a complete Anthropic message, whether returned
+// in one piece or accumulated from a stream.
+func (c *AnthropicLLMClient) toLLMResponse(resp *anthropic.Message) *LLMResponse {
 	llmResponse := &LLMResponse{
 		Content:      c.extractTextContent(resp),
 		FinishReason: string(resp.StopReason),
+		Usage: &TokenUsage{
+			Model:                    string(resp.Model),
+			InputTokens:              resp.Usage.InputTokens,
+			OutputTokens:             resp.Usage.OutputTokens,
+			CacheCreationInputTokens: resp.Usage.CacheCreationInputTokens,
+			CacheReadInputTokens:     resp.Usage.CacheReadInputTokens,
+		},
 	}
 
 	// Extract tool calls from response
@@ -158,7 +301,7 @@ func (c *AnthropicLLMClient) CompleteWithTools(ctx context.Context, messages []M
 		}
 	}
 
-	return llmResponse, nil
+	return llmResponse
 }
 
 // convertMessages separates system messages and converts to Anthropic format
@@ -0,0 +1,274 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DefaultOpenAIBaseURL is used when LLMConfig.BaseURL is empty.
+const DefaultOpenAIBaseURL = "https://api.openai.com/v1"
+
+// OpenAIChatLLMClient implements LLMClient against the OpenAI
+// /v1/chat/completions tool-calling protocol. Most self-hosted and
+// non-Anthropic providers speak it: DashScope (Qwen) compatible mode,
+// vLLM, Ollama and OpenAI itself, so only BaseURL and Model differ.
+//
+// It talks plain HTTP rather than pulling in an SDK: the subset KubeAgent
+// needs (messages, function tools, usage) has been stable for years, and
+// each provider's SDK lags its own extensions anyway.
+type OpenAIChatLLMClient struct {
+	httpClient *http.Client
+	config     *LLMConfig
+}
+
+// NewOpenAIChatLLMClient creates a client for an OpenAI-compatible
+// endpoint. The API key may be empty for local servers (Ollama, vLLM)
+// that do not check it.
+func NewOpenAIChatLLMClient(config *LLMConfig) (*OpenAIChatLLMClient, error) {
+	if config == nil {
+		return nil, fmt.Errorf("LLM config is required")
+	}
+	if config.Model == "" {
+		return nil, fmt.Errorf("model is required for provider %s", config.Provider)
+	}
+	return &OpenAIChatLLMClient{
+		httpClient: &http.Client{Timeout: 5 * time.Minute},
+		config:     config,
+	}, nil
+}
+
+// Wire types for /chat/completions. Only the fields KubeAgent uses.
+type (
+	openAIMessage struct {
+		Role       string           `json:"role"`
+		Content    *string          `json:"content"`
+		ToolCalls  []openAIToolCall `json:"tool_calls,omitempty"`
+		ToolCallID string           `json:"tool_call_id,omitempty"`
+	}
+
+	openAIToolCall struct {
+		ID       string `json:"id"`
+		Type     string `json:"type"`
+		Function struct {
+			Name string `json:"name"`
+			// Arguments is a JSON document encoded as a string.
+			Arguments string `json:"arguments"`
+		} `json:"function"`
+	}
+
+	openAITool struct {
+		Type     string `json:"type"`
+		Function struct {
+			Name        string          `json:"name"`
+			Description string          `json:"description,omitempty"`
+			Parameters  json.RawMessage `json:"parameters"`
+		} `json:"function"`
+	}
+
+	openAIRequest struct {
+		Model       string          `json:"model"`
+		Messages    []openAIMessage `json:"messages"`
+		Tools       []openAITool    `json:"tools,omitempty"`
+		MaxTokens   int             `json:"max_tokens,omitempty"`
+		Temperature *float32        `json:"temperature,omitempty"`
+	}
+
+	openAIResponse struct {
+		Model   string `json:"model"`
+		Choices []struct {
+			Message      openAIMessage `json:"message"`
+			FinishReason string        `json:"finish_reason"`
+		} `json:"choices"`
+		Usage *struct {
+			PromptTokens        int64 `json:"prompt_tokens"`
+			CompletionTokens    int64 `json:"completion_tokens"`
+			PromptTokensDetails *struct {
+				CachedTokens int64 `json:"cached_tokens"`
+			} `json:"prompt_tokens_details,omitempty"`
+		} `json:"usage,omitempty"`
+	}
+)
+
+// LLMStatusError is a non-2xx reply from an HTTP LLM endpoint. The
+// router uses StatusCode to tell outages (5xx, 429) from caller errors.
+type LLMStatusError struct {
+	StatusCode int
+	Status     string
+	Body       string
+}
+
+func (e *LLMStatusError) Error() string {
+	return e.Status + ": " + e.Body
+}
+
+// Complete sends a prompt and returns the completion
+func (c *OpenAIChatLLMClient) Complete(ctx context.Context, messages []Message) (string, error) {
+	resp, err := c.CompleteWithUsage(ctx, messages)
+	if err != nil {
+		return "", err
+	}
+	return resp.Content, nil
+}
+
+// CompleteWithUsage implements UsageLLMClient.
+func (c *OpenAIChatLLMClient) CompleteWithUsage(ctx context.Context, messages []Message) (*LLMResponse, error) {
+	return c.CompleteWithTools(ctx, messages, nil)
+}
+
+// CompleteWithTools sends a prompt with available tools
+func (c *OpenAIChatLLMClient) CompleteWithTools(ctx context.Context, messages []Message, tools []Tool) (*LLMResponse, error) {
+	request := openAIRequest{
+		Model:     c.config.Model,
+		Messages:  c.convertMessages(messages),
+		Tools:     c.convertTools(tools),
+		MaxTokens: c.config.MaxTokens,
+	}
+	if c.config.Temperature > 0 {
+		request.Temperature = &c.config.Temperature
+	}
+
+	resp, err := c.post(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Choices) == 0 {
+		return nil, fmt.Errorf("LLM API call failed: response has no choices")
+	}
+
+	choice := resp.Choices[0]
+	llmResponse := &LLMResponse{FinishReason: choice.FinishReason}
+	if choice.Message.Content != nil {
+		llmResponse.Content = *choice.Message.Content
+	}
+	for _, tc := range choice.Message.ToolCalls {
+		args := map[string]interface{}{}
+		if strings.TrimSpace(tc.Function.Arguments) != "" {
+			if err := json.Unmarshal([]byte(tc.Function.Arguments), &args); err != nil {
+				return nil, fmt.Errorf("LLM returned invalid arguments for %s: %w", tc.Function.Name, err)
+			}
+		}
+		llmResponse.ToolCalls = append(llmResponse.ToolCalls, ToolCall{
+			ID:        tc.ID,
+			Name:      tc.Function.Name,
+			Arguments: args,
+		})
+	}
+	if resp.Usage != nil {
+		usage := &TokenUsage{
+			Model:        resp.Model,
+			InputTokens:  resp.Usage.PromptTokens,
+			OutputTokens: resp.Usage.CompletionTokens,
+		}
+		// prompt_tokens includes the cached ones; split them out so
+		// they are priced at the cache-read rate.
+		if details := resp.Usage.PromptTokensDetails; details != nil {
+			usage.CacheReadInputTokens = details.CachedTokens
+			usage.InputTokens -= details.CachedTokens
+		}
+		llmResponse.Usage = usage
+	}
+
+	return llmResponse, nil
+}
+
+func (c *OpenAIChatLLMClient) post(ctx context.Context, request openAIRequest) (*openAIResponse, error) {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode LLM request: %w", err)
+	}
+
+	baseURL := c.config.BaseURL
+	if baseURL == "" {
+		baseURL = DefaultOpenAIBaseURL
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(baseURL, "/")+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("LLM API call failed: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.config.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.config.APIKey)
+	}
+
+	httpResp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("LLM API call failed: %w", err)
+	}
+	defer httpResp.Body.Close()
+
+	data, err := io.ReadAll(httpResp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("LLM API call failed: reading response: %w", err)
+	}
+	if httpResp.StatusCode/100 != 2 {
+		return nil, fmt.Errorf("LLM API call failed: %w", &LLMStatusError{
+			StatusCode: httpResp.StatusCode,
+			Status:     httpResp.Status,
+			Body:       truncateForStream(string(data)),
+		})
+	}
+
+	resp := &openAIResponse{}
+	if err := json.Unmarshal(data, resp); err != nil {
+		return nil, fmt.Errorf("LLM API call failed: decoding response: %w", err)
+	}
+	return resp, nil
+}
+
+// convertMessages maps the agent conversation onto chat messages. The
+// protocols line up one to one: unlike Anthropic, tool results stay
+// separate "tool" messages and system prompts stay in the list.
+func (c *OpenAIChatLLMClient) convertMessages(messages []Message) []openAIMessage {
+	result := make([]openAIMessage, 0, len(messages))
+	for _, msg := range messages {
+		content := msg.Content
+		converted := openAIMessage{Role: msg.Role, Content: &content}
+
+		switch msg.Role {
+		case "assistant":
+			// Some servers reject an empty string next to tool_calls;
+			// null is what the protocol specifies.
+			if content == "" && len(msg.ToolCalls) > 0 {
+				converted.Content = nil
+			}
+			for _, tc := range msg.ToolCalls {
+				args, _ := json.Marshal(tc.Arguments)
+				call := openAIToolCall{ID: tc.ID, Type: "function"}
+				call.Function.Name = tc.Name
+				call.Function.Arguments = string(args)
+				converted.ToolCalls = append(converted.ToolCalls, call)
+			}
+		case "tool":
+			// The protocol has no error flag; RunToolLoop already
+			// prefixes failures with "Error:", which models understand.
+			converted.ToolCallID = msg.ToolCallID
+		}
+		result = append(result, converted)
+	}
+	return result
+}
+
+// convertTools passes each tool's JSON schema through unchanged.
+func (c *OpenAIChatLLMClient) convertTools(tools []Tool) []openAITool {
+	if len(tools) == 0 {
+		return nil
+	}
+	result := make([]openAITool, len(tools))
+	for i, tool := range tools {
+		result[i].Type = "function"
+		result[i].Function.Name = tool.Name()
+		result[i].Function.Description = tool.Description()
+		schema := json.RawMessage(tool.ArgsSchema())
+		if !json.Valid(schema) {
+			schema = json.RawMessage(`{"type":"object","properties":{}}`)
+		}
+		result[i].Function.Parameters = schema
+	}
+	return result
+}
@@ -0,0 +1,180 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeChatServer is a scripted /chat/completions endpoint. reply gets
+// the decoded request and returns the assistant message and finish
+// reason; every request is kept for assertions.
+type fakeChatServer struct {
+	mu       sync.Mutex
+	requests []openAIRequest
+	reply    func(req openAIRequest) (openAIMessage, string)
+}
+
+func (f *fakeChatServer) start(t *testing.T) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" {
+			t.Errorf("Unexpected path %s", r.URL.Path)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer test-key" {
+			t.Errorf("Unexpected Authorization header %q", got)
+		}
+		var req openAIRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("Bad request body: %v", err)
+		}
+		f.mu.Lock()
+		f.requests = append(f.requests, req)
+		f.mu.Unlock()
+
+		message, finish := f.reply(req)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"model": "qwen-plus",
+			"choices": []map[string]interface{}{
+				{"message": message, "finish_reason": finish},
+			},
+			"usage": map[string]interface{}{
+				"prompt_tokens":         120,
+				"completion_tokens":     30,
+				"prompt_tokens_details": map[string]interface{}{"cached_tokens": 20},
+			},
+		})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newTestOpenAIClient(t *testing.T, server *httptest.Server) *OpenAIChatLLMClient {
+	client, err := NewLLMClient(&LLMConfig{Provider: "dashscope", Model: "qwen-plus", APIKey: "test-key", BaseURL: server.URL + "/v1/"})
+	if err != nil {
+		t.Fatalf("NewLLMClient failed: %v", err)
+	}
+	openai, ok := client.(*OpenAIChatLLMClient)
+	if !ok {
+		t.Fatalf("Expected OpenAIChatLLMClient for dashscope, got %T", client)
+	}
+	return openai
+}
+
+func text(s string) *string { return &s }
+
+type podLogTool struct{}
+
+func (podLogTool) Name() string        { return "LogTool" }
+func (podLogTool) Description() string { return "读取 Pod 日志" }
+func (podLogTool) ArgsSchema() string {
+	return `{"type":"object","properties":{"pod":{"type":"string"}},"required":["pod"]}`
+}
+func (podLogTool) Execute(params map[string]interface{}) (string, error) {
+	return "OOMKilled: " + params["pod"].(string), nil
+}
+
+func TestOpenAIChatLLMClient_ToolRoundTrip(t *testing.T) {
+	fake := &fakeChatServer{reply: func(req openAIRequest) (openAIMessage, string) {
+		if req.Messages[len(req.Messages)-1].Role != "tool" {
+			call := openAIToolCall{ID: "call_1", Type: "function"}
+			call.Function.Name = "LogTool"
+			call.Function.Arguments = `{"pod":"web-1"}`
+			return openAIMessage{Role: "assistant", ToolCalls: []openAIToolCall{call}}, "tool_calls"
+		}
+		return openAIMessage{Role: "assistant", Content: text("web-1 ran out of memory")}, "stop"
+	}}
+	client := newTestOpenAIClient(t, fake.start(t))
+
+	b := NewBaseAgent(&AgentConfig{Name: "diag", Type: AgentTypeDiagnostician}, client, NewNoOpLogger())
+	b.AddTool(podLogTool{})
+	ledger := NewUsageLedger(nil)
+	ctx := NewAgentContext(context.Background(), "req", "user", "trace")
+	ctx.SetUsageLedger(ledger)
+
+	out, err := b.RunToolLoop(ctx, "system", "why does web-1 restart?", 5)
+	if err != nil || out != "web-1 ran out of memory" {
+		t.Fatalf("Unexpected result %q (err %v)", out, err)
+	}
+
+	if len(fake.requests) != 2 {
+		t.Fatalf("Expected 2 requests, got %d", len(fake.requests))
+	}
+	first := fake.requests[0]
+	if len(first.Tools) != 1 || first.Tools[0].Function.Name != "LogTool" || !strings.Contains(string(first.Tools[0].Function.Parameters), `"required":["pod"]`) {
+		t.Errorf("Tool schema not passed through: %+v", first.Tools)
+	}
+	if first.Messages[0].Role != "system" {
+		t.Errorf("Expected system prompt to stay in the message list, got %+v", first.Messages[0])
+	}
+
+	second := fake.requests[1].Messages
+	assistant, tool := second[len(second)-2], second[len(second)-1]
+	if assistant.Content != nil || len(assistant.ToolCalls) != 1 || assistant.ToolCalls[0].Function.Arguments != `{"pod":"web-1"}` {
+		t.Errorf("Assistant tool call not round-tripped: %+v", assistant)
+	}
+	if tool.ToolCallID != "call_1" || tool.Content == nil || *tool.Content != "OOMKilled: web-1" {
+		t.Errorf("Tool result not round-tripped: %+v", tool)
+	}
+
+	usage := ledger.Report().Total
+	if usage.Calls != 2 || usage.InputTokens != 200 || usage.CacheReadInputTokens != 40 || usage.OutputTokens != 60 {
+		t.Errorf("Unexpected usage %+v", usage)
+	}
+}
+
+// TestOpenAIChatLLMClient_Coordinator runs the coordinator's planning,
+// execution and summary calls through the OpenAI wire protocol.
+func TestOpenAIChatLLMClient_Coordinator(t *testing.T) {
+	fake := &fakeChatServer{reply: func(req openAIRequest) (openAIMessage, string) {
+		lastMsg := *req.Messages[len(req.Messages)-1].Content
+		switch {
+		case contains(lastMsg, "intent"):
+			return openAIMessage{Role: "assistant", Content: text("diagnose")}, "stop"
+		case contains(lastMsg, "Break down"):
+			return openAIMessage{Role: "assistant", Content: text(`[{"id": "t1", "type": "diagnose", "description": "Test diagnosis", "assigned_agent": "diagnostician", "input": {}}]`)}, "stop"
+		}
+		return openAIMessage{Role: "assistant", Content: text("Test completed successfully")}, "stop"
+	}}
+	client := newTestOpenAIClient(t, fake.start(t))
+
+	coordinator := NewCoordinator(nil, client, NewMemoryStateStore(), NewNoOpLogger())
+	coordinator.RegisterAgent(&MockSpecialistAgent{
+		name:          "mock-diagnostician",
+		agentType:     AgentTypeDiagnostician,
+		canHandleFunc: func(taskType TaskType) bool { return taskType == TaskTypeDiagnose },
+	})
+
+	ctx := NewAgentContext(context.Background(), "req", "user", "trace")
+	plan, err := coordinator.Plan(ctx, &Request{ID: "req", User: "user", Input: "web-1 keeps restarting"})
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+	if len(plan.Tasks) != 1 || plan.Tasks[0].Type != TaskTypeDiagnose {
+		t.Fatalf("Unexpected plan: %+v", plan.Tasks)
+	}
+
+	response, err := coordinator.ExecutePlan(ctx, plan)
+	if err != nil {
+		t.Fatalf("ExecutePlan failed: %v", err)
+	}
+	if response.Result != "Test completed successfully" {
+		t.Errorf("Unexpected summary %q", response.Result)
+	}
+}
+
+func TestOpenAIChatLLMClient_HTTPError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error":{"message":"Invalid API-key provided."}}`, http.StatusUnauthorized)
+	}))
+	defer server.Close()
+	client := newTestOpenAIClient(t, server)
+
+	_, err := client.Complete(context.Background(), []Message{{Role: "user", Content: "hi"}})
+	if err == nil || !strings.Contains(err.Error(), "401") || !strings.Contains(err.Error(), "Invalid API-key") {
+		t.Errorf("Expected status and body in error, got %v", err)
+	}
+}
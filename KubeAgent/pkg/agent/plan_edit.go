@@ -0,0 +1,73 @@
+package agent
+
+import "fmt"
+
+// Task returns the plan's task with id, or nil.
+func (p *ExecutionPlan) Task(id string) *Task {
+	for _, task := range p.Tasks {
+		if task.ID == id {
+			return task
+		}
+	}
+	return nil
+}
+
+// DropTask removes the task with id and every task that depends on it,
+// directly or transitively: a remediation whose diagnosis was dropped
+// would otherwise run without its input. Conditions only name
+// dependencies, so they never refer to a removed task afterwards. It
+// returns the removed IDs, id first.
+func (p *ExecutionPlan) DropTask(id string) ([]string, error) {
+	if p.Task(id) == nil {
+		return nil, fmt.Errorf("plan has no task %q", id)
+	}
+
+	dropped := map[string]bool{id: true}
+	removed := []string{id}
+	for changed := true; changed; {
+		changed = false
+		for _, task := range p.Tasks {
+			if dropped[task.ID] {
+				continue
+			}
+			for _, dep := range task.Dependencies {
+				if dropped[dep] {
+					dropped[task.ID] = true
+					removed = append(removed, task.ID)
+					changed = true
+					break
+				}
+			}
+		}
+	}
+
+	kept := p.Tasks[:0]
+	for _, task := range p.Tasks {
+		if !dropped[task.ID] {
+			kept = append(kept, task)
+		}
+	}
+	p.Tasks = kept
+	return removed, nil
+}
+
+// DropRemediation removes the plan's remediate tasks and their
+// dependents, for requests that must not change the cluster however the
+// planner read them. It returns the removed IDs.
+func (p *ExecutionPlan) DropRemediation() []string {
+	var ids []string
+	for _, task := range p.Tasks {
+		if task.Type == TaskTypeRemediate {
+			ids = append(ids, task.ID)
+		}
+	}
+	var removed []string
+	for _, id := range ids {
+		// An earlier drop may already have taken it as a dependent.
+		if p.Task(id) != nil {
+			dropped, _ := p.DropTask(id)
+			removed = append(removed, dropped...)
+		}
+	}
+	return removed
+}
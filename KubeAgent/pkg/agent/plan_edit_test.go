@@ -0,0 +1,30 @@
+package agent
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExecutionPlan_DropTaskCascades(t *testing.T) {
+	plan := &ExecutionPlan{Tasks: []*Task{
+		{ID: "diagnose"},
+		{ID: "audit"},
+		{ID: "fix", Dependencies: []string{"diagnose"}, Condition: &TaskCondition{OnSuccess: []string{"diagnose"}}},
+		{ID: "verify", Dependencies: []string{"fix", "audit"}},
+	}}
+
+	removed, err := plan.DropTask("diagnose")
+	if err != nil {
+		t.Fatalf("DropTask failed: %v", err)
+	}
+	if !reflect.DeepEqual(removed, []string{"diagnose", "fix", "verify"}) {
+		t.Errorf("Expected dependents to be dropped too, got %v", removed)
+	}
+	if len(plan.Tasks) != 1 || plan.Task("audit") == nil {
+		t.Errorf("Expected only audit to remain, got %+v", plan.Tasks)
+	}
+
+	if _, err := plan.DropTask("diagnose"); err == nil {
+		t.Error("Expected an error for a task that is not in the plan")
+	}
+}
@@ -0,0 +1,161 @@
+package agent
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"kubeagent/pkg/agent/harness"
+)
+
+// FinalReportKey is the Response.Data key holding the FinalReport.
+const FinalReportKey = "final_report"
+
+// FinalReport is the machine-readable account of a plan run: what was
+// asked, what every task ended as and why, and what the audit trail
+// recorded along the way. It is built from the plan alone, so the same
+// run always yields the same report; the LLM only turns it into prose.
+type FinalReport struct {
+	Request string             `json:"request"`
+	Status  TaskStatus         `json:"status"`
+	Counts  map[TaskStatus]int `json:"counts"`
+	Tasks   []TaskReport       `json:"tasks"`
+
+	// Audit holds the notable events recorded while the plan ran:
+	// writes, verifications, decisions, and preflight checks that did
+	// not simply allow. Empty unless the coordinator has an AuditTrail.
+	Audit []harness.AuditEvent `json:"audit,omitempty"`
+}
+
+// TaskReport is one task's line in the FinalReport.
+type TaskReport struct {
+	ID           string                      `json:"id"`
+	Type         TaskType                    `json:"type"`
+	Description  string                      `json:"description"`
+	Agent        AgentType                   `json:"agent,omitempty"`
+	Status       TaskStatus                  `json:"status"`
+	Attempts     int                         `json:"attempts,omitempty"`
+	Error        string                      `json:"error,omitempty"`
+	SkipReason   string                      `json:"skip_reason,omitempty"`
+	Output       map[string]interface{}      `json:"output,omitempty"`
+	Verification *harness.VerificationResult `json:"verification,omitempty"`
+	Rollback     interface{}                 `json:"rollback,omitempty"`
+
+	// RetriedBy names the remediation iterateRemediation scheduled in
+	// place of this one after it failed verification.
+	RetriedBy string `json:"retried_by,omitempty"`
+}
+
+// WithAuditTrail lets the final report quote the audit events recorded
+// while a plan runs. The trail must also be one of the sinks the tools
+// and agents record to. Nil is tolerated and leaves the report without
+// audit highlights.
+func (c *BaseCoordinator) WithAuditTrail(t *harness.AuditTrail) *BaseCoordinator {
+	c.trail = t
+	return c
+}
+
+// buildReport assembles the FinalReport for plan, whose run started at
+// started.
+func (c *BaseCoordinator) buildReport(ctx *AgentContext, plan *ExecutionPlan, started time.Time) *FinalReport {
+	report := &FinalReport{
+		Request: planRequestInput(plan),
+		Counts:  map[TaskStatus]int{},
+	}
+
+	retriedBy := map[string]string{}
+	for _, task := range plan.Tasks {
+		if previous, ok := task.Input[PreviousAttemptKey].(map[string]interface{}); ok && task.Type == TaskTypeRemediate {
+			retriedBy[fmt.Sprint(previous["task"])] = task.ID
+		}
+	}
+
+	status := TaskStatusCompleted
+	for _, task := range plan.Tasks {
+		entry := TaskReport{
+			ID:           task.ID,
+			Type:         task.Type,
+			Description:  task.Description,
+			Agent:        task.AssignedAgent,
+			Status:       task.Status,
+			Attempts:     len(task.Attempts),
+			Verification: taskVerification(task),
+			RetriedBy:    retriedBy[task.ID],
+		}
+		if n := len(task.Attempts); n > 0 {
+			entry.Agent = task.Attempts[n-1].Agent
+		}
+		if rollback, ok := task.Output["rollback"].(map[string]interface{}); ok {
+			entry.Rollback = rollback["outcome"]
+		}
+		switch task.Status {
+		case TaskStatusCompleted:
+			entry.Output = task.Output
+		case TaskStatusSkipped:
+			entry.SkipReason = task.Error
+		case TaskStatusFailed:
+			entry.Error = task.Error
+			if entry.RetriedBy == "" && status != TaskStatusCancelled {
+				status = TaskStatusFailed
+			}
+		case TaskStatusCancelled:
+			entry.Error = task.Error
+			status = TaskStatusCancelled
+		default:
+			entry.Error = task.Error
+			if status == TaskStatusCompleted {
+				status = TaskStatusPending
+			}
+		}
+		report.Counts[task.Status]++
+		report.Tasks = append(report.Tasks, entry)
+	}
+	report.Status = status
+
+	if c.trail != nil {
+		report.Audit = auditHighlights(c.trail.Since(started, ctx.RequestID))
+	}
+	return report
+}
+
+// Summary is the report in one line, for when the LLM summary fails.
+func (r *FinalReport) Summary() string {
+	parts := []string{}
+	for _, status := range []TaskStatus{TaskStatusCompleted, TaskStatusFailed, TaskStatusSkipped, TaskStatusCancelled} {
+		if n := r.Counts[status]; n > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", n, status))
+		}
+	}
+	summary := fmt.Sprintf("Executed %d task(s): %s.", len(r.Tasks), strings.Join(parts, ", "))
+	for _, task := range r.Tasks {
+		if task.Status == TaskStatusFailed && task.RetriedBy == "" {
+			summary += fmt.Sprintf(" Task %s failed: %s.", task.ID, task.Error)
+		}
+	}
+	return summary + " See the final_report data field for details."
+}
+
+// planRequestInput is what the user asked, as Plan recorded it. Plans
+// made before the request was recorded only have its ID.
+func planRequestInput(plan *ExecutionPlan) string {
+	if input, _ := plan.Metadata["request"].(string); input != "" {
+		return input
+	}
+	return plan.RequestID
+}
+
+// auditHighlights drops the routine events: previews and preflight
+// checks that allowed without warnings.
+func auditHighlights(events []harness.AuditEvent) []harness.AuditEvent {
+	var out []harness.AuditEvent
+	for _, event := range events {
+		if event.Outcome == "previewed" {
+			continue
+		}
+		if event.Kind == harness.AuditPreflight && event.Outcome == string(harness.PreflightAllow) && event.Details["warnings"] == nil {
+			continue
+		}
+		out = append(out, event)
+	}
+	return out
+}
@@ -0,0 +1,91 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"kubeagent/pkg/agent/harness"
+)
+
+func TestExecutePlan_ReportsRequestAndFailures(t *testing.T) {
+	var prompt string
+	llm := &MockLLMClient{CompleteFunc: func(ctx context.Context, messages []Message) (string, error) {
+		prompt = messages[len(messages)-1].Content
+		return "web-1 was diagnosed but the fix failed", nil
+	}}
+	trail := harness.NewAuditTrail(0)
+	coordinator := NewCoordinator(nil, llm, nil, NewNoOpLogger()).
+		WithTaskRetryPolicy(noBackoff).
+		WithAuditTrail(trail)
+	coordinator.RegisterAgent(&MockSpecialistAgent{name: "diag", agentType: AgentTypeDiagnostician})
+	coordinator.RegisterAgent(&MockSpecialistAgent{
+		name:      "fix",
+		agentType: AgentTypeRemediator,
+		executeFunc: func(ctx *AgentContext, task *Task) (*Task, error) {
+			target := harness.AuditTarget{Kind: "Pod", Name: "web-1", Namespace: "demo"}
+			trail.Record(ctx.Context(), harness.AuditEvent{Kind: harness.AuditPreflight, RequestID: ctx.RequestID, Actor: "PatchTool", Action: "patch", Outcome: "allow", Target: target})
+			trail.Record(ctx.Context(), harness.AuditEvent{Kind: harness.AuditPreflight, RequestID: ctx.RequestID, Actor: "DeleteTool", Action: "delete", Outcome: "block", Reason: "protected namespace", Target: target})
+			return task, errors.New("delete blocked by preflight")
+		},
+	})
+
+	plan := &ExecutionPlan{ID: "plan", RequestID: "req-uuid", Metadata: map[string]interface{}{"request": "web-1 keeps restarting"}, Tasks: []*Task{
+		{ID: "diagnose", Type: TaskTypeDiagnose, AssignedAgent: AgentTypeDiagnostician},
+		{ID: "fix", Type: TaskTypeRemediate, AssignedAgent: AgentTypeRemediator, Dependencies: []string{"diagnose"}},
+		{ID: "report", Type: TaskTypeQuery, AssignedAgent: AgentTypeDiagnostician, Dependencies: []string{"fix"},
+			Condition: &TaskCondition{OnSuccess: []string{"fix"}}},
+	}}
+	response, err := coordinator.ExecutePlan(NewAgentContext(context.Background(), "req-uuid", "user", "trace"), plan)
+	if err != nil {
+		t.Fatalf("ExecutePlan failed: %v", err)
+	}
+
+	if !contains(prompt, "Original Request: web-1 keeps restarting") || !contains(prompt, "delete blocked by preflight") || !contains(prompt, "protected namespace") {
+		t.Errorf("Expected the summary prompt to carry the request, the failure and the block, got:\n%s", prompt)
+	}
+
+	report, ok := response.Data[FinalReportKey].(*FinalReport)
+	if !ok {
+		t.Fatalf("Expected a FinalReport in the response data, got %+v", response.Data)
+	}
+	if report.Request != "web-1 keeps restarting" || report.Status != TaskStatusFailed {
+		t.Errorf("Expected a failed report for the request, got %q %s", report.Request, report.Status)
+	}
+	if report.Counts[TaskStatusCompleted] != 1 || report.Counts[TaskStatusFailed] != 1 || report.Counts[TaskStatusSkipped] != 1 {
+		t.Errorf("Unexpected counts %v", report.Counts)
+	}
+	if fix := report.Tasks[1]; fix.Error != "delete blocked by preflight" || fix.Agent != AgentTypeRemediator || fix.Attempts != 1 {
+		t.Errorf("Expected the fix's failure in the report, got %+v", fix)
+	}
+	if skipped := report.Tasks[2]; skipped.SkipReason == "" || skipped.Error != "" {
+		t.Errorf("Expected the report task's skip reason, got %+v", skipped)
+	}
+	if len(report.Audit) != 1 || report.Audit[0].Outcome != "block" {
+		t.Errorf("Expected only the blocked preflight among the highlights, got %+v", report.Audit)
+	}
+}
+
+func TestExecutePlan_ReportFallsBackWithoutLLM(t *testing.T) {
+	coordinator, _, _ := iterationCoordinator(2)
+	coordinator.llmClient = &MockLLMClient{CompleteFunc: func(ctx context.Context, messages []Message) (string, error) {
+		return "", errors.New("LLM unavailable")
+	}}
+	plan := iterationPlan()
+	response, err := coordinator.ExecutePlan(NewAgentContext(context.Background(), "req", "user", "trace"), plan)
+	if err != nil {
+		t.Fatalf("ExecutePlan failed: %v", err)
+	}
+
+	report := response.Data[FinalReportKey].(*FinalReport)
+	if report.Status != TaskStatusCompleted {
+		t.Errorf("Expected the iteration to recover the run, got %s", report.Status)
+	}
+	fix := report.Tasks[1]
+	if fix.RetriedBy != "fix-remediate-1" || fix.Verification == nil || fix.Verification.Status != harness.VerificationFailed {
+		t.Errorf("Expected the failed fix to point at its retry and keep its verification, got %+v", fix)
+	}
+	if want := "Executed 5 task(s): 4 completed, 1 failed. See the final_report data field for details."; response.Result != want {
+		t.Errorf("Expected %q, got %q", want, response.Result)
+	}
+}
@@ -0,0 +1,105 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"kubeagent/pkg/agent/harness"
+)
+
+// AgentTypeReviewer is the AgentType reviewers' LLM calls are
+// attributed to; reviewers are not agents the coordinator dispatches.
+const AgentTypeReviewer AgentType = "reviewer"
+
+// LLMReviewer is a harness.Reviewer that asks an LLM to approve or
+// reject a proposed write. Give each reviewer in a ConsensusCheck a
+// different model (llmConfig, served by a RouterLLMClient) or a
+// different prompt so their mistakes are independent.
+type LLMReviewer struct {
+	name   string
+	client LLMClient
+	prompt string
+}
+
+// NewLLMReviewer builds a reviewer called name. llmConfig picks its
+// provider and model when client is a RouterLLMClient; nil uses the
+// client's default. The prompt defaults to fallbackReviewPrompt.
+func NewLLMReviewer(name string, client LLMClient, llmConfig *LLMConfig) *LLMReviewer {
+	config := &AgentConfig{Name: name, Type: AgentTypeReviewer, LLMConfig: llmConfig}
+	return &LLMReviewer{
+		name:   name,
+		client: scopeToAgent(client, config),
+		prompt: fallbackReviewPrompt,
+	}
+}
+
+// WithPrompt replaces the system prompt, e.g. with a skill body. An
+// empty prompt keeps the current one.
+func (r *LLMReviewer) WithPrompt(prompt string) *LLMReviewer {
+	if prompt != "" {
+		r.prompt = prompt
+	}
+	return r
+}
+
+// Name implements harness.Reviewer.
+func (r *LLMReviewer) Name() string { return r.name }
+
+// Review implements harness.Reviewer. A reply that is not the expected
+// JSON is an error, which ConsensusCheck counts as not approving.
+func (r *LLMReviewer) Review(ctx context.Context, req harness.PreflightRequest) (harness.Vote, error) {
+	action := map[string]interface{}{
+		"verb":      req.Verb,
+		"kind":      req.ResourceKind,
+		"name":      req.ResourceName,
+		"namespace": req.Namespace,
+	}
+	for key, value := range req.Metadata {
+		action[key] = value
+	}
+	proposed, _ := json.MarshalIndent(action, "", "  ")
+
+	// A review runs inside a write tool's call; its tokens count
+	// against the request that asked for the write.
+	ac := agentContextFrom(ctx)
+	if ac != nil {
+		if err := ac.checkBudget(); err != nil {
+			return harness.Vote{}, fmt.Errorf("review failed: %w", err)
+		}
+	}
+	resp, err := completeText(ctx, r.client, []Message{
+		{Role: "system", Content: r.prompt},
+		{Role: "user", Content: "Proposed action:\n" + string(proposed)},
+	})
+	if err != nil {
+		return harness.Vote{}, fmt.Errorf("review failed: %w", err)
+	}
+	if ac != nil {
+		_ = ac.recordUsage(AgentTypeReviewer, resp.Usage)
+	}
+
+	reply := strings.TrimSpace(resp.Content)
+	reply = strings.TrimPrefix(reply, "```json")
+	reply = strings.TrimPrefix(reply, "```")
+	reply = strings.TrimSuffix(reply, "```")
+
+	var verdict struct {
+		Approve   *bool  `json:"approve"`
+		Reasoning string `json:"reasoning"`
+	}
+	if err := json.Unmarshal([]byte(strings.TrimSpace(reply)), &verdict); err != nil || verdict.Approve == nil {
+		return harness.Vote{}, fmt.Errorf("reviewer reply is not {\"approve\": bool, \"reasoning\": string}: %.200s", reply)
+	}
+	return harness.Vote{Approve: *verdict.Approve, Reasoning: verdict.Reasoning}, nil
+}
+
+// fallbackReviewPrompt mirrors pkg/agent/skills/review.md for callers
+// without a Skills registry; the markdown file is authoritative.
+const fallbackReviewPrompt = `You are an independent reviewer guarding a Kubernetes cluster. Another agent wants to perform the write action described below. Decide on your own whether it should go ahead.
+
+Reject actions on production-looking targets that are not clearly the smallest safe fix, actions that remove data or capacity no controller brings back, and changes you cannot fully understand. Approve deleting Pods a controller recreates and narrow patches whose diff matches a plausible fix. When in doubt, reject.
+
+Reply with JSON only:
+{"approve": true|false, "reasoning": "one or two sentences"}`
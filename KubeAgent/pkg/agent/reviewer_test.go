@@ -0,0 +1,60 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"kubeagent/pkg/agent/harness"
+)
+
+func TestLLMReviewer_Review(t *testing.T) {
+	var prompt string
+	reply := "```json\n{\"approve\": false, \"reasoning\": \"scales web to zero in prod\"}\n```"
+	reviewer := NewLLMReviewer("safety", &MockLLMClient{
+		CompleteFunc: func(ctx context.Context, messages []Message) (string, error) {
+			prompt = messages[1].Content
+			return reply, nil
+		},
+	}, nil)
+
+	req := harness.PreflightRequest{Verb: "patch", ResourceKind: "deployment", ResourceName: "web", Namespace: "prod",
+		Metadata: map[string]interface{}{"patch": `{"spec":{"replicas":0}}`}}
+	vote, err := reviewer.Review(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Review failed: %v", err)
+	}
+	if vote.Approve || vote.Reasoning != "scales web to zero in prod" {
+		t.Errorf("Unexpected vote %+v", vote)
+	}
+	if !strings.Contains(prompt, `"namespace": "prod"`) || !strings.Contains(prompt, `replicas`) {
+		t.Errorf("Expected the proposed action in the prompt:\n%s", prompt)
+	}
+
+	reply = "Looks fine to me."
+	if _, err := reviewer.Review(context.Background(), req); err == nil {
+		t.Error("Expected an unparseable reply to be an error")
+	}
+}
+
+// TestLLMReviewer_HonoursRequestBudget checks a review made inside a
+// tool call is refused once the request's budget is spent.
+func TestLLMReviewer_HonoursRequestBudget(t *testing.T) {
+	calls := 0
+	reviewer := NewLLMReviewer("safety", &MockLLMClient{
+		CompleteFunc: func(ctx context.Context, messages []Message) (string, error) {
+			calls++
+			return `{"approve": true, "reasoning": "fine"}`, nil
+		},
+	}, nil)
+	ledger := NewUsageLedger(nil).WithBudget(UsageBudget{MaxTokens: 100})
+	_ = ledger.Record(AgentTypeRemediator, "fix", &TokenUsage{InputTokens: 200})
+	ac := NewAgentContext(context.Background(), "req", "user", "trace")
+	ac.SetUsageLedger(ledger)
+
+	_, err := reviewer.Review(withAgentContext(context.Background(), ac), harness.PreflightRequest{Verb: "delete"})
+	if !errors.Is(err, ErrBudgetExceeded) || calls != 0 {
+		t.Errorf("Expected the review to be refused without an LLM call, got %d call(s) (err %v)", calls, err)
+	}
+}
@@ -0,0 +1,355 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/anthropics/anthropic-sdk-go"
+
+	"kubeagent/pkg/agent/harness"
+)
+
+// LLMBackend is one provider behind a RouterLLMClient.
+type LLMBackend struct {
+	// Name identifies the backend in logs and is what
+	// AgentConfig.LLMConfig.Provider selects.
+	Name string
+	// Config is the backend's base configuration. Per-agent overrides
+	// (model, temperature, max tokens) are applied to a copy and built
+	// with the router's client factory. Nil disables overrides, which is
+	// what test doubles want.
+	Config *LLMConfig
+	Client LLMClient
+	// Retry governs attempts on this backend before the router falls
+	// back to the next one.
+	Retry harness.RetryPolicy
+}
+
+// NewLLMBackend builds a backend for config with NewLLMClient and
+// harness.DefaultRetryPolicy.
+func NewLLMBackend(config *LLMConfig) (*LLMBackend, error) {
+	client, err := NewLLMClient(config)
+	if err != nil {
+		return nil, err
+	}
+	return &LLMBackend{
+		Name:   strings.ToLower(config.Provider),
+		Config: config,
+		Client: client,
+		Retry:  harness.DefaultRetryPolicy(),
+	}, nil
+}
+
+// AgentScopedLLMClient is implemented by clients that route per agent.
+// NewBaseAgent and NewCoordinator hand their config to ForAgent, so an
+// agent's AgentConfig.LLMConfig takes effect without the agent knowing
+// a router is involved.
+type AgentScopedLLMClient interface {
+	ForAgent(config *AgentConfig) LLMClient
+}
+
+// scopeToAgent binds client to an agent when it supports it.
+func scopeToAgent(client LLMClient, config *AgentConfig) LLMClient {
+	if scoped, ok := client.(AgentScopedLLMClient); ok {
+		return scoped.ForAgent(config)
+	}
+	return client
+}
+
+// RouterLLMClient spreads calls over several providers.
+//
+// Routing: an agent whose AgentConfig.LLMConfig names a Provider is
+// served by that backend first; a Model (or Temperature / MaxTokens)
+// in it overrides the backend's own. Everything else goes to the
+// primary backend. That is what lets the coordinator's one-word intent
+// classification run on a small model while diagnosis keeps a large one.
+//
+// Fallback: each backend is tried under its own RetryPolicy; when the
+// attempts end in an outage-type error (5xx, 429, timeout, connection
+// refused) the next backend is tried with its default model, since a
+// model name rarely exists at another provider. Caller errors (4xx,
+// invalid arguments) and cancellation are returned at once.
+type RouterLLMClient struct {
+	backends  []*LLMBackend
+	logger    Logger
+	newClient func(*LLMConfig) (LLMClient, error)
+
+	mu      sync.Mutex
+	clients map[LLMConfig]LLMClient
+}
+
+// NewRouterLLMClient routes to primary, falling back to fallbacks in
+// order.
+func NewRouterLLMClient(logger Logger, primary *LLMBackend, fallbacks ...*LLMBackend) *RouterLLMClient {
+	return &RouterLLMClient{
+		backends:  append([]*LLMBackend{primary}, fallbacks...),
+		logger:    logger,
+		newClient: NewLLMClient,
+		clients:   make(map[LLMConfig]LLMClient),
+	}
+}
+
+// WithClientFactory replaces NewLLMClient for building per-agent
+// override clients. Nil is tolerated (keeps the default).
+func (r *RouterLLMClient) WithClientFactory(f func(*LLMConfig) (LLMClient, error)) *RouterLLMClient {
+	if f != nil {
+		r.newClient = f
+	}
+	return r
+}
+
+// ForAgent implements AgentScopedLLMClient. config is read on every
+// call, so setting config.LLMConfig after the agent is built works.
+func (r *RouterLLMClient) ForAgent(config *AgentConfig) LLMClient {
+	return &routedLLMClient{router: r, agent: config}
+}
+
+// Complete implements LLMClient for calls made outside any agent.
+func (r *RouterLLMClient) Complete(ctx context.Context, messages []Message) (string, error) {
+	return r.ForAgent(nil).Complete(ctx, messages)
+}
+
+// CompleteWithTools implements LLMClient.
+func (r *RouterLLMClient) CompleteWithTools(ctx context.Context, messages []Message, tools []Tool) (*LLMResponse, error) {
+	return r.ForAgent(nil).CompleteWithTools(ctx, messages, tools)
+}
+
+// CompleteWithUsage implements UsageLLMClient.
+func (r *RouterLLMClient) CompleteWithUsage(ctx context.Context, messages []Message) (*LLMResponse, error) {
+	return r.ForAgent(nil).(UsageLLMClient).CompleteWithUsage(ctx, messages)
+}
+
+// StreamWithTools implements StreamingLLMClient.
+func (r *RouterLLMClient) StreamWithTools(ctx context.Context, messages []Message, tools []Tool, onEvent StreamHandler) (*LLMResponse, error) {
+	return r.ForAgent(nil).(StreamingLLMClient).StreamWithTools(ctx, messages, tools, onEvent)
+}
+
+// route is one backend prepared for one agent's call.
+type route struct {
+	backend *LLMBackend
+	client  LLMClient
+	model   string
+}
+
+// routes orders the backends for agent and applies its overrides.
+func (r *RouterLLMClient) routes(agent *AgentConfig) ([]route, error) {
+	var override *LLMConfig
+	if agent != nil {
+		override = agent.LLMConfig
+	}
+
+	backends := r.backends
+	if override != nil && override.Provider != "" {
+		preferred := -1
+		for i, b := range backends {
+			if strings.EqualFold(b.Name, override.Provider) {
+				preferred = i
+				break
+			}
+		}
+		if preferred < 0 {
+			return nil, fmt.Errorf("agent %s asks for LLM provider %q, which is not configured", agent.Type, override.Provider)
+		}
+		reordered := []*LLMBackend{backends[preferred]}
+		reordered = append(reordered, backends[:preferred]...)
+		backends = append(reordered, backends[preferred+1:]...)
+	}
+
+	routes := make([]route, 0, len(backends))
+	for i, b := range backends {
+		rt := route{backend: b, client: b.Client}
+		if b.Config != nil {
+			rt.model = b.Config.Model
+		}
+		// Overrides only apply where the agent's call lands first;
+		// fallbacks keep their own model.
+		if i == 0 && override != nil && b.Config != nil {
+			client, model, err := r.overridden(b.Config, override)
+			if err != nil {
+				return nil, err
+			}
+			if client != nil {
+				rt.client, rt.model = client, model
+			}
+		}
+		routes = append(routes, rt)
+	}
+	return routes, nil
+}
+
+// overridden returns a client for base with override's non-zero fields
+// applied, building and caching it on first use. It returns a nil
+// client when the override changes nothing.
+func (r *RouterLLMClient) overridden(base, override *LLMConfig) (LLMClient, string, error) {
+	config := *base
+	if override.Model != "" {
+		config.Model = override.Model
+	}
+	if override.Temperature > 0 {
+		config.Temperature = override.Temperature
+	}
+	if override.MaxTokens > 0 {
+		config.MaxTokens = override.MaxTokens
+	}
+	if config == *base {
+		return nil, "", nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if client, ok := r.clients[config]; ok {
+		return client, config.Model, nil
+	}
+	client, err := r.newClient(&config)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to build %s client for model %s: %w", config.Provider, config.Model, err)
+	}
+	r.clients[config] = client
+	return client, config.Model, nil
+}
+
+// do runs call against each route until one succeeds or an error that
+// another provider would not fix comes back. call gets the attempt's
+// context from the retry loop, not ctx.
+func (r *RouterLLMClient) do(ctx context.Context, agent *AgentConfig, call func(ctx context.Context, client LLMClient) error) error {
+	routes, err := r.routes(agent)
+	if err != nil {
+		return err
+	}
+	var agentType AgentType
+	if agent != nil {
+		agentType = agent.Type
+	}
+
+	var lastErr error
+	for i, rt := range routes {
+		attempts := 0
+		err := rt.backend.Retry.Do(ctx, func(ctx context.Context) error {
+			attempts++
+			err := call(ctx, rt.client)
+			if err != nil && !isTransientLLMError(err) {
+				return harness.PermanentError(err)
+			}
+			return err
+		})
+		if err == nil {
+			r.logger.Info("LLM call served", map[string]interface{}{
+				"backend":    rt.backend.Name,
+				"model":      rt.model,
+				"agent_type": agentType,
+				"attempts":   attempts,
+				"fallback":   i > 0,
+			})
+			return nil
+		}
+		if ctx.Err() != nil || !isTransientLLMError(err) {
+			return err
+		}
+
+		lastErr = err
+		if i < len(routes)-1 {
+			r.logger.Warn("LLM backend unavailable, falling back", map[string]interface{}{
+				"backend":    rt.backend.Name,
+				"next":       routes[i+1].backend.Name,
+				"agent_type": agentType,
+				"error":      err.Error(),
+			})
+		}
+	}
+	return fmt.Errorf("all LLM backends failed: %w", lastErr)
+}
+
+// isTransientLLMError reports whether err looks like a provider outage
+// rather than a problem with the request: HTTP 429 and 5xx (including
+// Anthropic's 529 overloaded), timeouts and refused or reset
+// connections.
+func isTransientLLMError(err error) bool {
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	if errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ECONNRESET) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	status := 0
+	var statusErr *LLMStatusError
+	var apiErr *anthropic.Error
+	switch {
+	case errors.As(err, &statusErr):
+		status = statusErr.StatusCode
+	case errors.As(err, &apiErr):
+		status = apiErr.StatusCode
+	}
+	return status == 429 || status >= 500
+}
+
+// routedLLMClient is a RouterLLMClient bound to one agent.
+type routedLLMClient struct {
+	router *RouterLLMClient
+	agent  *AgentConfig
+}
+
+// Complete implements LLMClient.
+func (c *routedLLMClient) Complete(ctx context.Context, messages []Message) (string, error) {
+	var content string
+	err := c.router.do(ctx, c.agent, func(ctx context.Context, client LLMClient) error {
+		var err error
+		content, err = client.Complete(ctx, messages)
+		return err
+	})
+	return content, err
+}
+
+// CompleteWithUsage implements UsageLLMClient.
+func (c *routedLLMClient) CompleteWithUsage(ctx context.Context, messages []Message) (*LLMResponse, error) {
+	var resp *LLMResponse
+	err := c.router.do(ctx, c.agent, func(ctx context.Context, client LLMClient) error {
+		var err error
+		resp, err = completeText(ctx, client, messages)
+		return err
+	})
+	return resp, err
+}
+
+// CompleteWithTools implements LLMClient.
+func (c *routedLLMClient) CompleteWithTools(ctx context.Context, messages []Message, tools []Tool) (*LLMResponse, error) {
+	var resp *LLMResponse
+	err := c.router.do(ctx, c.agent, func(ctx context.Context, client LLMClient) error {
+		var err error
+		resp, err = client.CompleteWithTools(ctx, messages, tools)
+		return err
+	})
+	return resp, err
+}
+
+// StreamWithTools implements StreamingLLMClient. Backends that cannot
+// stream deliver their reply as one delta. A retry after a stream broke
+// off re-sends deltas already shown; the returned response is always
+// the one from the successful attempt.
+func (c *routedLLMClient) StreamWithTools(ctx context.Context, messages []Message, tools []Tool, onEvent StreamHandler) (*LLMResponse, error) {
+	var resp *LLMResponse
+	err := c.router.do(ctx, c.agent, func(ctx context.Context, client LLMClient) error {
+		var err error
+		if streaming, ok := client.(StreamingLLMClient); ok {
+			resp, err = streaming.StreamWithTools(ctx, messages, tools, onEvent)
+			return err
+		}
+		resp, err = client.CompleteWithTools(ctx, messages, tools)
+		if err == nil && onEvent != nil && resp.Content != "" {
+			onEvent(StreamEvent{Type: StreamTextDelta, Text: resp.Content})
+		}
+		return err
+	})
+	return resp, err
+}
@@ -0,0 +1,142 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"kubeagent/pkg/agent/harness"
+)
+
+// countingLLM answers with reply, or fails with err, and counts calls.
+type countingLLM struct {
+	mu    sync.Mutex
+	calls int
+	reply string
+	err   error
+}
+
+func (c *countingLLM) Complete(ctx context.Context, messages []Message) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.calls++
+	if c.err != nil {
+		return "", c.err
+	}
+	return c.reply, nil
+}
+
+func (c *countingLLM) CompleteWithTools(ctx context.Context, messages []Message, tools []Tool) (*LLMResponse, error) {
+	content, err := c.Complete(ctx, messages)
+	if err != nil {
+		return nil, err
+	}
+	return &LLMResponse{Content: content, FinishReason: "stop"}, nil
+}
+
+// logRecorder keeps Info/Warn entries for assertions.
+type logRecorder struct {
+	mu      sync.Mutex
+	entries []map[string]interface{}
+}
+
+func (l *logRecorder) Debug(string, map[string]interface{}) {}
+func (l *logRecorder) Error(string, map[string]interface{}) {}
+func (l *logRecorder) Info(msg string, fields map[string]interface{}) {
+	l.record(msg, fields)
+}
+func (l *logRecorder) Warn(msg string, fields map[string]interface{}) {
+	l.record(msg, fields)
+}
+func (l *logRecorder) record(msg string, fields map[string]interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	entry := map[string]interface{}{"msg": msg}
+	for k, v := range fields {
+		entry[k] = v
+	}
+	l.entries = append(l.entries, entry)
+}
+
+var fastRetry = harness.RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond, Jitter: 0}
+
+func TestRouter_FallsBackOnOutage(t *testing.T) {
+	primary := &countingLLM{err: &LLMStatusError{StatusCode: 503, Status: "503 Service Unavailable"}}
+	secondary := &countingLLM{reply: "from dashscope"}
+	logs := &logRecorder{}
+	router := NewRouterLLMClient(logs,
+		&LLMBackend{Name: "anthropic", Client: primary, Retry: fastRetry},
+		&LLMBackend{Name: "dashscope", Client: secondary, Retry: fastRetry},
+	)
+
+	out, err := router.Complete(context.Background(), []Message{{Role: "user", Content: "hi"}})
+	if err != nil || out != "from dashscope" {
+		t.Fatalf("Expected fallback answer, got %q (err %v)", out, err)
+	}
+	if primary.calls != 2 || secondary.calls != 1 {
+		t.Errorf("Expected primary retried per its policy then one fallback call, got %d/%d", primary.calls, secondary.calls)
+	}
+
+	served := logs.entries[len(logs.entries)-1]
+	if served["msg"] != "LLM call served" || served["backend"] != "dashscope" || served["fallback"] != true {
+		t.Errorf("Expected serving backend to be logged, got %+v", served)
+	}
+}
+
+func TestRouter_DoesNotFallBackOnCallerError(t *testing.T) {
+	primary := &countingLLM{err: &LLMStatusError{StatusCode: 400, Status: "400 Bad Request"}}
+	secondary := &countingLLM{reply: "unused"}
+	router := NewRouterLLMClient(NewNoOpLogger(),
+		&LLMBackend{Name: "anthropic", Client: primary, Retry: fastRetry},
+		&LLMBackend{Name: "dashscope", Client: secondary, Retry: fastRetry},
+	)
+
+	_, err := router.CompleteWithTools(context.Background(), []Message{{Role: "user", Content: "hi"}}, nil)
+	var statusErr *LLMStatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != 400 {
+		t.Fatalf("Expected the 400 to be returned, got %v", err)
+	}
+	if primary.calls != 1 || secondary.calls != 0 {
+		t.Errorf("Expected a single attempt and no fallback, got %d/%d", primary.calls, secondary.calls)
+	}
+}
+
+func TestRouter_PerAgentModelAndProvider(t *testing.T) {
+	var built []LLMConfig
+	factory := func(config *LLMConfig) (LLMClient, error) {
+		built = append(built, *config)
+		return &countingLLM{reply: config.Provider + "/" + config.Model}, nil
+	}
+	router := NewRouterLLMClient(NewNoOpLogger(),
+		&LLMBackend{Name: "anthropic", Config: &LLMConfig{Provider: "anthropic", Model: "claude-sonnet-4"}, Client: &countingLLM{reply: "anthropic/default"}, Retry: fastRetry},
+		&LLMBackend{Name: "dashscope", Config: &LLMConfig{Provider: "dashscope", Model: "qwen-plus"}, Client: &countingLLM{reply: "dashscope/default"}, Retry: fastRetry},
+	).WithClientFactory(factory)
+
+	ctx := NewAgentContext(context.Background(), "req", "user", "trace")
+	ask := func(config *AgentConfig) string {
+		out, err := NewBaseAgent(config, router, NewNoOpLogger()).CallLLM(ctx, "system", "hi")
+		if err != nil {
+			t.Fatalf("CallLLM failed: %v", err)
+		}
+		return out
+	}
+
+	if got := ask(&AgentConfig{Type: AgentTypeDiagnostician}); got != "anthropic/default" {
+		t.Errorf("Expected agents without LLMConfig on the primary, got %q", got)
+	}
+	small := &AgentConfig{Type: AgentTypeCoordinator, LLMConfig: &LLMConfig{Model: "claude-3-5-haiku-latest"}}
+	if got := ask(small); got != "anthropic/claude-3-5-haiku-latest" {
+		t.Errorf("Expected model override on the primary, got %q", got)
+	}
+	if got := ask(small); got != "anthropic/claude-3-5-haiku-latest" || len(built) != 1 {
+		t.Errorf("Expected the override client to be reused, built %d", len(built))
+	}
+	if got := ask(&AgentConfig{Type: AgentTypeKnowledge, LLMConfig: &LLMConfig{Provider: "dashscope"}}); got != "dashscope/default" {
+		t.Errorf("Expected provider selection, got %q", got)
+	}
+	if _, err := NewBaseAgent(&AgentConfig{Type: AgentTypeRemediator, LLMConfig: &LLMConfig{Provider: "ollama"}}, router, NewNoOpLogger()).CallLLM(ctx, "system", "hi"); err == nil {
+		t.Error("Expected an error for an unconfigured provider")
+	}
+}
@@ -0,0 +1,305 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// CostTier ranks how expensive an agent is to run: how many LLM calls
+// and tool round-trips a typical task takes, and whether it writes to
+// the cluster. The router prefers cheaper agents when others are equal.
+type CostTier int
+
+const (
+	CostTierLow CostTier = iota + 1
+	CostTierMedium
+	CostTierHigh
+)
+
+// Capabilities is what an agent advertises to the coordinator's router
+// beyond CanHandle.
+type Capabilities struct {
+	// TaskTypes the agent handles. CanHandle stays authoritative; this
+	// is what the router reports in its explanation.
+	TaskTypes []TaskType `json:"task_types,omitempty"`
+
+	// ResourceKinds the agent knows how to work on, e.g. "Pod". Empty
+	// means any kind.
+	ResourceKinds []string `json:"resource_kinds,omitempty"`
+
+	// Tools the agent can call, by Tool.Name().
+	Tools []string `json:"tools,omitempty"`
+
+	// CostTier defaults to CostTierMedium when unset.
+	CostTier CostTier `json:"cost_tier,omitempty"`
+}
+
+// CapableAgent is an Agent that advertises its Capabilities. Agents
+// that do not are routed on CanHandle alone and score as if they had
+// medium cost and unknown tools.
+type CapableAgent interface {
+	Agent
+
+	// Capabilities returns what the agent can do.
+	Capabilities() Capabilities
+}
+
+// RequiredToolsKey is the task input key listing the tools, by name,
+// a task needs. An agent advertising Capabilities without all of them
+// is not considered for the task.
+const RequiredToolsKey = "required_tools"
+
+// RoutingKey is the plan metadata key under which Plan records the
+// router's RoutingDecision for each task, keyed by task ID.
+const RoutingKey = "routing"
+
+// Routing scores. The plan's assignment outweighs everything else the
+// router knows, so it only decides between agents the plan left open
+// and picks the fallback.
+const (
+	routeAssignedScore = 100
+	routeTaskTypeScore = 10
+	routeKindScore     = 20
+	routeToolsScore    = 5
+	routeNoToolsScore  = -10
+	routeCostWeight    = 5
+)
+
+// AgentScore is one agent's standing for a task and why.
+type AgentScore struct {
+	Agent   AgentType `json:"agent"`
+	Score   int       `json:"score"`
+	Reasons []string  `json:"reasons"`
+}
+
+// RoutingDecision explains which agent runs a task. Candidates are
+// ranked best first: the first is chosen, the second is the fallback
+// if the first fails. Excluded lists the agents that were not eligible.
+type RoutingDecision struct {
+	Chosen     AgentType    `json:"chosen,omitempty"`
+	Fallback   AgentType    `json:"fallback,omitempty"`
+	Candidates []AgentScore `json:"candidates,omitempty"`
+	Excluded   []AgentScore `json:"excluded,omitempty"`
+}
+
+// Routing returns the decision Plan recorded for task id, or nil. A
+// plan reloaded from JSON holds the decisions as plain maps, which are
+// decoded again here.
+func (p *ExecutionPlan) Routing(id string) *RoutingDecision {
+	switch routing := p.Metadata[RoutingKey].(type) {
+	case map[string]*RoutingDecision:
+		return routing[id]
+	case map[string]interface{}:
+		raw, ok := routing[id]
+		if !ok {
+			return nil
+		}
+		data, err := json.Marshal(raw)
+		if err != nil {
+			return nil
+		}
+		decision := &RoutingDecision{}
+		if err := json.Unmarshal(data, decision); err != nil {
+			return nil
+		}
+		return decision
+	}
+	return nil
+}
+
+// route ranks the registered agents for task. The result is the same
+// for the same agents and task regardless of map order: ties go to the
+// agent type that sorts first.
+func (c *BaseCoordinator) route(task *Task) ([]Agent, *RoutingDecision) {
+	kind := taskResourceKind(task)
+	required := requiredTools(task)
+
+	c.agentsMutex.RLock()
+	type scored struct {
+		agent Agent
+		score AgentScore
+	}
+	var eligible []scored
+	decision := &RoutingDecision{}
+	for _, agent := range c.agents {
+		score, ok := scoreAgent(agent, task, kind, required)
+		if ok {
+			eligible = append(eligible, scored{agent, score})
+		} else {
+			decision.Excluded = append(decision.Excluded, score)
+		}
+	}
+	c.agentsMutex.RUnlock()
+
+	sort.Slice(eligible, func(i, j int) bool {
+		if eligible[i].score.Score != eligible[j].score.Score {
+			return eligible[i].score.Score > eligible[j].score.Score
+		}
+		return eligible[i].score.Agent < eligible[j].score.Agent
+	})
+	sort.Slice(decision.Excluded, func(i, j int) bool {
+		return decision.Excluded[i].Agent < decision.Excluded[j].Agent
+	})
+
+	agents := make([]Agent, len(eligible))
+	for i, s := range eligible {
+		agents[i] = s.agent
+		decision.Candidates = append(decision.Candidates, s.score)
+	}
+	if len(agents) > 0 {
+		decision.Chosen = agents[0].Type()
+	}
+	if len(agents) > 1 {
+		decision.Fallback = agents[1].Type()
+	}
+	return agents, decision
+}
+
+// scoreAgent scores agent for task and reports whether it may run it
+// at all. The agent the plan assigned is always eligible: decomposition
+// already checked the assignment, and the operator may have edited it.
+func scoreAgent(agent Agent, task *Task, kind string, required []string) (AgentScore, bool) {
+	score := AgentScore{Agent: agent.Type()}
+	add := func(points int, reason string) {
+		score.Score += points
+		score.Reasons = append(score.Reasons, reason)
+	}
+
+	assigned := task.AssignedAgent != "" && task.AssignedAgent == agent.Type()
+	if assigned {
+		add(routeAssignedScore, "assigned by the plan")
+	}
+
+	var caps *Capabilities
+	if capable, ok := agent.(CapableAgent); ok {
+		c := capable.Capabilities()
+		caps = &c
+	}
+
+	handles := agent.CanHandle(task.Type)
+	if !handles && caps != nil {
+		for _, t := range caps.TaskTypes {
+			if t == task.Type {
+				handles = true
+				break
+			}
+		}
+	}
+	if handles {
+		add(routeTaskTypeScore, fmt.Sprintf("handles %s tasks", task.Type))
+	} else if !assigned {
+		score.Reasons = append(score.Reasons, fmt.Sprintf("does not handle %s tasks", task.Type))
+		return score, false
+	}
+
+	if caps == nil {
+		if len(required) > 0 {
+			add(routeNoToolsScore, "does not advertise its tools")
+		}
+		add(-routeCostWeight*int(CostTierMedium), "cost unknown, assumed medium")
+		return score, true
+	}
+
+	if kind != "" && len(caps.ResourceKinds) > 0 {
+		if containsFold(caps.ResourceKinds, kind) {
+			add(routeKindScore, "works on "+kind)
+		} else if !assigned {
+			score.Reasons = append(score.Reasons, "does not work on "+kind)
+			return score, false
+		}
+	}
+
+	if len(required) > 0 {
+		var missing []string
+		for _, tool := range required {
+			if !containsFold(caps.Tools, tool) {
+				missing = append(missing, tool)
+			}
+		}
+		switch {
+		case len(missing) == 0:
+			add(routeToolsScore, "has "+strings.Join(required, ", "))
+		case !assigned:
+			score.Reasons = append(score.Reasons, "lacks "+strings.Join(missing, ", "))
+			return score, false
+		default:
+			score.Reasons = append(score.Reasons, "lacks "+strings.Join(missing, ", "))
+		}
+	}
+
+	tier := caps.CostTier
+	if tier == 0 {
+		tier = CostTierMedium
+	}
+	add(-routeCostWeight*int(tier), fmt.Sprintf("cost tier %d", tier))
+	return score, true
+}
+
+// explainRouting returns the router's decision for every task, for the
+// plan's metadata.
+func (c *BaseCoordinator) explainRouting(tasks []*Task) map[string]*RoutingDecision {
+	routing := make(map[string]*RoutingDecision, len(tasks))
+	for _, task := range tasks {
+		_, routing[task.ID] = c.route(task)
+	}
+	return routing
+}
+
+// shouldFallBack reports whether a task whose agent failed with err
+// may be handed to the next candidate. Cancellation and an exhausted
+// budget stop the request, not just this agent, and a remediation that
+// failed verification has already changed the cluster: that is for
+// iterateRemediation to re-diagnose, not for another agent to retry.
+func shouldFallBack(ctx *AgentContext, task *Task, err error) bool {
+	if ctx.Context().Err() != nil || errors.Is(err, context.Canceled) || errors.Is(err, ErrBudgetExceeded) {
+		return false
+	}
+	return failedVerification(task) == nil
+}
+
+// taskResourceKind is the kind of object a task is about, if its input
+// says: resource_kind, or Pod when it names a pod.
+func taskResourceKind(task *Task) string {
+	if kind, _ := task.Input["resource_kind"].(string); kind != "" {
+		return kind
+	}
+	if name, _ := task.Input["pod_name"].(string); name != "" {
+		return "Pod"
+	}
+	return ""
+}
+
+// requiredTools reads RequiredToolsKey, which may have gone through
+// JSON.
+func requiredTools(task *Task) []string {
+	switch tools := task.Input[RequiredToolsKey].(type) {
+	case []string:
+		return tools
+	case []interface{}:
+		out := make([]string, 0, len(tools))
+		for _, tool := range tools {
+			if name, ok := tool.(string); ok && name != "" {
+				out = append(out, name)
+			}
+		}
+		return out
+	case string:
+		if tools != "" {
+			return []string{tools}
+		}
+	}
+	return nil
+}
+
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
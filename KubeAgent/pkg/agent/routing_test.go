@@ -0,0 +1,169 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"kubeagent/pkg/agent/harness"
+)
+
+// capableAgent is a MockSpecialistAgent that advertises Capabilities.
+type capableAgent struct {
+	*MockSpecialistAgent
+	caps Capabilities
+}
+
+func (a *capableAgent) Capabilities() Capabilities { return a.caps }
+
+func newCapableAgent(agentType AgentType, caps Capabilities, execute func(*AgentContext, *Task) (*Task, error)) *capableAgent {
+	return &capableAgent{
+		MockSpecialistAgent: &MockSpecialistAgent{
+			name:      string(agentType),
+			agentType: agentType,
+			canHandleFunc: func(taskType TaskType) bool {
+				for _, t := range caps.TaskTypes {
+					if t == taskType {
+						return true
+					}
+				}
+				return false
+			},
+			executeFunc: execute,
+		},
+		caps: caps,
+	}
+}
+
+func TestRoute_IsDeterministicAndPrefersCheaperAgents(t *testing.T) {
+	coordinator := NewCoordinator(nil, &MockLLMClient{}, nil, NewNoOpLogger())
+	coordinator.RegisterAgent(newCapableAgent("expensive", Capabilities{TaskTypes: []TaskType{TaskTypeQuery}, CostTier: CostTierHigh}, nil))
+	coordinator.RegisterAgent(newCapableAgent("cheap-b", Capabilities{TaskTypes: []TaskType{TaskTypeQuery}, CostTier: CostTierLow}, nil))
+	coordinator.RegisterAgent(newCapableAgent("cheap-a", Capabilities{TaskTypes: []TaskType{TaskTypeQuery}, CostTier: CostTierLow}, nil))
+
+	task := &Task{ID: "q", Type: TaskTypeQuery}
+	for i := 0; i < 20; i++ {
+		agents, decision := coordinator.route(task)
+		if len(agents) != 3 || agents[0].Type() != "cheap-a" || agents[1].Type() != "cheap-b" || agents[2].Type() != "expensive" {
+			t.Fatalf("Expected cheap-a, cheap-b, expensive, got %+v", decision.Candidates)
+		}
+		if decision.Chosen != "cheap-a" || decision.Fallback != "cheap-b" {
+			t.Fatalf("Expected cheap-a with cheap-b as fallback, got %+v", decision)
+		}
+	}
+
+	// The plan's assignment outweighs cost.
+	task.AssignedAgent = "expensive"
+	if agent, err := coordinator.selectAgentForTask(task); err != nil || agent.Type() != "expensive" {
+		t.Errorf("Expected the assigned agent, got %v, %v", agent, err)
+	}
+}
+
+func TestRoute_ExcludesAgentsWithoutKindOrTools(t *testing.T) {
+	coordinator := NewCoordinator(nil, &MockLLMClient{}, nil, NewNoOpLogger())
+	coordinator.RegisterAgent(newCapableAgent("pods", Capabilities{
+		TaskTypes: []TaskType{TaskTypeRemediate}, ResourceKinds: []string{"Pod"}, Tools: []string{"patch_resource"}, CostTier: CostTierLow,
+	}, nil))
+	coordinator.RegisterAgent(newCapableAgent("readonly", Capabilities{
+		TaskTypes: []TaskType{TaskTypeRemediate}, Tools: []string{"get_resource"}, CostTier: CostTierLow,
+	}, nil))
+	coordinator.RegisterAgent(newCapableAgent("writer", Capabilities{
+		TaskTypes: []TaskType{TaskTypeRemediate}, Tools: []string{"get_resource", "patch_resource"}, CostTier: CostTierHigh,
+	}, nil))
+
+	task := &Task{ID: "r", Type: TaskTypeRemediate, Input: map[string]interface{}{
+		"resource_kind":  "deployment",
+		RequiredToolsKey: []interface{}{"patch_resource"},
+	}}
+	agents, decision := coordinator.route(task)
+	if len(agents) != 1 || decision.Chosen != "writer" || decision.Fallback != "" {
+		t.Fatalf("Expected only writer to qualify, got %+v", decision)
+	}
+	if len(decision.Excluded) != 2 || decision.Excluded[0].Agent != "pods" || decision.Excluded[1].Agent != "readonly" {
+		t.Fatalf("Expected pods and readonly to be excluded, got %+v", decision.Excluded)
+	}
+	if reasons := decision.Excluded[0].Reasons; reasons[len(reasons)-1] != "does not work on deployment" {
+		t.Errorf("Expected pods to be excluded for the kind, got %v", reasons)
+	}
+	if reasons := decision.Excluded[1].Reasons; reasons[len(reasons)-1] != "lacks patch_resource" {
+		t.Errorf("Expected readonly to be excluded for the tool, got %v", reasons)
+	}
+}
+
+func TestExecute_FallsBackToSecondAgent(t *testing.T) {
+	coordinator := NewCoordinator(nil, &MockLLMClient{}, nil, NewNoOpLogger()).WithTaskRetryPolicy(noBackoff)
+	coordinator.RegisterAgent(newCapableAgent("primary", Capabilities{TaskTypes: []TaskType{TaskTypeQuery}, CostTier: CostTierLow},
+		func(ctx *AgentContext, task *Task) (*Task, error) {
+			return task, errors.New("model refused")
+		}))
+	coordinator.RegisterAgent(newCapableAgent("secondary", Capabilities{TaskTypes: []TaskType{TaskTypeQuery}, CostTier: CostTierMedium},
+		func(ctx *AgentContext, task *Task) (*Task, error) {
+			task.Output = map[string]interface{}{"answer": "3 pods"}
+			return task, nil
+		}))
+
+	task := &Task{ID: "q", Type: TaskTypeQuery}
+	result, err := coordinator.Execute(NewAgentContext(context.Background(), "req", "user", "trace"), task)
+	if err != nil {
+		t.Fatalf("Expected the fallback to succeed, got %v", err)
+	}
+	if result.Status != TaskStatusCompleted || result.Output["answer"] != "3 pods" {
+		t.Errorf("Expected the secondary's output, got %s %+v", result.Status, result.Output)
+	}
+	if len(task.Attempts) != 2 || task.Attempts[0].Agent != "primary" || task.Attempts[1].Agent != "secondary" {
+		t.Errorf("Expected one attempt per agent, got %+v", task.Attempts)
+	}
+}
+
+func TestExecute_DoesNotFallBackAfterFailedVerification(t *testing.T) {
+	coordinator := NewCoordinator(nil, &MockLLMClient{}, nil, NewNoOpLogger()).WithTaskRetryPolicy(noBackoff)
+	coordinator.RegisterAgent(newCapableAgent("primary", Capabilities{TaskTypes: []TaskType{TaskTypeRemediate}, CostTier: CostTierLow},
+		func(ctx *AgentContext, task *Task) (*Task, error) {
+			task.Output = map[string]interface{}{"verification": &harness.VerificationResult{Status: harness.VerificationFailed}}
+			return task, errors.New("verification failed")
+		}))
+	secondaryRan := false
+	coordinator.RegisterAgent(newCapableAgent("secondary", Capabilities{TaskTypes: []TaskType{TaskTypeRemediate}, CostTier: CostTierHigh},
+		func(ctx *AgentContext, task *Task) (*Task, error) {
+			secondaryRan = true
+			return task, nil
+		}))
+
+	task := &Task{ID: "fix", Type: TaskTypeRemediate}
+	if _, err := coordinator.Execute(NewAgentContext(context.Background(), "req", "user", "trace"), task); err == nil {
+		t.Fatal("Expected the failed verification to fail the task")
+	}
+	if secondaryRan || task.Status != TaskStatusFailed {
+		t.Errorf("Expected no fallback after a failed verification, got %s (secondary ran: %v)", task.Status, secondaryRan)
+	}
+}
+
+func TestPlan_RecordsRoutingDecisions(t *testing.T) {
+	llm, _ := scriptedPlanner(`[
+		{"id": "d", "type": "diagnose", "description": "find cause", "assigned_agent": "diagnostician", "input": {"pod_name": "web-1"}}
+	]`)
+	coordinator := newPlanningCoordinator(llm)
+	plan, err := coordinator.Plan(NewAgentContext(context.Background(), "req", "user", "trace"), &Request{ID: "req", Input: "why is web-1 crashing"})
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+
+	decision := plan.Routing("d")
+	if decision == nil || decision.Chosen != AgentTypeDiagnostician || len(decision.Excluded) != 1 || decision.Excluded[0].Agent != AgentTypeRemediator {
+		t.Fatalf("Expected the diagnostician to be chosen over the remediator, got %+v", decision)
+	}
+
+	// A plan written with --plan-out and read back keeps the explanation.
+	data, err := json.Marshal(plan)
+	if err != nil {
+		t.Fatalf("Failed to encode plan: %v", err)
+	}
+	reloaded := &ExecutionPlan{}
+	if err := json.Unmarshal(data, reloaded); err != nil {
+		t.Fatalf("Failed to decode plan: %v", err)
+	}
+	if decision := reloaded.Routing("d"); decision == nil || decision.Chosen != AgentTypeDiagnostician || len(decision.Candidates[0].Reasons) == 0 {
+		t.Errorf("Expected the routing to survive JSON, got %+v", decision)
+	}
+}
@@ -0,0 +1,156 @@
+package agent
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// DefaultMaxConcurrency is how many plan tasks run at once unless
+// WithScheduler says otherwise. Every task holds at least one LLM
+// conversation and usually a few API server calls, so a wide plan
+// should not open them all at the same moment.
+const DefaultMaxConcurrency = 4
+
+// SchedulerConfig bounds how executeDependencyBased runs plan tasks,
+// across all the plans a coordinator runs at once. Zero values mean no
+// limit.
+type SchedulerConfig struct {
+	// MaxConcurrency caps the tasks running at once across all agents.
+	MaxConcurrency int
+
+	// Agents holds limits for individual agent types, e.g. one
+	// remediator at a time even when diagnosis runs four wide.
+	Agents map[AgentType]AgentLimit
+}
+
+// AgentLimit bounds the tasks of one agent type.
+type AgentLimit struct {
+	// MaxConcurrency caps the agent type's tasks running at once.
+	MaxConcurrency int
+
+	// MinInterval is the least time between two task starts of the
+	// agent type. Retries inside a task are paced by the task retry
+	// policy instead.
+	MinInterval time.Duration
+}
+
+// DefaultSchedulerConfig caps the whole plan at DefaultMaxConcurrency
+// and leaves agent types unlimited.
+func DefaultSchedulerConfig() SchedulerConfig {
+	return SchedulerConfig{MaxConcurrency: DefaultMaxConcurrency}
+}
+
+// dispatchSlots tracks running tasks and start times against a
+// SchedulerConfig. The coordinator holds one for all the plans it runs,
+// so the limits bound the process, not each plan on its own.
+type dispatchSlots struct {
+	mu        sync.Mutex
+	config    SchedulerConfig
+	running   int
+	byAgent   map[AgentType]int
+	nextStart map[AgentType]time.Time
+	freed     chan struct{}
+}
+
+func newDispatchSlots(config SchedulerConfig) *dispatchSlots {
+	return &dispatchSlots{
+		config:    config,
+		byAgent:   make(map[AgentType]int),
+		nextStart: make(map[AgentType]time.Time),
+		freed:     make(chan struct{}),
+	}
+}
+
+// acquire takes a slot for a task of agentType starting at now, if the
+// limits admit one. When only the agent's MinInterval is in the way, it
+// also returns when the task may start, so the scheduler knows when to
+// look again; a full slot frees up with a task finishing instead (see
+// released).
+func (s *dispatchSlots) acquire(agentType AgentType, now time.Time) (bool, time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.config.MaxConcurrency > 0 && s.running >= s.config.MaxConcurrency {
+		return false, time.Time{}
+	}
+	limit := s.config.Agents[agentType]
+	if limit.MaxConcurrency > 0 && s.byAgent[agentType] >= limit.MaxConcurrency {
+		return false, time.Time{}
+	}
+	if next := s.nextStart[agentType]; now.Before(next) {
+		return false, next
+	}
+	s.running++
+	s.byAgent[agentType]++
+	if limit.MinInterval > 0 {
+		s.nextStart[agentType] = now.Add(limit.MinInterval)
+	}
+	return true, time.Time{}
+}
+
+// release records a task of agentType finishing.
+func (s *dispatchSlots) release(agentType AgentType) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.running--
+	s.byAgent[agentType]--
+	close(s.freed)
+	s.freed = make(chan struct{})
+}
+
+// wait acquires a slot for a task of agentType once the limits admit
+// one, or returns ctx's error.
+func (s *dispatchSlots) wait(ctx context.Context, agentType AgentType) error {
+	for {
+		freed := s.released()
+		ok, at := s.acquire(agentType, time.Now())
+		if ok {
+			return nil
+		}
+		var timer *time.Timer
+		var due <-chan time.Time
+		if !at.IsZero() {
+			timer = time.NewTimer(time.Until(at))
+			due = timer.C
+		}
+		select {
+		case <-freed:
+		case <-due:
+		case <-ctx.Done():
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+}
+
+// released is closed when the next slot is released, by any plan.
+// Take it before trying to acquire, or a release in between is missed.
+func (s *dispatchSlots) released() <-chan struct{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.freed
+}
+
+// checkpointSnapshot is the plan as checkpointPlan should see it while
+// tasks are in flight: those tasks, which their goroutines are still
+// writing, are replaced by copies taken before they were prepared. A
+// resumed run re-executes them from scratch either way.
+func checkpointSnapshot(plan *ExecutionPlan, unfinished map[string]*Task) *ExecutionPlan {
+	if len(unfinished) == 0 {
+		return plan
+	}
+	snapshot := *plan
+	snapshot.Tasks = make([]*Task, len(plan.Tasks))
+	for i, task := range plan.Tasks {
+		if saved, ok := unfinished[task.ID]; ok {
+			snapshot.Tasks[i] = saved
+		} else {
+			snapshot.Tasks[i] = task
+		}
+	}
+	return &snapshot
+}
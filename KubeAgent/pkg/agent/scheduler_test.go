@@ -0,0 +1,224 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// gauge records how many tasks its agents run at once, overall and per
+// agent type, and when each task started.
+type gauge struct {
+	mu      sync.Mutex
+	running map[AgentType]int
+	peak    map[AgentType]int
+	total   int
+	maxAll  int
+	starts  []time.Time
+}
+
+func newGauge() *gauge {
+	return &gauge{running: map[AgentType]int{}, peak: map[AgentType]int{}}
+}
+
+// agent returns a mock agent whose tasks take hold and report to g.
+func (g *gauge) agent(agentType AgentType, hold time.Duration) *MockSpecialistAgent {
+	return &MockSpecialistAgent{
+		name:      string(agentType),
+		agentType: agentType,
+		executeFunc: func(ctx *AgentContext, task *Task) (*Task, error) {
+			g.mu.Lock()
+			g.running[agentType]++
+			g.total++
+			if g.running[agentType] > g.peak[agentType] {
+				g.peak[agentType] = g.running[agentType]
+			}
+			if g.total > g.maxAll {
+				g.maxAll = g.total
+			}
+			g.starts = append(g.starts, time.Now())
+			g.mu.Unlock()
+
+			time.Sleep(hold)
+
+			g.mu.Lock()
+			g.running[agentType]--
+			g.total--
+			g.mu.Unlock()
+			return task, nil
+		},
+	}
+}
+
+func TestExecutePlan_ConcurrencyLimits(t *testing.T) {
+	g := newGauge()
+	coordinator := NewCoordinator(nil, &MockLLMClient{}, NewMemoryStateStore(), NewNoOpLogger()).
+		WithScheduler(SchedulerConfig{
+			MaxConcurrency: 3,
+			Agents:         map[AgentType]AgentLimit{AgentTypeRemediator: {MaxConcurrency: 1}},
+		})
+	coordinator.RegisterAgent(g.agent(AgentTypeDiagnostician, 20*time.Millisecond))
+	coordinator.RegisterAgent(g.agent(AgentTypeRemediator, 20*time.Millisecond))
+
+	plan := &ExecutionPlan{ID: "plan"}
+	for i := 0; i < 6; i++ {
+		plan.Tasks = append(plan.Tasks,
+			&Task{ID: fmt.Sprintf("diagnose-%d", i), Type: TaskTypeDiagnose, AssignedAgent: AgentTypeDiagnostician},
+			&Task{ID: fmt.Sprintf("fix-%d", i), Type: TaskTypeRemediate, AssignedAgent: AgentTypeRemediator})
+	}
+	if _, err := coordinator.ExecutePlan(NewAgentContext(context.Background(), "req", "user", "trace"), plan); err != nil {
+		t.Fatalf("ExecutePlan failed: %v", err)
+	}
+
+	if g.maxAll != 3 || g.peak[AgentTypeRemediator] != 1 {
+		t.Errorf("Expected at most 3 tasks and 1 remediation at once, got %d and %d", g.maxAll, g.peak[AgentTypeRemediator])
+	}
+	for _, task := range plan.Tasks {
+		if task.Status != TaskStatusCompleted {
+			t.Errorf("Expected task %s completed, got %s", task.ID, task.Status)
+		}
+	}
+}
+
+func TestExecutePlan_StartsTaskWhenDependenciesFinish(t *testing.T) {
+	followUpStarted := make(chan struct{})
+	coordinator := NewCoordinator(nil, &MockLLMClient{}, nil, NewNoOpLogger())
+	coordinator.RegisterAgent(&MockSpecialistAgent{
+		name:      "diag",
+		agentType: AgentTypeDiagnostician,
+		executeFunc: func(ctx *AgentContext, task *Task) (*Task, error) {
+			switch task.ID {
+			case "slow":
+				// Only returns once the fast branch moved on without it.
+				select {
+				case <-followUpStarted:
+					return task, nil
+				case <-time.After(2 * time.Second):
+					return task, errors.New("follow-up waited for the slow sibling")
+				}
+			case "follow-up":
+				close(followUpStarted)
+			}
+			return task, nil
+		},
+	})
+
+	plan := &ExecutionPlan{ID: "plan", Tasks: []*Task{
+		{ID: "slow", Type: TaskTypeDiagnose, AssignedAgent: AgentTypeDiagnostician},
+		{ID: "fast", Type: TaskTypeDiagnose, AssignedAgent: AgentTypeDiagnostician},
+		{ID: "follow-up", Type: TaskTypeDiagnose, AssignedAgent: AgentTypeDiagnostician, Dependencies: []string{"fast"}},
+	}}
+	response, err := coordinator.ExecutePlan(NewAgentContext(context.Background(), "req", "user", "trace"), plan)
+	if err != nil {
+		t.Fatalf("ExecutePlan failed: %v", err)
+	}
+	if len(response.Errors) != 0 {
+		t.Errorf("Unexpected errors %v", response.Errors)
+	}
+}
+
+func TestExecutePlan_AgentMinInterval(t *testing.T) {
+	const interval = 30 * time.Millisecond
+	g := newGauge()
+	coordinator := NewCoordinator(nil, &MockLLMClient{}, nil, NewNoOpLogger()).
+		WithScheduler(SchedulerConfig{Agents: map[AgentType]AgentLimit{AgentTypeDiagnostician: {MinInterval: interval}}})
+	coordinator.RegisterAgent(g.agent(AgentTypeDiagnostician, 0))
+
+	plan := &ExecutionPlan{ID: "plan"}
+	for i := 0; i < 3; i++ {
+		plan.Tasks = append(plan.Tasks, &Task{ID: fmt.Sprintf("diagnose-%d", i), Type: TaskTypeDiagnose, AssignedAgent: AgentTypeDiagnostician})
+	}
+	if _, err := coordinator.ExecutePlan(NewAgentContext(context.Background(), "req", "user", "trace"), plan); err != nil {
+		t.Fatalf("ExecutePlan failed: %v", err)
+	}
+
+	if len(g.starts) != 3 {
+		t.Fatalf("Expected 3 starts, got %d", len(g.starts))
+	}
+	for i := 1; i < len(g.starts); i++ {
+		if gap := g.starts[i].Sub(g.starts[i-1]); gap < interval {
+			t.Errorf("Start %d came %s after the previous one, want at least %s", i, gap, interval)
+		}
+	}
+}
+
+// TestExecutePlan_LimitsSharedAcrossPlans checks concurrent plans
+// draw on the coordinator's slots instead of each getting their own.
+func TestExecutePlan_LimitsSharedAcrossPlans(t *testing.T) {
+	g := newGauge()
+	coordinator := NewCoordinator(nil, &MockLLMClient{}, nil, NewNoOpLogger()).
+		WithScheduler(SchedulerConfig{Agents: map[AgentType]AgentLimit{AgentTypeRemediator: {MaxConcurrency: 1}}})
+	coordinator.RegisterAgent(g.agent(AgentTypeRemediator, 20*time.Millisecond))
+
+	var wg sync.WaitGroup
+	plans := make([]*ExecutionPlan, 3)
+	for i := range plans {
+		plans[i] = &ExecutionPlan{ID: fmt.Sprintf("plan-%d", i), Tasks: []*Task{
+			{ID: "fix-a", Type: TaskTypeRemediate, AssignedAgent: AgentTypeRemediator},
+			{ID: "fix-b", Type: TaskTypeRemediate, AssignedAgent: AgentTypeRemediator},
+		}}
+		wg.Add(1)
+		go func(plan *ExecutionPlan) {
+			defer wg.Done()
+			if _, err := coordinator.ExecutePlan(NewAgentContext(context.Background(), plan.ID, "user", "trace"), plan); err != nil {
+				t.Errorf("ExecutePlan %s failed: %v", plan.ID, err)
+			}
+		}(plans[i])
+	}
+	wg.Wait()
+
+	if g.peak[AgentTypeRemediator] != 1 {
+		t.Errorf("Expected 1 remediation at once across plans, got %d", g.peak[AgentTypeRemediator])
+	}
+	metrics := coordinator.GetMetrics()
+	if metrics.TasksCompleted != 3 {
+		t.Errorf("Expected every plan counted once, got %d", metrics.TasksCompleted)
+	}
+	metrics.TasksCompleted = 0
+	if coordinator.GetMetrics().TasksCompleted != 3 {
+		t.Error("Expected GetMetrics to return a copy")
+	}
+	for _, plan := range plans {
+		for _, task := range plan.Tasks {
+			if task.Status != TaskStatusCompleted {
+				t.Errorf("Expected %s/%s completed, got %s", plan.ID, task.ID, task.Status)
+			}
+		}
+	}
+}
+
+// TestExecutePlan_FallbackRunsUnderItsOwnLimit checks a task that
+// falls back to the runner-up takes a slot for that agent: the
+// primary's limit must not carry it past the fallback's.
+func TestExecutePlan_FallbackRunsUnderItsOwnLimit(t *testing.T) {
+	g := newGauge()
+	coordinator := NewCoordinator(nil, &MockLLMClient{}, nil, NewNoOpLogger()).
+		WithTaskRetryPolicy(noBackoff).
+		WithScheduler(SchedulerConfig{Agents: map[AgentType]AgentLimit{"secondary": {MaxConcurrency: 1}}})
+	coordinator.RegisterAgent(newCapableAgent("primary", Capabilities{TaskTypes: []TaskType{TaskTypeQuery}, CostTier: CostTierLow},
+		func(ctx *AgentContext, task *Task) (*Task, error) {
+			return task, errors.New("model refused")
+		}))
+	secondary := g.agent("secondary", 20*time.Millisecond)
+	coordinator.RegisterAgent(newCapableAgent("secondary", Capabilities{TaskTypes: []TaskType{TaskTypeQuery}, CostTier: CostTierMedium}, secondary.executeFunc))
+
+	plan := &ExecutionPlan{ID: "plan"}
+	for i := 0; i < 3; i++ {
+		plan.Tasks = append(plan.Tasks, &Task{ID: fmt.Sprintf("q-%d", i), Type: TaskTypeQuery})
+	}
+	if _, err := coordinator.ExecutePlan(NewAgentContext(context.Background(), "req", "user", "trace"), plan); err != nil {
+		t.Fatalf("ExecutePlan failed: %v", err)
+	}
+
+	if g.peak["secondary"] != 1 {
+		t.Errorf("Expected 1 fallback at once, got %d", g.peak["secondary"])
+	}
+	for _, task := range plan.Tasks {
+		if task.Status != TaskStatusCompleted {
+			t.Errorf("Expected task %s completed by the fallback, got %s", task.ID, task.Status)
+		}
+	}
+}
@@ -0,0 +1,304 @@
+package agent
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultSessionTokens is roughly how many tokens of conversation a
+// Session carries into prompts before its older turns are summarised.
+const DefaultSessionTokens = 2000
+
+const (
+	// sessionKeptTurns is how many of the newest turns are always kept
+	// verbatim, however large: they are what "that pod" refers to.
+	sessionKeptTurns = 2
+
+	// sessionResultChars caps how much of a turn's result is kept.
+	sessionResultChars = 1200
+
+	// sessionMaxEntities caps each entity list, newest first.
+	sessionMaxEntities = 5
+)
+
+// Turn is one request in a Session and how it ended.
+type Turn struct {
+	RequestID string     `json:"request_id"`
+	Input     string     `json:"input"`
+	Status    TaskStatus `json:"status"`
+	Result    string     `json:"result"`
+	At        time.Time  `json:"at"`
+}
+
+// SessionEntities are the objects a conversation has been about, newest
+// first, so a follow-up can say "that pod" or "the same namespace".
+type SessionEntities struct {
+	Namespaces []string `json:"namespaces,omitempty"`
+	// Pods are namespace/name.
+	Pods []string `json:"pods,omitempty"`
+	// Resources are "Kind namespace/name" for objects other than pods.
+	Resources []string `json:"resources,omitempty"`
+}
+
+// Session carries a conversation across the requests of one chat,
+// analyze or kubecheck session. Plan and the specialists' prompts see
+// its earlier turns and the entities they touched through the
+// AgentContext; without a session every request stands alone, as
+// before.
+//
+// The newest turns are kept verbatim. When the rendered conversation
+// grows past the token budget, older turns are folded into a running
+// summary, by the summariser LLM if one is set and mechanically
+// otherwise.
+type Session struct {
+	mu         sync.Mutex
+	id         string
+	summarizer LLMClient
+	maxTokens  int
+
+	summary  string
+	turns    []Turn
+	history  []Turn
+	entities SessionEntities
+}
+
+// NewSession starts an empty session.
+func NewSession(id string) *Session {
+	return &Session{id: id, maxTokens: DefaultSessionTokens}
+}
+
+// WithSummarizer sets the LLM that summarises older turns. Nil is
+// tolerated: older turns are then reduced to their requests and
+// outcomes.
+func (s *Session) WithSummarizer(client LLMClient) *Session {
+	s.summarizer = client
+	return s
+}
+
+// WithMaxTokens sets the token budget for the conversation carried
+// into prompts. Zero or less means DefaultSessionTokens.
+func (s *Session) WithMaxTokens(n int) *Session {
+	if n <= 0 {
+		n = DefaultSessionTokens
+	}
+	s.maxTokens = n
+	return s
+}
+
+// ID returns the session's ID.
+func (s *Session) ID() string { return s.id }
+
+// History returns every turn since the session started or was last
+// reset, summarised or not.
+func (s *Session) History() []Turn {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Turn(nil), s.history...)
+}
+
+// Entities returns what the conversation has been about so far.
+func (s *Session) Entities() SessionEntities {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return SessionEntities{
+		Namespaces: append([]string(nil), s.entities.Namespaces...),
+		Pods:       append([]string(nil), s.entities.Pods...),
+		Resources:  append([]string(nil), s.entities.Resources...),
+	}
+}
+
+// Reset forgets the conversation.
+func (s *Session) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.summary = ""
+	s.turns = nil
+	s.history = nil
+	s.entities = SessionEntities{}
+}
+
+// Record adds a finished request to the session: its outcome, and the
+// namespaces, pods and other objects its tasks named. plan and
+// response may be nil when planning failed. Summarising older turns
+// makes an LLM call under ctx, metered like the coordinator's own.
+func (s *Session) Record(ctx *AgentContext, request *Request, plan *ExecutionPlan, response *Response) {
+	turn := Turn{RequestID: request.ID, Input: request.Input, Status: TaskStatusFailed, At: time.Now()}
+	if response != nil {
+		turn.Status = response.Status
+		turn.Result = truncate(response.Result, sessionResultChars)
+		if report, ok := response.Data[FinalReportKey].(*FinalReport); ok {
+			turn.Status = report.Status
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.turns = append(s.turns, turn)
+	s.history = append(s.history, turn)
+	if plan != nil {
+		for _, task := range plan.Tasks {
+			s.noteEntities(task.Input)
+			s.noteEntities(task.Output)
+		}
+	}
+	s.compact(ctx)
+}
+
+// Prompt renders the conversation for a prompt, or "" when there is
+// none yet.
+func (s *Session) Prompt() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.render()
+}
+
+func (s *Session) render() string {
+	if s.summary == "" && len(s.turns) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("Conversation so far (most recent last):\n")
+	if s.summary != "" {
+		fmt.Fprintf(&b, "Earlier: %s\n", s.summary)
+	}
+	for _, turn := range s.turns {
+		fmt.Fprintf(&b, "- User: %s\n  Outcome (%s): %s\n", turn.Input, turn.Status, turn.Result)
+	}
+	if e := s.entities; len(e.Namespaces)+len(e.Pods)+len(e.Resources) > 0 {
+		b.WriteString("Mentioned so far (newest first):")
+		if len(e.Namespaces) > 0 {
+			fmt.Fprintf(&b, " namespaces: %s;", strings.Join(e.Namespaces, ", "))
+		}
+		if len(e.Pods) > 0 {
+			fmt.Fprintf(&b, " pods: %s;", strings.Join(e.Pods, ", "))
+		}
+		if len(e.Resources) > 0 {
+			fmt.Fprintf(&b, " resources: %s;", strings.Join(e.Resources, ", "))
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString(`Resolve references such as "that pod" or "the same namespace" against this conversation.`)
+	return b.String()
+}
+
+// compact folds every turn but the kept ones into the summary once the
+// rendered conversation outgrows the budget. The mechanical fallback
+// keeps the summary to about half the budget.
+func (s *Session) compact(ctx *AgentContext) {
+	if estimateTokens(s.render()) <= s.maxTokens || len(s.turns) <= sessionKeptTurns {
+		return
+	}
+	folded := s.turns[:len(s.turns)-sessionKeptTurns]
+	s.turns = append([]Turn(nil), s.turns[len(s.turns)-sessionKeptTurns:]...)
+
+	if summary, err := s.summarize(ctx, folded); err == nil && summary != "" {
+		s.summary = summary
+		return
+	}
+	lines := []string{}
+	if s.summary != "" {
+		lines = append(lines, s.summary)
+	}
+	for _, turn := range folded {
+		lines = append(lines, fmt.Sprintf("asked %q (%s)", truncate(turn.Input, 120), turn.Status))
+	}
+	s.summary = truncate(strings.Join(lines, "; "), s.maxTokens*2)
+}
+
+func (s *Session) summarize(ctx *AgentContext, folded []Turn) (string, error) {
+	if s.summarizer == nil || ctx == nil {
+		return "", fmt.Errorf("no summarizer")
+	}
+	if err := ctx.checkBudget(); err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	if s.summary != "" {
+		fmt.Fprintf(&b, "Summary so far: %s\n\n", s.summary)
+	}
+	for _, turn := range folded {
+		fmt.Fprintf(&b, "User: %s\nOutcome (%s): %s\n\n", turn.Input, turn.Status, turn.Result)
+	}
+	messages := []Message{
+		{Role: "system", Content: "You summarise Kubernetes operations conversations."},
+		{Role: "user", Content: fmt.Sprintf(`Summarise the following conversation in at most %d words. Keep every namespace, pod and resource name, what was found, what was changed and what failed.
+
+%s
+Respond with only the summary.`, s.maxTokens/4, b.String())},
+	}
+	resp, err := completeText(ctx.Context(), s.summarizer, messages)
+	if err != nil {
+		return "", err
+	}
+	_ = ctx.recordUsage(AgentTypeCoordinator, resp.Usage)
+	return strings.TrimSpace(resp.Content), nil
+}
+
+// noteEntities records the objects a task's input or output names.
+func (s *Session) noteEntities(values map[string]interface{}) {
+	str := func(keys ...string) string {
+		for _, key := range keys {
+			if v, _ := values[key].(string); v != "" {
+				return v
+			}
+		}
+		return ""
+	}
+	namespace := str("namespace")
+	if namespace != "" {
+		s.entities.Namespaces = pushRecent(s.entities.Namespaces, namespace)
+	}
+	if pod := str("pod_name", "podName"); pod != "" {
+		s.entities.Pods = pushRecent(s.entities.Pods, qualify(namespace, pod))
+	}
+	if kind, name := str("resource_kind"), str("resource_name"); kind != "" && name != "" && !strings.EqualFold(kind, "pod") {
+		s.entities.Resources = pushRecent(s.entities.Resources, kind+" "+qualify(namespace, name))
+	}
+}
+
+func qualify(namespace, name string) string {
+	if namespace == "" {
+		return name
+	}
+	return namespace + "/" + name
+}
+
+// pushRecent moves v to the front of list, capped at sessionMaxEntities.
+func pushRecent(list []string, v string) []string {
+	out := []string{v}
+	for _, item := range list {
+		if item != v && len(out) < sessionMaxEntities {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+// estimateTokens is a rough count, about four bytes per token for
+// English; the budget it is checked against is rough too.
+func estimateTokens(s string) int {
+	return (len(s) + 3) / 4
+}
+
+// truncate cuts s to n runes; the conversation is often not English.
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n]) + "…"
+}
+
+// withConversation prefixes prompt with the session's conversation, if
+// there is one.
+func withConversation(ctx *AgentContext, prompt string) string {
+	if ctx.session == nil {
+		return prompt
+	}
+	if conversation := ctx.session.Prompt(); conversation != "" {
+		return conversation + "\n\n" + prompt
+	}
+	return prompt
+}
@@ -0,0 +1,108 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func sessionTurn(session *Session, ctx *AgentContext, input, result string, tasks ...*Task) {
+	session.Record(ctx,
+		&Request{ID: input, Input: input},
+		&ExecutionPlan{Tasks: tasks},
+		&Response{Status: TaskStatusCompleted, Result: result})
+}
+
+func TestSession_CarriesTurnsAndEntitiesIntoPlan(t *testing.T) {
+	session := NewSession("s1")
+	ctx := NewAgentContext(context.Background(), "r1", "user", "trace")
+	ctx.SetSession(session)
+	sessionTurn(session, ctx, "why is web-1 crashing in demo", "web-1 is OOMKilled",
+		&Task{Input: map[string]interface{}{"pod_name": "web-1", "namespace": "demo"},
+			Output: map[string]interface{}{"resource_kind": "Deployment", "resource_name": "web", "namespace": "demo"}})
+
+	entities := session.Entities()
+	if len(entities.Namespaces) != 1 || entities.Namespaces[0] != "demo" || entities.Pods[0] != "demo/web-1" || entities.Resources[0] != "Deployment demo/web" {
+		t.Fatalf("Unexpected entities %+v", entities)
+	}
+
+	llm, seen := scriptedPlanner(`[{"id": "d", "type": "diagnose", "description": "check it", "assigned_agent": "diagnostician"}]`)
+	coordinator := newPlanningCoordinator(llm)
+	plan, err := coordinator.Plan(ctx, &Request{ID: "r2", Input: "now restart that pod"})
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+	prompt := (*seen)[0][1].Content
+	for _, want := range []string{"why is web-1 crashing in demo", "web-1 is OOMKilled", "pods: demo/web-1", "User Request: now restart that pod"} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("Expected the decomposition prompt to contain %q, got:\n%s", want, prompt)
+		}
+	}
+	if plan.Metadata["session"] != "s1" {
+		t.Errorf("Expected the plan to name its session, got %v", plan.Metadata["session"])
+	}
+}
+
+func TestSession_SpecialistPromptsSeeConversation(t *testing.T) {
+	var prompt string
+	llm := &MockLLMClient{CompleteFunc: func(ctx context.Context, messages []Message) (string, error) {
+		prompt = messages[1].Content
+		return "ok", nil
+	}}
+	session := NewSession("s1")
+	ctx := NewAgentContext(context.Background(), "r1", "user", "trace")
+	sessionTurn(session, ctx, "list pods in demo", "web-1 and web-2")
+	ctx.SetSession(session)
+
+	base := NewBaseAgent(&AgentConfig{Name: "diag", Type: AgentTypeDiagnostician}, llm, NewNoOpLogger())
+	if _, err := base.CallLLM(ctx, "system", "describe the first one"); err != nil {
+		t.Fatalf("CallLLM failed: %v", err)
+	}
+	if !strings.Contains(prompt, "list pods in demo") || !strings.HasSuffix(prompt, "describe the first one") {
+		t.Errorf("Expected the conversation before the prompt, got:\n%s", prompt)
+	}
+}
+
+func TestSession_SummarisesOlderTurns(t *testing.T) {
+	var asked string
+	summarizer := &MockLLMClient{CompleteFunc: func(ctx context.Context, messages []Message) (string, error) {
+		asked += messages[1].Content
+		return "checked web-1 and web-2 in demo", nil
+	}}
+	session := NewSession("s1").WithSummarizer(summarizer).WithMaxTokens(60)
+	ctx := NewAgentContext(context.Background(), "r", "user", "trace")
+	for _, input := range []string{"check web-1", "check web-2", "check web-3", "check web-4"} {
+		sessionTurn(session, ctx, input, strings.Repeat("finding ", 10))
+	}
+
+	prompt := session.Prompt()
+	if !strings.Contains(prompt, "Earlier: checked web-1 and web-2 in demo") || strings.Contains(prompt, "User: check web-1") ||
+		!strings.Contains(prompt, "User: check web-4") {
+		t.Errorf("Expected old turns summarised and the newest kept, got:\n%s", prompt)
+	}
+	if !strings.Contains(asked, "check web-1") {
+		t.Errorf("Expected the summariser to see the folded turns, got:\n%s", asked)
+	}
+	if len(session.History()) != 4 {
+		t.Errorf("Expected /history to keep every turn, got %d", len(session.History()))
+	}
+
+	session.Reset()
+	if session.Prompt() != "" || len(session.History()) != 0 || len(session.Entities().Pods) != 0 {
+		t.Error("Expected Reset to forget everything")
+	}
+}
+
+func TestSession_SummarisesMechanicallyWithoutLLM(t *testing.T) {
+	session := NewSession("s1").WithSummarizer(&MockLLMClient{CompleteFunc: func(ctx context.Context, messages []Message) (string, error) {
+		return "", errors.New("LLM unavailable")
+	}}).WithMaxTokens(60)
+	ctx := NewAgentContext(context.Background(), "r", "user", "trace")
+	for _, input := range []string{"check web-1", "check web-2", "check web-3"} {
+		sessionTurn(session, ctx, input, strings.Repeat("finding ", 10))
+	}
+	if prompt := session.Prompt(); !strings.Contains(prompt, `Earlier: asked "check web-1" (completed)`) {
+		t.Errorf("Expected a mechanical summary, got:\n%s", prompt)
+	}
+}
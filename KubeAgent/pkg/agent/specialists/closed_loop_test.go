@@ -0,0 +1,187 @@
+package specialists
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"kubeagent/pkg/agent"
+	"kubeagent/pkg/agent/harness"
+	"kubeagent/pkg/k8s"
+	pkgtools "kubeagent/pkg/tools"
+)
+
+// crashloopFixture is the k8s package's crash-looping cluster; the
+// specialists' tests run against the same one.
+const crashloopFixture = "../../k8s/testdata/crashloop.yaml"
+
+// fixedPodYAML is what the scripted Remediator "decides" to recreate.
+const fixedPodYAML = `apiVersion: v1
+kind: Pod
+metadata:
+  name: web-1
+  namespace: demo
+spec:
+  containers:
+    - name: web
+      image: example.com/web:1.4.2
+      env:
+        - name: DATABASE_URL
+          value: postgres://db.demo:5432/web
+status:
+  phase: Running
+`
+
+// scriptedClusterLLM plays the part of the model for one crash-looping
+// pod: plan diagnose → remediate (the fix consuming the diagnosis through
+// an input reference), read the logs, preview the fix with a
+// dry-run, then delete and recreate the pod with the missing env var. Each agent's turn is keyed
+// off its user prompt and the tool results seen so far.
+func scriptedClusterLLM(t *testing.T) *agent.MockLLMClient {
+	return &agent.MockLLMClient{
+		CompleteFunc: func(ctx context.Context, messages []agent.Message) (string, error) {
+			prompt := messages[len(messages)-1].Content
+			switch {
+			case strings.Contains(prompt, "identify the primary intent"):
+				return "remediate", nil
+			case strings.Contains(prompt, "Break down"):
+				return `[
+  {"id": "diagnose-web", "type": "diagnose", "assigned_agent": "diagnostician",
+   "description": "Find out why web-1 is crash-looping",
+   "input": {"pod_name": "web-1", "namespace": "demo"}, "dependencies": []},
+  {"id": "fix-web", "type": "remediate", "assigned_agent": "remediator",
+   "description": "Fix web-1",
+   "input": {"root_cause": "{{tasks.diagnose-web.output.root_cause}}"},
+   "dependencies": ["diagnose-web"], "condition": {"on_success": ["diagnose-web"]}}
+]`, nil
+			}
+			return "web-1 was restarted with DATABASE_URL set and is now Running.", nil
+		},
+		CompleteWithToolsFunc: func(ctx context.Context, messages []agent.Message, tools []agent.Tool) (*agent.LLMResponse, error) {
+			userPrompt := messages[1].Content
+			var toolResults []agent.Message
+			for _, m := range messages {
+				if m.Role == "tool" {
+					if m.IsError {
+						t.Errorf("Tool call failed: %s", m.Content)
+					}
+					toolResults = append(toolResults, m)
+				}
+			}
+
+			if strings.HasPrefix(userPrompt, "Diagnose") {
+				if len(toolResults) == 0 {
+					return toolCall("LogTool", map[string]interface{}{"podName": "web-1", "namespace": "demo"}), nil
+				}
+				diagnosis, _ := json.Marshal(map[string]interface{}{
+					"root_cause": "container exits on start: " + strings.TrimSpace(toolResults[0].Content),
+					"error_type": "CrashLoopBackOff",
+					"confidence": 0.9,
+				})
+				return &agent.LLMResponse{Content: string(diagnosis), FinishReason: "end_turn"}, nil
+			}
+
+			// The fix task's input only references the diagnosis; the
+			// coordinator resolves it and the target comes from
+			// upstream_outputs.
+			if !strings.Contains(userPrompt, "DATABASE_URL is not set") {
+				t.Errorf("Remediation prompt lacks the upstream diagnosis: %s", userPrompt)
+			}
+
+			switch len(toolResults) {
+			case 0:
+				return toolCall("CreateTool", map[string]interface{}{"yaml": fixedPodYAML, "dry_run": true}), nil
+			case 1:
+				if !strings.Contains(toolResults[0].Content, "+ spec.containers[0].env") {
+					t.Errorf("Dry-run diff missing env change: %s", toolResults[0].Content)
+				}
+				return toolCall("DeleteTool", map[string]interface{}{"resource": "pod", "name": "web-1", "namespace": "demo"}), nil
+			case 2:
+				return toolCall("CreateTool", map[string]interface{}{"yaml": fixedPodYAML}), nil
+			}
+			return &agent.LLMResponse{
+				Content:      `{"remediation_type": "config_change", "actions_taken": ["recreated web-1 with DATABASE_URL"], "risk_level": "medium"}`,
+				FinishReason: "end_turn",
+			}, nil
+		},
+	}
+}
+
+func toolCall(name string, args map[string]interface{}) *agent.LLMResponse {
+	return &agent.LLMResponse{
+		ToolCalls:    []agent.ToolCall{{ID: "call-" + name, Name: name, Arguments: args}},
+		FinishReason: "tool_use",
+	}
+}
+
+// TestClosedLoop_FakeCluster runs the same wiring as `kubeagent fix`
+// against the in-memory cluster: diagnose reads fixture logs, remediate
+// mutates the fake, and K8sVerifier confirms the pod converged.
+func TestClosedLoop_FakeCluster(t *testing.T) {
+	client, err := k8s.NewFakeClientFromFixtures(crashloopFixture)
+	if err != nil {
+		t.Fatalf("Failed to load fixtures: %v", err)
+	}
+
+	llm := scriptedClusterLLM(t)
+	logger := agent.NewNoOpLogger()
+	coordinator := agent.NewCoordinator(nil, llm, agent.NewMemoryStateStore(), logger)
+
+	diagnostician := NewDiagnosticianAgent(llm, logger)
+	diagnostician.AddTool(pkgtools.NewLogTool(client))
+	diagnostician.AddTool(pkgtools.NewEventTool(client))
+	coordinator.RegisterAgent(diagnostician)
+
+	preflight := harness.NewPreflightChain().
+		Add(harness.NewProtectedNamespaceCheck("kube-system")).
+		Add(harness.NewResourceExistsCheck(client))
+	remediator := NewRemediatorAgent(llm, logger).
+		WithVerifier(harness.NewK8sVerifier(client))
+	remediator.AddTool(pkgtools.NewDeleteTool(client).WithPreflight(preflight))
+	remediator.AddTool(pkgtools.NewCreateTool(client).WithPreflight(preflight))
+	coordinator.RegisterAgent(remediator)
+
+	ctx := agent.NewAgentContext(context.Background(), "req-closed-loop", "test-user", "trace-closed-loop")
+	plan, err := coordinator.Plan(ctx, &agent.Request{
+		ID:    "req-closed-loop",
+		Input: "web-1 in demo keeps crashing, fix it",
+	})
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+	if _, err := coordinator.ExecutePlan(ctx, plan); err != nil {
+		t.Fatalf("ExecutePlan failed: %v", err)
+	}
+
+	diagnosis := plan.Tasks[0]
+	if diagnosis.Status != agent.TaskStatusCompleted {
+		t.Fatalf("Diagnose task not completed: %s (%s)", diagnosis.Status, diagnosis.Error)
+	}
+	if rootCause, _ := diagnosis.Output["root_cause"].(string); !strings.Contains(rootCause, "DATABASE_URL is not set") {
+		t.Errorf("Diagnosis did not see fixture logs: %q", rootCause)
+	}
+
+	fix := plan.Tasks[1]
+	if fix.Status != agent.TaskStatusCompleted {
+		t.Fatalf("Remediate task not completed: %s (%s)", fix.Status, fix.Error)
+	}
+	if fix.Input["pod_name"] != "web-1" || fix.Input["namespace"] != "demo" {
+		t.Errorf("Expected the target to be adopted from the diagnosis, got %+v", fix.Input)
+	}
+	verification, ok := fix.Output["verification"].(*harness.VerificationResult)
+	if !ok || verification.Status != harness.VerificationPassed {
+		t.Errorf("Expected passed verification, got %+v", fix.Output["verification"])
+	}
+
+	state, err := client.GetResourceState("pod", "web-1", "demo")
+	if err != nil {
+		t.Fatalf("GetResourceState failed: %v", err)
+	}
+	if !state.Ready {
+		t.Errorf("Pod did not converge: %+v", state)
+	}
+	if plan.Status != agent.TaskStatusCompleted {
+		t.Errorf("Expected completed plan, got %s", plan.Status)
+	}
+}
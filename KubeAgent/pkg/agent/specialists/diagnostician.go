@@ -51,6 +51,17 @@ func (d *DiagnosticianAgent) CanHandle(taskType agent.TaskType) bool {
 	return taskType == agent.TaskTypeDiagnose || taskType == agent.TaskTypeQuery
 }
 
+// Capabilities advertises the diagnostician to the coordinator's
+// router. It reads any kind of object but never writes, so it is
+// cheaper than the remediator.
+func (d *DiagnosticianAgent) Capabilities() agent.Capabilities {
+	return agent.Capabilities{
+		TaskTypes: []agent.TaskType{agent.TaskTypeDiagnose, agent.TaskTypeQuery},
+		Tools:     d.ToolNames(),
+		CostTier:  agent.CostTierMedium,
+	}
+}
+
 // Execute executes a diagnostic task using the agentic tool-use loop
 func (d *DiagnosticianAgent) Execute(ctx *agent.AgentContext, task *agent.Task) (*agent.Task, error) {
 	startTime := time.Now()
@@ -68,7 +79,14 @@ func (d *DiagnosticianAgent) Execute(ctx *agent.AgentContext, task *agent.Task)
 	now := time.Now()
 	task.StartedAt = &now
 
-	diagnosis, err := d.diagnose(ctx, podName, namespace, task.Description)
+	// A re-diagnosis after a failed remediation starts from what that
+	// attempt did and what verification saw.
+	description := task.Description
+	if previous := describePreviousAttempt(task.Input); previous != "" {
+		description += "\n\n" + previous + "\nExplain why it did not work before proposing a root cause."
+	}
+
+	diagnosis, err := d.diagnose(ctx, podName, namespace, description)
 	if err != nil {
 		task.Status = agent.TaskStatusFailed
 		task.Error = err.Error()
@@ -31,6 +31,10 @@ type RemediatorAgent struct {
 	// skills sources the LLM-facing system prompt. nil means use the
 	// inline fallback (kept for backward compatibility).
 	skills *harness.Skills
+
+	// snapshots records pre-change objects and rolls them back when
+	// verification fails. nil disables rollback.
+	snapshots *Snapshotter
 }
 
 // NewRemediatorAgent creates a new remediator agent without any
@@ -76,11 +80,30 @@ func (r *RemediatorAgent) WithSkills(s *harness.Skills) *RemediatorAgent {
 	return r
 }
 
+// WithSnapshots enables automatic rollback. The same Snapshotter must be
+// passed to the write tools' WithSnapshots so their changes are
+// captured. Pass nil to keep rollback disabled.
+func (r *RemediatorAgent) WithSnapshots(s *Snapshotter) *RemediatorAgent {
+	r.snapshots = s
+	return r
+}
+
 // CanHandle checks if the remediator can handle a task type
 func (r *RemediatorAgent) CanHandle(taskType agent.TaskType) bool {
 	return taskType == agent.TaskTypeRemediate
 }
 
+// Capabilities advertises the remediator to the coordinator's router.
+// Writes are the most expensive thing an agent does: each one goes
+// through preflight, snapshots and verification.
+func (r *RemediatorAgent) Capabilities() agent.Capabilities {
+	return agent.Capabilities{
+		TaskTypes: []agent.TaskType{agent.TaskTypeRemediate},
+		Tools:     r.ToolNames(),
+		CostTier:  agent.CostTierHigh,
+	}
+}
+
 // Execute runs the remediation tool loop, then verifies the outcome.
 //
 // Verification policy:
@@ -93,13 +116,22 @@ func (r *RemediatorAgent) CanHandle(taskType agent.TaskType) bool {
 //   - The verification result becomes part of task.Output under
 //     "verification", so downstream consumers (Coordinator, UI, tests)
 //     can inspect it without re-running the check.
+//   - A failed verification triggers a rollback when a Snapshotter is
+//     wired: the objects the tools changed are restored and verified
+//     again, and the report lands in task.Output["rollback"]. The task
+//     still fails — the fix did not work — but the cluster is back
+//     where it started instead of wherever the LLM left it.
 func (r *RemediatorAgent) Execute(ctx *agent.AgentContext, task *agent.Task) (*agent.Task, error) {
 	startTime := time.Now()
+	// The write tools file snapshots under the task of their call;
+	// make sure that is this task even when called outside a plan.
+	ctx = ctx.ForTask(task.ID)
 
 	task.Status = agent.TaskStatusRunning
 	now := time.Now()
 	task.StartedAt = &now
 
+	adoptUpstreamDiagnosis(task)
 	rootCause, _ := task.Input["root_cause"].(string)
 	errorType, _ := task.Input["error_type"].(string)
 	diagnosis, _ := task.Input["diagnosis"].(map[string]any)
@@ -108,9 +140,20 @@ func (r *RemediatorAgent) Execute(ctx *agent.AgentContext, task *agent.Task) (*a
 		rootCause = task.Description
 	}
 
+	if r.snapshots != nil {
+		r.snapshots.begin(ctx.RequestID, task.ID)
+		defer r.snapshots.end(ctx.RequestID, task.ID)
+	}
+
 	// Phase 1: run the LLM-driven remediation tool loop.
-	result, err := r.remediate(ctx, rootCause, errorType, diagnosis)
+	result, err := r.remediate(ctx, rootCause, errorType, diagnosis, describePreviousAttempt(task.Input))
 	if err != nil {
+		// A loop that failed or timed out after a write must not be
+		// retried from scratch: the coordinator would redo the change
+		// on top of whatever the first attempt left behind.
+		if r.snapshots != nil && r.snapshots.captured(ctx.Context(), ctx.RequestID, task.ID) {
+			err = fmt.Errorf("%w: %w", err, agent.ErrChangesApplied)
+		}
 		// Audit the failure before returning, so operators see it.
 		r.audit(ctx, harness.AuditAction, task,
 			"remediation_failed", "failure", err.Error(), nil)
@@ -146,6 +189,10 @@ func (r *RemediatorAgent) Execute(ctx *agent.AgentContext, task *agent.Task) (*a
 		if verification.Status == harness.VerificationFailed {
 			task.Status = agent.TaskStatusFailed
 			task.Error = "post-action verification failed: " + verification.Summary
+			if rollback := r.rollback(ctx, task); rollback != nil {
+				result["rollback"] = rollback
+				task.Error += "; rollback " + rollback["outcome"].(string)
+			}
 			task.Output = result
 			task.Output["remediation_time"] = time.Since(startTime).String()
 			completedAt := time.Now()
@@ -170,11 +217,12 @@ func (r *RemediatorAgent) Analyze(ctx *agent.AgentContext, input map[string]any)
 	errorType, _ := input["error_type"].(string)
 	diagnosis, _ := input["diagnosis"].(map[string]any)
 
-	return r.remediate(ctx, rootCause, errorType, diagnosis)
+	return r.remediate(ctx, rootCause, errorType, diagnosis, describePreviousAttempt(input))
 }
 
-// remediate runs the agentic tool-use loop to generate and apply fixes
-func (r *RemediatorAgent) remediate(ctx *agent.AgentContext, rootCause, errorType string, diagnosis map[string]any) (map[string]any, error) {
+// remediate runs the agentic tool-use loop to generate and apply fixes.
+// previousAttempt, when set, is what an earlier iteration tried.
+func (r *RemediatorAgent) remediate(ctx *agent.AgentContext, rootCause, errorType string, diagnosis map[string]any, previousAttempt string) (map[string]any, error) {
 	systemPrompt := r.remediatePrompt()
 
 	diagnosisJSON, _ := json.Marshal(diagnosis)
@@ -186,6 +234,9 @@ Diagnosis Details: %s
 
 Use the available tools to fix the issue. Ask for human confirmation before applying dangerous changes.
 Return a summary in JSON format when done.`, rootCause, errorType, string(diagnosisJSON))
+	if previousAttempt != "" {
+		userPrompt += "\n\n" + previousAttempt + "\nDo not repeat that fix unless the diagnosis above explains why it would work now."
+	}
 
 	response, err := r.RunToolLoop(ctx, systemPrompt, userPrompt, 0)
 	if err != nil {
@@ -206,6 +257,84 @@ Return a summary in JSON format when done.`, rootCause, errorType, string(diagno
 	return result, nil
 }
 
+// rollback restores the task's snapshots and verifies the restored
+// state, auditing both. Returns nil when rollback is not wired.
+func (r *RemediatorAgent) rollback(ctx *agent.AgentContext, task *agent.Task) map[string]interface{} {
+	if r.snapshots == nil {
+		return nil
+	}
+
+	report, err := r.snapshots.rollback(ctx.Context(), ctx.RequestID, task.ID)
+	if err != nil {
+		r.audit(ctx, harness.AuditDecision, task, "rollback", "failure", err.Error(), nil)
+		return map[string]interface{}{"outcome": "failure", "error": err.Error()}
+	}
+
+	outcome := report.Outcome()
+	r.audit(ctx, harness.AuditDecision, task, "rollback", outcome,
+		"post-action verification failed",
+		map[string]interface{}{"steps": report.Steps})
+
+	summary := map[string]interface{}{
+		"outcome": outcome,
+		"steps":   report.Steps,
+	}
+	if outcome == "nothing_to_restore" {
+		return summary
+	}
+
+	verification := r.snapshots.verifyRestored(report)
+	r.audit(ctx, harness.AuditVerification, task,
+		"post_rollback_verify", string(verification.Status),
+		verification.Summary, verification.Observations)
+	summary["verification"] = verification
+	return summary
+}
+
+// adoptUpstreamDiagnosis fills what the remediator works from (root
+// cause, error type, the diagnosis itself and the target pod) from a
+// dependency's diagnosis in agent.UpstreamOutputsKey. Inputs the planner
+// wired explicitly win. Writing them into task.Input means verification,
+// rollback and audit all see the same target.
+func adoptUpstreamDiagnosis(task *agent.Task) {
+	upstream, _ := task.Input[agent.UpstreamOutputsKey].(map[string]any)
+	for _, dep := range task.Dependencies {
+		diagnosis, ok := upstream[dep].(map[string]any)
+		if !ok || diagnosis["root_cause"] == nil {
+			continue
+		}
+		if _, ok := task.Input["diagnosis"]; !ok {
+			task.Input["diagnosis"] = diagnosis
+		}
+		for _, key := range []string{"root_cause", "error_type", "pod_name", "namespace"} {
+			if v := stringFrom(diagnosis, key); v != "" && stringFrom(task.Input, key) == "" {
+				task.Input[key] = v
+			}
+		}
+		return
+	}
+}
+
+// describePreviousAttempt renders agent.PreviousAttemptKey, which the
+// coordinator sets when it re-diagnoses and retries a remediation that
+// failed verification, for the LLM. It returns "" on a first attempt.
+func describePreviousAttempt(input map[string]any) string {
+	attempt, ok := input[agent.PreviousAttemptKey].(map[string]any)
+	if !ok {
+		return ""
+	}
+	actions, _ := json.Marshal(attempt["actions_taken"])
+	verification, _ := json.Marshal(attempt["verification"])
+	note := fmt.Sprintf(`A previous remediation (iteration %v) did not fix the issue.
+Root cause it worked from: %v
+Actions it took: %s
+Post-action verification: %s`, attempt["iteration"], attempt["root_cause"], actions, verification)
+	if rollback, ok := attempt["rollback"]; ok {
+		note += fmt.Sprintf("\nIts changes were rolled back (%v).", rollback)
+	}
+	return note
+}
+
 // verifyOutcome runs the configured Verifier against whatever target the
 // task carried. We extract the target from task.Input (the same fields
 // the Diagnostician produced), because that is the most authoritative
@@ -338,13 +467,14 @@ func firstNonEmpty(values ...string) string {
 // fallbackRemediatePrompt mirrors pkg/agent/skills/remediate.md so the
 // agent still works when no Skills registry is wired in. Keep the two
 // in sync; the markdown file is authoritative.
-const fallbackRemediatePrompt = `You are a Kubernetes remediation expert. You have tools to create/delete resources, execute kubectl commands, and ask for human approval.
+const fallbackRemediatePrompt = `You are a Kubernetes remediation expert. You have tools to patch/apply/create/delete resources, execute kubectl commands, and ask for human approval.
 
 Your workflow:
 1. Analyze the diagnosis and generate a remediation plan
-2. For dangerous operations (delete, modify production resources), use the HumanTool to ask for confirmation before proceeding
-3. Apply the fix using available tools (CreateTool, DeleteTool, KubeTool)
-4. Report what actions were taken
+2. Before any PatchTool, ApplyTool or CreateTool call, run it with "dry_run": true and review the returned diff
+3. For dangerous operations (delete, modify production resources), use the HumanTool to ask for confirmation before proceeding; pending dry-run diffs are shown to the operator and must be approved. If it answers "rejected" (possibly with a reason), do not write; report the reason
+4. Apply the fix using available tools (PatchTool for in-place field changes, ApplyTool or CreateTool for full YAML, DeleteTool, KubeTool)
+5. Report what actions were taken
 
 Return your final result in JSON format:
 {
@@ -0,0 +1,262 @@
+package specialists
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"kubeagent/pkg/agent"
+	"kubeagent/pkg/agent/harness"
+	"kubeagent/pkg/k8s"
+	pkgtools "kubeagent/pkg/tools"
+)
+
+// badFixPodYAML is a "fix" that does not fix anything: the recreated pod
+// still reports CrashLoopBackOff, so verification fails.
+const badFixPodYAML = `apiVersion: v1
+kind: Pod
+metadata:
+  name: web-1
+  namespace: demo
+spec:
+  containers:
+    - name: web
+      image: example.com/web:1.5.0
+status:
+  phase: Running
+  containerStatuses:
+    - name: web
+      ready: false
+      state:
+        waiting:
+          reason: CrashLoopBackOff
+`
+
+type auditRecorder struct {
+	mu     sync.Mutex
+	events []harness.AuditEvent
+}
+
+func (a *auditRecorder) Record(_ context.Context, e harness.AuditEvent) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.events = append(a.events, e)
+	return nil
+}
+
+func (a *auditRecorder) find(action string) *harness.AuditEvent {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for i := range a.events {
+		if a.events[i].Action == action {
+			return &a.events[i]
+		}
+	}
+	return nil
+}
+
+// scriptedBadFixLLM scales the Deployment down, then delete-and-recreates
+// web-1 with a wrong image. Both changes must be undone by rollback.
+func scriptedBadFixLLM(t *testing.T) *agent.MockLLMClient {
+	scale := map[string]interface{}{
+		"resource": "deployment", "name": "web", "namespace": "demo",
+		"patch": `{"spec":{"replicas":1}}`,
+	}
+	return &agent.MockLLMClient{
+		CompleteWithToolsFunc: func(ctx context.Context, messages []agent.Message, tools []agent.Tool) (*agent.LLMResponse, error) {
+			steps := 0
+			for _, m := range messages {
+				if m.Role == "tool" {
+					if m.IsError {
+						t.Errorf("Tool call failed: %s", m.Content)
+					}
+					steps++
+				}
+			}
+
+			switch steps {
+			case 0:
+				dryRun := map[string]interface{}{"dry_run": true}
+				for k, v := range scale {
+					dryRun[k] = v
+				}
+				return toolCall("PatchTool", dryRun), nil
+			case 1:
+				return toolCall("PatchTool", scale), nil
+			case 2:
+				return toolCall("CreateTool", map[string]interface{}{"yaml": badFixPodYAML, "dry_run": true}), nil
+			case 3:
+				return toolCall("DeleteTool", map[string]interface{}{"resource": "pod", "name": "web-1", "namespace": "demo"}), nil
+			case 4:
+				return toolCall("CreateTool", map[string]interface{}{"yaml": badFixPodYAML}), nil
+			}
+			return &agent.LLMResponse{
+				Content:      `{"remediation_type": "config_change", "actions_taken": ["scaled web", "recreated web-1"], "risk_level": "medium"}`,
+				FinishReason: "end_turn",
+			}, nil
+		},
+	}
+}
+
+// TestRemediator_RollsBackFailedFix checks the undo path end to end:
+// snapshots taken by the write tools land in the StateStore, a failed
+// verification restores both objects, and the rollback and its own
+// verification are audited.
+func TestRemediator_RollsBackFailedFix(t *testing.T) {
+	client, err := k8s.NewFakeClientFromFixtures(crashloopFixture)
+	if err != nil {
+		t.Fatalf("Failed to load fixtures: %v", err)
+	}
+	store := agent.NewMemoryStateStore()
+	audit := &auditRecorder{}
+	snapshots := NewSnapshotter(client, store)
+
+	remediator := NewRemediatorAgent(scriptedBadFixLLM(t), agent.NewNoOpLogger()).
+		WithVerifier(harness.NewK8sVerifier(client)).
+		WithAuditor(audit).
+		WithSnapshots(snapshots)
+	remediator.AddTool(pkgtools.NewPatchTool(client).WithSnapshots(snapshots))
+	remediator.AddTool(pkgtools.NewDeleteTool(client).WithSnapshots(snapshots))
+	remediator.AddTool(pkgtools.NewCreateTool(client).WithSnapshots(snapshots))
+
+	ctx := agent.NewAgentContext(context.Background(), "req-rollback", "test-user", "trace-rollback")
+	task := &agent.Task{
+		ID:    "fix-web",
+		Type:  agent.TaskTypeRemediate,
+		Input: map[string]interface{}{"pod_name": "web-1", "namespace": "demo", "root_cause": "DATABASE_URL missing"},
+	}
+	task, err = remediator.Execute(ctx, task)
+	if err == nil || task.Status != agent.TaskStatusFailed {
+		t.Fatalf("Expected failed verification, got status %s (err %v)", task.Status, err)
+	}
+	if !strings.Contains(task.Error, "rollback success") {
+		t.Errorf("Expected rollback in task error, got %q", task.Error)
+	}
+
+	saved, err := store.LoadSnapshots(context.Background(), "req-rollback")
+	if err != nil {
+		t.Fatalf("LoadSnapshots failed: %v", err)
+	}
+	// web-1 is captured once (before the delete), not again before the
+	// re-create: rollback must return to the original pod.
+	if len(saved) != 2 || saved[0].Resource != "Deployment" || saved[1].Resource != "Pod" || saved[1].Tool != "DeleteTool" {
+		t.Fatalf("Unexpected snapshots: %+v", saved)
+	}
+
+	pod, err := client.GetResourceState("pod", "web-1", "demo")
+	if err != nil || !pod.Exists {
+		t.Fatalf("web-1 not restored: %+v (err %v)", pod, err)
+	}
+	if image := pod.Raw.Object["spec"].(map[string]interface{})["containers"].([]interface{})[0].(map[string]interface{})["image"]; image != "example.com/web:1.4.2" {
+		t.Errorf("Expected original image after rollback, got %v", image)
+	}
+	deploy, err := client.GetResourceState("deployment", "web", "demo")
+	if err != nil || !strings.HasSuffix(deploy.Phase, "/2") {
+		t.Errorf("Expected 2 replicas after rollback, got %+v (err %v)", deploy, err)
+	}
+
+	decision := audit.find("rollback")
+	if decision == nil || decision.Kind != harness.AuditDecision || decision.Outcome != "success" {
+		t.Errorf("Expected successful rollback decision event, got %+v", decision)
+	}
+	recheck := audit.find("post_rollback_verify")
+	if recheck == nil || recheck.Outcome != string(harness.VerificationPassed) {
+		t.Errorf("Expected passed post-rollback verification, got %+v", recheck)
+	}
+}
+
+// TestRemediator_FailureAfterWriteIsFinal checks a tool loop that dies
+// after a write is marked agent.ErrChangesApplied, so the coordinator
+// does not start the remediation over, while one that dies before any
+// write stays retryable.
+func TestRemediator_FailureAfterWriteIsFinal(t *testing.T) {
+	client, err := k8s.NewFakeClientFromFixtures(crashloopFixture)
+	if err != nil {
+		t.Fatalf("Failed to load fixtures: %v", err)
+	}
+	scale := map[string]interface{}{
+		"resource": "deployment", "name": "web", "namespace": "demo",
+		"patch": `{"spec":{"replicas":1}}`,
+	}
+	llm := &agent.MockLLMClient{
+		CompleteWithToolsFunc: func(ctx context.Context, messages []agent.Message, tools []agent.Tool) (*agent.LLMResponse, error) {
+			steps := 0
+			for _, m := range messages {
+				if m.Role == "tool" {
+					steps++
+				}
+			}
+			switch steps {
+			case 0:
+				dryRun := map[string]interface{}{"dry_run": true}
+				for k, v := range scale {
+					dryRun[k] = v
+				}
+				return toolCall("PatchTool", dryRun), nil
+			case 1:
+				return toolCall("PatchTool", scale), nil
+			}
+			return nil, context.DeadlineExceeded
+		},
+	}
+	snapshots := NewSnapshotter(client, agent.NewMemoryStateStore())
+	remediator := NewRemediatorAgent(llm, agent.NewNoOpLogger()).WithSnapshots(snapshots)
+	remediator.AddTool(pkgtools.NewPatchTool(client).WithSnapshots(snapshots))
+
+	ctx := agent.NewAgentContext(context.Background(), "req-partial", "test-user", "trace-partial")
+	task := &agent.Task{ID: "fix-web", Type: agent.TaskTypeRemediate, Input: map[string]interface{}{"root_cause": "too many replicas"}}
+	if _, err := remediator.Execute(ctx, task); !errors.Is(err, agent.ErrChangesApplied) {
+		t.Errorf("Expected ErrChangesApplied after the patch, got %v", err)
+	}
+
+	other := &agent.Task{ID: "fix-db", Type: agent.TaskTypeRemediate, Input: map[string]interface{}{"root_cause": "unknown"}}
+	llm.CompleteWithToolsFunc = func(context.Context, []agent.Message, []agent.Tool) (*agent.LLMResponse, error) {
+		return nil, context.DeadlineExceeded
+	}
+	if _, err := remediator.Execute(ctx, other); err == nil || errors.Is(err, agent.ErrChangesApplied) {
+		t.Errorf("Expected a retryable failure without writes, got %v", err)
+	}
+}
+
+// TestSnapshotter_FilesCapturesByCallScope checks that two tasks
+// remediating at once each get their own snapshots, whatever order
+// they started in.
+func TestSnapshotter_FilesCapturesByCallScope(t *testing.T) {
+	client, err := k8s.NewFakeClientFromFixtures(crashloopFixture)
+	if err != nil {
+		t.Fatalf("Failed to load fixtures: %v", err)
+	}
+	store := agent.NewMemoryStateStore()
+	snapshots := NewSnapshotter(client, store)
+	snapshots.begin("req-1", "fix-web")
+	snapshots.begin("req-2", "fix-pod")
+
+	first := harness.WithRequestScope(context.Background(), "req-1", "fix-web")
+	second := harness.WithRequestScope(context.Background(), "req-2", "fix-pod")
+	if err := snapshots.Capture(first, "PatchTool", "deployment", "web", "demo"); err != nil {
+		t.Fatalf("Capture failed: %v", err)
+	}
+	if err := snapshots.Capture(second, "DeleteTool", "pod", "web-1", "demo"); err != nil {
+		t.Fatalf("Capture failed: %v", err)
+	}
+	// The same object in another task is captured again for that task.
+	if err := snapshots.Capture(second, "PatchTool", "deployment", "web", "demo"); err != nil {
+		t.Fatalf("Capture failed: %v", err)
+	}
+
+	saved, _ := store.LoadSnapshots(context.Background(), "req-1")
+	if len(saved) != 1 || saved[0].TaskID != "fix-web" || saved[0].Resource != "Deployment" {
+		t.Errorf("Unexpected snapshots for req-1: %+v", saved)
+	}
+	saved, _ = store.LoadSnapshots(context.Background(), "req-2")
+	if len(saved) != 2 || saved[0].TaskID != "fix-pod" || saved[0].Resource != "Pod" || saved[1].Resource != "Deployment" {
+		t.Errorf("Unexpected snapshots for req-2: %+v", saved)
+	}
+
+	snapshots.end("req-1", "fix-web")
+	if err := snapshots.Capture(first, "PatchTool", "deployment", "web", "demo"); err == nil {
+		t.Error("Expected a capture after the task ended to be refused")
+	}
+}
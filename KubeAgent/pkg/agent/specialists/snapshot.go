@@ -0,0 +1,275 @@
+package specialists
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"kubeagent/pkg/agent"
+	"kubeagent/pkg/agent/harness"
+	"kubeagent/pkg/k8s"
+)
+
+// Snapshotter gives the Remediator an undo button.
+//
+// The write tools call Capture (via tools.SnapshotRecorder) right before
+// every mutation; the object as it was is saved to the StateStore under
+// the current request ID. When post-action verification fails, the
+// Remediator calls rollback to put every touched object back, newest
+// change first, and then checks the restored state.
+//
+// Only the first capture of an object per task is kept: if the LLM
+// patches the same Deployment twice, rollback must return it to the
+// state before the first patch, not the intermediate one.
+//
+// One Snapshotter serves parallel tasks and concurrent requests, so a
+// capture is filed under the request and task of the tool call's
+// context (harness.RequestScope), never under "the current task".
+type Snapshotter struct {
+	client *k8s.Client
+	store  agent.StateStore
+
+	mu   sync.Mutex
+	seen map[string]map[string]bool
+	seq  int
+}
+
+// NewSnapshotter wires the cluster client and the store snapshots are
+// persisted to. A durable store (BoltStateStore) lets an operator roll
+// back by hand after a crash, since snapshots outlive the process.
+func NewSnapshotter(client *k8s.Client, store agent.StateStore) *Snapshotter {
+	return &Snapshotter{client: client, store: store, seen: make(map[string]map[string]bool)}
+}
+
+// begin opens the capture scope of one remediation attempt; end closes
+// it. Captures outside an open scope are refused.
+func (s *Snapshotter) begin(requestID, taskID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.seen[requestID+"/"+taskID] = make(map[string]bool)
+}
+
+func (s *Snapshotter) end(requestID, taskID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.seen, requestID+"/"+taskID)
+}
+
+// Capture implements tools.SnapshotRecorder.
+func (s *Snapshotter) Capture(ctx context.Context, tool, resource, name, namespace string) error {
+	requestID, taskID := harness.RequestScope(ctx)
+	state, err := s.client.GetResourceState(resource, name, namespace)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	seen, ok := s.seen[requestID+"/"+taskID]
+	if requestID == "" || !ok {
+		return fmt.Errorf("no remediation in progress")
+	}
+
+	key := strings.Join([]string{state.Kind, namespace, name}, "/")
+	if seen[key] {
+		return nil
+	}
+
+	s.seq++
+	snapshot := &agent.ResourceSnapshot{
+		ID:        fmt.Sprintf("%s-%d", taskID, s.seq),
+		RequestID: requestID,
+		TaskID:    taskID,
+		Tool:      tool,
+		Resource:  state.Kind,
+		Name:      name,
+		Namespace: namespace,
+		TakenAt:   time.Now(),
+	}
+	if state.Exists {
+		snapshot.Object = runtime.DeepCopyJSON(state.Raw.Object)
+	}
+	if err := s.store.SaveSnapshot(context.WithoutCancel(ctx), snapshot); err != nil {
+		return err
+	}
+	seen[key] = true
+	return nil
+}
+
+// captured reports whether taskID snapshotted any object, in this or
+// an earlier attempt, that is whether it wrote to the cluster. When the
+// store cannot tell, it assumes it did.
+func (s *Snapshotter) captured(ctx context.Context, requestID, taskID string) bool {
+	snapshots, err := s.store.LoadSnapshots(context.WithoutCancel(ctx), requestID)
+	if err != nil {
+		return true
+	}
+	for _, snapshot := range snapshots {
+		if snapshot.TaskID == taskID {
+			return true
+		}
+	}
+	return false
+}
+
+// Rollback step outcomes.
+const (
+	RollbackRestored = "restored"
+	RollbackSkipped  = "skipped"
+	RollbackFailed   = "failed"
+)
+
+// RollbackStep is what happened to one snapshotted object.
+type RollbackStep struct {
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	Tool      string `json:"tool"`
+	Outcome   string `json:"outcome"`
+	Message   string `json:"message"`
+
+	snapshot *agent.ResourceSnapshot
+}
+
+// RollbackReport summarises a rollback for task output and audit.
+type RollbackReport struct {
+	Steps []RollbackStep `json:"steps"`
+}
+
+// Outcome rolls the steps up into one audit outcome: "success" when
+// every restore worked, "failure" when none did, "partial" otherwise,
+// and "nothing_to_restore" when no write was snapshotted.
+func (r *RollbackReport) Outcome() string {
+	restored, failed := 0, 0
+	for _, step := range r.Steps {
+		switch step.Outcome {
+		case RollbackRestored:
+			restored++
+		case RollbackFailed:
+			failed++
+		}
+	}
+	switch {
+	case restored == 0 && failed == 0:
+		return "nothing_to_restore"
+	case failed == 0:
+		return "success"
+	case restored == 0:
+		return "failure"
+	}
+	return "partial"
+}
+
+// rollback restores every object snapshotted for taskID, newest change
+// first. Objects with a controller owner (Pods of a ReplicaSet, ...)
+// are skipped: the controller has already replaced them, and
+// re-creating the old Pod would only fight it. Rolling back the owner
+// is what brings the old Pods back.
+func (s *Snapshotter) rollback(ctx context.Context, requestID, taskID string) (*RollbackReport, error) {
+	snapshots, err := s.store.LoadSnapshots(ctx, requestID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load snapshots: %w", err)
+	}
+
+	report := &RollbackReport{Steps: make([]RollbackStep, 0)}
+	for i := len(snapshots) - 1; i >= 0; i-- {
+		snapshot := snapshots[i]
+		if snapshot.TaskID != taskID {
+			continue
+		}
+		step := RollbackStep{
+			Kind:      snapshot.Resource,
+			Name:      snapshot.Name,
+			Namespace: snapshot.Namespace,
+			Tool:      snapshot.Tool,
+			snapshot:  snapshot,
+		}
+
+		if owner := controllerOwner(snapshot.Object); owner != "" {
+			step.Outcome = RollbackSkipped
+			step.Message = fmt.Sprintf("managed by %s; its controller reconciles this object", owner)
+		} else if msg, err := s.client.RestoreResource(snapshot.Resource, snapshot.Name, snapshot.Namespace, snapshot.Object); err != nil {
+			step.Outcome = RollbackFailed
+			step.Message = err.Error()
+		} else {
+			step.Outcome = RollbackRestored
+			step.Message = msg
+		}
+		report.Steps = append(report.Steps, step)
+	}
+	return report, nil
+}
+
+// verifyRestored is the second verification: it does not ask whether
+// the workload is healthy (it was not, before the remediation) but
+// whether each restored object matches its snapshot again.
+func (s *Snapshotter) verifyRestored(report *RollbackReport) *harness.VerificationResult {
+	result := &harness.VerificationResult{
+		Status:       harness.VerificationPassed,
+		Observations: map[string]interface{}{},
+		CheckedAt:    time.Now(),
+	}
+
+	checked := 0
+	var mismatched []string
+	for _, step := range report.Steps {
+		if step.Outcome != RollbackRestored {
+			continue
+		}
+		checked++
+		target := fmt.Sprintf("%s %s/%s", step.Kind, step.Namespace, step.Name)
+
+		state, err := s.client.GetResourceState(step.Kind, step.Name, step.Namespace)
+		switch {
+		case err != nil:
+			mismatched = append(mismatched, target)
+			result.Observations[target] = err.Error()
+		case step.snapshot.Object == nil && state.Exists:
+			mismatched = append(mismatched, target)
+			result.Observations[target] = "still exists, expected it to be deleted"
+		case step.snapshot.Object != nil && !state.Exists:
+			mismatched = append(mismatched, target)
+			result.Observations[target] = "missing, expected it to be restored"
+		case step.snapshot.Object != nil:
+			if changes := k8s.DiffObjects(step.snapshot.Object, state.Raw.Object); len(changes) > 0 {
+				mismatched = append(mismatched, target)
+				result.Observations[target] = changes
+			} else {
+				result.Observations[target] = "matches snapshot"
+			}
+		default:
+			result.Observations[target] = "absent, as before the change"
+		}
+	}
+
+	switch {
+	case checked == 0:
+		result.Status = harness.VerificationInconclusive
+		result.Summary = "nothing was restored"
+	case len(mismatched) > 0:
+		result.Status = harness.VerificationFailed
+		result.Summary = "restored state differs from snapshot: " + strings.Join(mismatched, ", ")
+	default:
+		result.Summary = fmt.Sprintf("%d object(s) match their pre-change snapshot", checked)
+	}
+	return result
+}
+
+// controllerOwner returns "Kind/name" of obj's controller owner
+// reference, or "" when it has none.
+func controllerOwner(obj map[string]interface{}) string {
+	if obj == nil {
+		return ""
+	}
+	u := &unstructured.Unstructured{Object: obj}
+	if ref := metav1.GetControllerOfNoCopy(u); ref != nil {
+		return ref.Kind + "/" + ref.Name
+	}
+	return ""
+}
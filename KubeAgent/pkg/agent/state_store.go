@@ -3,23 +3,30 @@ package agent
 import (
 	"context"
 	"fmt"
+	"sort"
 	"sync"
+
+	"kubeagent/pkg/agent/harness"
 )
 
 // MemoryStateStore is an in-memory implementation of StateStore
 type MemoryStateStore struct {
-	contexts map[string]*AgentContext
-	tasks    map[string]*Task
-	plans    map[string]*ExecutionPlan
-	mu       sync.RWMutex
+	contexts  map[string]*AgentContext
+	tasks     map[string]*Task
+	plans     map[string]*ExecutionPlan
+	snapshots map[string][]*ResourceSnapshot
+	approvals map[string]*harness.ApprovalRecord
+	mu        sync.RWMutex
 }
 
 // NewMemoryStateStore creates a new in-memory state store
 func NewMemoryStateStore() *MemoryStateStore {
 	return &MemoryStateStore{
-		contexts: make(map[string]*AgentContext),
-		tasks:    make(map[string]*Task),
-		plans:    make(map[string]*ExecutionPlan),
+		contexts:  make(map[string]*AgentContext),
+		tasks:     make(map[string]*Task),
+		plans:     make(map[string]*ExecutionPlan),
+		snapshots: make(map[string][]*ResourceSnapshot),
+		approvals: make(map[string]*harness.ApprovalRecord),
 	}
 }
 
@@ -103,6 +110,67 @@ func (m *MemoryStateStore) UpdateTaskStatus(ctx context.Context, taskID string,
 	return nil
 }
 
+// SaveSnapshot appends a snapshot to its request's list
+func (m *MemoryStateStore) SaveSnapshot(ctx context.Context, snapshot *ResourceSnapshot) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if snapshot.RequestID == "" {
+		return fmt.Errorf("snapshot %s has no request ID", snapshot.ID)
+	}
+	m.snapshots[snapshot.RequestID] = append(m.snapshots[snapshot.RequestID], snapshot)
+	return nil
+}
+
+// LoadSnapshots returns a request's snapshots in the order taken. A
+// request without snapshots yields an empty list, not an error.
+func (m *MemoryStateStore) LoadSnapshots(ctx context.Context, requestID string) ([]*ResourceSnapshot, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	snapshots := make([]*ResourceSnapshot, len(m.snapshots[requestID]))
+	copy(snapshots, m.snapshots[requestID])
+	return snapshots, nil
+}
+
+// SaveApproval stores an approval, replacing an earlier record with
+// the same ID
+func (m *MemoryStateStore) SaveApproval(ctx context.Context, record *harness.ApprovalRecord) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if record.ID == "" {
+		return fmt.Errorf("approval has no ID")
+	}
+	copied := *record
+	m.approvals[record.ID] = &copied
+	return nil
+}
+
+// LoadApprovals returns every stored approval, oldest first
+func (m *MemoryStateStore) LoadApprovals(ctx context.Context) ([]*harness.ApprovalRecord, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	records := make([]*harness.ApprovalRecord, 0, len(m.approvals))
+	for _, record := range m.approvals {
+		copied := *record
+		records = append(records, &copied)
+	}
+	sortApprovals(records)
+	return records, nil
+}
+
+// sortApprovals orders approvals oldest first, by ID on ties.
+func sortApprovals(records []*harness.ApprovalRecord) {
+	sort.Slice(records, func(i, j int) bool {
+		if !records[i].CreatedAt.Equal(records[j].CreatedAt) {
+			return records[i].CreatedAt.Before(records[j].CreatedAt)
+		}
+		return records[i].ID < records[j].ID
+	})
+}
+
 // GetAllTasks returns all tasks (useful for debugging)
 func (m *MemoryStateStore) GetAllTasks() []*Task {
 	m.mu.RLock()
@@ -127,6 +195,16 @@ func (m *MemoryStateStore) GetAllPlans() []*ExecutionPlan {
 	return plans
 }
 
+// ListPlans returns every stored plan, newest first, like
+// BoltStateStore.ListPlans.
+func (m *MemoryStateStore) ListPlans(ctx context.Context) ([]*ExecutionPlan, error) {
+	plans := m.GetAllPlans()
+	sort.Slice(plans, func(i, j int) bool {
+		return plans[i].CreatedAt.After(plans[j].CreatedAt)
+	})
+	return plans, nil
+}
+
 // Clear clears all stored data (useful for testing)
 func (m *MemoryStateStore) Clear() {
 	m.mu.Lock()
@@ -135,4 +213,6 @@ func (m *MemoryStateStore) Clear() {
 	m.contexts = make(map[string]*AgentContext)
 	m.tasks = make(map[string]*Task)
 	m.plans = make(map[string]*ExecutionPlan)
+	m.snapshots = make(map[string][]*ResourceSnapshot)
+	m.approvals = make(map[string]*harness.ApprovalRecord)
 }
@@ -0,0 +1,74 @@
+package agent
+
+import (
+	"context"
+	"unicode/utf8"
+)
+
+// StreamEventType identifies what a StreamEvent reports.
+type StreamEventType string
+
+const (
+	// StreamTaskStarted and StreamTaskFinished bracket each task the
+	// coordinator hands to a specialist.
+	StreamTaskStarted  StreamEventType = "task_started"
+	StreamTaskFinished StreamEventType = "task_finished"
+	// StreamTextDelta is a chunk of the model's visible reply.
+	StreamTextDelta StreamEventType = "text_delta"
+	// StreamThinkingDelta is a chunk of extended-thinking output, for
+	// providers and models that expose it.
+	StreamThinkingDelta StreamEventType = "thinking_delta"
+	// StreamToolCall is emitted once the model has finished a tool call
+	// (arguments complete), right before the tool runs.
+	StreamToolCall StreamEventType = "tool_call"
+	// StreamToolResult carries the (truncated) tool output.
+	StreamToolResult StreamEventType = "tool_result"
+)
+
+// StreamEvent is one unit of live progress. Agent and TaskID are filled
+// in by the tool loop, so LLM clients only set Type and Text.
+type StreamEvent struct {
+	Type     StreamEventType `json:"type"`
+	Agent    AgentType       `json:"agent,omitempty"`
+	TaskID   string          `json:"task_id,omitempty"`
+	Text     string          `json:"text,omitempty"`
+	ToolCall *ToolCall       `json:"tool_call,omitempty"`
+	IsError  bool            `json:"is_error,omitempty"`
+	Status   TaskStatus      `json:"status,omitempty"`
+}
+
+// StreamHandler receives stream events. Tasks in the same dependency
+// round run concurrently, so a handler may be called from several
+// goroutines and must do its own locking.
+type StreamHandler func(event StreamEvent)
+
+// StreamingLLMClient is an optional extension of LLMClient for
+// providers that can stream. The final LLMResponse is the same one
+// CompleteWithTools would have returned; onEvent sees text and
+// thinking deltas while it is being generated. tools may be empty.
+//
+// Clients that do not implement it still work with a stream handler:
+// the tool loop emits each reply as a single delta once it arrives.
+type StreamingLLMClient interface {
+	LLMClient
+	StreamWithTools(ctx context.Context, messages []Message, tools []Tool, onEvent StreamHandler) (*LLMResponse, error)
+}
+
+// maxStreamedToolResult caps the tool output copied into a
+// StreamToolResult event. Logs can be megabytes; the conversation keeps
+// the full text, the progress display only needs a glimpse.
+const maxStreamedToolResult = 2000
+
+// truncateForStream cuts s to maxStreamedToolResult bytes without
+// splitting a UTF-8 sequence (tool descriptions and results are often
+// Chinese).
+func truncateForStream(s string) string {
+	if len(s) <= maxStreamedToolResult {
+		return s
+	}
+	cut := maxStreamedToolResult
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut] + "…"
+}
@@ -0,0 +1,177 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	"kubeagent/pkg/agent/harness"
+)
+
+// streamingMock thinks, calls LogTool once, then streams its answer in
+// two deltas.
+type streamingMock struct {
+	MockLLMClient
+}
+
+func (m *streamingMock) StreamWithTools(ctx context.Context, messages []Message, tools []Tool, onEvent StreamHandler) (*LLMResponse, error) {
+	if messages[len(messages)-1].Role != "tool" {
+		onEvent(StreamEvent{Type: StreamThinkingDelta, Text: "need the logs"})
+		return &LLMResponse{
+			ToolCalls:    []ToolCall{{ID: "call-1", Name: "LogTool", Arguments: map[string]interface{}{"pod": "web-1"}}},
+			FinishReason: "tool_use",
+		}, nil
+	}
+	onEvent(StreamEvent{Type: StreamTextDelta, Text: "OOM"})
+	onEvent(StreamEvent{Type: StreamTextDelta, Text: "Killed"})
+	return &LLMResponse{Content: "OOMKilled", FinishReason: "end_turn"}, nil
+}
+
+type bigLogTool struct{}
+
+func (bigLogTool) Name() string        { return "LogTool" }
+func (bigLogTool) Description() string { return "logs" }
+func (bigLogTool) ArgsSchema() string  { return `{"type":"object"}` }
+func (bigLogTool) Execute(map[string]interface{}) (string, error) {
+	return strings.Repeat("日志", maxStreamedToolResult), nil
+}
+
+type eventRecorder struct {
+	mu     sync.Mutex
+	events []StreamEvent
+}
+
+func (r *eventRecorder) handle(e StreamEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, e)
+}
+
+func (r *eventRecorder) types() []StreamEventType {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	types := make([]StreamEventType, len(r.events))
+	for i, e := range r.events {
+		types[i] = e.Type
+	}
+	return types
+}
+
+func TestRunToolLoop_StreamsEvents(t *testing.T) {
+	b := NewBaseAgent(&AgentConfig{Name: "diag", Type: AgentTypeDiagnostician}, &streamingMock{}, NewNoOpLogger())
+	b.AddTool(bigLogTool{})
+
+	recorder := &eventRecorder{}
+	ctx := NewAgentContext(context.Background(), "req", "user", "trace").ForTask("task-1")
+	ctx.SetStreamHandler(recorder.handle)
+
+	out, err := b.RunToolLoop(ctx, "system", "why is web-1 crashing?", 5)
+	if err != nil || out != "OOMKilled" {
+		t.Fatalf("Unexpected result %q (err %v)", out, err)
+	}
+
+	want := []StreamEventType{StreamThinkingDelta, StreamToolCall, StreamToolResult, StreamTextDelta, StreamTextDelta}
+	if got := recorder.types(); strings.Join(toStrings(got), ",") != strings.Join(toStrings(want), ",") {
+		t.Fatalf("Expected events %v, got %v", want, got)
+	}
+	for _, e := range recorder.events {
+		if e.Agent != AgentTypeDiagnostician || e.TaskID != "task-1" {
+			t.Errorf("Event not attributed to agent and task: %+v", e)
+		}
+	}
+	result := recorder.events[2]
+	if result.ToolCall == nil || result.ToolCall.Name != "LogTool" || result.IsError {
+		t.Errorf("Unexpected tool result event: %+v", result)
+	}
+	if len(result.Text) > maxStreamedToolResult+len("…") || !strings.HasSuffix(result.Text, "…") {
+		t.Errorf("Tool result not truncated: %d bytes", len(result.Text))
+	}
+}
+
+// scopedLogTool is a ContextTool remembering the scope it ran under.
+type scopedLogTool struct {
+	bigLogTool
+	requestID, taskID string
+}
+
+func (s *scopedLogTool) ExecuteContext(ctx context.Context, params map[string]interface{}) (string, error) {
+	s.requestID, s.taskID = harness.RequestScope(ctx)
+	return "ok", nil
+}
+
+// A ContextTool is called with the request's context, tagged with the
+// request and task, so an approval it waits on can name both.
+func TestRunToolLoop_ScopesContextTools(t *testing.T) {
+	b := NewBaseAgent(&AgentConfig{Name: "diag", Type: AgentTypeDiagnostician}, &streamingMock{}, NewNoOpLogger())
+	tool := &scopedLogTool{}
+	b.AddTool(tool)
+
+	ctx := NewAgentContext(context.Background(), "req", "user", "trace").ForTask("task-1")
+	ctx.SetStreamHandler((&eventRecorder{}).handle)
+	if _, err := b.RunToolLoop(ctx, "system", "why is web-1 crashing?", 5); err != nil {
+		t.Fatalf("RunToolLoop failed: %v", err)
+	}
+	if tool.requestID != "req" || tool.taskID != "task-1" {
+		t.Errorf("Expected the tool to run for req/task-1, got %q/%q", tool.requestID, tool.taskID)
+	}
+}
+
+// A plain LLMClient still streams: each reply arrives as one delta.
+func TestRunToolLoop_NonStreamingClientEmitsWholeReply(t *testing.T) {
+	b := NewBaseAgent(&AgentConfig{Name: "diag", Type: AgentTypeDiagnostician}, &MockLLMClient{}, NewNoOpLogger())
+	recorder := &eventRecorder{}
+	ctx := NewAgentContext(context.Background(), "req", "user", "trace")
+	ctx.SetStreamHandler(recorder.handle)
+
+	if _, err := b.RunToolLoop(ctx, "system", "hi", 1); err != nil {
+		t.Fatalf("RunToolLoop failed: %v", err)
+	}
+	if len(recorder.events) != 1 || recorder.events[0].Text != "Mock response" {
+		t.Errorf("Expected a single whole-reply delta, got %+v", recorder.events)
+	}
+}
+
+func TestCoordinatorExecute_EmitsTaskEvents(t *testing.T) {
+	coordinator := NewCoordinator(nil, &MockLLMClient{}, NewMemoryStateStore(), NewNoOpLogger())
+	coordinator.RegisterAgent(&MockSpecialistAgent{
+		name:          "diag",
+		agentType:     AgentTypeDiagnostician,
+		canHandleFunc: func(TaskType) bool { return true },
+		executeFunc: func(ctx *AgentContext, task *Task) (*Task, error) {
+			ctx.Emit(StreamEvent{Type: StreamTextDelta, Text: "working"})
+			return task, nil
+		},
+	})
+
+	recorder := &eventRecorder{}
+	ctx := NewAgentContext(context.Background(), "req", "user", "trace")
+	ctx.SetStreamHandler(recorder.handle)
+
+	task := &Task{ID: "t1", Type: TaskTypeDiagnose, Description: "check web-1"}
+	if _, err := coordinator.Execute(ctx, task); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	if len(recorder.events) != 3 {
+		t.Fatalf("Expected started, delta, finished; got %+v", recorder.events)
+	}
+	started, delta, finished := recorder.events[0], recorder.events[1], recorder.events[2]
+	if started.Type != StreamTaskStarted || started.Text != "check web-1" || started.Agent != AgentTypeDiagnostician {
+		t.Errorf("Unexpected start event: %+v", started)
+	}
+	if delta.TaskID != "t1" {
+		t.Errorf("Agent event not scoped to task: %+v", delta)
+	}
+	if finished.Type != StreamTaskFinished || finished.Status != TaskStatusCompleted {
+		t.Errorf("Unexpected finish event: %+v", finished)
+	}
+}
+
+func toStrings(types []StreamEventType) []string {
+	out := make([]string, len(types))
+	for i, t := range types {
+		out[i] = string(t)
+	}
+	return out
+}
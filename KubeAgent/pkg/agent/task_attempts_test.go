@@ -0,0 +1,131 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"kubeagent/pkg/agent/harness"
+)
+
+var noBackoff = harness.RetryPolicy{InitialBackoff: time.Millisecond, Jitter: 0}
+
+func attemptAgent(maxRetries int, timeout time.Duration, execute func(*AgentContext, *Task) (*Task, error)) *MockSpecialistAgent {
+	return &MockSpecialistAgent{
+		name:          "diag",
+		agentType:     AgentTypeDiagnostician,
+		canHandleFunc: func(TaskType) bool { return true },
+		executeFunc:   execute,
+		config:        &AgentConfig{Name: "diag", Type: AgentTypeDiagnostician, MaxRetries: maxRetries, Timeout: timeout},
+	}
+}
+
+func TestExecute_RetriesUpToMaxRetries(t *testing.T) {
+	calls := 0
+	coordinator := NewCoordinator(nil, &MockLLMClient{}, nil, NewNoOpLogger()).WithTaskRetryPolicy(noBackoff)
+	coordinator.RegisterAgent(attemptAgent(2, 0, func(ctx *AgentContext, task *Task) (*Task, error) {
+		calls++
+		if calls < 3 {
+			return task, errors.New("LLM API call failed: 529 overloaded")
+		}
+		task.Output = map[string]interface{}{"result": "ok"}
+		return task, nil
+	}))
+
+	task := &Task{ID: "t1", Type: TaskTypeDiagnose, AssignedAgent: AgentTypeDiagnostician}
+	_, err := coordinator.Execute(NewAgentContext(context.Background(), "req", "user", "trace"), task)
+	if err != nil || task.Status != TaskStatusCompleted {
+		t.Fatalf("Expected success on the third attempt, got %s (err %v)", task.Status, err)
+	}
+	if len(task.Attempts) != 3 || task.Attempts[0].Status != TaskStatusFailed || task.Attempts[2].Status != TaskStatusCompleted || task.Attempts[2].Number != 3 {
+		t.Errorf("Unexpected attempt history %+v", task.Attempts)
+	}
+	if task.Error != "" {
+		t.Errorf("Expected the error of failed attempts to be cleared, got %q", task.Error)
+	}
+}
+
+func TestExecute_TimeoutPerAttempt(t *testing.T) {
+	coordinator := NewCoordinator(nil, &MockLLMClient{}, nil, NewNoOpLogger()).WithTaskRetryPolicy(noBackoff)
+	coordinator.RegisterAgent(attemptAgent(1, 20*time.Millisecond, func(ctx *AgentContext, task *Task) (*Task, error) {
+		<-ctx.Context().Done()
+		return task, ctx.Context().Err()
+	}))
+
+	task := &Task{ID: "t1", Type: TaskTypeDiagnose, AssignedAgent: AgentTypeDiagnostician}
+	_, err := coordinator.Execute(NewAgentContext(context.Background(), "req", "user", "trace"), task)
+	if err == nil || task.Status != TaskStatusFailed {
+		t.Fatalf("Expected the task to fail, got %s (err %v)", task.Status, err)
+	}
+	if len(task.Attempts) != 2 || !strings.Contains(task.Attempts[1].Error, "timed out after 20ms") {
+		t.Errorf("Expected two timed-out attempts, got %+v", task.Attempts)
+	}
+	if !strings.Contains(task.Error, "failed after 2 attempts") {
+		t.Errorf("Unexpected task error %q", task.Error)
+	}
+}
+
+func TestExecute_BudgetErrorIsNotRetried(t *testing.T) {
+	calls := 0
+	coordinator := NewCoordinator(nil, &MockLLMClient{}, nil, NewNoOpLogger()).WithTaskRetryPolicy(noBackoff)
+	coordinator.RegisterAgent(attemptAgent(3, 0, func(ctx *AgentContext, task *Task) (*Task, error) {
+		calls++
+		return task, ErrBudgetExceeded
+	}))
+
+	task := &Task{ID: "t1", Type: TaskTypeDiagnose, AssignedAgent: AgentTypeDiagnostician}
+	_, err := coordinator.Execute(NewAgentContext(context.Background(), "req", "user", "trace"), task)
+	if !errors.Is(err, ErrBudgetExceeded) || calls != 1 {
+		t.Errorf("Expected one attempt ending in ErrBudgetExceeded, got %d (err %v)", calls, err)
+	}
+}
+
+func TestExecute_FailureAfterWriteIsNotRetried(t *testing.T) {
+	calls := 0
+	coordinator := NewCoordinator(nil, &MockLLMClient{}, nil, NewNoOpLogger()).WithTaskRetryPolicy(noBackoff)
+	coordinator.RegisterAgent(attemptAgent(3, 0, func(ctx *AgentContext, task *Task) (*Task, error) {
+		calls++
+		return task, fmt.Errorf("remediation failed: timed out: %w", ErrChangesApplied)
+	}))
+
+	task := &Task{ID: "t1", Type: TaskTypeDiagnose, AssignedAgent: AgentTypeDiagnostician}
+	_, err := coordinator.Execute(NewAgentContext(context.Background(), "req", "user", "trace"), task)
+	if !errors.Is(err, ErrChangesApplied) || calls != 1 {
+		t.Errorf("Expected one attempt ending in ErrChangesApplied, got %d (err %v)", calls, err)
+	}
+}
+
+func TestExecutePlan_CancellationMarksTasksCancelled(t *testing.T) {
+	parent, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	coordinator := NewCoordinator(nil, &MockLLMClient{}, NewMemoryStateStore(), NewNoOpLogger()).WithTaskRetryPolicy(noBackoff)
+	coordinator.RegisterAgent(attemptAgent(3, time.Minute, func(ctx *AgentContext, task *Task) (*Task, error) {
+		cancel() // operator hits Ctrl-C while the first task runs
+		<-ctx.Context().Done()
+		return task, ctx.Context().Err()
+	}))
+
+	plan := &ExecutionPlan{ID: "plan", Tasks: []*Task{
+		{ID: "diagnose", Type: TaskTypeDiagnose, AssignedAgent: AgentTypeDiagnostician},
+		{ID: "fix", Type: TaskTypeDiagnose, AssignedAgent: AgentTypeDiagnostician, Dependencies: []string{"diagnose"}},
+	}}
+	response, err := coordinator.ExecutePlan(NewAgentContext(parent, "req", "user", "trace"), plan)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+	if response == nil || response.Status != TaskStatusCancelled || plan.Status != TaskStatusCancelled {
+		t.Fatalf("Expected a cancelled response and plan, got %+v / %s", response, plan.Status)
+	}
+
+	running, pending := plan.Tasks[0], plan.Tasks[1]
+	if running.Status != TaskStatusCancelled || len(running.Attempts) != 1 || running.Attempts[0].Status != TaskStatusCancelled {
+		t.Errorf("Expected the running task to be cancelled without a retry, got %s %+v", running.Status, running.Attempts)
+	}
+	if pending.Status != TaskStatusCancelled || len(pending.Attempts) != 0 {
+		t.Errorf("Expected the unstarted task to be cancelled, got %s %+v", pending.Status, pending.Attempts)
+	}
+}
@@ -21,12 +21,12 @@ const (
 type TaskStatus string
 
 const (
-	TaskStatusPending    TaskStatus = "pending"
-	TaskStatusRunning    TaskStatus = "running"
-	TaskStatusCompleted  TaskStatus = "completed"
-	TaskStatusFailed     TaskStatus = "failed"
-	TaskStatusCancelled  TaskStatus = "cancelled"
-	TaskStatusSkipped    TaskStatus = "skipped"
+	TaskStatusPending   TaskStatus = "pending"
+	TaskStatusRunning   TaskStatus = "running"
+	TaskStatusCompleted TaskStatus = "completed"
+	TaskStatusFailed    TaskStatus = "failed"
+	TaskStatusCancelled TaskStatus = "cancelled"
+	TaskStatusSkipped   TaskStatus = "skipped"
 )
 
 // TaskType represents different types of tasks
@@ -44,20 +44,20 @@ const (
 type ExecutionMode string
 
 const (
-	ExecutionModeSequential ExecutionMode = "sequential"
-	ExecutionModeParallel   ExecutionMode = "parallel"
+	ExecutionModeSequential  ExecutionMode = "sequential"
+	ExecutionModeParallel    ExecutionMode = "parallel"
 	ExecutionModeConditional ExecutionMode = "conditional"
 )
 
 // Request represents a user request to the agent system
 type Request struct {
-	ID          string                 `json:"id"`
-	User        string                 `json:"user"`
-	Input       string                 `json:"input"`
-	Intent      string                 `json:"intent,omitempty"`
-	Context     map[string]interface{} `json:"context,omitempty"`
-	Metadata    map[string]string      `json:"metadata,omitempty"`
-	CreatedAt   time.Time              `json:"created_at"`
+	ID        string                 `json:"id"`
+	User      string                 `json:"user"`
+	Input     string                 `json:"input"`
+	Intent    string                 `json:"intent,omitempty"`
+	Context   map[string]interface{} `json:"context,omitempty"`
+	Metadata  map[string]string      `json:"metadata,omitempty"`
+	CreatedAt time.Time              `json:"created_at"`
 }
 
 // Response represents the final response from the agent system
@@ -92,11 +92,24 @@ type Task struct {
 	Error         string                 `json:"error,omitempty"`
 	Dependencies  []string               `json:"dependencies,omitempty"`
 	Condition     *TaskCondition         `json:"condition,omitempty"`
+	Attempts      []TaskAttempt          `json:"attempts,omitempty"`
 	CreatedAt     time.Time              `json:"created_at"`
 	StartedAt     *time.Time             `json:"started_at,omitempty"`
 	CompletedAt   *time.Time             `json:"completed_at,omitempty"`
 }
 
+// TaskAttempt is one run of a task by an agent. The coordinator appends
+// one per attempt, across resumes, so a task's history shows every
+// retry, timeout and cancellation.
+type TaskAttempt struct {
+	Number     int        `json:"number"`
+	Agent      AgentType  `json:"agent"`
+	Status     TaskStatus `json:"status"`
+	Error      string     `json:"error,omitempty"`
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt time.Time  `json:"finished_at"`
+}
+
 // ExecutionPlan represents a plan for executing multiple tasks
 type ExecutionPlan struct {
 	ID            string                 `json:"id"`
@@ -109,9 +122,28 @@ type ExecutionPlan struct {
 	UpdatedAt     time.Time              `json:"updated_at"`
 }
 
+// ResourceSnapshot is the state of one cluster object just before a
+// write tool changed it. Object is nil when the object did not exist,
+// i.e. the write created it and rolling back means deleting it.
+type ResourceSnapshot struct {
+	ID        string                 `json:"id"`
+	RequestID string                 `json:"request_id"`
+	TaskID    string                 `json:"task_id,omitempty"`
+	Tool      string                 `json:"tool"`
+	Resource  string                 `json:"resource"`
+	Name      string                 `json:"name"`
+	Namespace string                 `json:"namespace"`
+	Object    map[string]interface{} `json:"object,omitempty"`
+	TakenAt   time.Time              `json:"taken_at"`
+}
+
 // AgentContext contains shared context for agent execution
 type AgentContext struct {
 	ctx           context.Context
+	stream        StreamHandler
+	taskID        string
+	usage         *UsageLedger
+	session       *Session
 	RequestID     string                 `json:"request_id"`
 	UserID        string                 `json:"user_id"`
 	TraceID       string                 `json:"trace_id"`
@@ -137,6 +169,102 @@ func (ac *AgentContext) Context() context.Context {
 	return ac.ctx
 }
 
+// SetStreamHandler turns on live progress: every agent and the
+// coordinator running under this context report to h. Nil turns it off.
+func (ac *AgentContext) SetStreamHandler(h StreamHandler) {
+	ac.stream = h
+}
+
+// Streaming reports whether a stream handler is installed.
+func (ac *AgentContext) Streaming() bool {
+	return ac.stream != nil
+}
+
+// Emit sends event to the stream handler, tagging it with the task this
+// context was scoped to. No-op without a handler.
+func (ac *AgentContext) Emit(event StreamEvent) {
+	if ac.stream == nil {
+		return
+	}
+	if event.TaskID == "" {
+		event.TaskID = ac.taskID
+	}
+	ac.stream(event)
+}
+
+// ForTask returns a shallow copy whose stream events, token usage and
+// tool calls are attributed to taskID. State, the ledger and the
+// underlying context are shared with ac; only the attribution differs,
+// which is what lets concurrent tasks report through one handler and
+// one ledger.
+func (ac *AgentContext) ForTask(taskID string) *AgentContext {
+	scoped := *ac
+	scoped.taskID = taskID
+	return &scoped
+}
+
+// withContext returns a shallow copy running under ctx, which is how a
+// single task attempt gets its own deadline.
+func (ac *AgentContext) withContext(ctx context.Context) *AgentContext {
+	scoped := *ac
+	scoped.ctx = ctx
+	return &scoped
+}
+
+// SetUsageLedger turns on token and cost accounting for the request.
+// Nil turns it off.
+func (ac *AgentContext) SetUsageLedger(l *UsageLedger) {
+	ac.usage = l
+}
+
+// UsageLedger returns the request's ledger, or nil.
+func (ac *AgentContext) UsageLedger() *UsageLedger {
+	return ac.usage
+}
+
+// SetSession carries a conversation into this request's planning and
+// prompts. Nil turns it off.
+func (ac *AgentContext) SetSession(s *Session) {
+	ac.session = s
+}
+
+// Session returns the request's conversation, or nil.
+func (ac *AgentContext) Session() *Session {
+	return ac.session
+}
+
+// agentContextKey carries the AgentContext a tool call runs under.
+type agentContextKey struct{}
+
+// withAgentContext tags ctx with ac, so LLM calls a tool makes on the
+// agent's behalf (consensus reviewers) are metered against the request.
+func withAgentContext(ctx context.Context, ac *AgentContext) context.Context {
+	return context.WithValue(ctx, agentContextKey{}, ac)
+}
+
+// agentContextFrom returns the AgentContext ctx was tagged with, or nil.
+func agentContextFrom(ctx context.Context) *AgentContext {
+	ac, _ := ctx.Value(agentContextKey{}).(*AgentContext)
+	return ac
+}
+
+// recordUsage books one LLM call against the request. The error is the
+// ledger's budget error; without a ledger nothing is limited.
+func (ac *AgentContext) recordUsage(agentType AgentType, u *TokenUsage) error {
+	if ac.usage == nil {
+		return nil
+	}
+	return ac.usage.Record(agentType, ac.taskID, u)
+}
+
+// checkBudget reports whether the request may make another LLM call.
+func (ac *AgentContext) checkBudget() error {
+	if ac.usage == nil {
+		return nil
+	}
+	return ac.usage.Check()
+}
+
 // SetState sets a value in the state
 func (ac *AgentContext) SetState(key string, value interface{}) {
 	ac.State[key] = value
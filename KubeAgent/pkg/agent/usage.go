@@ -0,0 +1,270 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// TokenUsage is what one LLM call consumed, as reported by the provider.
+// Model is the model that actually answered, which is what prices are
+// looked up by.
+type TokenUsage struct {
+	Model                    string `json:"model,omitempty"`
+	InputTokens              int64  `json:"input_tokens"`
+	OutputTokens             int64  `json:"output_tokens"`
+	CacheCreationInputTokens int64  `json:"cache_creation_input_tokens,omitempty"`
+	CacheReadInputTokens     int64  `json:"cache_read_input_tokens,omitempty"`
+}
+
+// UsageLLMClient is an optional extension of LLMClient for providers
+// that report token usage on plain completions too. Complete only
+// returns a string, so without it planning and summary calls go
+// unmetered.
+type UsageLLMClient interface {
+	LLMClient
+	CompleteWithUsage(ctx context.Context, messages []Message) (*LLMResponse, error)
+}
+
+// completeText runs a tool-less completion, keeping usage when the
+// client can report it.
+func completeText(ctx context.Context, client LLMClient, messages []Message) (*LLMResponse, error) {
+	if metered, ok := client.(UsageLLMClient); ok {
+		return metered.CompleteWithUsage(ctx, messages)
+	}
+	content, err := client.Complete(ctx, messages)
+	if err != nil {
+		return nil, err
+	}
+	return &LLMResponse{Content: content}, nil
+}
+
+// ModelPrice is a model's list price in USD per million tokens.
+type ModelPrice struct {
+	InputPerMTok      float64 `json:"input_per_mtok"`
+	OutputPerMTok     float64 `json:"output_per_mtok"`
+	CacheWritePerMTok float64 `json:"cache_write_per_mtok,omitempty"`
+	CacheReadPerMTok  float64 `json:"cache_read_per_mtok,omitempty"`
+}
+
+// PriceTable maps model names to prices. A key also matches every model
+// it is a prefix of, so "claude-sonnet-4" prices dated snapshots such as
+// "claude-sonnet-4-20250514"; the longest matching key wins.
+type PriceTable map[string]ModelPrice
+
+// DefaultPriceTable returns list prices for the Anthropic models KubeAgent
+// defaults to. Other providers (MiniMax, DashScope, ...) must be added
+// with LoadPriceTable; until then their calls are counted but not priced.
+func DefaultPriceTable() PriceTable {
+	return PriceTable{
+		"claude-opus-4":     {InputPerMTok: 15, OutputPerMTok: 75, CacheWritePerMTok: 18.75, CacheReadPerMTok: 1.5},
+		"claude-sonnet-4":   {InputPerMTok: 3, OutputPerMTok: 15, CacheWritePerMTok: 3.75, CacheReadPerMTok: 0.3},
+		"claude-haiku-4":    {InputPerMTok: 1, OutputPerMTok: 5, CacheWritePerMTok: 1.25, CacheReadPerMTok: 0.1},
+		"claude-3-5-haiku":  {InputPerMTok: 0.8, OutputPerMTok: 4, CacheWritePerMTok: 1, CacheReadPerMTok: 0.08},
+		"claude-3-7-sonnet": {InputPerMTok: 3, OutputPerMTok: 15, CacheWritePerMTok: 3.75, CacheReadPerMTok: 0.3},
+	}
+}
+
+// LoadPriceTable reads a JSON object of model name to ModelPrice and
+// layers it over DefaultPriceTable, so a file only needs the models it
+// adds or re-prices.
+func LoadPriceTable(path string) (PriceTable, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read price table: %w", err)
+	}
+	overrides := PriceTable{}
+	if err := json.Unmarshal(data, &overrides); err != nil {
+		return nil, fmt.Errorf("failed to parse price table %s: %w", path, err)
+	}
+	table := DefaultPriceTable()
+	for model, price := range overrides {
+		table[model] = price
+	}
+	return table, nil
+}
+
+// Cost prices u. ok is false when no entry matches the model.
+func (p PriceTable) Cost(u *TokenUsage) (cost float64, ok bool) {
+	price, ok := p[u.Model]
+	if !ok {
+		best := ""
+		for model := range p {
+			if strings.HasPrefix(u.Model, model) && len(model) > len(best) {
+				best = model
+			}
+		}
+		if best == "" {
+			return 0, false
+		}
+		price = p[best]
+	}
+	cost = float64(u.InputTokens)*price.InputPerMTok +
+		float64(u.OutputTokens)*price.OutputPerMTok +
+		float64(u.CacheCreationInputTokens)*price.CacheWritePerMTok +
+		float64(u.CacheReadInputTokens)*price.CacheReadPerMTok
+	return cost / 1e6, true
+}
+
+// UsageBudget caps what one request may spend. Zero fields are no limit.
+type UsageBudget struct {
+	MaxTokens  int64   `json:"max_tokens,omitempty"`
+	MaxCostUSD float64 `json:"max_cost_usd,omitempty"`
+}
+
+// ErrBudgetExceeded is returned (wrapped) once a request has spent its
+// UsageBudget. Check with errors.Is.
+var ErrBudgetExceeded = errors.New("request budget exceeded")
+
+// UsageTotals aggregates calls for one slice of a UsageReport.
+type UsageTotals struct {
+	Calls                    int     `json:"calls"`
+	InputTokens              int64   `json:"input_tokens"`
+	OutputTokens             int64   `json:"output_tokens"`
+	CacheCreationInputTokens int64   `json:"cache_creation_input_tokens,omitempty"`
+	CacheReadInputTokens     int64   `json:"cache_read_input_tokens,omitempty"`
+	CostUSD                  float64 `json:"cost_usd"`
+}
+
+// TotalTokens is every token in the totals, cached or not.
+func (t *UsageTotals) TotalTokens() int64 {
+	return t.InputTokens + t.OutputTokens + t.CacheCreationInputTokens + t.CacheReadInputTokens
+}
+
+func (t *UsageTotals) add(u *TokenUsage, cost float64) {
+	t.Calls++
+	t.InputTokens += u.InputTokens
+	t.OutputTokens += u.OutputTokens
+	t.CacheCreationInputTokens += u.CacheCreationInputTokens
+	t.CacheReadInputTokens += u.CacheReadInputTokens
+	t.CostUSD += cost
+}
+
+// UsageReport is the per-request accounting stored in
+// Response.Data["usage"]. Calls made outside any task (planning, the
+// final summary) are under ByTask[""] and ByAgent["coordinator"].
+type UsageReport struct {
+	Total   UsageTotals                `json:"total"`
+	ByAgent map[AgentType]*UsageTotals `json:"by_agent"`
+	ByTask  map[string]*UsageTotals    `json:"by_task"`
+	// Unpriced lists models with no PriceTable entry; their tokens are
+	// counted but CostUSD understates the real spend.
+	Unpriced []string     `json:"unpriced_models,omitempty"`
+	Budget   *UsageBudget `json:"budget,omitempty"`
+}
+
+// UsageLedger accounts token usage and cost for one request and
+// enforces its budget. Tasks of a dependency round run concurrently, so
+// all methods are safe for concurrent use.
+type UsageLedger struct {
+	mu       sync.Mutex
+	prices   PriceTable
+	budget   UsageBudget
+	report   UsageReport
+	unpriced map[string]bool
+}
+
+// NewUsageLedger creates an unlimited ledger. Nil prices uses
+// DefaultPriceTable.
+func NewUsageLedger(prices PriceTable) *UsageLedger {
+	if prices == nil {
+		prices = DefaultPriceTable()
+	}
+	return &UsageLedger{
+		prices: prices,
+		report: UsageReport{
+			ByAgent: make(map[AgentType]*UsageTotals),
+			ByTask:  make(map[string]*UsageTotals),
+		},
+		unpriced: make(map[string]bool),
+	}
+}
+
+// WithBudget sets the per-request limit.
+func (l *UsageLedger) WithBudget(budget UsageBudget) *UsageLedger {
+	l.budget = budget
+	return l
+}
+
+// Record adds one call's usage. The call has already happened, so it is
+// always counted; the returned error says the budget is now spent and
+// the caller should stop making calls.
+func (l *UsageLedger) Record(agentType AgentType, taskID string, u *TokenUsage) error {
+	if u == nil {
+		return l.Check()
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	cost, ok := l.prices.Cost(u)
+	if !ok && u.Model != "" {
+		l.unpriced[u.Model] = true
+	}
+	l.report.Total.add(u, cost)
+	totalsFor(l.report.ByAgent, agentType).add(u, cost)
+	totalsFor(l.report.ByTask, taskID).add(u, cost)
+	return l.exceeded()
+}
+
+// Check returns the budget error if the request has already spent its
+// budget, so work that has not started yet (another task, the final
+// summary) can be skipped.
+func (l *UsageLedger) Check() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.exceeded()
+}
+
+func (l *UsageLedger) exceeded() error {
+	total := &l.report.Total
+	if l.budget.MaxTokens > 0 && total.TotalTokens() > l.budget.MaxTokens {
+		return fmt.Errorf("%w: %d tokens used, limit is %d", ErrBudgetExceeded, total.TotalTokens(), l.budget.MaxTokens)
+	}
+	if l.budget.MaxCostUSD > 0 && total.CostUSD > l.budget.MaxCostUSD {
+		return fmt.Errorf("%w: $%.4f spent, limit is $%.4f", ErrBudgetExceeded, total.CostUSD, l.budget.MaxCostUSD)
+	}
+	return nil
+}
+
+func totalsFor[K comparable](m map[K]*UsageTotals, key K) *UsageTotals {
+	t, ok := m[key]
+	if !ok {
+		t = &UsageTotals{}
+		m[key] = t
+	}
+	return t
+}
+
+// Report returns a copy of the accounting so far.
+func (l *UsageLedger) Report() *UsageReport {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	report := &UsageReport{
+		Total:   l.report.Total,
+		ByAgent: make(map[AgentType]*UsageTotals, len(l.report.ByAgent)),
+		ByTask:  make(map[string]*UsageTotals, len(l.report.ByTask)),
+	}
+	for k, v := range l.report.ByAgent {
+		totals := *v
+		report.ByAgent[k] = &totals
+	}
+	for k, v := range l.report.ByTask {
+		totals := *v
+		report.ByTask[k] = &totals
+	}
+	for model := range l.unpriced {
+		report.Unpriced = append(report.Unpriced, model)
+	}
+	sort.Strings(report.Unpriced)
+	if l.budget != (UsageBudget{}) {
+		budget := l.budget
+		report.Budget = &budget
+	}
+	return report
+}
@@ -0,0 +1,69 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestPriceTable_Cost(t *testing.T) {
+	prices := DefaultPriceTable()
+
+	cost, ok := prices.Cost(&TokenUsage{
+		Model:                "claude-sonnet-4-20250514",
+		InputTokens:          1_000_000,
+		OutputTokens:         100_000,
+		CacheReadInputTokens: 1_000_000,
+	})
+	if !ok || math.Abs(cost-(3+1.5+0.3)) > 1e-9 {
+		t.Errorf("Expected dated snapshot priced as claude-sonnet-4 ($4.80), got %v (ok %v)", cost, ok)
+	}
+
+	if _, ok := prices.Cost(&TokenUsage{Model: "MiniMax-M2.5", InputTokens: 10}); ok {
+		t.Error("Expected unknown model to be unpriced")
+	}
+}
+
+// toolHungryLLM never stops calling tools; every call costs 600 tokens.
+func toolHungryLLM() *MockLLMClient {
+	return &MockLLMClient{
+		CompleteWithToolsFunc: func(ctx context.Context, messages []Message, tools []Tool) (*LLMResponse, error) {
+			return &LLMResponse{
+				ToolCalls:    []ToolCall{{ID: "call", Name: "LogTool", Arguments: map[string]interface{}{}}},
+				FinishReason: "tool_use",
+				Usage:        &TokenUsage{Model: "claude-sonnet-4-20250514", InputTokens: 500, OutputTokens: 100},
+			}, nil
+		},
+	}
+}
+
+func TestRunToolLoop_StopsAtBudget(t *testing.T) {
+	b := NewBaseAgent(&AgentConfig{Name: "diag", Type: AgentTypeDiagnostician}, toolHungryLLM(), NewNoOpLogger())
+	b.AddTool(bigLogTool{})
+
+	ledger := NewUsageLedger(nil).WithBudget(UsageBudget{MaxTokens: 1000})
+	ctx := NewAgentContext(context.Background(), "req", "user", "trace")
+	ctx.SetUsageLedger(ledger)
+
+	_, err := b.RunToolLoop(ctx.ForTask("diagnose-1"), "system", "find the bug", 10)
+	if !errors.Is(err, ErrBudgetExceeded) {
+		t.Fatalf("Expected budget error, got %v", err)
+	}
+
+	report := ledger.Report()
+	if report.Total.Calls != 2 || report.Total.TotalTokens() != 1200 {
+		t.Errorf("Expected the loop to stop after the second call, got %+v", report.Total)
+	}
+	if task := report.ByTask["diagnose-1"]; task == nil || task.Calls != 2 {
+		t.Errorf("Expected usage attributed to the task, got %+v", report.ByTask)
+	}
+	if byAgent := report.ByAgent[AgentTypeDiagnostician]; byAgent == nil || math.Abs(byAgent.CostUSD-2*(500*3+100*15)/1e6) > 1e-9 {
+		t.Errorf("Unexpected per-agent totals: %+v", byAgent)
+	}
+
+	// Once spent, no further call is made at all.
+	if _, err := b.CallLLM(ctx, "system", "one more"); !errors.Is(err, ErrBudgetExceeded) {
+		t.Errorf("Expected CallLLM to refuse after the budget is spent, got %v", err)
+	}
+}
@@ -0,0 +1,44 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+
+	"kubeagent/pkg/agent"
+	"kubeagent/pkg/agent/harness"
+)
+
+// planApproval is the approval request for running plan. Plans share
+// the queue with HumanTool's per-action approvals, so one reviewer
+// sees both in GET /api/v1/approvals.
+func planApproval(id, requestID string, plan *agent.ExecutionPlan) harness.ApprovalRequest {
+	risk := harness.RiskLow
+	if hasRemediation(plan) {
+		risk = harness.RiskHigh
+	}
+	return harness.ApprovalRequest{
+		ID:        id,
+		Kind:      harness.ApprovalPlan,
+		RequestID: requestID,
+		PlanID:    plan.ID,
+		Actor:     "api",
+		Prompt:    summarizePlan(plan),
+		Risk:      risk,
+	}
+}
+
+// summarizePlan is one line per task, enough to decide without
+// fetching the plan.
+func summarizePlan(plan *agent.ExecutionPlan) string {
+	var b strings.Builder
+	for i, task := range plan.Tasks {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "%s [%s] %s", task.ID, task.Type, task.Description)
+		if len(task.Dependencies) > 0 {
+			fmt.Fprintf(&b, " (after %s)", strings.Join(task.Dependencies, ", "))
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,123 @@
+package api
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"kubeagent/pkg/agent"
+	"kubeagent/pkg/agent/harness"
+)
+
+// DefaultEventHistory is how many events a request keeps for clients
+// that connect late or reconnect with Last-Event-ID.
+const DefaultEventHistory = 1000
+
+// EventType tells the kinds of Event apart; it is also the SSE event
+// name.
+type EventType string
+
+const (
+	// EventStatus is a change of the request's status.
+	EventStatus EventType = "status"
+	// EventStream is agent progress: tasks starting and finishing,
+	// model output, tool calls.
+	EventStream EventType = "stream"
+	// EventAudit is a harness audit event recorded for the request.
+	EventAudit EventType = "audit"
+)
+
+// Event is one entry of a request's live feed.
+type Event struct {
+	ID     int                 `json:"id"`
+	Type   EventType           `json:"type"`
+	Time   time.Time           `json:"time"`
+	Status RequestStatus       `json:"status,omitempty"`
+	Error  string              `json:"error,omitempty"`
+	Stream *agent.StreamEvent  `json:"stream,omitempty"`
+	Audit  *harness.AuditEvent `json:"audit,omitempty"`
+}
+
+// eventLog is a request's bounded event history. Readers wait on
+// changed() rather than on a channel of their own, so a slow SSE
+// client never blocks the agents: it just reads further behind, and
+// past the history it skips ahead.
+type eventLog struct {
+	mu      sync.Mutex
+	events  []Event
+	nextID  int
+	limit   int
+	closed  bool
+	changed chan struct{}
+}
+
+func newEventLog(limit int) *eventLog {
+	if limit <= 0 {
+		limit = DefaultEventHistory
+	}
+	return &eventLog{nextID: 1, limit: limit, changed: make(chan struct{})}
+}
+
+// append stamps and stores event and wakes every reader. Events after
+// close are dropped.
+func (l *eventLog) append(event Event) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		return
+	}
+	event.ID = l.nextID
+	l.nextID++
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	l.events = append(l.events, event)
+	if len(l.events) > l.limit {
+		l.events = append(l.events[:0], l.events[len(l.events)-l.limit:]...)
+	}
+	close(l.changed)
+	l.changed = make(chan struct{})
+}
+
+// close ends the feed: readers drain what is left and stop.
+func (l *eventLog) close() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		return
+	}
+	l.closed = true
+	close(l.changed)
+}
+
+// since returns the events after id, whether the log is closed, and a
+// channel closed on the next change.
+func (l *eventLog) since(id int) ([]Event, bool, <-chan struct{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var out []Event
+	for _, event := range l.events {
+		if event.ID > id {
+			out = append(out, event)
+		}
+	}
+	return out, l.closed, l.changed
+}
+
+// auditFeed is the AuditLogger returned by Server.Auditor: it copies
+// each event into the feed of the request it belongs to.
+type auditFeed struct {
+	server *Server
+}
+
+// Record implements harness.AuditLogger. Events of unknown or finished
+// requests are ignored; the other sinks in the tee still keep them.
+func (f auditFeed) Record(ctx context.Context, event harness.AuditEvent) error {
+	if event.RequestID == "" {
+		return nil
+	}
+	if req := f.server.lookup(event.RequestID); req != nil {
+		req.events.append(Event{Type: EventAudit, Time: event.Timestamp, Audit: &event})
+	}
+	return nil
+}
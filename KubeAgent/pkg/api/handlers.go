@@ -0,0 +1,282 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"kubeagent/pkg/agent"
+)
+
+// errNotFound is returned for unknown request, plan and task IDs.
+var errNotFound = errors.New("not found")
+
+// maxBodyBytes caps request bodies; a request input is a sentence, not
+// a file upload.
+const maxBodyBytes = 1 << 20
+
+// RequestDetail is GET /api/v1/requests/{id}: the record and the plan
+// with each task's current status.
+type RequestDetail struct {
+	RequestRecord
+	Plan *agent.ExecutionPlan `json:"plan,omitempty"`
+}
+
+// TaskRecord is a task together with the request and plan it belongs
+// to.
+type TaskRecord struct {
+	RequestID string `json:"request_id"`
+	PlanID    string `json:"plan_id"`
+	*agent.Task
+}
+
+// Handler is the HTTP API. Everything under /api/v1 needs the bearer
+// token when one is set; /healthz never does.
+func (s *Server) Handler() http.Handler {
+	api := http.NewServeMux()
+	api.HandleFunc("POST /api/v1/requests", s.handleSubmit)
+	api.HandleFunc("GET /api/v1/requests", s.handleListRequests)
+	api.HandleFunc("GET /api/v1/requests/{id}", s.handleGetRequest)
+	api.HandleFunc("DELETE /api/v1/requests/{id}", s.handleCancel)
+	api.HandleFunc("GET /api/v1/requests/{id}/events", s.handleEvents)
+	api.HandleFunc("GET /api/v1/plans/{id}", s.handleGetPlan)
+	api.HandleFunc("GET /api/v1/tasks", s.handleListTasks)
+	api.HandleFunc("GET /api/v1/tasks/{id}", s.handleGetTask)
+	approvals := s.queue.Handler("/api/v1/approvals")
+	api.Handle("GET /api/v1/approvals", approvals)
+	api.Handle("POST /api/v1/approvals/{id}", approvals)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	})
+	mux.Handle("/api/", requireToken(s.token, api))
+	for pattern, handler := range s.routes {
+		mux.Handle(pattern, handler)
+	}
+	return s.logRequests(mux)
+}
+
+func (s *Server) handleSubmit(w http.ResponseWriter, r *http.Request) {
+	var submit SubmitRequest
+	if err := decodeBody(w, r, &submit); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	record, err := s.Submit(submit)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	w.Header().Set("Location", "/api/v1/requests/"+record.ID)
+	writeJSON(w, http.StatusAccepted, record)
+}
+
+func (s *Server) handleListRequests(w http.ResponseWriter, r *http.Request) {
+	status := RequestStatus(r.URL.Query().Get("status"))
+	s.mu.Lock()
+	records := make([]RequestRecord, 0, len(s.order))
+	for i := len(s.order) - 1; i >= 0; i-- {
+		record := s.requests[s.order[i]].record
+		if status != "" && record.Status != status {
+			continue
+		}
+		// The list is an index; the full response is one GET away.
+		record.Response = nil
+		records = append(records, record)
+	}
+	s.mu.Unlock()
+	writeJSON(w, http.StatusOK, records)
+}
+
+func (s *Server) handleGetRequest(w http.ResponseWriter, r *http.Request) {
+	req := s.lookup(r.PathValue("id"))
+	if req == nil {
+		record, plan, ok := s.storedRequest(r.PathValue("id"))
+		if !ok {
+			writeError(w, http.StatusNotFound, "request not found")
+			return
+		}
+		writeJSON(w, http.StatusOK, RequestDetail{RequestRecord: record, Plan: plan})
+		return
+	}
+	s.mu.Lock()
+	data, err := json.Marshal(RequestDetail{RequestRecord: req.record, Plan: req.plan})
+	s.mu.Unlock()
+	writeEncoded(w, http.StatusOK, data, err)
+}
+
+func (s *Server) handleCancel(w http.ResponseWriter, r *http.Request) {
+	record, err := s.Cancel(r.PathValue("id"))
+	switch {
+	case errors.Is(err, errNotFound):
+		writeError(w, http.StatusNotFound, "request not found")
+	case err != nil:
+		writeError(w, http.StatusConflict, err.Error())
+	default:
+		writeJSON(w, http.StatusAccepted, record)
+	}
+}
+
+func (s *Server) handleGetPlan(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	s.mu.Lock()
+	var data []byte
+	var err error = errNotFound
+	for _, req := range s.requests {
+		if req.plan != nil && req.plan.ID == id {
+			data, err = json.Marshal(req.plan)
+			break
+		}
+	}
+	s.mu.Unlock()
+	if errors.Is(err, errNotFound) {
+		if plan, ok := s.storedPlan(id); ok {
+			writeJSON(w, http.StatusOK, plan)
+			return
+		}
+		writeError(w, http.StatusNotFound, "plan not found")
+		return
+	}
+	writeEncoded(w, http.StatusOK, data, err)
+}
+
+func (s *Server) handleListTasks(w http.ResponseWriter, r *http.Request) {
+	requestID := r.URL.Query().Get("request_id")
+	status := agent.TaskStatus(r.URL.Query().Get("status"))
+	s.mu.Lock()
+	tasks := []TaskRecord{}
+	for i := len(s.order) - 1; i >= 0; i-- {
+		req := s.requests[s.order[i]]
+		if req.plan == nil || (requestID != "" && req.record.ID != requestID) {
+			continue
+		}
+		for _, task := range req.plan.Tasks {
+			if status == "" || task.Status == status {
+				tasks = append(tasks, TaskRecord{RequestID: req.record.ID, PlanID: req.plan.ID, Task: task})
+			}
+		}
+	}
+	if requestID == "" || s.requests[requestID] != nil {
+		data, err := json.Marshal(tasks)
+		s.mu.Unlock()
+		writeEncoded(w, http.StatusOK, data, err)
+		return
+	}
+	s.mu.Unlock()
+
+	// A request the server no longer tracks still has its plan stored.
+	if _, plan, ok := s.storedRequest(requestID); ok {
+		for _, task := range plan.Tasks {
+			if status == "" || task.Status == status {
+				tasks = append(tasks, TaskRecord{RequestID: requestID, PlanID: plan.ID, Task: task})
+			}
+		}
+	}
+	writeJSON(w, http.StatusOK, tasks)
+}
+
+// handleGetTask finds a task by ID. Task IDs come from the planner and
+// are only unique within a plan, so the newest request's task wins
+// unless ?request_id= narrows it down. Tasks of requests the server no
+// longer tracks are looked up in the state store.
+func (s *Server) handleGetTask(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	requestID := r.URL.Query().Get("request_id")
+	s.mu.Lock()
+	var data []byte
+	var err error = errNotFound
+	for i := len(s.order) - 1; i >= 0 && errors.Is(err, errNotFound); i-- {
+		req := s.requests[s.order[i]]
+		if req.plan == nil || (requestID != "" && req.record.ID != requestID) {
+			continue
+		}
+		if task := req.plan.Task(id); task != nil {
+			data, err = json.Marshal(TaskRecord{RequestID: req.record.ID, PlanID: req.plan.ID, Task: task})
+		}
+	}
+	s.mu.Unlock()
+	if errors.Is(err, errNotFound) {
+		if record, ok := s.storedTaskRecord(id, requestID); ok {
+			writeJSON(w, http.StatusOK, record)
+			return
+		}
+		writeError(w, http.StatusNotFound, "task not found")
+		return
+	}
+	writeEncoded(w, http.StatusOK, data, err)
+}
+
+// handleEvents streams a request's events as Server-Sent Events until
+// the request finishes or the client goes away. A reconnecting client
+// sends Last-Event-ID and resumes after it.
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	req := s.lookup(r.PathValue("id"))
+	if req == nil {
+		writeError(w, http.StatusNotFound, "request not found")
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "streaming unsupported")
+		return
+	}
+	last, _ := strconv.Atoi(r.Header.Get("Last-Event-ID"))
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		events, closed, changed := req.events.since(last)
+		for _, event := range events {
+			data, err := json.Marshal(event)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+			last = event.ID
+		}
+		flusher.Flush()
+		if closed {
+			return
+		}
+		select {
+		case <-changed:
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+func decodeBody(w http.ResponseWriter, r *http.Request, v interface{}) error {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return fmt.Errorf("invalid JSON body: %w", err)
+	}
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	data, err := json.Marshal(v)
+	writeEncoded(w, status, data, err)
+}
+
+func writeEncoded(w http.ResponseWriter, status int, data []byte, err error) {
+	if err != nil {
+		http.Error(w, `{"error":"failed to encode response"}`, http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(append(data, '\n'))
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
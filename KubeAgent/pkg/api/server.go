@@ -0,0 +1,744 @@
+// Package api serves the coordinator over HTTP: requests are submitted
+// and run asynchronously, their plans, tasks and live progress can be
+// polled or streamed, and plans that write to the cluster wait for an
+// approval posted by a person through the same queue HumanTool uses.
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"kubeagent/pkg/agent"
+	"kubeagent/pkg/agent/harness"
+)
+
+// Defaults for Server.
+const (
+	DefaultMaxConcurrent    = 4
+	DefaultRetainedRequests = 500
+)
+
+// RequestStatus is where a submitted request is in its lifecycle.
+type RequestStatus string
+
+const (
+	RequestQueued           RequestStatus = "queued"
+	RequestPlanning         RequestStatus = "planning"
+	RequestAwaitingApproval RequestStatus = "awaiting_approval"
+	RequestRunning          RequestStatus = "running"
+	RequestCompleted        RequestStatus = "completed"
+	RequestFailed           RequestStatus = "failed"
+	RequestRejected         RequestStatus = "rejected"
+	RequestCancelled        RequestStatus = "cancelled"
+)
+
+// Finished reports whether the request will not change any more.
+func (s RequestStatus) Finished() bool {
+	switch s {
+	case RequestCompleted, RequestFailed, RequestRejected, RequestCancelled:
+		return true
+	}
+	return false
+}
+
+// SubmitRequest is the body of POST /api/v1/requests.
+type SubmitRequest struct {
+	Input   string                 `json:"input"`
+	Intent  string                 `json:"intent,omitempty"`
+	Context map[string]interface{} `json:"context,omitempty"`
+	User    string                 `json:"user,omitempty"`
+
+	// ApprovalRequired holds the plan for approval even when the
+	// server would run it straight away.
+	ApprovalRequired bool `json:"approval_required,omitempty"`
+
+	// DiagnoseOnly drops the remediate tasks the planner adds anyway,
+	// and whatever depends on them, before the plan is approved or run.
+	DiagnoseOnly bool `json:"diagnose_only,omitempty"`
+}
+
+// RequestRecord is what the API reports about a request.
+type RequestRecord struct {
+	ID               string          `json:"id"`
+	User             string          `json:"user"`
+	Input            string          `json:"input"`
+	Status           RequestStatus   `json:"status"`
+	ApprovalRequired bool            `json:"approval_required,omitempty"`
+	PlanID           string          `json:"plan_id,omitempty"`
+	ApprovalID       string          `json:"approval_id,omitempty"`
+	Result           string          `json:"result,omitempty"`
+	Error            string          `json:"error,omitempty"`
+	Response         *agent.Response `json:"response,omitempty"`
+	CreatedAt        time.Time       `json:"created_at"`
+	UpdatedAt        time.Time       `json:"updated_at"`
+}
+
+// request is a submitted request and everything the server tracks
+// about it. record and plan are guarded by Server.mu; the plan is the
+// server's own copy (see snapshotPlan), never the one being executed.
+type request struct {
+	record RequestRecord
+	submit SubmitRequest
+	plan   *agent.ExecutionPlan
+	events *eventLog
+	cancel context.CancelFunc
+}
+
+// Server runs submitted requests on a coordinator and serves their
+// state over HTTP.
+type Server struct {
+	coordinator agent.CoordinatorAgent
+	logger      agent.Logger
+	store       agent.StateStore
+
+	token               string
+	retained            int
+	approveRemediation  bool
+	setup               func(ctx *agent.AgentContext)
+	slots               chan struct{}
+	approvals           *harness.ApprovalGate
+	queue               *harness.HTTPApprover
+	approvalListeners   []func(harness.ApprovalRecord)
+	routes              map[string]http.Handler
+	baseCtx             context.Context
+	stopRequests        context.CancelFunc
+	shutdownGracePeriod time.Duration
+	running             sync.WaitGroup
+
+	mu       sync.Mutex
+	requests map[string]*request
+	order    []string
+}
+
+// NewServer builds a server for coordinator. Without WithToken every
+// caller is trusted; plans with remediate tasks wait for approval
+// unless WithRemediationApproval(false).
+func NewServer(coordinator agent.CoordinatorAgent, logger agent.Logger) *Server {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &Server{
+		coordinator:         coordinator,
+		logger:              logger,
+		retained:            DefaultRetainedRequests,
+		approveRemediation:  true,
+		slots:               make(chan struct{}, DefaultMaxConcurrent),
+		baseCtx:             ctx,
+		stopRequests:        cancel,
+		shutdownGracePeriod: 30 * time.Second,
+		requests:            make(map[string]*request),
+		routes:              make(map[string]http.Handler),
+	}
+	queue := harness.NewHTTPApprover(nil)
+	return s.WithApprovals(harness.NewApprovalGate(queue), queue)
+}
+
+// WithToken requires "Authorization: Bearer <token>" on every API
+// call. Empty disables authentication.
+func (s *Server) WithToken(token string) *Server {
+	s.token = token
+	return s
+}
+
+// WithMaxConcurrent caps the requests planning or executing at once;
+// the rest wait in RequestQueued. Values below 1 are ignored.
+func (s *Server) WithMaxConcurrent(n int) *Server {
+	if n >= 1 {
+		s.slots = make(chan struct{}, n)
+	}
+	return s
+}
+
+// WithRetainedRequests caps how many finished requests are kept for
+// polling; the oldest are forgotten first. Values below 1 are ignored.
+func (s *Server) WithRetainedRequests(n int) *Server {
+	if n >= 1 {
+		s.retained = n
+	}
+	return s
+}
+
+// WithRemediationApproval decides whether plans containing a
+// remediate task wait for POST /api/v1/approvals/{id} before they
+// start. On by default: a reviewer sees the whole plan up front rather
+// than one HumanTool prompt at a time.
+func (s *Server) WithRemediationApproval(required bool) *Server {
+	s.approveRemediation = required
+	return s
+}
+
+// WithApprovals replaces the gate plan approvals go through and the
+// queue served under /api/v1/approvals, where whatever the gate leaves
+// to a person waits. Share both with HumanTool so tool approvals land
+// in the same queue. Nil arguments are ignored.
+func (s *Server) WithApprovals(gate *harness.ApprovalGate, queue *harness.HTTPApprover) *Server {
+	if gate != nil && queue != nil {
+		s.approvals = gate
+		s.queue = queue.WithNotifier(s.notifyApproval)
+	}
+	return s
+}
+
+// OnApproval calls notify with every approval that starts waiting in
+// the queue, plan and HumanTool approvals alike. Chat front ends use
+// it to post approve/reject buttons. Nil is tolerated.
+func (s *Server) OnApproval(notify func(harness.ApprovalRecord)) *Server {
+	if notify != nil {
+		s.mu.Lock()
+		s.approvalListeners = append(s.approvalListeners, notify)
+		s.mu.Unlock()
+	}
+	return s
+}
+
+// WithRoute serves handler at pattern next to the API, outside the
+// bearer token check: chat platforms sign their callbacks instead, and
+// the handler must verify that itself.
+func (s *Server) WithRoute(pattern string, handler http.Handler) *Server {
+	s.routes[pattern] = handler
+	return s
+}
+
+// WithStateStore looks up plans and tasks the server no longer tracks
+// in store, which should be the coordinator's: requests dropped by
+// WithRetainedRequests and, with a durable store, requests of an
+// earlier process. Nil is tolerated.
+func (s *Server) WithStateStore(store agent.StateStore) *Server {
+	s.store = store
+	return s
+}
+
+// WithContextSetup runs setup on each request's context before it is
+// planned, e.g. to attach a usage ledger. Nil is tolerated.
+func (s *Server) WithContextSetup(setup func(ctx *agent.AgentContext)) *Server {
+	s.setup = setup
+	return s
+}
+
+// Auditor is an AuditLogger that feeds audit events into the SSE
+// stream of the request they belong to. Tee it with the other sinks.
+func (s *Server) Auditor() harness.AuditLogger {
+	return auditFeed{server: s}
+}
+
+// ListenAndServe serves on addr until ctx is done, then stops taking
+// connections, cancels running requests (their tasks are marked
+// cancelled) and waits briefly for in-flight responses.
+func (s *Server) ListenAndServe(ctx context.Context, addr string) error {
+	httpServer := &http.Server{
+		Addr:              addr,
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	errc := make(chan error, 1)
+	go func() { errc <- httpServer.ListenAndServe() }()
+
+	select {
+	case err := <-errc:
+		s.stopRequests()
+		return err
+	case <-ctx.Done():
+	}
+	s.stopRequests()
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), s.shutdownGracePeriod)
+	defer cancel()
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	if err := <-errc; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	s.Stop(shutdownCtx)
+	return nil
+}
+
+// Stop cancels every request that has not finished and waits until
+// they have checkpointed their plans or ctx is done. ListenAndServe
+// calls it on shutdown; hosts that only use Submit (the operator) call
+// it themselves.
+func (s *Server) Stop(ctx context.Context) {
+	s.stopRequests()
+	drained := make(chan struct{})
+	go func() {
+		s.running.Wait()
+		close(drained)
+	}()
+	select {
+	case <-drained:
+	case <-ctx.Done():
+		s.logger.Warn("Requests still running at shutdown", nil)
+	}
+}
+
+// Submit registers the request and starts it in the background.
+func (s *Server) Submit(submit SubmitRequest) (RequestRecord, error) {
+	if submit.Input == "" {
+		return RequestRecord{}, fmt.Errorf("input is required")
+	}
+	if submit.User == "" {
+		submit.User = "api"
+	}
+	now := time.Now()
+	ctx, cancel := context.WithCancel(s.baseCtx)
+	req := &request{
+		record: RequestRecord{
+			ID:               uuid.New().String(),
+			User:             submit.User,
+			Input:            submit.Input,
+			Status:           RequestQueued,
+			ApprovalRequired: submit.ApprovalRequired,
+			CreatedAt:        now,
+			UpdatedAt:        now,
+		},
+		submit: submit,
+		events: newEventLog(DefaultEventHistory),
+		cancel: cancel,
+	}
+
+	s.mu.Lock()
+	s.requests[req.record.ID] = req
+	s.order = append(s.order, req.record.ID)
+	s.forgetFinishedLocked()
+	record := req.record
+	s.mu.Unlock()
+
+	req.events.append(Event{Type: EventStatus, Status: RequestQueued})
+	s.running.Add(1)
+	go func() {
+		defer s.running.Done()
+		s.run(ctx, req)
+	}()
+	return record, nil
+}
+
+// Request returns the current record of request id. A request the
+// server no longer tracks is rebuilt from its stored plan, see
+// storedRequest.
+func (s *Server) Request(id string) (RequestRecord, bool) {
+	req := s.lookup(id)
+	if req == nil {
+		record, _, ok := s.storedRequest(id)
+		return record, ok
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return req.record, true
+}
+
+// Plan returns a copy of the plan of request id with each task's
+// current status, once the request has been planned.
+func (s *Server) Plan(id string) (*agent.ExecutionPlan, bool) {
+	req := s.lookup(id)
+	if req == nil {
+		_, plan, ok := s.storedRequest(id)
+		return plan, ok
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if req.plan == nil {
+		return nil, false
+	}
+	return snapshotPlan(req.plan), true
+}
+
+// Wait blocks until request id finishes or ctx is done and returns its
+// record. Every request finishes once the server shuts down.
+func (s *Server) Wait(ctx context.Context, id string) (RequestRecord, error) {
+	req := s.lookup(id)
+	if req == nil {
+		return RequestRecord{}, errNotFound
+	}
+	for {
+		_, closed, changed := req.events.since(math.MaxInt)
+		if closed {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			return req.record, nil
+		}
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return RequestRecord{}, ctx.Err()
+		}
+	}
+}
+
+// ResolveApproval decides a pending approval, as POST
+// /api/v1/approvals/{id} does.
+func (s *Server) ResolveApproval(id string, decision harness.ApprovalDecision) (harness.ApprovalRecord, error) {
+	return s.queue.Resolve(id, decision)
+}
+
+// PendingApprovals lists the approvals waiting for a decision, oldest
+// first.
+func (s *Server) PendingApprovals() []harness.ApprovalRecord {
+	return s.queue.Pending()
+}
+
+// Cancel stops a request that has not finished. Tasks already running
+// are marked cancelled, like Ctrl-C in the CLI.
+func (s *Server) Cancel(id string) (RequestRecord, error) {
+	req := s.lookup(id)
+	if req == nil {
+		return RequestRecord{}, errNotFound
+	}
+	s.mu.Lock()
+	record := req.record
+	s.mu.Unlock()
+	if record.Status.Finished() {
+		return record, fmt.Errorf("request %s already %s", id, record.Status)
+	}
+	req.cancel()
+	return record, nil
+}
+
+// run takes a request from queued to a finished status.
+func (s *Server) run(ctx context.Context, req *request) {
+	defer req.events.close()
+	defer req.cancel()
+
+	if !s.acquire(ctx) {
+		s.finish(req, nil, ctx.Err())
+		return
+	}
+	held := true
+	defer func() {
+		if held {
+			s.release()
+		}
+	}()
+
+	s.setStatus(req, RequestPlanning)
+	agentCtx := agent.NewAgentContext(ctx, req.record.ID, req.submit.User, uuid.New().String())
+	agentCtx.SetStreamHandler(func(event agent.StreamEvent) {
+		s.observeStream(req, event)
+	})
+	if s.setup != nil {
+		s.setup(agentCtx)
+	}
+
+	plan, err := s.coordinator.Plan(agentCtx, &agent.Request{
+		ID:        req.record.ID,
+		User:      req.submit.User,
+		Input:     req.submit.Input,
+		Intent:    req.submit.Intent,
+		Context:   req.submit.Context,
+		CreatedAt: req.record.CreatedAt,
+	})
+	if err != nil {
+		s.finish(req, nil, fmt.Errorf("planning failed: %w", err))
+		return
+	}
+	if req.submit.DiagnoseOnly {
+		plan.DropRemediation()
+		if len(plan.Tasks) == 0 {
+			s.finish(req, nil, fmt.Errorf("plan %s has no diagnosis to run", plan.ID))
+			return
+		}
+	}
+	s.mu.Lock()
+	req.record.PlanID = plan.ID
+	req.plan = snapshotPlan(plan)
+	s.mu.Unlock()
+
+	if req.submit.ApprovalRequired || (s.approveRemediation && hasRemediation(plan)) {
+		// Waiting for a person must not hold a slot other requests
+		// could plan or execute in.
+		s.release()
+		held = false
+		decision := s.awaitApproval(ctx, req, plan)
+		if ctx.Err() != nil {
+			s.finish(req, nil, ctx.Err())
+			return
+		}
+		if !decision.Approved {
+			s.setFinal(req, RequestRejected, "", decision.Reason)
+			return
+		}
+		for _, id := range decision.Drop {
+			if plan.Task(id) == nil {
+				continue
+			}
+			if _, err := plan.DropTask(id); err != nil {
+				s.finish(req, nil, err)
+				return
+			}
+		}
+		s.mu.Lock()
+		req.plan = snapshotPlan(plan)
+		s.mu.Unlock()
+		if !s.acquire(ctx) {
+			s.finish(req, nil, ctx.Err())
+			return
+		}
+		held = true
+	}
+
+	s.mu.Lock()
+	req.plan.Status = agent.TaskStatusRunning
+	s.mu.Unlock()
+	s.setStatus(req, RequestRunning)
+	response, err := s.coordinator.ExecutePlan(agentCtx, plan)
+	// Every task goroutine has returned; the plan is safe to copy.
+	s.mu.Lock()
+	req.plan = snapshotPlan(plan)
+	s.mu.Unlock()
+	s.finish(req, response, err)
+}
+
+// awaitApproval parks the request until a decision is posted, the
+// gate's timeout rejects it or ctx is cancelled.
+func (s *Server) awaitApproval(ctx context.Context, req *request, plan *agent.ExecutionPlan) harness.ApprovalDecision {
+	approval := planApproval(uuid.New().String(), req.record.ID, plan)
+	s.mu.Lock()
+	req.record.ApprovalID = approval.ID
+	s.mu.Unlock()
+	s.setStatus(req, RequestAwaitingApproval)
+	s.logger.Info("Plan awaiting approval", map[string]interface{}{
+		"request_id":  req.record.ID,
+		"approval_id": approval.ID,
+	})
+	return s.approvals.Decide(ctx, approval)
+}
+
+// finish records the outcome of a request.
+func (s *Server) finish(req *request, response *agent.Response, err error) {
+	status := RequestCompleted
+	switch {
+	case errors.Is(err, context.Canceled) || (response != nil && response.Status == agent.TaskStatusCancelled):
+		status = RequestCancelled
+	case err != nil || response == nil || response.Status == agent.TaskStatusFailed:
+		status = RequestFailed
+	}
+	var message string
+	if err != nil {
+		message = err.Error()
+	}
+
+	s.mu.Lock()
+	req.record.Response = response
+	if response != nil {
+		req.record.Result = response.Result
+	}
+	s.mu.Unlock()
+	s.setFinal(req, status, message, "")
+}
+
+// setFinal moves the request to a finished status. reason explains a
+// rejection; message is the error, if any.
+func (s *Server) setFinal(req *request, status RequestStatus, message, reason string) {
+	s.mu.Lock()
+	req.record.Status = status
+	req.record.Error = message
+	if reason != "" {
+		req.record.Error = "rejected: " + reason
+	}
+	req.record.UpdatedAt = time.Now()
+	errText := req.record.Error
+	s.mu.Unlock()
+
+	req.events.append(Event{Type: EventStatus, Status: status, Error: errText})
+	s.logger.Info("Request finished", map[string]interface{}{
+		"request_id": req.record.ID,
+		"status":     status,
+	})
+}
+
+func (s *Server) setStatus(req *request, status RequestStatus) {
+	s.mu.Lock()
+	req.record.Status = status
+	req.record.UpdatedAt = time.Now()
+	s.mu.Unlock()
+	req.events.append(Event{Type: EventStatus, Status: status})
+}
+
+// observeStream forwards a stream event to the feed and keeps the
+// server's copy of the plan's task statuses current.
+func (s *Server) observeStream(req *request, event agent.StreamEvent) {
+	if event.Type == agent.StreamTaskStarted || event.Type == agent.StreamTaskFinished {
+		s.mu.Lock()
+		if req.plan != nil {
+			updateTaskView(req.plan, event)
+		}
+		s.mu.Unlock()
+	}
+	req.events.append(Event{Type: EventStream, Stream: &event})
+}
+
+func (s *Server) notifyApproval(record harness.ApprovalRecord) {
+	s.mu.Lock()
+	listeners := append([]func(harness.ApprovalRecord){}, s.approvalListeners...)
+	s.mu.Unlock()
+	for _, notify := range listeners {
+		notify(record)
+	}
+}
+
+func (s *Server) acquire(ctx context.Context) bool {
+	select {
+	case s.slots <- struct{}{}:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func (s *Server) release() {
+	<-s.slots
+}
+
+func (s *Server) lookup(id string) *request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests[id]
+}
+
+// planLister is implemented by state stores that can enumerate their
+// plans, which finding a request's plan takes.
+type planLister interface {
+	ListPlans(ctx context.Context) ([]*agent.ExecutionPlan, error)
+}
+
+// storedPlans lists the state store's plans, newest first, or nothing
+// when the store cannot list them.
+func (s *Server) storedPlans() []*agent.ExecutionPlan {
+	lister, ok := s.store.(planLister)
+	if !ok {
+		return nil
+	}
+	plans, err := lister.ListPlans(s.baseCtx)
+	if err != nil {
+		s.logger.Warn("Failed to list stored plans", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return nil
+	}
+	return plans
+}
+
+// storedRequest rebuilds request id from the newest plan the state
+// store holds for it. Only planned requests are stored, and a plan that
+// never finished was cut short by the process that ran it, so it counts
+// as failed.
+func (s *Server) storedRequest(id string) (RequestRecord, *agent.ExecutionPlan, bool) {
+	if id == "" {
+		return RequestRecord{}, nil, false
+	}
+	for _, plan := range s.storedPlans() {
+		if plan.RequestID != id {
+			continue
+		}
+		record := RequestRecord{ID: id, PlanID: plan.ID, CreatedAt: plan.CreatedAt, UpdatedAt: plan.UpdatedAt}
+		record.Input, _ = plan.Metadata["request"].(string)
+		switch plan.Status {
+		case agent.TaskStatusCompleted:
+			record.Status = RequestCompleted
+		case agent.TaskStatusCancelled:
+			record.Status = RequestCancelled
+		case agent.TaskStatusFailed:
+			record.Status = RequestFailed
+		default:
+			record.Status = RequestFailed
+			record.Error = fmt.Sprintf("plan %s did not finish: the server running it stopped", plan.ID)
+		}
+		return record, plan, true
+	}
+	return RequestRecord{}, nil, false
+}
+
+// storedPlan loads plan id from the state store.
+func (s *Server) storedPlan(id string) (*agent.ExecutionPlan, bool) {
+	if s.store == nil {
+		return nil, false
+	}
+	plan, err := s.store.LoadPlan(s.baseCtx, id)
+	return plan, err == nil && plan != nil
+}
+
+// storedTaskRecord finds task id in the newest stored plan that has it,
+// narrowed to requestID's plan if set. A task no stored plan lists is
+// loaded on its own, without the request and plan it belongs to.
+func (s *Server) storedTaskRecord(id, requestID string) (TaskRecord, bool) {
+	for _, plan := range s.storedPlans() {
+		if requestID != "" && plan.RequestID != requestID {
+			continue
+		}
+		if task := plan.Task(id); task != nil {
+			return TaskRecord{RequestID: plan.RequestID, PlanID: plan.ID, Task: task}, true
+		}
+	}
+	if s.store == nil || requestID != "" {
+		return TaskRecord{}, false
+	}
+	task, err := s.store.LoadTask(s.baseCtx, id)
+	if err != nil || task == nil {
+		return TaskRecord{}, false
+	}
+	return TaskRecord{Task: task}, true
+}
+
+// forgetFinishedLocked drops the oldest finished requests beyond the
+// retention limit. Requests still in flight are never dropped.
+func (s *Server) forgetFinishedLocked() {
+	excess := len(s.order) - s.retained
+	if excess <= 0 {
+		return
+	}
+	kept := s.order[:0]
+	for _, id := range s.order {
+		if excess > 0 && s.requests[id].record.Status.Finished() {
+			delete(s.requests, id)
+			excess--
+			continue
+		}
+		kept = append(kept, id)
+	}
+	s.order = kept
+}
+
+// snapshotPlan deep-copies plan so the API can serve it while the
+// coordinator keeps mutating the original's tasks.
+func snapshotPlan(plan *agent.ExecutionPlan) *agent.ExecutionPlan {
+	data, err := json.Marshal(plan)
+	if err != nil {
+		return &agent.ExecutionPlan{ID: plan.ID, RequestID: plan.RequestID, Status: plan.Status}
+	}
+	var copied agent.ExecutionPlan
+	if err := json.Unmarshal(data, &copied); err != nil {
+		return &agent.ExecutionPlan{ID: plan.ID, RequestID: plan.RequestID, Status: plan.Status}
+	}
+	return &copied
+}
+
+// updateTaskView applies a task start or finish to the server's copy
+// of the plan. Tasks the coordinator added during execution (another
+// remediation iteration) are appended.
+func updateTaskView(plan *agent.ExecutionPlan, event agent.StreamEvent) {
+	task := plan.Task(event.TaskID)
+	if task == nil {
+		task = &agent.Task{ID: event.TaskID, Description: event.Text}
+		plan.Tasks = append(plan.Tasks, task)
+	}
+	task.AssignedAgent = event.Agent
+	if event.Type == agent.StreamTaskStarted {
+		task.Status = agent.TaskStatusRunning
+		return
+	}
+	task.Status = event.Status
+	task.Error = event.Text
+}
+
+func hasRemediation(plan *agent.ExecutionPlan) bool {
+	for _, task := range plan.Tasks {
+		if task.Type == agent.TaskTypeRemediate {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,374 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"kubeagent/pkg/agent"
+	"kubeagent/pkg/agent/harness"
+)
+
+// scriptedCoordinator plans the tasks it was given and "executes" a
+// plan by completing every task, reporting progress like the real
+// coordinator does. Given a store, it saves its plans there too.
+type scriptedCoordinator struct {
+	agent.CoordinatorAgent
+	tasks    func() []*agent.Task
+	auditor  harness.AuditLogger
+	store    agent.StateStore
+	executed chan *agent.ExecutionPlan
+}
+
+func newScriptedCoordinator(tasks ...*agent.Task) *scriptedCoordinator {
+	return &scriptedCoordinator{
+		tasks: func() []*agent.Task {
+			out := make([]*agent.Task, len(tasks))
+			for i, task := range tasks {
+				copied := *task
+				out[i] = &copied
+			}
+			return out
+		},
+		executed: make(chan *agent.ExecutionPlan, 4),
+	}
+}
+
+func (c *scriptedCoordinator) Plan(ctx *agent.AgentContext, request *agent.Request) (*agent.ExecutionPlan, error) {
+	plan := &agent.ExecutionPlan{
+		ID:        "plan-" + request.ID,
+		RequestID: request.ID,
+		Tasks:     c.tasks(),
+		Status:    agent.TaskStatusPending,
+		Metadata:  map[string]interface{}{"request": request.Input},
+		CreatedAt: time.Now(),
+	}
+	if c.store != nil {
+		c.store.SavePlan(ctx.Context(), plan)
+	}
+	return plan, nil
+}
+
+func (c *scriptedCoordinator) ExecutePlan(ctx *agent.AgentContext, plan *agent.ExecutionPlan) (*agent.Response, error) {
+	for _, task := range plan.Tasks {
+		ctx.Emit(agent.StreamEvent{Type: agent.StreamTaskStarted, TaskID: task.ID, Agent: agent.AgentTypeDiagnostician})
+		if c.auditor != nil {
+			c.auditor.Record(ctx.Context(), harness.AuditEvent{Kind: harness.AuditAction, RequestID: ctx.RequestID, Action: "inspect"})
+		}
+		task.Status = agent.TaskStatusCompleted
+		ctx.Emit(agent.StreamEvent{Type: agent.StreamTaskFinished, TaskID: task.ID, Agent: agent.AgentTypeDiagnostician, Status: task.Status})
+	}
+	plan.Status = agent.TaskStatusCompleted
+	if c.store != nil {
+		c.store.SavePlan(ctx.Context(), plan)
+	}
+	c.executed <- plan
+	return &agent.Response{RequestID: ctx.RequestID, Status: agent.TaskStatusCompleted, Result: "all good"}, nil
+}
+
+func call(t *testing.T, handler http.Handler, method, path, token string, body interface{}) *httptest.ResponseRecorder {
+	t.Helper()
+	var reader *bytes.Reader
+	if body != nil {
+		data, _ := json.Marshal(body)
+		reader = bytes.NewReader(data)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+	r := httptest.NewRequest(method, path, reader)
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w
+}
+
+func waitForStatus(t *testing.T, server *Server, id string, want RequestStatus) RequestRecord {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		req := server.lookup(id)
+		server.mu.Lock()
+		record := req.record
+		server.mu.Unlock()
+		if record.Status == want {
+			return record
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("Request %s never reached %s", id, want)
+	return RequestRecord{}
+}
+
+func TestServer_RequiresBearerToken(t *testing.T) {
+	handler := NewServer(newScriptedCoordinator(), agent.NewNoOpLogger()).WithToken("s3cret").Handler()
+
+	if w := call(t, handler, "GET", "/api/v1/requests", "", nil); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without a token, got %d", w.Code)
+	}
+	if w := call(t, handler, "GET", "/api/v1/requests", "wrong", nil); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 with a wrong token, got %d", w.Code)
+	}
+	if w := call(t, handler, "GET", "/api/v1/requests", "s3cret", nil); w.Code != http.StatusOK {
+		t.Errorf("Expected 200 with the token, got %d", w.Code)
+	}
+	if w := call(t, handler, "GET", "/healthz", "", nil); w.Code != http.StatusOK {
+		t.Errorf("Expected the health check to be open, got %d", w.Code)
+	}
+}
+
+func TestServer_RunsRequestAsynchronously(t *testing.T) {
+	coordinator := newScriptedCoordinator(&agent.Task{ID: "d", Type: agent.TaskTypeDiagnose})
+	server := NewServer(coordinator, agent.NewNoOpLogger())
+	coordinator.auditor = server.Auditor()
+	handler := server.Handler()
+
+	w := call(t, handler, "POST", "/api/v1/requests", "", SubmitRequest{Input: "why is web-1 crashing"})
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("Expected 202, got %d: %s", w.Code, w.Body)
+	}
+	var submitted RequestRecord
+	json.Unmarshal(w.Body.Bytes(), &submitted)
+	if w.Header().Get("Location") != "/api/v1/requests/"+submitted.ID {
+		t.Errorf("Unexpected Location %q", w.Header().Get("Location"))
+	}
+	waitForStatus(t, server, submitted.ID, RequestCompleted)
+
+	var detail RequestDetail
+	json.Unmarshal(call(t, handler, "GET", "/api/v1/requests/"+submitted.ID, "", nil).Body.Bytes(), &detail)
+	if detail.Result != "all good" || detail.Plan == nil || detail.Plan.Tasks[0].Status != agent.TaskStatusCompleted {
+		t.Errorf("Unexpected detail %+v", detail)
+	}
+	if w := call(t, handler, "GET", "/api/v1/tasks/d?request_id="+submitted.ID, "", nil); w.Code != http.StatusOK {
+		t.Errorf("Expected the task to be found, got %d", w.Code)
+	}
+	if w := call(t, handler, "GET", "/api/v1/plans/"+detail.PlanID, "", nil); w.Code != http.StatusOK {
+		t.Errorf("Expected the plan to be found, got %d", w.Code)
+	}
+
+	// The finished request's feed replays from the start and ends.
+	w = call(t, handler, "GET", "/api/v1/requests/"+submitted.ID+"/events", "", nil)
+	if w.Header().Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("Expected an event stream, got %q", w.Header().Get("Content-Type"))
+	}
+	var names []string
+	scanner := bufio.NewScanner(w.Body)
+	for scanner.Scan() {
+		if name, ok := strings.CutPrefix(scanner.Text(), "event: "); ok {
+			names = append(names, name)
+		}
+	}
+	want := "status status status stream audit stream status"
+	if got := strings.Join(names, " "); got != want {
+		t.Errorf("Expected events %q, got %q", want, got)
+	}
+}
+
+func TestServer_FallsBackToStateStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.db")
+	store, err := agent.NewBoltStateStore(path)
+	if err != nil {
+		t.Fatalf("Failed to open the state store: %v", err)
+	}
+	coordinator := newScriptedCoordinator(&agent.Task{ID: "d", Type: agent.TaskTypeDiagnose})
+	coordinator.store = store
+	server := NewServer(coordinator, agent.NewNoOpLogger()).WithStateStore(store)
+	finished, _ := server.Submit(SubmitRequest{Input: "why is web-1 crashing"})
+	waitForStatus(t, server, finished.ID, RequestCompleted)
+	// A plan the stopped server never finished.
+	store.SavePlan(context.Background(), &agent.ExecutionPlan{
+		ID: "plan-cut-short", RequestID: "cut-short", Status: agent.TaskStatusRunning, CreatedAt: time.Now(),
+		Tasks: []*agent.Task{{ID: "d", Type: agent.TaskTypeDiagnose, Status: agent.TaskStatusRunning}},
+	})
+	store.Close()
+
+	// A new server on the same store knows neither request in memory.
+	store, err = agent.NewBoltStateStore(path)
+	if err != nil {
+		t.Fatalf("Failed to reopen the state store: %v", err)
+	}
+	defer store.Close()
+	server = NewServer(newScriptedCoordinator(), agent.NewNoOpLogger()).WithStateStore(store)
+	handler := server.Handler()
+
+	var detail RequestDetail
+	w := call(t, handler, "GET", "/api/v1/requests/"+finished.ID, "", nil)
+	json.Unmarshal(w.Body.Bytes(), &detail)
+	if w.Code != http.StatusOK || detail.Status != RequestCompleted || detail.Input != "why is web-1 crashing" || detail.Plan == nil {
+		t.Fatalf("Expected the finished request from the store, got %d: %s", w.Code, w.Body)
+	}
+	if w := call(t, handler, "GET", "/api/v1/plans/plan-"+finished.ID, "", nil); w.Code != http.StatusOK {
+		t.Errorf("Expected the plan to be found, got %d", w.Code)
+	}
+	var task TaskRecord
+	w = call(t, handler, "GET", "/api/v1/tasks/d?request_id="+finished.ID, "", nil)
+	json.Unmarshal(w.Body.Bytes(), &task)
+	if w.Code != http.StatusOK || task.PlanID != "plan-"+finished.ID || task.Status != agent.TaskStatusCompleted {
+		t.Errorf("Expected the stored task, got %d: %s", w.Code, w.Body)
+	}
+	var tasks []TaskRecord
+	json.Unmarshal(call(t, handler, "GET", "/api/v1/tasks?request_id="+finished.ID, "", nil).Body.Bytes(), &tasks)
+	if len(tasks) != 1 || tasks[0].RequestID != finished.ID {
+		t.Errorf("Expected the request's one task, got %+v", tasks)
+	}
+
+	record, ok := server.Request("cut-short")
+	if !ok || record.Status != RequestFailed || record.Error == "" {
+		t.Errorf("Expected the unfinished plan to count as failed, got %+v", record)
+	}
+	if _, ok := server.Request("unknown"); ok {
+		t.Error("Expected an unknown request to stay unknown")
+	}
+}
+
+// diagnostician is a BaseAgent that takes diagnose tasks.
+type diagnostician struct {
+	*agent.BaseAgent
+}
+
+func (d *diagnostician) CanHandle(taskType agent.TaskType) bool {
+	return taskType == agent.TaskTypeDiagnose
+}
+
+// TestServer_ConcurrentRequestsShareCoordinator runs several requests at
+// once through one real coordinator, as `serve` does; run with -race.
+func TestServer_ConcurrentRequestsShareCoordinator(t *testing.T) {
+	planner := &agent.MockLLMClient{
+		CompleteFunc: func(ctx context.Context, messages []agent.Message) (string, error) {
+			if strings.Contains(messages[len(messages)-1].Content, "primary intent") {
+				return "diagnose", nil
+			}
+			return `[
+				{"id": "events", "type": "diagnose", "description": "read the events", "assigned_agent": "diagnostician"},
+				{"id": "logs", "type": "diagnose", "description": "read the logs", "assigned_agent": "diagnostician"}
+			]`, nil
+		},
+	}
+	store := agent.NewMemoryStateStore()
+	coordinator := agent.NewCoordinator(nil, planner, store, agent.NewNoOpLogger()).
+		WithScheduler(agent.SchedulerConfig{MaxConcurrency: 3})
+	diag := &diagnostician{agent.NewBaseAgent(
+		&agent.AgentConfig{Name: "diag", Type: agent.AgentTypeDiagnostician}, planner, agent.NewNoOpLogger())}
+	coordinator.RegisterAgent(diag)
+	server := NewServer(coordinator, agent.NewNoOpLogger()).WithStateStore(store).WithMaxConcurrent(4)
+
+	const requests = 4
+	ids := make(chan string, requests)
+	var wg sync.WaitGroup
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			record, err := server.Submit(SubmitRequest{Input: fmt.Sprintf("why is web-%d crashing", i)})
+			if err != nil {
+				t.Errorf("Submit failed: %v", err)
+				return
+			}
+			ids <- record.ID
+		}(i)
+	}
+	wg.Wait()
+	close(ids)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for id := range ids {
+		record, err := server.Wait(ctx, id)
+		if err != nil || record.Status != RequestCompleted {
+			t.Errorf("Expected request %s completed, got %s (%v): %s", id, record.Status, err, record.Error)
+		}
+	}
+	if plans, tasks := coordinator.GetMetrics().TasksCompleted, diag.GetMetrics().TasksCompleted; plans != requests || tasks != 2*requests {
+		t.Errorf("Expected %d plans and %d tasks completed, got %d and %d", requests, 2*requests, plans, tasks)
+	}
+}
+
+func TestServer_RemediationWaitsForApproval(t *testing.T) {
+	coordinator := newScriptedCoordinator(
+		&agent.Task{ID: "d", Type: agent.TaskTypeDiagnose},
+		&agent.Task{ID: "r", Type: agent.TaskTypeRemediate, Dependencies: []string{"d"}},
+	)
+	server := NewServer(coordinator, agent.NewNoOpLogger())
+	handler := server.Handler()
+
+	approved, _ := server.Submit(SubmitRequest{Input: "fix web-1"})
+	record := waitForStatus(t, server, approved.ID, RequestAwaitingApproval)
+	var pending []harness.ApprovalRecord
+	json.Unmarshal(call(t, handler, "GET", "/api/v1/approvals", "", nil).Body.Bytes(), &pending)
+	if len(pending) != 1 || pending[0].ID != record.ApprovalID || pending[0].Kind != harness.ApprovalPlan || !strings.Contains(pending[0].Prompt, "r [remediate]") {
+		t.Fatalf("Expected the plan in the approval queue, got %+v", pending)
+	}
+
+	w := call(t, handler, "POST", "/api/v1/approvals/"+record.ApprovalID, "", harness.ApprovalDecision{Approved: true, Drop: []string{"r"}})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected the decision to be accepted, got %d: %s", w.Code, w.Body)
+	}
+	waitForStatus(t, server, approved.ID, RequestCompleted)
+	if plan := <-coordinator.executed; len(plan.Tasks) != 1 || plan.Tasks[0].ID != "d" {
+		t.Errorf("Expected only the diagnosis to run, got %d task(s)", len(plan.Tasks))
+	}
+	if w := call(t, handler, "POST", "/api/v1/approvals/"+record.ApprovalID, "", harness.ApprovalDecision{Approved: true}); w.Code != http.StatusNotFound {
+		t.Errorf("Expected a second decision to be refused, got %d", w.Code)
+	}
+
+	rejected, _ := server.Submit(SubmitRequest{Input: "fix web-2"})
+	record = waitForStatus(t, server, rejected.ID, RequestAwaitingApproval)
+	call(t, handler, "POST", "/api/v1/approvals/"+record.ApprovalID, "", harness.ApprovalDecision{Approved: false, Reason: "not during the sale"})
+	if record := waitForStatus(t, server, rejected.ID, RequestRejected); !strings.Contains(record.Error, "not during the sale") {
+		t.Errorf("Expected the rejection reason, got %q", record.Error)
+	}
+
+	cancelled, _ := server.Submit(SubmitRequest{Input: "fix web-3"})
+	waitForStatus(t, server, cancelled.ID, RequestAwaitingApproval)
+	if w := call(t, handler, "DELETE", "/api/v1/requests/"+cancelled.ID, "", nil); w.Code != http.StatusAccepted {
+		t.Fatalf("Expected the cancel to be accepted, got %d", w.Code)
+	}
+	waitForStatus(t, server, cancelled.ID, RequestCancelled)
+	if len(server.queue.Pending()) != 0 {
+		t.Error("Expected a cancelled request to leave the approval queue")
+	}
+}
+
+func TestServer_UnansweredApprovalIsRejected(t *testing.T) {
+	coordinator := newScriptedCoordinator(&agent.Task{ID: "r", Type: agent.TaskTypeRemediate})
+	queue := harness.NewHTTPApprover(agent.NewMemoryStateStore())
+	trail := harness.NewAuditTrail(0)
+	gate := harness.NewApprovalGate(queue).WithTimeout(20 * time.Millisecond).WithAuditor(trail)
+	server := NewServer(coordinator, agent.NewNoOpLogger()).WithApprovals(gate, queue)
+
+	submitted, _ := server.Submit(SubmitRequest{Input: "fix web-1"})
+	record := waitForStatus(t, server, submitted.ID, RequestRejected)
+	if !strings.Contains(record.Error, "no decision within") {
+		t.Errorf("Expected a timeout rejection, got %q", record.Error)
+	}
+	events := trail.Since(time.Time{}, submitted.ID)
+	if len(events) != 1 || events[0].Kind != harness.AuditDecision || events[0].Outcome != "rejected" {
+		t.Errorf("Expected one rejected decision in the audit trail, got %+v", events)
+	}
+}
+
+func TestServer_ListenAndServeStopsRequests(t *testing.T) {
+	coordinator := newScriptedCoordinator(&agent.Task{ID: "r", Type: agent.TaskTypeRemediate})
+	server := NewServer(coordinator, agent.NewNoOpLogger())
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- server.ListenAndServe(ctx, "127.0.0.1:0") }()
+
+	record, _ := server.Submit(SubmitRequest{Input: "fix web-1"})
+	waitForStatus(t, server, record.ID, RequestAwaitingApproval)
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("Expected a clean shutdown, got %v", err)
+	}
+	waitForStatus(t, server, record.ID, RequestCancelled)
+}
@@ -0,0 +1,168 @@
+// Package integrations turns chat platforms into front ends of the API
+// server: a message or slash command becomes a Request, the request's
+// final report goes back to the conversation it came from, and plan
+// and HumanTool approvals can be decided there.
+//
+// Dispatcher holds everything the platforms share; the slack and
+// dingtalk subpackages only verify callbacks, parse the platform's
+// payloads and render messages in its markup.
+package integrations
+
+import (
+	"fmt"
+	"strings"
+
+	"kubeagent/pkg/agent"
+	"kubeagent/pkg/agent/harness"
+	"kubeagent/pkg/api"
+)
+
+// Command verbs.
+const (
+	VerbDiagnose = "diagnose"
+	VerbFix      = "fix"
+	VerbAsk      = "ask"
+	VerbStatus   = "status"
+	VerbApprove  = "approve"
+	VerbReject   = "reject"
+	VerbHelp     = "help"
+)
+
+// Usage lists the commands, for "help" and for input that parses to
+// nothing.
+const Usage = "用法：\n" +
+	"diagnose <pod> [-n <namespace>]  诊断 Pod\n" +
+	"fix <pod> [-n <namespace>]       诊断并修复 Pod（修复需审批）\n" +
+	"ask <问题>                        任意自然语言请求\n" +
+	"status [<request-id>]            查看请求状态\n" +
+	"approve <approval-id|task-id> [原因]  批准\n" +
+	"reject <approval-id|task-id> [原因]   拒绝"
+
+// Command is one parsed chat command.
+type Command struct {
+	Verb      string
+	Pod       string
+	Namespace string
+	// ID is the request ID for status, and the approval or task ID for
+	// approve and reject.
+	ID string
+	// Text is the question for ask and the reason for approve and
+	// reject.
+	Text string
+}
+
+// ParseCommand parses "diagnose web-1 -n shop", "approve 1f0c… looks
+// fine" and the like. A leading "/kubeagent" is ignored, so the same
+// parser serves Slack's slash command text and a DingTalk message.
+// Anything that is not a known verb is treated as a question.
+func ParseCommand(text string) (Command, error) {
+	fields := strings.Fields(text)
+	if len(fields) > 0 && strings.EqualFold(fields[0], "/kubeagent") {
+		fields = fields[1:]
+	}
+	if len(fields) == 0 {
+		return Command{Verb: VerbHelp}, nil
+	}
+
+	verb := strings.ToLower(fields[0])
+	args := fields[1:]
+	switch verb {
+	case VerbDiagnose, VerbFix:
+		return parseTarget(verb, args)
+	case VerbStatus:
+		cmd := Command{Verb: verb}
+		if len(args) > 0 {
+			cmd.ID = args[0]
+		}
+		return cmd, nil
+	case VerbApprove, VerbReject:
+		if len(args) == 0 {
+			return Command{}, fmt.Errorf("%s needs an approval or task ID", verb)
+		}
+		return Command{Verb: verb, ID: args[0], Text: strings.Join(args[1:], " ")}, nil
+	case VerbHelp:
+		return Command{Verb: verb}, nil
+	case VerbAsk:
+		if len(args) == 0 {
+			return Command{}, fmt.Errorf("ask needs a question")
+		}
+		return Command{Verb: VerbAsk, Text: strings.Join(args, " ")}, nil
+	}
+	return Command{Verb: VerbAsk, Text: strings.Join(fields, " ")}, nil
+}
+
+// parseTarget reads "<pod>", "<namespace>/<pod>" and "<pod> -n <namespace>".
+func parseTarget(verb string, args []string) (Command, error) {
+	cmd := Command{Verb: verb, Namespace: "default"}
+	for i := 0; i < len(args); i++ {
+		switch arg := args[i]; {
+		case arg == "-n" || arg == "--namespace":
+			if i+1 == len(args) {
+				return Command{}, fmt.Errorf("%s needs a namespace name", arg)
+			}
+			cmd.Namespace = args[i+1]
+			i++
+		case strings.HasPrefix(arg, "--namespace="):
+			cmd.Namespace = strings.TrimPrefix(arg, "--namespace=")
+		case cmd.Pod == "":
+			cmd.Pod = arg
+		default:
+			return Command{}, fmt.Errorf("unexpected argument %q", arg)
+		}
+	}
+	if namespace, pod, ok := strings.Cut(cmd.Pod, "/"); ok {
+		cmd.Namespace, cmd.Pod = namespace, pod
+	}
+	if cmd.Pod == "" {
+		return Command{}, fmt.Errorf("%s needs a pod name", verb)
+	}
+	return cmd, nil
+}
+
+// Submission is the request cmd asks for, on behalf of user. ok is
+// false for verbs that do not start a request.
+func (c Command) Submission(user string) (submit api.SubmitRequest, ok bool) {
+	switch c.Verb {
+	case VerbDiagnose, VerbFix:
+		intent, verb := string(agent.TaskTypeDiagnose), "Diagnose"
+		if c.Verb == VerbFix {
+			intent, verb = string(agent.TaskTypeRemediate), "Diagnose and fix"
+		}
+		return api.SubmitRequest{
+			Input:  fmt.Sprintf("%s pod %q in namespace %q.", verb, c.Pod, c.Namespace),
+			Intent: intent,
+			Context: map[string]interface{}{
+				"namespace":     c.Namespace,
+				"pod_name":      c.Pod,
+				"resource_kind": "Pod",
+				"resource_name": c.Pod,
+			},
+			User: user,
+		}, true
+	case VerbAsk:
+		return api.SubmitRequest{Input: c.Text, User: user}, true
+	}
+	return api.SubmitRequest{}, false
+}
+
+// FindApproval picks the pending approval id refers to: an approval ID,
+// or the ID of the task a HumanTool approval was asked for, as long as
+// exactly one pending approval belongs to that task.
+func FindApproval(pending []harness.ApprovalRecord, id string) (harness.ApprovalRecord, error) {
+	var byTask []harness.ApprovalRecord
+	for _, record := range pending {
+		if record.ID == id {
+			return record, nil
+		}
+		if record.TaskID == id {
+			byTask = append(byTask, record)
+		}
+	}
+	switch len(byTask) {
+	case 0:
+		return harness.ApprovalRecord{}, fmt.Errorf("no pending approval %s", id)
+	case 1:
+		return byTask[0], nil
+	}
+	return harness.ApprovalRecord{}, fmt.Errorf("task %s has %d pending approvals; use the approval ID", id, len(byTask))
+}
@@ -0,0 +1,73 @@
+package integrations
+
+import (
+	"testing"
+
+	"kubeagent/pkg/agent/harness"
+)
+
+func TestParseCommand(t *testing.T) {
+	cases := []struct {
+		text string
+		want Command
+	}{
+		{"", Command{Verb: VerbHelp}},
+		{"/kubeagent diagnose web-1", Command{Verb: VerbDiagnose, Pod: "web-1", Namespace: "default"}},
+		{"diagnose shop/web-1", Command{Verb: VerbDiagnose, Pod: "web-1", Namespace: "shop"}},
+		{"FIX web-1 -n shop", Command{Verb: VerbFix, Pod: "web-1", Namespace: "shop"}},
+		{"fix --namespace=shop web-1", Command{Verb: VerbFix, Pod: "web-1", Namespace: "shop"}},
+		{"approve 42 looks fine", Command{Verb: VerbApprove, ID: "42", Text: "looks fine"}},
+		{"reject 42", Command{Verb: VerbReject, ID: "42"}},
+		{"status", Command{Verb: VerbStatus}},
+		{"status abc", Command{Verb: VerbStatus, ID: "abc"}},
+		{"ask why is web-1 slow", Command{Verb: VerbAsk, Text: "why is web-1 slow"}},
+		{"为什么 web-1 一直重启", Command{Verb: VerbAsk, Text: "为什么 web-1 一直重启"}},
+	}
+	for _, tc := range cases {
+		got, err := ParseCommand(tc.text)
+		if err != nil {
+			t.Errorf("ParseCommand(%q): %v", tc.text, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("ParseCommand(%q) = %+v, want %+v", tc.text, got, tc.want)
+		}
+	}
+
+	for _, text := range []string{"diagnose", "fix web-1 -n", "diagnose web-1 web-2", "approve", "ask"} {
+		if _, err := ParseCommand(text); err == nil {
+			t.Errorf("Expected ParseCommand(%q) to fail", text)
+		}
+	}
+}
+
+func TestCommand_Submission(t *testing.T) {
+	submit, ok := Command{Verb: VerbFix, Pod: "web-1", Namespace: "shop"}.Submission("slack:ann")
+	if !ok || submit.Intent != "remediate" || submit.User != "slack:ann" ||
+		submit.Context["namespace"] != "shop" || submit.Context["pod_name"] != "web-1" {
+		t.Errorf("Unexpected fix submission %+v", submit)
+	}
+	if _, ok := (Command{Verb: VerbApprove, ID: "42"}).Submission("slack:ann"); ok {
+		t.Error("Expected approve not to start a request")
+	}
+}
+
+func TestFindApproval(t *testing.T) {
+	pending := []harness.ApprovalRecord{
+		{ApprovalRequest: harness.ApprovalRequest{ID: "a1", TaskID: "task-1"}},
+		{ApprovalRequest: harness.ApprovalRequest{ID: "a2", TaskID: "task-2"}},
+		{ApprovalRequest: harness.ApprovalRequest{ID: "a3", TaskID: "task-2"}},
+	}
+	if record, err := FindApproval(pending, "a2"); err != nil || record.ID != "a2" {
+		t.Errorf("Expected a2 by approval ID, got %+v, %v", record, err)
+	}
+	if record, err := FindApproval(pending, "task-1"); err != nil || record.ID != "a1" {
+		t.Errorf("Expected a1 by task ID, got %+v, %v", record, err)
+	}
+	if _, err := FindApproval(pending, "task-2"); err == nil {
+		t.Error("Expected an ambiguous task ID to fail")
+	}
+	if _, err := FindApproval(pending, "missing"); err == nil {
+		t.Error("Expected an unknown ID to fail")
+	}
+}
@@ -0,0 +1,318 @@
+// Package dingtalk is the DingTalk front end: an outgoing robot that
+// takes commands from @-mentions, answers in the conversation through
+// its session webhook and posts approvals as action cards.
+package dingtalk
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"kubeagent/pkg/agent"
+	"kubeagent/pkg/agent/harness"
+	"kubeagent/pkg/integrations"
+)
+
+const (
+	// maxTimestampSkew is how old a signed callback may be; DingTalk
+	// documents one hour.
+	maxTimestampSkew = time.Hour
+	// maxMarkdownText keeps a message well under DingTalk's 20000 byte
+	// limit.
+	maxMarkdownText = 4000
+	maxBodyBytes    = 1 << 20
+)
+
+// Bot serves DingTalk's outgoing robot callback and posts through
+// session and group robot webhooks.
+type Bot struct {
+	dispatcher    *integrations.Dispatcher
+	appSecret     string
+	webhook       string
+	webhookSecret string
+	client        *http.Client
+	logger        agent.Logger
+	now           func() time.Time
+}
+
+// conversation is where follow-ups go: the session webhook of the
+// message that started a request, valid until expires. DingTalk has no
+// threads, so this is the conversation itself. A zero conversation
+// posts to the group webhook.
+type conversation struct {
+	webhook string
+	expires time.Time
+}
+
+// NewBot creates a bot verifying callbacks with the robot's appSecret.
+func NewBot(backend integrations.Backend, appSecret string, logger agent.Logger) *Bot {
+	b := &Bot{
+		appSecret: appSecret,
+		client:    &http.Client{Timeout: 10 * time.Second},
+		logger:    logger,
+		now:       time.Now,
+	}
+	b.dispatcher = integrations.NewDispatcher("dingtalk", backend, b, logger)
+	return b
+}
+
+// WithWebhook sets a group robot webhook, signed with secret when it
+// is not empty. It takes follow-ups whose session webhook expired
+// (they last about 90 minutes) and approvals of requests not started
+// from DingTalk.
+func (b *Bot) WithWebhook(webhookURL, secret string) *Bot {
+	b.webhook, b.webhookSecret = webhookURL, secret
+	if webhookURL != "" {
+		b.dispatcher.WithApprovalThread(conversation{})
+	}
+	return b
+}
+
+// WithHTTPClient replaces the client used for webhooks. Nil is
+// tolerated.
+func (b *Bot) WithHTTPClient(client *http.Client) *Bot {
+	if client != nil {
+		b.client = client
+	}
+	return b
+}
+
+// WithApprovers allows these DingTalk staff IDs to approve and reject;
+// without it nobody decides from chat.
+func (b *Bot) WithApprovers(staffIDs ...string) *Bot {
+	b.dispatcher.WithApprovers(staffIDs...)
+	return b
+}
+
+// NotifyApproval posts a newly pending approval; register it with
+// api.Server.OnApproval.
+func (b *Bot) NotifyApproval(record harness.ApprovalRecord) {
+	b.dispatcher.NotifyApproval(record)
+}
+
+// callback is the part of an outgoing robot message the bot reads.
+type callback struct {
+	MsgType string `json:"msgtype"`
+	Text    struct {
+		Content string `json:"content"`
+	} `json:"text"`
+	SenderNick                string `json:"senderNick"`
+	SenderStaffID             string `json:"senderStaffId"`
+	SenderID                  string `json:"senderId"`
+	ConversationID            string `json:"conversationId"`
+	SessionWebhook            string `json:"sessionWebhook"`
+	SessionWebhookExpiredTime int64  `json:"sessionWebhookExpiredTime"`
+}
+
+// Handler serves the outgoing robot callback at prefix. The answer is
+// the HTTP response, which DingTalk posts to the conversation.
+func (b *Bot) Handler(prefix string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST "+strings.TrimSuffix(prefix, "/"), b.handle)
+	return mux
+}
+
+func (b *Bot) handle(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := b.verify(r.Header); err != nil {
+		b.logger.Warn("Rejected DingTalk callback", map[string]interface{}{"error": err.Error()})
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	var msg callback
+	if err := json.Unmarshal(body, &msg); err != nil {
+		http.Error(w, "invalid payload", http.StatusBadRequest)
+		return
+	}
+	if msg.MsgType != "text" {
+		writeReply(w, integrations.Usage)
+		return
+	}
+
+	cmd, err := integrations.ParseCommand(msg.Text.Content)
+	if err != nil {
+		writeReply(w, fmt.Sprintf("%v\n%s", err, integrations.Usage))
+		return
+	}
+	sender := integrations.Sender{ID: msg.SenderStaffID, Name: msg.SenderNick}
+	if sender.ID == "" {
+		// Robots in external groups only see the encrypted sender ID.
+		sender.ID = msg.SenderID
+	}
+	conv := conversation{webhook: msg.SessionWebhook}
+	if msg.SessionWebhookExpiredTime > 0 {
+		conv.expires = time.UnixMilli(msg.SessionWebhookExpiredTime)
+	}
+	writeReply(w, b.dispatcher.Run(sender, cmd, conv))
+}
+
+// PostOutcome implements integrations.Poster.
+func (b *Bot) PostOutcome(t integrations.Thread, outcome integrations.Outcome) error {
+	conv, ok := t.(conversation)
+	if !ok {
+		return fmt.Errorf("not a DingTalk conversation: %T", t)
+	}
+	title := fmt.Sprintf("%s 请求 %s %s", outcome.Icon(), outcome.RequestID, outcome.Status)
+	var text strings.Builder
+	fmt.Fprintf(&text, "### %s", title)
+	if outcome.Error != "" {
+		fmt.Fprintf(&text, "\n\n%s", outcome.Error)
+	}
+	if outcome.Result != "" {
+		fmt.Fprintf(&text, "\n\n%s", outcome.Result)
+	}
+	writeList(&text, "任务", outcome.Tasks)
+	writeList(&text, "验证", outcome.Verification)
+	return b.send(conv, map[string]interface{}{
+		"msgtype": "markdown",
+		"markdown": map[string]string{
+			"title": title,
+			"text":  truncate(text.String(), maxMarkdownText),
+		},
+	})
+}
+
+// PostApproval implements integrations.Poster. The buttons use the
+// dtmd scheme, which makes the clicking user send "approve <id>" to the
+// robot; in group chats DingTalk may still need the robot @-mentioned.
+func (b *Bot) PostApproval(t integrations.Thread, record harness.ApprovalRecord) error {
+	conv, ok := t.(conversation)
+	if !ok {
+		return fmt.Errorf("not a DingTalk conversation: %T", t)
+	}
+	title := integrations.ApprovalTitle(record)
+	text := fmt.Sprintf("### %s\n\n```\n%s\n```\n\n%s", title,
+		integrations.ApprovalBody(record, maxMarkdownText), integrations.ApprovalHint(record))
+	return b.send(conv, map[string]interface{}{
+		"msgtype": "actionCard",
+		"actionCard": map[string]interface{}{
+			"title":          title,
+			"text":           text,
+			"btnOrientation": "1",
+			"btns": []map[string]string{
+				{"title": "批准", "actionURL": sendMessageURL(integrations.VerbApprove + " " + record.ID)},
+				{"title": "拒绝", "actionURL": sendMessageURL(integrations.VerbReject + " " + record.ID)},
+			},
+		},
+	})
+}
+
+func sendMessageURL(content string) string {
+	return "dtmd://dingtalkclient/sendMessage?content=" + url.QueryEscape(content)
+}
+
+func writeList(b *strings.Builder, heading string, lines []string) {
+	if len(lines) == 0 {
+		return
+	}
+	fmt.Fprintf(b, "\n\n**%s**\n", heading)
+	for _, line := range lines {
+		fmt.Fprintf(b, "\n- %s", line)
+	}
+}
+
+// send posts msg to the conversation's session webhook while it is
+// valid, and to the group webhook otherwise.
+func (b *Bot) send(conv conversation, msg interface{}) error {
+	target := conv.webhook
+	if target == "" || (!conv.expires.IsZero() && b.now().After(conv.expires)) {
+		if b.webhook == "" {
+			return errors.New("session webhook expired and no group webhook configured")
+		}
+		target = b.signedWebhook()
+	}
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	resp, err := b.client.Post(target, "application/json; charset=utf-8", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		ErrCode int    `json:"errcode"`
+		ErrMsg  string `json:"errmsg"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxBodyBytes)).Decode(&result); err != nil {
+		return fmt.Errorf("dingtalk webhook: HTTP %d: %w", resp.StatusCode, err)
+	}
+	if result.ErrCode != 0 {
+		return fmt.Errorf("dingtalk webhook: %d %s", result.ErrCode, result.ErrMsg)
+	}
+	return nil
+}
+
+// signedWebhook appends the timestamp and signature a group robot with
+// "加签" security requires.
+func (b *Bot) signedWebhook() string {
+	if b.webhookSecret == "" {
+		return b.webhook
+	}
+	ts := strconv.FormatInt(b.now().UnixMilli(), 10)
+	separator := "&"
+	if !strings.Contains(b.webhook, "?") {
+		separator = "?"
+	}
+	return b.webhook + separator + "timestamp=" + ts + "&sign=" + url.QueryEscape(Sign(b.webhookSecret, ts))
+}
+
+// verify checks the timestamp and sign headers of a callback.
+func (b *Bot) verify(header http.Header) error {
+	ts := header.Get("timestamp")
+	millis, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return errors.New("missing timestamp")
+	}
+	if skew := b.now().Sub(time.UnixMilli(millis)); math.Abs(float64(skew)) > float64(maxTimestampSkew) {
+		return errors.New("stale timestamp")
+	}
+	if !hmac.Equal([]byte(header.Get("sign")), []byte(Sign(b.appSecret, ts))) {
+		return errors.New("invalid signature")
+	}
+	return nil
+}
+
+// Sign computes DingTalk's signature of timestamp ts (milliseconds):
+// base64(HMAC-SHA256(secret, ts + "\n" + secret)). Callbacks and signed
+// group webhooks use the same scheme.
+func Sign(secret, ts string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ts + "\n" + secret))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func truncate(s string, limit int) string {
+	if len(s) <= limit {
+		return s
+	}
+	for limit > 0 && s[limit]&0xC0 == 0x80 {
+		limit--
+	}
+	return s[:limit] + "\n…"
+}
+
+func writeReply(w http.ResponseWriter, content string) {
+	data, _ := json.Marshal(map[string]interface{}{
+		"msgtype": "text",
+		"text":    map[string]string{"content": content},
+	})
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
@@ -0,0 +1,152 @@
+package dingtalk
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"kubeagent/pkg/agent"
+	"kubeagent/pkg/agent/agenttest"
+	"kubeagent/pkg/api"
+	"kubeagent/pkg/integrations"
+)
+
+const testSecret = "app-secret"
+
+// posted is a message a webhook stand-in received.
+type posted struct {
+	query url.Values
+	body  map[string]interface{}
+}
+
+// webhookStandIn plays a session or group robot webhook.
+func webhookStandIn(t *testing.T) (*httptest.Server, chan posted) {
+	t.Helper()
+	received := make(chan posted, 16)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		received <- posted{query: r.URL.Query(), body: body}
+		w.Write([]byte(`{"errcode":0,"errmsg":"ok"}`))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, received
+}
+
+func nextPosted(t *testing.T, received chan posted, msgtype string) posted {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case p := <-received:
+			if p.body["msgtype"] == msgtype {
+				return p
+			}
+		case <-timeout:
+			t.Fatalf("Timed out waiting for a %s message", msgtype)
+		}
+	}
+}
+
+// say sends a signed outgoing robot callback and returns the text
+// reply.
+func say(t *testing.T, handler http.Handler, secret, staffID, content, sessionWebhook string) (int, string) {
+	t.Helper()
+	body, _ := json.Marshal(map[string]interface{}{
+		"msgtype":                   "text",
+		"text":                      map[string]string{"content": " " + content},
+		"senderNick":                staffID,
+		"senderStaffId":             staffID,
+		"conversationId":            "cid-1",
+		"sessionWebhook":            sessionWebhook,
+		"sessionWebhookExpiredTime": time.Now().Add(time.Hour).UnixMilli(),
+	})
+	r := httptest.NewRequest("POST", "/dingtalk", bytes.NewReader(body))
+	ts := strconv.FormatInt(time.Now().UnixMilli(), 10)
+	r.Header.Set("timestamp", ts)
+	r.Header.Set("sign", Sign(secret, ts))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	var reply struct {
+		Text struct {
+			Content string `json:"content"`
+		} `json:"text"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &reply)
+	return w.Code, reply.Text.Content
+}
+
+func setup(t *testing.T) (*Bot, http.Handler) {
+	t.Helper()
+	server := api.NewServer(&agenttest.FixingCoordinator{}, agent.NewNoOpLogger())
+	bot := NewBot(server, testSecret, agent.NewNoOpLogger()).WithApprovers("boss")
+	server.OnApproval(bot.NotifyApproval).WithRoute("/dingtalk", bot.Handler("/dingtalk"))
+	return bot, server.Handler()
+}
+
+func TestBot_RejectsUnsignedCallbacks(t *testing.T) {
+	_, handler := setup(t)
+	if code, _ := say(t, handler, "wrong-secret", "boss", "status", ""); code != http.StatusUnauthorized {
+		t.Errorf("Expected a bad signature to be refused, got %d", code)
+	}
+}
+
+func TestBot_FixIsApprovedFromTheConversation(t *testing.T) {
+	session, received := webhookStandIn(t)
+	_, handler := setup(t)
+
+	code, reply := say(t, handler, testSecret, "boss", "fix shop/web-1", session.URL)
+	if code != http.StatusOK || !strings.Contains(reply, "已提交请求") {
+		t.Fatalf("Expected the request to be submitted, got %d %q", code, reply)
+	}
+
+	card := nextPosted(t, received, "actionCard").body["actionCard"].(map[string]interface{})
+	buttons := card["btns"].([]interface{})
+	if len(buttons) != 2 || !strings.Contains(card["title"].(string), "计划等待审批") {
+		t.Fatalf("Expected a plan approval card with two buttons, got %+v", card)
+	}
+	approveURL, _ := url.Parse(buttons[0].(map[string]interface{})["actionURL"].(string))
+	if approveURL.Scheme != "dtmd" {
+		t.Errorf("Expected a dtmd button, got %s", approveURL)
+	}
+	// Clicking the button sends its content as a message to the robot.
+	command := approveURL.Query().Get("content")
+
+	if _, reply := say(t, handler, testSecret, "intern", command, session.URL); !strings.Contains(reply, "无权") {
+		t.Errorf("Expected a non-approver to be refused, got %q", reply)
+	}
+	if _, reply := say(t, handler, testSecret, "boss", command, session.URL); !strings.HasPrefix(reply, "✅ boss 批准了") {
+		t.Errorf("Expected the approval to be confirmed, got %q", reply)
+	}
+
+	markdown := nextPosted(t, received, "markdown").body["markdown"].(map[string]interface{})
+	text := markdown["text"].(string)
+	if !strings.Contains(text, "completed") || !strings.Contains(text, "raised the memory limit") || !strings.Contains(text, "fix: passed - pod is Ready") {
+		t.Errorf("Expected the report and verification, got %q", text)
+	}
+}
+
+func TestBot_ExpiredSessionFallsBackToSignedGroupWebhook(t *testing.T) {
+	group, received := webhookStandIn(t)
+	bot, _ := setup(t)
+	bot.WithWebhook(group.URL+"/robot/send?access_token=t", "group-secret")
+
+	expired := conversation{webhook: "http://127.0.0.1:1/unreachable", expires: time.Now().Add(-time.Minute)}
+	if err := bot.PostOutcome(expired, integrations.Outcome{RequestID: "r1", Status: api.RequestCompleted}); err != nil {
+		t.Fatalf("PostOutcome: %v", err)
+	}
+	p := nextPosted(t, received, "markdown")
+	if p.query.Get("access_token") != "t" || p.query.Get("sign") != Sign("group-secret", p.query.Get("timestamp")) {
+		t.Errorf("Expected a signed group webhook call, got %v", p.query)
+	}
+
+	if err := NewBot(nil, testSecret, agent.NewNoOpLogger()).PostOutcome(expired, integrations.Outcome{}); err == nil {
+		t.Error("Expected an expired session without a group webhook to fail")
+	}
+}
@@ -0,0 +1,255 @@
+package integrations
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"kubeagent/pkg/agent"
+	"kubeagent/pkg/agent/harness"
+	"kubeagent/pkg/api"
+)
+
+// Backend is what a chat front end needs from the API server.
+// *api.Server implements it.
+type Backend interface {
+	Submit(submit api.SubmitRequest) (api.RequestRecord, error)
+	Request(id string) (api.RequestRecord, bool)
+	Wait(ctx context.Context, id string) (api.RequestRecord, error)
+	PendingApprovals() []harness.ApprovalRecord
+	ResolveApproval(id string, decision harness.ApprovalDecision) (harness.ApprovalRecord, error)
+}
+
+// Thread is where a platform posts follow-ups: a Slack channel and
+// thread timestamp, a DingTalk session webhook. The Dispatcher only
+// stores it and hands it back to the Poster.
+type Thread interface{}
+
+// Poster posts to a platform on the Dispatcher's behalf.
+type Poster interface {
+	// PostOutcome reports a finished request in thread.
+	PostOutcome(thread Thread, outcome Outcome) error
+	// PostApproval asks for a decision in thread, with approve and
+	// reject buttons where the platform has them.
+	PostApproval(thread Thread, record harness.ApprovalRecord) error
+}
+
+// Sender is the person behind a chat command.
+type Sender struct {
+	// ID is the platform's stable user ID, which WithApprovers lists.
+	ID   string
+	Name string
+}
+
+// Dispatcher runs chat commands against a Backend and routes what
+// happens afterwards back to the conversation: the final outcome of
+// each request it started, and every approval those requests wait on.
+type Dispatcher struct {
+	platform       string
+	backend        Backend
+	poster         Poster
+	logger         agent.Logger
+	approvers      map[string]bool
+	approvalThread Thread
+
+	mu      sync.Mutex
+	threads map[string]Thread
+}
+
+// NewDispatcher creates a dispatcher for platform ("slack",
+// "dingtalk"), which prefixes the user names recorded in requests and
+// approvals.
+func NewDispatcher(platform string, backend Backend, poster Poster, logger agent.Logger) *Dispatcher {
+	return &Dispatcher{
+		platform: platform,
+		backend:  backend,
+		poster:   poster,
+		logger:   logger,
+		threads:  make(map[string]Thread),
+	}
+}
+
+// WithApprovers allows these user IDs to approve and reject. Without
+// it nobody decides from chat: anyone who can reach the bot could,
+// and approvals are then left to the API.
+func (d *Dispatcher) WithApprovers(ids ...string) *Dispatcher {
+	d.approvers = make(map[string]bool, len(ids))
+	for _, id := range ids {
+		d.approvers[id] = true
+	}
+	return d
+}
+
+// WithApprovalThread is where approvals of requests that were not
+// started from this chat (the API, another bot) are posted. Nil, the
+// default, leaves them to their own front end.
+func (d *Dispatcher) WithApprovalThread(thread Thread) *Dispatcher {
+	d.approvalThread = thread
+	return d
+}
+
+// Run executes cmd for sender and returns the immediate answer.
+// Commands that start a request report to thread once it finishes, and
+// post its approvals there while it runs.
+func (d *Dispatcher) Run(sender Sender, cmd Command, thread Thread) string {
+	user := d.platform + ":" + defaultString(sender.Name, sender.ID)
+	if submit, ok := cmd.Submission(user); ok {
+		return d.submit(submit, thread)
+	}
+	switch cmd.Verb {
+	case VerbApprove, VerbReject:
+		return d.Decide(sender, cmd.ID, cmd.Verb == VerbApprove, cmd.Text)
+	case VerbStatus:
+		return d.status(cmd.ID)
+	}
+	return Usage
+}
+
+func (d *Dispatcher) submit(submit api.SubmitRequest, thread Thread) string {
+	// Held across Submit so an approval the request raises right away
+	// cannot arrive before its thread is known.
+	d.mu.Lock()
+	record, err := d.backend.Submit(submit)
+	if err == nil {
+		d.threads[record.ID] = thread
+	}
+	d.mu.Unlock()
+	if err != nil {
+		return fmt.Sprintf("❌ 提交失败：%v", err)
+	}
+
+	go d.follow(record.ID, thread)
+	return fmt.Sprintf("🔍 已提交请求 %s，完成后会在这里回复结果。", record.ID)
+}
+
+// follow posts the request's outcome to thread once it finishes.
+func (d *Dispatcher) follow(requestID string, thread Thread) {
+	record, err := d.backend.Wait(context.Background(), requestID)
+	d.mu.Lock()
+	delete(d.threads, requestID)
+	d.mu.Unlock()
+	if err != nil {
+		d.logger.Warn("Lost track of chat request", map[string]interface{}{
+			"platform":   d.platform,
+			"request_id": requestID,
+			"error":      err.Error(),
+		})
+		return
+	}
+	if err := d.poster.PostOutcome(thread, OutcomeOf(record)); err != nil {
+		d.logger.Warn("Failed to post request outcome", map[string]interface{}{
+			"platform":   d.platform,
+			"request_id": requestID,
+			"error":      err.Error(),
+		})
+	}
+}
+
+// NotifyApproval posts a newly pending approval to the thread of the
+// request it belongs to. Register it with api.Server.OnApproval.
+func (d *Dispatcher) NotifyApproval(record harness.ApprovalRecord) {
+	d.mu.Lock()
+	thread, ok := d.threads[record.RequestID]
+	d.mu.Unlock()
+	if !ok {
+		thread = d.approvalThread
+	}
+	if thread == nil {
+		return
+	}
+	if err := d.poster.PostApproval(thread, record); err != nil {
+		d.logger.Warn("Failed to post approval", map[string]interface{}{
+			"platform":    d.platform,
+			"approval_id": record.ID,
+			"error":       err.Error(),
+		})
+	}
+}
+
+// Decide approves or rejects the pending approval id (an approval ID
+// or a task ID, see FindApproval) for sender, and returns the answer
+// to show in the conversation.
+func (d *Dispatcher) Decide(sender Sender, id string, approved bool, reason string) string {
+	if len(d.approvers) == 0 {
+		return "⛔ 未配置聊天审批人，请通过 API 审批"
+	}
+	if !d.approvers[sender.ID] {
+		return fmt.Sprintf("⛔ %s 无权审批", defaultString(sender.Name, sender.ID))
+	}
+	pending, err := FindApproval(d.backend.PendingApprovals(), id)
+	if err != nil {
+		return "❌ " + err.Error()
+	}
+	approver := d.platform + ":" + defaultString(sender.Name, sender.ID)
+	record, err := d.backend.ResolveApproval(pending.ID, harness.ApprovalDecision{
+		Approved: approved,
+		Reason:   reason,
+		Approver: approver,
+	})
+	if err != nil {
+		return "❌ " + err.Error()
+	}
+	d.logger.Info("Approval decided from chat", map[string]interface{}{
+		"platform":    d.platform,
+		"approval_id": record.ID,
+		"request_id":  record.RequestID,
+		"approved":    approved,
+		"approver":    approver,
+	})
+	verdict := "✅ %s 批准了 %s"
+	if !approved {
+		verdict = "🚫 %s 拒绝了 %s"
+	}
+	answer := fmt.Sprintf(verdict, defaultString(sender.Name, sender.ID), ApprovalTitle(record))
+	if reason != "" {
+		answer += "：" + reason
+	}
+	return answer
+}
+
+// status describes one request, or without an ID the requests this
+// dispatcher is following and the approvals waiting.
+func (d *Dispatcher) status(id string) string {
+	if id != "" {
+		record, ok := d.backend.Request(id)
+		if !ok {
+			return fmt.Sprintf("❌ 未找到请求 %s", id)
+		}
+		line := fmt.Sprintf("请求 %s：%s", record.ID, record.Status)
+		if record.Error != "" {
+			line += "（" + record.Error + "）"
+		}
+		return line
+	}
+
+	d.mu.Lock()
+	ids := make([]string, 0, len(d.threads))
+	for requestID := range d.threads {
+		ids = append(ids, requestID)
+	}
+	d.mu.Unlock()
+	sort.Strings(ids)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "进行中的请求：%d", len(ids))
+	for _, requestID := range ids {
+		if record, ok := d.backend.Request(requestID); ok {
+			fmt.Fprintf(&b, "\n%s %s", record.ID, record.Status)
+		}
+	}
+	pending := d.backend.PendingApprovals()
+	fmt.Fprintf(&b, "\n待审批：%d", len(pending))
+	for _, record := range pending {
+		fmt.Fprintf(&b, "\n%s %s", record.ID, ApprovalTitle(record))
+	}
+	return b.String()
+}
+
+func defaultString(s, fallback string) string {
+	if s == "" {
+		return fallback
+	}
+	return s
+}
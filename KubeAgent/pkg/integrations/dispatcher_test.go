@@ -0,0 +1,172 @@
+package integrations
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"kubeagent/pkg/agent"
+	"kubeagent/pkg/agent/harness"
+	"kubeagent/pkg/api"
+)
+
+// fakeBackend finishes every request as soon as Wait is released.
+type fakeBackend struct {
+	mu        sync.Mutex
+	submitted []api.SubmitRequest
+	pending   []harness.ApprovalRecord
+	resolved  map[string]harness.ApprovalDecision
+	finish    chan struct{}
+}
+
+func newFakeBackend() *fakeBackend {
+	return &fakeBackend{resolved: make(map[string]harness.ApprovalDecision), finish: make(chan struct{})}
+}
+
+func (b *fakeBackend) Submit(submit api.SubmitRequest) (api.RequestRecord, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.submitted = append(b.submitted, submit)
+	return api.RequestRecord{ID: "req-1", Status: api.RequestQueued}, nil
+}
+
+func (b *fakeBackend) Request(id string) (api.RequestRecord, bool) {
+	return api.RequestRecord{ID: id, Status: api.RequestRunning}, id == "req-1"
+}
+
+func (b *fakeBackend) Wait(ctx context.Context, id string) (api.RequestRecord, error) {
+	<-b.finish
+	return api.RequestRecord{
+		ID:     id,
+		Status: api.RequestCompleted,
+		Result: "restarted web-1",
+		Response: &agent.Response{Data: map[string]interface{}{
+			agent.FinalReportKey: &agent.FinalReport{Tasks: []agent.TaskReport{{
+				ID:           "fix",
+				Type:         agent.TaskTypeRemediate,
+				Status:       agent.TaskStatusCompleted,
+				Verification: &harness.VerificationResult{Status: "passed", Summary: "pod is Ready"},
+			}}},
+		}},
+	}, nil
+}
+
+func (b *fakeBackend) PendingApprovals() []harness.ApprovalRecord {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]harness.ApprovalRecord(nil), b.pending...)
+}
+
+func (b *fakeBackend) ResolveApproval(id string, decision harness.ApprovalDecision) (harness.ApprovalRecord, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for i, record := range b.pending {
+		if record.ID == id {
+			b.pending = append(b.pending[:i], b.pending[i+1:]...)
+			b.resolved[id] = decision
+			return record, nil
+		}
+	}
+	return harness.ApprovalRecord{}, harness.ErrApprovalNotFound
+}
+
+type post struct {
+	thread   Thread
+	outcome  *Outcome
+	approval *harness.ApprovalRecord
+}
+
+type recordingPoster struct{ posts chan post }
+
+func (p *recordingPoster) PostOutcome(thread Thread, outcome Outcome) error {
+	p.posts <- post{thread: thread, outcome: &outcome}
+	return nil
+}
+
+func (p *recordingPoster) PostApproval(thread Thread, record harness.ApprovalRecord) error {
+	p.posts <- post{thread: thread, approval: &record}
+	return nil
+}
+
+func nextPost(t *testing.T, posts chan post) post {
+	t.Helper()
+	select {
+	case p := <-posts:
+		return p
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for a post")
+		return post{}
+	}
+}
+
+func TestDispatcher_FollowsRequestToItsThread(t *testing.T) {
+	backend := newFakeBackend()
+	poster := &recordingPoster{posts: make(chan post, 4)}
+	d := NewDispatcher("slack", backend, poster, agent.NewNoOpLogger()).WithApprovalThread("ops")
+
+	reply := d.Run(Sender{ID: "U1", Name: "ann"}, Command{Verb: VerbFix, Pod: "web-1", Namespace: "shop"}, "thread-1")
+	if !strings.Contains(reply, "req-1") {
+		t.Errorf("Expected the request ID in the reply, got %q", reply)
+	}
+	if backend.submitted[0].User != "slack:ann" {
+		t.Errorf("Expected the platform user on the request, got %q", backend.submitted[0].User)
+	}
+
+	d.NotifyApproval(harness.ApprovalRecord{ApprovalRequest: harness.ApprovalRequest{ID: "a1", RequestID: "req-1"}})
+	if p := nextPost(t, poster.posts); p.thread != "thread-1" || p.approval == nil {
+		t.Errorf("Expected the approval in the request's thread, got %+v", p)
+	}
+	d.NotifyApproval(harness.ApprovalRecord{ApprovalRequest: harness.ApprovalRequest{ID: "a2", RequestID: "from-api"}})
+	if p := nextPost(t, poster.posts); p.thread != "ops" {
+		t.Errorf("Expected a foreign approval in the approval thread, got %+v", p)
+	}
+
+	close(backend.finish)
+	p := nextPost(t, poster.posts)
+	if p.thread != "thread-1" || p.outcome == nil {
+		t.Fatalf("Expected the outcome in the request's thread, got %+v", p)
+	}
+	if len(p.outcome.Verification) != 1 || !strings.Contains(p.outcome.Verification[0], "pod is Ready") {
+		t.Errorf("Expected the verification result in the outcome, got %+v", p.outcome)
+	}
+}
+
+func TestDispatcher_Decide(t *testing.T) {
+	backend := newFakeBackend()
+	backend.pending = []harness.ApprovalRecord{{ApprovalRequest: harness.ApprovalRequest{ID: "a1", TaskID: "fix"}}}
+	d := NewDispatcher("dingtalk", backend, &recordingPoster{}, agent.NewNoOpLogger()).WithApprovers("boss")
+
+	if reply := d.Decide(Sender{ID: "intern"}, "fix", true, ""); !strings.Contains(reply, "无权") {
+		t.Errorf("Expected a non-approver to be refused, got %q", reply)
+	}
+	if len(backend.resolved) != 0 {
+		t.Fatal("Expected nothing to be resolved by a non-approver")
+	}
+
+	reply := d.Run(Sender{ID: "boss", Name: "Li"}, Command{Verb: VerbReject, ID: "fix", Text: "not now"}, nil)
+	if !strings.Contains(reply, "拒绝") || !strings.Contains(reply, "not now") {
+		t.Errorf("Unexpected reply %q", reply)
+	}
+	decision := backend.resolved["a1"]
+	if decision.Approved || decision.Approver != "dingtalk:Li" || decision.Reason != "not now" {
+		t.Errorf("Unexpected decision %+v", decision)
+	}
+	if reply := d.Decide(Sender{ID: "boss"}, "fix", true, ""); !strings.HasPrefix(reply, "❌") {
+		t.Errorf("Expected a decided approval to be gone, got %q", reply)
+	}
+}
+
+func TestDispatcher_DecideWithoutApproversRefusesEveryone(t *testing.T) {
+	backend := newFakeBackend()
+	backend.pending = []harness.ApprovalRecord{{ApprovalRequest: harness.ApprovalRequest{ID: "a1", TaskID: "fix"}}}
+	d := NewDispatcher("slack", backend, &recordingPoster{}, agent.NewNoOpLogger())
+
+	if reply := d.Decide(Sender{ID: "anyone"}, "a1", true, ""); !strings.Contains(reply, "未配置") {
+		t.Errorf("Expected chat approvals to be refused without approvers, got %q", reply)
+	}
+	if len(backend.resolved) != 0 {
+		t.Fatal("Expected nothing to be resolved without approvers")
+	}
+}
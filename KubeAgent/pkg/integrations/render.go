@@ -0,0 +1,149 @@
+package integrations
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"kubeagent/pkg/agent"
+	"kubeagent/pkg/agent/harness"
+	"kubeagent/pkg/api"
+)
+
+// Outcome is what gets posted back when a request finishes, in a form
+// each platform renders in its own markup.
+type Outcome struct {
+	RequestID string
+	Status    api.RequestStatus
+	// Result is the coordinator's prose summary.
+	Result string
+	Error  string
+	// Tasks has one line per task of the final report.
+	Tasks []string
+	// Verification has one line per task the verifier checked: the
+	// part of the report that says whether a fix actually worked.
+	Verification []string
+}
+
+// OutcomeOf summarizes a finished request from its record and final
+// report.
+func OutcomeOf(record api.RequestRecord) Outcome {
+	outcome := Outcome{
+		RequestID: record.ID,
+		Status:    record.Status,
+		Result:    record.Result,
+		Error:     record.Error,
+	}
+	report := ReportOf(record.Response)
+	if report == nil {
+		return outcome
+	}
+	for _, task := range report.Tasks {
+		line := fmt.Sprintf("%s [%s] %s", task.ID, task.Type, task.Status)
+		switch {
+		case task.Error != "":
+			line += ": " + task.Error
+		case task.SkipReason != "":
+			line += ": " + task.SkipReason
+		}
+		outcome.Tasks = append(outcome.Tasks, line)
+		if v := task.Verification; v != nil {
+			line := fmt.Sprintf("%s: %s", task.ID, v.Status)
+			if v.Summary != "" {
+				line += " - " + v.Summary
+			}
+			if task.Rollback != nil {
+				line += fmt.Sprintf(" (rollback: %v)", task.Rollback)
+			}
+			outcome.Verification = append(outcome.Verification, line)
+		}
+	}
+	return outcome
+}
+
+// Icon is a status marker that reads the same on every platform.
+func (o Outcome) Icon() string {
+	switch o.Status {
+	case api.RequestCompleted:
+		return "✅"
+	case api.RequestRejected:
+		return "🚫"
+	case api.RequestCancelled:
+		return "⏹"
+	}
+	return "❌"
+}
+
+// ReportOf extracts the FinalReport from a response. In process it is
+// the coordinator's pointer; after a JSON round trip it is a map.
+func ReportOf(response *agent.Response) *agent.FinalReport {
+	if response == nil || response.Data == nil {
+		return nil
+	}
+	switch report := response.Data[agent.FinalReportKey].(type) {
+	case nil:
+		return nil
+	case *agent.FinalReport:
+		return report
+	case agent.FinalReport:
+		return &report
+	default:
+		data, err := json.Marshal(report)
+		if err != nil {
+			return nil
+		}
+		var decoded agent.FinalReport
+		if err := json.Unmarshal(data, &decoded); err != nil {
+			return nil
+		}
+		return &decoded
+	}
+}
+
+// ApprovalTitle is the one-line heading of an approval message.
+func ApprovalTitle(record harness.ApprovalRecord) string {
+	if record.Kind == harness.ApprovalPlan {
+		return fmt.Sprintf("计划等待审批（风险 %s）", record.Risk)
+	}
+	title := fmt.Sprintf("操作等待审批（风险 %s）", record.Risk)
+	if record.Target.Name != "" {
+		title += fmt.Sprintf(": %s %s/%s", record.Target.Kind, record.Target.Namespace, record.Target.Name)
+	}
+	return title
+}
+
+// ApprovalHint tells people how to answer without the buttons.
+func ApprovalHint(record harness.ApprovalRecord) string {
+	return fmt.Sprintf("approve %s / reject %s <原因>", record.ID, record.ID)
+}
+
+// ApprovalBody is the prompt and any dry-run diffs, capped at limit
+// bytes so a large diff cannot exceed a platform's message size.
+func ApprovalBody(record harness.ApprovalRecord, limit int) string {
+	var b strings.Builder
+	b.WriteString(record.Prompt)
+	for _, diff := range record.Diffs {
+		b.WriteString("\n\n")
+		b.WriteString(diff)
+	}
+	body := b.String()
+	if limit > 0 && len(body) > limit {
+		body = truncateUTF8(body, limit) + "\n…"
+	}
+	return body
+}
+
+// truncateUTF8 cuts s to at most n bytes without splitting a rune.
+func truncateUTF8(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !isRuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+func isRuneStart(b byte) bool {
+	return b&0xC0 != 0x80
+}
@@ -0,0 +1,382 @@
+// Package slack is the Slack front end: the /kubeagent slash command
+// submits requests and answers in a thread, and approvals are posted
+// to that thread with Block Kit approve/reject buttons.
+package slack
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"kubeagent/pkg/agent"
+	"kubeagent/pkg/agent/harness"
+	"kubeagent/pkg/integrations"
+)
+
+// DefaultAPIURL is Slack's Web API.
+const DefaultAPIURL = "https://slack.com/api"
+
+const (
+	// maxTimestampSkew is how old a signed callback may be; Slack
+	// recommends five minutes against replays.
+	maxTimestampSkew = 5 * time.Minute
+	// maxSectionText stays under Block Kit's 3000 character limit for
+	// a section's text.
+	maxSectionText = 2800
+	maxBodyBytes   = 1 << 20
+)
+
+// Action IDs of the approval buttons.
+const (
+	actionApprove = "approve"
+	actionReject  = "reject"
+)
+
+// Bot serves Slack's slash command and interactivity callbacks and
+// posts to Slack through chat.postMessage.
+type Bot struct {
+	dispatcher    *integrations.Dispatcher
+	signingSecret string
+	token         string
+	apiURL        string
+	client        *http.Client
+	logger        agent.Logger
+	now           func() time.Time
+}
+
+// thread is a Slack channel and the timestamp of the message replies
+// hang under. An empty ts posts to the channel itself.
+type thread struct {
+	channel string
+	ts      string
+}
+
+// NewBot creates a bot verifying callbacks with signingSecret and
+// posting with the bot token.
+func NewBot(backend integrations.Backend, signingSecret, botToken string, logger agent.Logger) *Bot {
+	b := &Bot{
+		signingSecret: signingSecret,
+		token:         botToken,
+		apiURL:        DefaultAPIURL,
+		client:        &http.Client{Timeout: 10 * time.Second},
+		logger:        logger,
+		now:           time.Now,
+	}
+	b.dispatcher = integrations.NewDispatcher("slack", backend, b, logger)
+	return b
+}
+
+// WithAPIURL replaces the Web API base URL, for tests against a local
+// stand-in.
+func (b *Bot) WithAPIURL(apiURL string) *Bot {
+	b.apiURL = strings.TrimSuffix(apiURL, "/")
+	return b
+}
+
+// WithHTTPClient replaces the client used for the Web API. Nil is
+// tolerated.
+func (b *Bot) WithHTTPClient(client *http.Client) *Bot {
+	if client != nil {
+		b.client = client
+	}
+	return b
+}
+
+// WithApprovers allows these Slack user IDs to use the approve buttons
+// and commands; without it nobody decides from chat.
+func (b *Bot) WithApprovers(userIDs ...string) *Bot {
+	b.dispatcher.WithApprovers(userIDs...)
+	return b
+}
+
+// WithApprovalChannel posts approvals of requests not started from
+// Slack to channel.
+func (b *Bot) WithApprovalChannel(channel string) *Bot {
+	if channel != "" {
+		b.dispatcher.WithApprovalThread(thread{channel: channel})
+	}
+	return b
+}
+
+// NotifyApproval posts a newly pending approval; register it with
+// api.Server.OnApproval.
+func (b *Bot) NotifyApproval(record harness.ApprovalRecord) {
+	b.dispatcher.NotifyApproval(record)
+}
+
+// Handler serves the slash command at prefix/commands and button
+// clicks at prefix/interactions. Both verify Slack's signature.
+func (b *Bot) Handler(prefix string) http.Handler {
+	prefix = strings.TrimSuffix(prefix, "/")
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST "+prefix+"/commands", b.handleCommand)
+	mux.HandleFunc("POST "+prefix+"/interactions", b.handleInteraction)
+	return mux
+}
+
+// handleCommand answers /kubeagent. A command that starts a request
+// gets a channel message of its own, and everything about the request
+// is posted in that message's thread.
+func (b *Bot) handleCommand(w http.ResponseWriter, r *http.Request) {
+	form, err := b.verifiedForm(w, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	sender := integrations.Sender{ID: form.Get("user_id"), Name: form.Get("user_name")}
+	text := form.Get("text")
+	cmd, err := integrations.ParseCommand(text)
+	if err != nil {
+		writeCommandReply(w, false, fmt.Sprintf("%v\n%s", err, integrations.Usage))
+		return
+	}
+
+	if _, starts := cmd.Submission(""); starts {
+		channel := form.Get("channel_id")
+		ts, err := b.post(message{Channel: channel, Text: fmt.Sprintf("🔍 <@%s> /kubeagent %s", sender.ID, text)})
+		if err != nil {
+			writeCommandReply(w, false, fmt.Sprintf("无法在此频道发消息，请先邀请机器人：%v", err))
+			return
+		}
+		reply := b.dispatcher.Run(sender, cmd, thread{channel: channel, ts: ts})
+		if _, err := b.post(message{Channel: channel, ThreadTS: ts, Text: reply}); err != nil {
+			b.logger.Warn("Failed to post to Slack", map[string]interface{}{"error": err.Error()})
+		}
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	// Decisions are announced to the channel; help and status are
+	// only for the person who asked.
+	decision := cmd.Verb == integrations.VerbApprove || cmd.Verb == integrations.VerbReject
+	writeCommandReply(w, decision, b.dispatcher.Run(sender, cmd, nil))
+}
+
+// interaction is the part of a block_actions payload the bot reads.
+type interaction struct {
+	Type string `json:"type"`
+	User struct {
+		ID       string `json:"id"`
+		Username string `json:"username"`
+	} `json:"user"`
+	Channel struct {
+		ID string `json:"id"`
+	} `json:"channel"`
+	Message struct {
+		TS       string `json:"ts"`
+		ThreadTS string `json:"thread_ts"`
+	} `json:"message"`
+	Actions []struct {
+		ActionID string `json:"action_id"`
+		Value    string `json:"value"`
+	} `json:"actions"`
+}
+
+// handleInteraction decides an approval from its button and answers in
+// the thread the buttons were posted in.
+func (b *Bot) handleInteraction(w http.ResponseWriter, r *http.Request) {
+	form, err := b.verifiedForm(w, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	var payload interaction
+	if err := json.Unmarshal([]byte(form.Get("payload")), &payload); err != nil {
+		http.Error(w, "invalid payload", http.StatusBadRequest)
+		return
+	}
+	// Slack wants an answer within three seconds; the reply is posted
+	// separately.
+	w.WriteHeader(http.StatusOK)
+	if payload.Type != "block_actions" {
+		return
+	}
+
+	sender := integrations.Sender{ID: payload.User.ID, Name: payload.User.Username}
+	ts := payload.Message.ThreadTS
+	if ts == "" {
+		ts = payload.Message.TS
+	}
+	for _, action := range payload.Actions {
+		if action.ActionID != actionApprove && action.ActionID != actionReject {
+			continue
+		}
+		reply := b.dispatcher.Decide(sender, action.Value, action.ActionID == actionApprove, "")
+		if _, err := b.post(message{Channel: payload.Channel.ID, ThreadTS: ts, Text: reply}); err != nil {
+			b.logger.Warn("Failed to post to Slack", map[string]interface{}{"error": err.Error()})
+		}
+	}
+}
+
+// PostOutcome implements integrations.Poster.
+func (b *Bot) PostOutcome(t integrations.Thread, outcome integrations.Outcome) error {
+	th, ok := t.(thread)
+	if !ok {
+		return fmt.Errorf("not a Slack thread: %T", t)
+	}
+	var text strings.Builder
+	fmt.Fprintf(&text, "%s *请求 %s %s*", outcome.Icon(), outcome.RequestID, outcome.Status)
+	if outcome.Error != "" {
+		fmt.Fprintf(&text, "\n%s", outcome.Error)
+	}
+	if outcome.Result != "" {
+		fmt.Fprintf(&text, "\n\n%s", outcome.Result)
+	}
+	writeList(&text, "任务", outcome.Tasks)
+	writeList(&text, "验证", outcome.Verification)
+	_, err := b.post(message{Channel: th.channel, ThreadTS: th.ts, Text: text.String()})
+	return err
+}
+
+// PostApproval implements integrations.Poster.
+func (b *Bot) PostApproval(t integrations.Thread, record harness.ApprovalRecord) error {
+	th, ok := t.(thread)
+	if !ok {
+		return fmt.Errorf("not a Slack thread: %T", t)
+	}
+	title := integrations.ApprovalTitle(record)
+	body := fmt.Sprintf("*%s*\n```%s```", title, integrations.ApprovalBody(record, maxSectionText))
+	_, err := b.post(message{
+		Channel:  th.channel,
+		ThreadTS: th.ts,
+		Text:     title,
+		Blocks: []block{
+			{Type: "section", Text: &textObject{Type: "mrkdwn", Text: body}},
+			{Type: "context", Elements: []element{{Type: "mrkdwn", Text: integrations.ApprovalHint(record)}}},
+			{Type: "actions", Elements: []element{
+				{Type: "button", ActionID: actionApprove, Value: record.ID, Style: "primary", Text: &textObject{Type: "plain_text", Text: "批准"}},
+				{Type: "button", ActionID: actionReject, Value: record.ID, Style: "danger", Text: &textObject{Type: "plain_text", Text: "拒绝"}},
+			}},
+		},
+	})
+	return err
+}
+
+func writeList(b *strings.Builder, heading string, lines []string) {
+	if len(lines) == 0 {
+		return
+	}
+	fmt.Fprintf(b, "\n\n*%s*", heading)
+	for _, line := range lines {
+		fmt.Fprintf(b, "\n• %s", line)
+	}
+}
+
+// message is a chat.postMessage call.
+type message struct {
+	Channel  string  `json:"channel"`
+	ThreadTS string  `json:"thread_ts,omitempty"`
+	Text     string  `json:"text"`
+	Blocks   []block `json:"blocks,omitempty"`
+}
+
+type block struct {
+	Type     string      `json:"type"`
+	Text     *textObject `json:"text,omitempty"`
+	Elements []element   `json:"elements,omitempty"`
+}
+
+// element is a context element (Type and Text as mrkdwn) or a button.
+type element struct {
+	Type     string      `json:"type"`
+	Text     interface{} `json:"text,omitempty"`
+	ActionID string      `json:"action_id,omitempty"`
+	Value    string      `json:"value,omitempty"`
+	Style    string      `json:"style,omitempty"`
+}
+
+type textObject struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// post sends msg and returns the posted message's timestamp.
+func (b *Bot) post(msg message) (string, error) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequest(http.MethodPost, b.apiURL+"/chat.postMessage", bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req.Header.Set("Authorization", "Bearer "+b.token)
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+		TS    string `json:"ts"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxBodyBytes)).Decode(&result); err != nil {
+		return "", fmt.Errorf("chat.postMessage: HTTP %d: %w", resp.StatusCode, err)
+	}
+	if !result.OK {
+		return "", fmt.Errorf("chat.postMessage: %s", result.Error)
+	}
+	return result.TS, nil
+}
+
+// verifiedForm reads the callback body, checks Slack's signature over
+// it and parses it as a form.
+func (b *Bot) verifiedForm(w http.ResponseWriter, r *http.Request) (url.Values, error) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	if err != nil {
+		return nil, err
+	}
+	if err := b.verify(r.Header, body); err != nil {
+		b.logger.Warn("Rejected Slack callback", map[string]interface{}{"error": err.Error()})
+		return nil, err
+	}
+	return url.ParseQuery(string(body))
+}
+
+// verify checks X-Slack-Signature: v0=HMAC-SHA256(secret, "v0:" + ts + ":" + body).
+func (b *Bot) verify(header http.Header, body []byte) error {
+	ts := header.Get("X-Slack-Request-Timestamp")
+	seconds, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return errors.New("missing request timestamp")
+	}
+	if skew := b.now().Sub(time.Unix(seconds, 0)); math.Abs(float64(skew)) > float64(maxTimestampSkew) {
+		return errors.New("stale request timestamp")
+	}
+	if !hmac.Equal([]byte(header.Get("X-Slack-Signature")), []byte(Sign(b.signingSecret, ts, body))) {
+		return errors.New("invalid signature")
+	}
+	return nil
+}
+
+// Sign computes the X-Slack-Signature of body sent at timestamp ts.
+func Sign(secret, ts string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "v0:%s:", ts)
+	mac.Write(body)
+	return "v0=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func writeCommandReply(w http.ResponseWriter, inChannel bool, text string) {
+	responseType := "ephemeral"
+	if inChannel {
+		responseType = "in_channel"
+	}
+	data, _ := json.Marshal(map[string]string{"response_type": responseType, "text": text})
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
@@ -0,0 +1,171 @@
+package slack
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"kubeagent/pkg/agent"
+	"kubeagent/pkg/agent/agenttest"
+	"kubeagent/pkg/api"
+)
+
+const testSecret = "signing-secret"
+
+// slackStandIn plays chat.postMessage and keeps what was posted.
+type slackStandIn struct {
+	mu       sync.Mutex
+	lastTS   int
+	messages chan message
+}
+
+func (s *slackStandIn) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/chat.postMessage" || r.Header.Get("Authorization") != "Bearer xoxb-test" {
+		json.NewEncoder(w).Encode(map[string]interface{}{"ok": false, "error": "invalid_auth"})
+		return
+	}
+	var msg message
+	json.NewDecoder(r.Body).Decode(&msg)
+	s.mu.Lock()
+	s.lastTS++
+	ts := strconv.Itoa(s.lastTS)
+	s.mu.Unlock()
+	s.messages <- msg
+	json.NewEncoder(w).Encode(map[string]interface{}{"ok": true, "ts": ts})
+}
+
+// nextMessage waits for a posted message that match accepts.
+func (s *slackStandIn) nextMessage(t *testing.T, match func(message) bool) message {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case msg := <-s.messages:
+			if match(msg) {
+				return msg
+			}
+		case <-timeout:
+			t.Fatal("Timed out waiting for a Slack message")
+		}
+	}
+}
+
+func signedRequest(path string, form url.Values, secret string) *http.Request {
+	body := form.Encode()
+	r := httptest.NewRequest("POST", path, strings.NewReader(body))
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	r.Header.Set("X-Slack-Request-Timestamp", ts)
+	r.Header.Set("X-Slack-Signature", Sign(secret, ts, []byte(body)))
+	return r
+}
+
+func setup(t *testing.T) (*slackStandIn, http.Handler) {
+	t.Helper()
+	standIn := &slackStandIn{messages: make(chan message, 16)}
+	slackAPI := httptest.NewServer(standIn)
+	t.Cleanup(slackAPI.Close)
+
+	server := api.NewServer(&agenttest.FixingCoordinator{}, agent.NewNoOpLogger())
+	bot := NewBot(server, testSecret, "xoxb-test", agent.NewNoOpLogger()).
+		WithAPIURL(slackAPI.URL).
+		WithApprovers("U1")
+	server.OnApproval(bot.NotifyApproval).WithRoute("/slack/", bot.Handler("/slack"))
+	return standIn, server.Handler()
+}
+
+func TestBot_RejectsUnsignedCallbacks(t *testing.T) {
+	_, handler := setup(t)
+	form := url.Values{"text": {"diagnose web-1"}, "user_id": {"U1"}, "channel_id": {"C1"}}
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, signedRequest("/slack/commands", form, "wrong-secret"))
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected a bad signature to be refused, got %d", w.Code)
+	}
+
+	r := signedRequest("/slack/commands", form, testSecret)
+	r.Header.Set("X-Slack-Request-Timestamp", strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10))
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected a stale timestamp to be refused, got %d", w.Code)
+	}
+}
+
+func TestBot_FixIsApprovedFromTheThread(t *testing.T) {
+	standIn, handler := setup(t)
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, signedRequest("/slack/commands", url.Values{
+		"text": {"fix web-1 -n shop"}, "user_id": {"U1"}, "user_name": {"ann"}, "channel_id": {"C1"},
+	}, testSecret))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected the command to be accepted, got %d: %s", w.Code, w.Body)
+	}
+	parent := standIn.nextMessage(t, func(m message) bool { return m.ThreadTS == "" })
+	if parent.Channel != "C1" || !strings.Contains(parent.Text, "fix web-1") {
+		t.Errorf("Unexpected parent message %+v", parent)
+	}
+
+	approval := standIn.nextMessage(t, func(m message) bool { return len(m.Blocks) > 0 })
+	if approval.ThreadTS != "1" || !strings.Contains(approval.Text, "计划等待审批") {
+		t.Errorf("Expected the plan approval in the request's thread, got %+v", approval)
+	}
+	buttons := approval.Blocks[len(approval.Blocks)-1].Elements
+	if len(buttons) != 2 || buttons[0].ActionID != actionApprove || buttons[1].ActionID != actionReject {
+		t.Fatalf("Expected approve and reject buttons, got %+v", buttons)
+	}
+
+	click := func(userID string) {
+		payload, _ := json.Marshal(map[string]interface{}{
+			"type":    "block_actions",
+			"user":    map[string]string{"id": userID, "username": "ann"},
+			"channel": map[string]string{"id": "C1"},
+			"message": map[string]string{"ts": "3", "thread_ts": "1"},
+			"actions": []map[string]string{{"action_id": actionApprove, "value": buttons[0].Value}},
+		})
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, signedRequest("/slack/interactions", url.Values{"payload": {string(payload)}}, testSecret))
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected the click to be accepted, got %d", w.Code)
+		}
+	}
+	click("U2")
+	if denied := standIn.nextMessage(t, func(m message) bool { return strings.Contains(m.Text, "无权") }); denied.ThreadTS != "1" {
+		t.Errorf("Expected the refusal in the thread, got %+v", denied)
+	}
+	click("U1")
+	// The confirmation and the outcome race each other to the thread.
+	var approved bool
+	var outcome message
+	for !approved || outcome.Text == "" {
+		msg := standIn.nextMessage(t, func(message) bool { return true })
+		switch {
+		case strings.HasPrefix(msg.Text, "✅ ann 批准了"):
+			approved = true
+		case strings.Contains(msg.Text, "completed"):
+			outcome = msg
+		}
+	}
+	if outcome.ThreadTS != "1" || !strings.Contains(outcome.Text, "raised the memory limit") || !strings.Contains(outcome.Text, "fix: passed - pod is Ready") {
+		t.Errorf("Expected the report and verification in the thread, got %q", outcome.Text)
+	}
+}
+
+func TestBot_StatusIsEphemeral(t *testing.T) {
+	_, handler := setup(t)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, signedRequest("/slack/commands", url.Values{"text": {"status"}, "user_id": {"U1"}}, testSecret))
+	var reply map[string]string
+	json.Unmarshal(w.Body.Bytes(), &reply)
+	if reply["response_type"] != "ephemeral" || !strings.Contains(reply["text"], "待审批：0") {
+		t.Errorf("Unexpected status reply %+v", reply)
+	}
+}
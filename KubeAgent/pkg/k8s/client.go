@@ -63,6 +63,30 @@ func NewClient() (*Client, error) {
 	}, nil
 }
 
+// NewClientFromInterfaces builds a Client from already-constructed
+// client-go interfaces. NewClient is the production path; this one
+// exists so tests (see NewFakeClient) and embedders that manage their
+// own rest.Config can supply the clientsets and RESTMapper directly.
+func NewClientFromInterfaces(clientset kubernetes.Interface, dynamicClient dynamic.Interface, mapper meta.RESTMapper) *Client {
+	return &Client{
+		clientset:     clientset,
+		dynamicClient: dynamicClient,
+		restMapper:    mapper,
+	}
+}
+
+// Clientset returns the typed clientset, for callers that need more
+// than the helpers here, such as informers.
+func (c *Client) Clientset() kubernetes.Interface {
+	return c.clientset
+}
+
+// RestConfig resolves the cluster the way NewClient does, for callers
+// that build their own clients (the operator's manager).
+func RestConfig() (*rest.Config, error) {
+	return getRestConfig()
+}
+
 func getRestConfig() (*rest.Config, error) {
 	// Try in-cluster config first (when running inside K8s)
 	if config, err := rest.InClusterConfig(); err == nil {
@@ -0,0 +1,281 @@
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	yamlutil "k8s.io/apimachinery/pkg/runtime/serializer/yaml"
+	"k8s.io/client-go/dynamic"
+)
+
+// Change operations reported by DiffObjects.
+const (
+	ChangeAdd     = "add"
+	ChangeRemove  = "remove"
+	ChangeReplace = "replace"
+)
+
+// FieldChange is one leaf-level difference between the live object and
+// what a write would leave behind. Path uses dotted field names with
+// [i] for list indices, e.g. "spec.containers[0].image".
+type FieldChange struct {
+	Path string      `json:"path"`
+	Op   string      `json:"op"`
+	Old  interface{} `json:"old,omitempty"`
+	New  interface{} `json:"new,omitempty"`
+}
+
+// DryRunResult is the preview of a write: the server's verdict on the
+// object (admission, validation and defaulting all ran, nothing was
+// persisted) and the structured diff against the live object.
+type DryRunResult struct {
+	Kind       string        `json:"kind"`
+	Name       string        `json:"name"`
+	Namespace  string        `json:"namespace"`
+	LiveExists bool          `json:"live_exists"`
+	Changes    []FieldChange `json:"changes"`
+	// Warnings flags previews that are known to differ from what the
+	// real call will do, e.g. a create that will hit AlreadyExists.
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// DryRunCreate previews CreateResource without persisting anything.
+//
+// The object is submitted with DryRun=All so the API server runs the
+// full admission chain; a rejection there comes back as an error, which
+// is exactly the early signal a remediation wants. The server's answer
+// (with defaults applied) is then diffed against the live object.
+//
+// When the object already exists the server refuses the dry-run with
+// AlreadyExists. That is still a useful preview — the usual remediation
+// is delete-then-recreate — so the submitted object is diffed against
+// the live one instead and a warning notes the create will fail as-is.
+func (c *Client) DryRunCreate(yamlContent string) (*DryRunResult, error) {
+	obj := &unstructured.Unstructured{}
+	dec := yamlutil.NewDecodingSerializer(unstructured.UnstructuredJSONScheme)
+	_, gvk, err := dec.Decode([]byte(yamlContent), nil, obj)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode YAML: %w", err)
+	}
+
+	mapping, err := c.restMapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get REST mapping for %v: %w", gvk, err)
+	}
+
+	namespace := obj.GetNamespace()
+	if namespace == "" {
+		namespace = "default"
+	}
+
+	var ri dynamic.ResourceInterface
+	if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+		ri = c.dynamicClient.Resource(mapping.Resource).Namespace(namespace)
+	} else {
+		ri = c.dynamicClient.Resource(mapping.Resource)
+	}
+
+	result := &DryRunResult{
+		Kind:      gvk.Kind,
+		Name:      obj.GetName(),
+		Namespace: namespace,
+	}
+
+	var live map[string]interface{}
+	if existing, err := ri.Get(context.TODO(), obj.GetName(), metav1.GetOptions{}); err == nil {
+		result.LiveExists = true
+		live = existing.Object
+	} else if !isNotFoundError(err) {
+		return nil, fmt.Errorf("failed to get live %s/%s: %w", gvk.Kind, obj.GetName(), err)
+	}
+
+	desired := obj.Object
+	previewed, err := ri.Create(context.TODO(), obj, metav1.CreateOptions{DryRun: []string{metav1.DryRunAll}})
+	switch {
+	case err == nil:
+		desired = previewed.Object
+	case apierrors.IsAlreadyExists(err):
+		result.Warnings = append(result.Warnings,
+			"resource already exists: a create will fail until it is deleted; removed fields may be server defaults")
+	default:
+		return nil, fmt.Errorf("dry-run create rejected: %w", err)
+	}
+
+	result.Changes = DiffObjects(live, desired)
+	return result, nil
+}
+
+// diffIgnoredFields are server-managed and change on every write, so
+// they would drown the real change in noise. status is owned by
+// controllers, not by whoever submits the object.
+var diffIgnoredFields = map[string]bool{
+	"status":                     true,
+	"metadata.resourceVersion":   true,
+	"metadata.uid":               true,
+	"metadata.creationTimestamp": true,
+	"metadata.generation":        true,
+	"metadata.managedFields":     true,
+	"metadata.selfLink":          true,
+}
+
+// DiffObjects returns the leaf-level changes that turn live into
+// desired. A nil live object yields one "add" per top-level field.
+func DiffObjects(live, desired map[string]interface{}) []FieldChange {
+	changes := make([]FieldChange, 0)
+	diffValue("", toGeneric(live), toGeneric(desired), &changes)
+	return changes
+}
+
+// toGeneric normalises typed numbers (int64 from the decoder vs float64
+// from JSON) by round-tripping through JSON, so equal values compare
+// equal regardless of where they came from.
+func toGeneric(obj map[string]interface{}) interface{} {
+	if obj == nil {
+		return nil
+	}
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return obj
+	}
+	var out interface{}
+	if err := json.Unmarshal(data, &out); err != nil {
+		return obj
+	}
+	return out
+}
+
+func diffValue(path string, old, new interface{}, changes *[]FieldChange) {
+	if diffIgnoredFields[path] {
+		return
+	}
+
+	oldMap, oldIsMap := old.(map[string]interface{})
+	newMap, newIsMap := new.(map[string]interface{})
+	if oldIsMap && newIsMap {
+		keys := make(map[string]bool)
+		for k := range oldMap {
+			keys[k] = true
+		}
+		for k := range newMap {
+			keys[k] = true
+		}
+		sorted := make([]string, 0, len(keys))
+		for k := range keys {
+			sorted = append(sorted, k)
+		}
+		sort.Strings(sorted)
+
+		for _, k := range sorted {
+			child := k
+			if path != "" {
+				child = path + "." + k
+			}
+			oldChild, inOld := oldMap[k]
+			newChild, inNew := newMap[k]
+			switch {
+			case !inOld:
+				if !diffIgnoredFields[child] {
+					*changes = append(*changes, FieldChange{Path: child, Op: ChangeAdd, New: newChild})
+				}
+			case !inNew:
+				if !diffIgnoredFields[child] {
+					*changes = append(*changes, FieldChange{Path: child, Op: ChangeRemove, Old: oldChild})
+				}
+			default:
+				diffValue(child, oldChild, newChild, changes)
+			}
+		}
+		return
+	}
+
+	oldList, oldIsList := old.([]interface{})
+	newList, newIsList := new.([]interface{})
+	if oldIsList && newIsList {
+		for i := 0; i < len(oldList) || i < len(newList); i++ {
+			child := fmt.Sprintf("%s[%d]", path, i)
+			switch {
+			case i >= len(oldList):
+				*changes = append(*changes, FieldChange{Path: child, Op: ChangeAdd, New: newList[i]})
+			case i >= len(newList):
+				*changes = append(*changes, FieldChange{Path: child, Op: ChangeRemove, Old: oldList[i]})
+			default:
+				diffValue(child, oldList[i], newList[i], changes)
+			}
+		}
+		return
+	}
+
+	if old == nil && new == nil {
+		return
+	}
+	if path == "" {
+		// Whole-object add or remove (no live object, or nothing desired):
+		// report per top-level field so the output stays readable.
+		if old == nil {
+			diffValue("", map[string]interface{}{}, new, changes)
+			return
+		}
+		if new == nil {
+			diffValue("", old, map[string]interface{}{}, changes)
+			return
+		}
+	}
+	if !reflect.DeepEqual(old, new) {
+		*changes = append(*changes, FieldChange{Path: path, Op: ChangeReplace, Old: old, New: new})
+	}
+}
+
+// maxRenderedValue caps how much of a single value Render prints. A
+// whole container spec inline is unreadable in a terminal prompt; the
+// structured Changes keep the full value for audit.
+const maxRenderedValue = 120
+
+// Render formats the preview as a compact, human-readable diff for
+// terminal prompts and tool results.
+func (r *DryRunResult) Render() string {
+	var b strings.Builder
+	target := fmt.Sprintf("%s %s/%s", r.Kind, r.Namespace, r.Name)
+	if r.LiveExists {
+		fmt.Fprintf(&b, "--- live %s\n+++ dry-run %s\n", target, target)
+	} else {
+		fmt.Fprintf(&b, "--- (not found) %s\n+++ dry-run %s\n", target, target)
+	}
+
+	if len(r.Changes) == 0 {
+		b.WriteString("  (no changes)\n")
+	}
+	for _, change := range r.Changes {
+		switch change.Op {
+		case ChangeAdd:
+			fmt.Fprintf(&b, "+ %s: %s\n", change.Path, renderValue(change.New))
+		case ChangeRemove:
+			fmt.Fprintf(&b, "- %s: %s\n", change.Path, renderValue(change.Old))
+		default:
+			fmt.Fprintf(&b, "~ %s: %s -> %s\n", change.Path, renderValue(change.Old), renderValue(change.New))
+		}
+	}
+	for _, w := range r.Warnings {
+		fmt.Fprintf(&b, "! %s\n", w)
+	}
+	return b.String()
+}
+
+func renderValue(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	s := string(data)
+	if len(s) > maxRenderedValue {
+		s = s[:maxRenderedValue] + "…"
+	}
+	return s
+}
@@ -0,0 +1,113 @@
+package k8s
+
+import (
+	"strings"
+	"testing"
+)
+
+const fixedWebPod = `apiVersion: v1
+kind: Pod
+metadata:
+  name: web-1
+  namespace: demo
+spec:
+  containers:
+    - name: web
+      image: example.com/web:1.4.3
+`
+
+// TestDiffObjects pins the path format and op semantics the preview
+// renderer and the audit trail rely on.
+func TestDiffObjects(t *testing.T) {
+	live := map[string]interface{}{
+		"metadata": map[string]interface{}{"name": "web", "resourceVersion": "42", "labels": map[string]interface{}{"tier": "web"}},
+		"spec": map[string]interface{}{
+			"replicas":   int64(2),
+			"containers": []interface{}{map[string]interface{}{"image": "web:1"}},
+		},
+		"status": map[string]interface{}{"readyReplicas": int64(1)},
+	}
+	desired := map[string]interface{}{
+		"metadata": map[string]interface{}{"name": "web"},
+		"spec": map[string]interface{}{
+			"replicas":   float64(2),
+			"containers": []interface{}{map[string]interface{}{"image": "web:2"}, map[string]interface{}{"image": "sidecar"}},
+		},
+	}
+
+	got := map[string]string{}
+	for _, c := range DiffObjects(live, desired) {
+		got[c.Path] = c.Op
+	}
+	want := map[string]string{
+		"metadata.labels":          ChangeRemove,
+		"spec.containers[0].image": ChangeReplace,
+		"spec.containers[1]":       ChangeAdd,
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Expected %v, got %v", want, got)
+	}
+	for path, op := range want {
+		if got[path] != op {
+			t.Errorf("%s: expected %s, got %q", path, op, got[path])
+		}
+	}
+}
+
+// TestDryRunCreate_ExistingObject covers the common remediation: the
+// object is live, so the preview diffs against it and warns that a
+// plain create would be rejected. Nothing may be persisted.
+func TestDryRunCreate_ExistingObject(t *testing.T) {
+	client := newCrashLoopClient(t)
+
+	result, err := client.DryRunCreate(fixedWebPod)
+	if err != nil {
+		t.Fatalf("DryRunCreate failed: %v", err)
+	}
+	if !result.LiveExists || len(result.Warnings) == 0 {
+		t.Errorf("Expected live object and AlreadyExists warning, got %+v", result)
+	}
+
+	rendered := result.Render()
+	if !strings.Contains(rendered, `~ spec.containers[0].image: "example.com/web:1.4.2" -> "example.com/web:1.4.3"`) {
+		t.Errorf("Image change missing from diff:\n%s", rendered)
+	}
+	if strings.Contains(rendered, "status") || strings.Contains(rendered, "resourceVersion") {
+		t.Errorf("Server-managed fields leaked into diff:\n%s", rendered)
+	}
+
+	state, err := client.GetResourceState("pod", "web-1", "demo")
+	if err != nil {
+		t.Fatalf("GetResourceState failed: %v", err)
+	}
+	if state.Reason != "CrashLoopBackOff" {
+		t.Errorf("Dry-run must not modify the live object, got %+v", state)
+	}
+}
+
+// TestDryRunCreate_NewObject checks a create of a missing object is
+// previewed as additions and, crucially, not actually created.
+func TestDryRunCreate_NewObject(t *testing.T) {
+	client := newCrashLoopClient(t)
+
+	result, err := client.DryRunCreate(strings.ReplaceAll(fixedWebPod, "web-1", "web-2"))
+	if err != nil {
+		t.Fatalf("DryRunCreate failed: %v", err)
+	}
+	if result.LiveExists || len(result.Warnings) != 0 {
+		t.Errorf("Expected clean preview of a new object, got %+v", result)
+	}
+	for _, c := range result.Changes {
+		if c.Op != ChangeAdd {
+			t.Errorf("Expected only additions, got %+v", c)
+		}
+	}
+
+	state, err := client.GetResourceState("pod", "web-2", "demo")
+	if err != nil {
+		t.Fatalf("GetResourceState failed: %v", err)
+	}
+	if state.Exists {
+		t.Error("Dry-run persisted the object")
+	}
+}
@@ -0,0 +1,383 @@
+package k8s
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/applyconfigurations"
+	"k8s.io/client-go/dynamic"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/kubernetes/scheme"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/rest"
+	fakerest "k8s.io/client-go/rest/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// FakeLogsAnnotation lets a fixture Pod carry the text GetPodLogs should
+// return for it. client-go's fake clientset always answers "fake logs",
+// which is useless for exercising a diagnosis, and keeping the logs on
+// the Pod itself means one YAML file fully describes a scenario.
+const FakeLogsAnnotation = "kubeagent.io/fake-logs"
+
+// NewFakeClient returns a Client backed entirely by in-memory client-go
+// fakes, seeded with objects. It needs no cluster and no kubeconfig.
+//
+// The typed and dynamic fakes normally keep separate object trackers, so
+// a Pod deleted through DeleteResource (dynamic) would still be visible
+// to GetPodLogs (typed). Here both are wired to the typed clientset's
+// tracker, so every Client method observes the same cluster state.
+//
+// Known gaps versus a real API server: field selectors are ignored
+// (GetPodEvents returns every Pod event in the namespace), nothing
+// reconciles (a deleted Deployment's Pods stay put), only the kinds in
+// DefaultRESTMapper can be stored, and dry-runs skip admission and
+// defaulting.
+func NewFakeClient(objects ...runtime.Object) *Client {
+	clientset := kubefake.NewClientset(objects...)
+	tracker := clientset.Tracker()
+
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(scheme.Scheme, nil)
+	dynamicClient.PrependReactor("*", "*", typedObjectReaction(tracker))
+
+	return NewClientFromInterfaces(fakeLogsClientset{clientset}, fakeDynamic{Interface: dynamicClient, tracker: tracker}, DefaultRESTMapper())
+}
+
+// NewFakeClientFromFixtures is NewFakeClient seeded from YAML fixture
+// files (see LoadFixtures).
+func NewFakeClientFromFixtures(paths ...string) (*Client, error) {
+	objects, err := LoadFixtures(paths...)
+	if err != nil {
+		return nil, err
+	}
+	return NewFakeClient(objects...), nil
+}
+
+// LoadFixtures decodes every document in the given YAML (or JSON) files
+// into typed objects. Files may hold several documents separated by
+// "---", and a path may be a directory, in which case its *.yaml / *.yml
+// files are loaded in lexical order.
+func LoadFixtures(paths ...string) ([]runtime.Object, error) {
+	var objects []runtime.Object
+	for _, path := range paths {
+		files, err := fixtureFiles(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			objs, err := decodeFixtureFile(file)
+			if err != nil {
+				return nil, err
+			}
+			objects = append(objects, objs...)
+		}
+	}
+	return objects, nil
+}
+
+func fixtureFiles(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat fixture %s: %w", path, err)
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+
+	var files []string
+	for _, pattern := range []string{"*.yaml", "*.yml"} {
+		matches, err := filepath.Glob(filepath.Join(path, pattern))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, matches...)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+func decodeFixtureFile(file string) ([]runtime.Object, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fixture %s: %w", file, err)
+	}
+
+	var objects []runtime.Object
+	reader := utilyaml.NewYAMLReader(bufio.NewReader(bytes.NewReader(data)))
+	for {
+		doc, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to split fixture %s: %w", file, err)
+		}
+		if len(bytes.TrimSpace(doc)) == 0 {
+			continue
+		}
+
+		obj, _, err := scheme.Codecs.UniversalDeserializer().Decode(doc, nil, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode fixture %s: %w", file, err)
+		}
+		objects = append(objects, obj)
+	}
+	return objects, nil
+}
+
+// DefaultRESTMapper is a static RESTMapper covering the built-in kinds
+// KubeAgent's tools touch. It stands in for discovery when there is no
+// API server to ask.
+func DefaultRESTMapper() meta.RESTMapper {
+	mapper := meta.NewDefaultRESTMapper(nil)
+
+	namespaced := []schema.GroupVersionKind{
+		v1.SchemeGroupVersion.WithKind("Pod"),
+		v1.SchemeGroupVersion.WithKind("Service"),
+		v1.SchemeGroupVersion.WithKind("ConfigMap"),
+		v1.SchemeGroupVersion.WithKind("Secret"),
+		v1.SchemeGroupVersion.WithKind("Event"),
+		v1.SchemeGroupVersion.WithKind("ServiceAccount"),
+		v1.SchemeGroupVersion.WithKind("PersistentVolumeClaim"),
+		v1.SchemeGroupVersion.WithKind("Endpoints"),
+		appsv1.SchemeGroupVersion.WithKind("Deployment"),
+		appsv1.SchemeGroupVersion.WithKind("StatefulSet"),
+		appsv1.SchemeGroupVersion.WithKind("DaemonSet"),
+		appsv1.SchemeGroupVersion.WithKind("ReplicaSet"),
+		batchv1.SchemeGroupVersion.WithKind("Job"),
+		batchv1.SchemeGroupVersion.WithKind("CronJob"),
+		networkingv1.SchemeGroupVersion.WithKind("Ingress"),
+	}
+	for _, gvk := range namespaced {
+		mapper.Add(gvk, meta.RESTScopeNamespace)
+	}
+
+	clusterScoped := []schema.GroupVersionKind{
+		v1.SchemeGroupVersion.WithKind("Namespace"),
+		v1.SchemeGroupVersion.WithKind("Node"),
+		v1.SchemeGroupVersion.WithKind("PersistentVolume"),
+	}
+	for _, gvk := range clusterScoped {
+		mapper.Add(gvk, meta.RESTScopeRoot)
+	}
+
+	return mapper
+}
+
+// typedObjectReaction serves dynamic-client actions from the typed
+// tracker. Objects arriving from the dynamic client are unstructured;
+// they are converted to their typed form first, otherwise the typed
+// clientset would later find an Unstructured where it expects a *v1.Pod.
+func typedObjectReaction(tracker k8stesting.ObjectTracker) k8stesting.ReactionFunc {
+	react := k8stesting.ObjectReaction(tracker)
+	return func(action k8stesting.Action) (bool, runtime.Object, error) {
+		switch a := action.(type) {
+		case k8stesting.CreateActionImpl:
+			obj, err := toTyped(a.Object)
+			if err != nil {
+				return true, nil, err
+			}
+			a.Object = obj
+			return react(a)
+		case k8stesting.UpdateActionImpl:
+			obj, err := toTyped(a.Object)
+			if err != nil {
+				return true, nil, err
+			}
+			a.Object = obj
+			return react(a)
+		}
+		return react(action)
+	}
+}
+
+func toTyped(obj runtime.Object) (runtime.Object, error) {
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return obj, nil
+	}
+	typed, err := scheme.Scheme.New(u.GroupVersionKind())
+	if err != nil {
+		return nil, fmt.Errorf("fake client: unsupported kind %s: %w", u.GroupVersionKind(), err)
+	}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, typed); err != nil {
+		return nil, fmt.Errorf("fake client: failed to convert %s: %w", u.GroupVersionKind(), err)
+	}
+	return typed, nil
+}
+
+// fakeLogsClientset overrides Pods().GetLogs so it answers with the
+// FakeLogsAnnotation of the Pod instead of client-go's canned text.
+type fakeLogsClientset struct {
+	kubernetes.Interface
+}
+
+func (f fakeLogsClientset) CoreV1() corev1client.CoreV1Interface {
+	return fakeLogsCoreV1{f.Interface.CoreV1()}
+}
+
+type fakeLogsCoreV1 struct {
+	corev1client.CoreV1Interface
+}
+
+func (f fakeLogsCoreV1) Pods(namespace string) corev1client.PodInterface {
+	return fakeLogsPods{PodInterface: f.CoreV1Interface.Pods(namespace), namespace: namespace}
+}
+
+type fakeLogsPods struct {
+	corev1client.PodInterface
+	namespace string
+}
+
+func (p fakeLogsPods) GetLogs(name string, opts *v1.PodLogOptions) *rest.Request {
+	body := "fake logs"
+	if pod, err := p.Get(context.TODO(), name, metav1.GetOptions{}); err == nil {
+		if logs, ok := pod.Annotations[FakeLogsAnnotation]; ok {
+			body = logs
+		}
+	}
+
+	client := &fakerest.RESTClient{
+		Client: fakerest.CreateHTTPClient(func(*http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(strings.NewReader(body)),
+			}, nil
+		}),
+		NegotiatedSerializer: scheme.Codecs.WithoutConversion(),
+		GroupVersion:         v1.SchemeGroupVersion,
+		VersionedAPIPath:     fmt.Sprintf("/api/v1/namespaces/%s/pods/%s/log", p.namespace, name),
+	}
+	return client.Request()
+}
+
+// fakeDynamic fills in what the client-go dynamic fake gets wrong for
+// write previews and server-side apply: it drops Create/Patch/Apply
+// options, so DryRun would persist and Apply would lose its field
+// manager and force flag. Create, Patch and Apply are therefore served
+// here, straight from the shared tracker, with options intact.
+//
+// Dry-runs replay the action against a scratch tracker seeded with a
+// copy of the live object, so admission-free defaults aside, the result
+// is exactly what the real write would produce and nothing is stored.
+type fakeDynamic struct {
+	dynamic.Interface
+	tracker k8stesting.ObjectTracker
+}
+
+func (d fakeDynamic) Resource(gvr schema.GroupVersionResource) dynamic.NamespaceableResourceInterface {
+	inner := d.Interface.Resource(gvr)
+	return fakeNamespaceable{
+		fakeResource: fakeResource{ResourceInterface: inner, tracker: d.tracker, gvr: gvr},
+		inner:        inner,
+	}
+}
+
+type fakeNamespaceable struct {
+	fakeResource
+	inner dynamic.NamespaceableResourceInterface
+}
+
+func (n fakeNamespaceable) Namespace(namespace string) dynamic.ResourceInterface {
+	return fakeResource{
+		ResourceInterface: n.inner.Namespace(namespace),
+		tracker:           n.tracker,
+		gvr:               n.gvr,
+		namespace:         namespace,
+	}
+}
+
+type fakeResource struct {
+	dynamic.ResourceInterface
+	tracker   k8stesting.ObjectTracker
+	gvr       schema.GroupVersionResource
+	namespace string
+}
+
+func (r fakeResource) Create(ctx context.Context, obj *unstructured.Unstructured, opts metav1.CreateOptions, subresources ...string) (*unstructured.Unstructured, error) {
+	if len(opts.DryRun) == 0 || len(subresources) > 0 {
+		return r.ResourceInterface.Create(ctx, obj, opts, subresources...)
+	}
+	typed, err := toTyped(obj)
+	if err != nil {
+		return nil, apierrors.NewBadRequest(err.Error())
+	}
+	return r.react(obj.GetName(), true, k8stesting.NewCreateActionWithOptions(r.gvr, r.namespace, typed, opts))
+}
+
+func (r fakeResource) Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts metav1.PatchOptions, subresources ...string) (*unstructured.Unstructured, error) {
+	if len(subresources) > 0 {
+		return r.ResourceInterface.Patch(ctx, name, pt, data, opts, subresources...)
+	}
+	return r.react(name, len(opts.DryRun) > 0, k8stesting.NewPatchActionWithOptions(r.gvr, r.namespace, name, pt, data, opts))
+}
+
+func (r fakeResource) Apply(ctx context.Context, name string, obj *unstructured.Unstructured, opts metav1.ApplyOptions, subresources ...string) (*unstructured.Unstructured, error) {
+	if len(subresources) > 0 {
+		return r.ResourceInterface.Apply(ctx, name, obj, opts, subresources...)
+	}
+	data, err := runtime.Encode(unstructured.UnstructuredJSONScheme, obj)
+	if err != nil {
+		return nil, err
+	}
+	force := opts.Force
+	patchOpts := metav1.PatchOptions{DryRun: opts.DryRun, Force: &force, FieldManager: opts.FieldManager}
+	return r.react(name, len(opts.DryRun) > 0, k8stesting.NewPatchActionWithOptions(r.gvr, r.namespace, name, types.ApplyPatchType, data, patchOpts))
+}
+
+// react runs action through ObjectReaction, against a scratch copy of
+// the tracker when dryRun is set, and returns the result unstructured.
+func (r fakeResource) react(name string, dryRun bool, action k8stesting.Action) (*unstructured.Unstructured, error) {
+	tracker := r.tracker
+	if dryRun {
+		tracker = newFakeTracker()
+		if live, err := r.tracker.Get(r.gvr, r.namespace, name); err == nil {
+			if err := tracker.Add(live.DeepCopyObject()); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	_, obj, err := k8stesting.ObjectReaction(tracker)(action)
+	if err != nil {
+		return nil, err
+	}
+	out := &unstructured.Unstructured{}
+	if err := scheme.Scheme.Convert(obj, out, nil); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// newFakeTracker matches the tracker kubefake.NewClientset builds, so
+// scratch dry-runs behave like the real fake (managed fields included).
+func newFakeTracker() k8stesting.ObjectTracker {
+	return k8stesting.NewFieldManagedObjectTracker(
+		scheme.Scheme,
+		scheme.Codecs.UniversalDecoder(),
+		applyconfigurations.NewTypeConverter(scheme.Scheme),
+	)
+}
@@ -0,0 +1,131 @@
+package k8s
+
+import (
+	"strings"
+	"testing"
+)
+
+func newCrashLoopClient(t *testing.T) *Client {
+	t.Helper()
+	client, err := NewFakeClientFromFixtures("testdata/crashloop.yaml")
+	if err != nil {
+		t.Fatalf("Failed to load fixtures: %v", err)
+	}
+	return client
+}
+
+// TestFakeClient_ReadsFixtures covers the read paths the Diagnostician
+// tools use: logs (from the fake-logs annotation), events and state.
+func TestFakeClient_ReadsFixtures(t *testing.T) {
+	client := newCrashLoopClient(t)
+
+	logs, err := client.GetPodLogs("web-1", "demo", "")
+	if err != nil {
+		t.Fatalf("GetPodLogs failed: %v", err)
+	}
+	if !strings.Contains(logs, "DATABASE_URL is not set") {
+		t.Errorf("Expected fixture logs, got %q", logs)
+	}
+
+	events, err := client.GetPodEvents("web-1", "demo")
+	if err != nil {
+		t.Fatalf("GetPodEvents failed: %v", err)
+	}
+	if !strings.Contains(events, "Back-off restarting") {
+		t.Errorf("Expected BackOff event, got %s", events)
+	}
+
+	state, err := client.GetResourceState("pod", "web-1", "demo")
+	if err != nil {
+		t.Fatalf("GetResourceState failed: %v", err)
+	}
+	if !state.Exists || state.Ready || state.Reason != "CrashLoopBackOff" {
+		t.Errorf("Unexpected pod state: %+v", state)
+	}
+
+	deploy, err := client.GetResourceState("deployment", "web", "demo")
+	if err != nil {
+		t.Fatalf("GetResourceState failed: %v", err)
+	}
+	if deploy.Phase != "1/2" || deploy.Ready {
+		t.Errorf("Unexpected deployment state: %+v", deploy)
+	}
+
+	list, err := client.ListResources("pods", "demo")
+	if err != nil {
+		t.Fatalf("ListResources failed: %v", err)
+	}
+	if !strings.Contains(list, `"web-1"`) {
+		t.Errorf("Expected web-1 in list, got %s", list)
+	}
+}
+
+// TestFakeClient_SharedState is the property the fake exists for: a
+// write through the dynamic client must be visible to the typed one and
+// vice versa, otherwise remediate → verify would check stale state.
+func TestFakeClient_SharedState(t *testing.T) {
+	client := newCrashLoopClient(t)
+
+	if _, err := client.DeleteResource("pod", "web-1", "demo"); err != nil {
+		t.Fatalf("DeleteResource failed: %v", err)
+	}
+	if _, err := client.GetPodLogs("web-1", "demo", ""); err == nil {
+		t.Error("Typed client still sees the deleted pod")
+	}
+	state, err := client.GetResourceState("pod", "web-1", "demo")
+	if err != nil {
+		t.Fatalf("GetResourceState failed: %v", err)
+	}
+	if state.Exists {
+		t.Error("Deleted pod still exists")
+	}
+
+	_, err = client.CreateResource(`apiVersion: v1
+kind: Pod
+metadata:
+  name: web-1
+  namespace: demo
+  annotations:
+    kubeagent.io/fake-logs: "listening on :8080"
+spec:
+  containers:
+    - name: web
+      image: example.com/web:1.4.3
+status:
+  phase: Running
+`)
+	if err != nil {
+		t.Fatalf("CreateResource failed: %v", err)
+	}
+
+	logs, err := client.GetPodLogs("web-1", "demo", "")
+	if err != nil {
+		t.Fatalf("GetPodLogs after create failed: %v", err)
+	}
+	if logs != "listening on :8080" {
+		t.Errorf("Expected recreated pod logs, got %q", logs)
+	}
+	state, err = client.GetResourceState("pod", "web-1", "demo")
+	if err != nil {
+		t.Fatalf("GetResourceState failed: %v", err)
+	}
+	if !state.Ready {
+		t.Errorf("Expected recreated pod to be ready, got %+v", state)
+	}
+}
+
+// TestLoadFixtures_Errors makes sure a broken fixture fails loudly
+// instead of silently seeding an empty cluster.
+func TestLoadFixtures_Errors(t *testing.T) {
+	if _, err := LoadFixtures("testdata/does-not-exist.yaml"); err == nil {
+		t.Error("Expected error for missing fixture")
+	}
+
+	objects, err := LoadFixtures("testdata")
+	if err != nil {
+		t.Fatalf("LoadFixtures on directory failed: %v", err)
+	}
+	if len(objects) != 3 {
+		t.Errorf("Expected 3 objects from testdata, got %d", len(objects))
+	}
+}
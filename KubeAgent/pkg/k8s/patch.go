@@ -0,0 +1,207 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	yamlutil "k8s.io/apimachinery/pkg/runtime/serializer/yaml"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+)
+
+// PatchType names the patch formats Patch accepts, in the vocabulary of
+// `kubectl patch --type`.
+type PatchType string
+
+const (
+	// PatchStrategic is a strategic merge patch: lists such as
+	// containers are merged by key (name) instead of replaced. Built-in
+	// kinds only; CRDs reject it.
+	PatchStrategic PatchType = "strategic"
+	// PatchMerge is an RFC 7386 JSON merge patch.
+	PatchMerge PatchType = "merge"
+	// PatchJSON is an RFC 6902 JSON patch (a list of operations).
+	PatchJSON PatchType = "json"
+)
+
+// DefaultFieldManager owns the fields KubeAgent sets via server-side
+// apply, so `kubectl get -o yaml --show-managed-fields` shows which
+// changes came from the agent.
+const DefaultFieldManager = "kubeagent"
+
+// ParsePatchType maps a user/LLM supplied name to a PatchType. Empty
+// means strategic, matching kubectl's default.
+func ParsePatchType(s string) (PatchType, error) {
+	switch PatchType(s) {
+	case "", PatchStrategic:
+		return PatchStrategic, nil
+	case PatchMerge, PatchJSON:
+		return PatchType(s), nil
+	}
+	return "", fmt.Errorf("unsupported patch type %q (use strategic, merge or json)", s)
+}
+
+func (p PatchType) apiType() types.PatchType {
+	switch p {
+	case PatchMerge:
+		return types.MergePatchType
+	case PatchJSON:
+		return types.JSONPatchType
+	}
+	return types.StrategicMergePatchType
+}
+
+// Patch applies patch to an existing resource. Unlike CreateResource it
+// works on live objects, so a remediation can bump an image or a memory
+// limit in place and let the controller roll it out.
+func (c *Client) Patch(resource, name, namespace string, patchType PatchType, patch string) (string, error) {
+	mapping, ri, err := c.patchTarget(resource, namespace)
+	if err != nil {
+		return "", err
+	}
+
+	patched, err := ri.Patch(context.TODO(), name, patchType.apiType(), []byte(patch), metav1.PatchOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to patch %s/%s: %w", resource, name, err)
+	}
+	return fmt.Sprintf("Patched %s/%s in namespace %s (%s patch)",
+		mapping.GroupVersionKind.Kind, patched.GetName(), namespace, patchType), nil
+}
+
+// DryRunPatch previews Patch: the server applies the patch with
+// DryRun=All and the result is diffed against the live object.
+func (c *Client) DryRunPatch(resource, name, namespace string, patchType PatchType, patch string) (*DryRunResult, error) {
+	mapping, ri, err := c.patchTarget(resource, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	live, err := ri.Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get live %s/%s: %w", resource, name, err)
+	}
+
+	previewed, err := ri.Patch(context.TODO(), name, patchType.apiType(), []byte(patch),
+		metav1.PatchOptions{DryRun: []string{metav1.DryRunAll}})
+	if err != nil {
+		return nil, fmt.Errorf("dry-run patch rejected: %w", err)
+	}
+
+	return &DryRunResult{
+		Kind:       mapping.GroupVersionKind.Kind,
+		Name:       name,
+		Namespace:  namespace,
+		LiveExists: true,
+		Changes:    DiffObjects(live.Object, previewed.Object),
+	}, nil
+}
+
+// Apply submits yamlContent with server-side apply. The object is
+// created if missing and otherwise merged field by field, with
+// fieldManager recorded as the owner of every field in the YAML. A
+// conflict with another manager fails unless force is set.
+func (c *Client) Apply(yamlContent, fieldManager string, force bool) (string, error) {
+	obj, mapping, ri, err := c.applyTarget(yamlContent)
+	if err != nil {
+		return "", err
+	}
+	if fieldManager == "" {
+		fieldManager = DefaultFieldManager
+	}
+
+	applied, err := ri.Apply(context.TODO(), obj.GetName(), obj,
+		metav1.ApplyOptions{FieldManager: fieldManager, Force: force})
+	if err != nil {
+		return "", fmt.Errorf("failed to apply %s/%s: %w", mapping.GroupVersionKind.Kind, obj.GetName(), err)
+	}
+	return fmt.Sprintf("Applied %s/%s in namespace %s (field manager %s)",
+		mapping.GroupVersionKind.Kind, applied.GetName(), obj.GetNamespace(), fieldManager), nil
+}
+
+// DryRunApply previews Apply against the live object (or against
+// nothing, when the object would be created).
+func (c *Client) DryRunApply(yamlContent, fieldManager string, force bool) (*DryRunResult, error) {
+	obj, mapping, ri, err := c.applyTarget(yamlContent)
+	if err != nil {
+		return nil, err
+	}
+	if fieldManager == "" {
+		fieldManager = DefaultFieldManager
+	}
+
+	result := &DryRunResult{
+		Kind:      mapping.GroupVersionKind.Kind,
+		Name:      obj.GetName(),
+		Namespace: obj.GetNamespace(),
+	}
+
+	var live map[string]interface{}
+	if existing, err := ri.Get(context.TODO(), obj.GetName(), metav1.GetOptions{}); err == nil {
+		result.LiveExists = true
+		live = existing.Object
+	} else if !isNotFoundError(err) {
+		return nil, fmt.Errorf("failed to get live %s/%s: %w", mapping.GroupVersionKind.Kind, obj.GetName(), err)
+	}
+
+	previewed, err := ri.Apply(context.TODO(), obj.GetName(), obj, metav1.ApplyOptions{
+		FieldManager: fieldManager,
+		Force:        force,
+		DryRun:       []string{metav1.DryRunAll},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("dry-run apply rejected: %w", err)
+	}
+
+	result.Changes = DiffObjects(live, previewed.Object)
+	return result, nil
+}
+
+// patchTarget resolves resource to its mapping and resource interface.
+func (c *Client) patchTarget(resource, namespace string) (*meta.RESTMapping, dynamic.ResourceInterface, error) {
+	mapping, err := c.mappingFor(resource)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to resolve resource '%s': %w", resource, err)
+	}
+
+	var ri dynamic.ResourceInterface
+	if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+		ri = c.dynamicClient.Resource(mapping.Resource).Namespace(namespace)
+	} else {
+		ri = c.dynamicClient.Resource(mapping.Resource)
+	}
+	return mapping, ri, nil
+}
+
+// applyTarget decodes yamlContent and resolves where to send it. The
+// namespace defaults to "default" as in CreateResource, and is written
+// back onto the object because apply requires it to match the URL.
+func (c *Client) applyTarget(yamlContent string) (*unstructured.Unstructured, *meta.RESTMapping, dynamic.ResourceInterface, error) {
+	obj := &unstructured.Unstructured{}
+	dec := yamlutil.NewDecodingSerializer(unstructured.UnstructuredJSONScheme)
+	_, gvk, err := dec.Decode([]byte(yamlContent), nil, obj)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to decode YAML: %w", err)
+	}
+	if obj.GetName() == "" {
+		return nil, nil, nil, fmt.Errorf("metadata.name is required for apply")
+	}
+
+	mapping, err := c.restMapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to get REST mapping for %v: %w", gvk, err)
+	}
+
+	var ri dynamic.ResourceInterface
+	if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+		if obj.GetNamespace() == "" {
+			obj.SetNamespace("default")
+		}
+		ri = c.dynamicClient.Resource(mapping.Resource).Namespace(obj.GetNamespace())
+	} else {
+		ri = c.dynamicClient.Resource(mapping.Resource)
+	}
+	return obj, mapping, ri, nil
+}
@@ -0,0 +1,134 @@
+package k8s
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const imagePatch = `{"spec":{"template":{"spec":{"containers":[{"name":"web","image":"example.com/web:1.4.3"}]}}}}`
+
+// TestPatch_Strategic checks the in-place image bump a remediation most
+// often makes: the dry-run shows exactly that field changing without
+// persisting it, and the real patch merges the container by name.
+func TestPatch_Strategic(t *testing.T) {
+	client := newCrashLoopClient(t)
+
+	result, err := client.DryRunPatch("deployment", "web", "demo", PatchStrategic, imagePatch)
+	if err != nil {
+		t.Fatalf("DryRunPatch failed: %v", err)
+	}
+	if len(result.Changes) != 1 || result.Changes[0].Path != "spec.template.spec.containers[0].image" {
+		t.Errorf("Expected only the image to change, got %+v", result.Changes)
+	}
+	if image := deploymentImage(t, client); image != "example.com/web:1.4.2" {
+		t.Fatalf("Dry-run persisted the patch: image is %s", image)
+	}
+
+	if _, err := client.Patch("deployment", "web", "demo", PatchStrategic, imagePatch); err != nil {
+		t.Fatalf("Patch failed: %v", err)
+	}
+	if image := deploymentImage(t, client); image != "example.com/web:1.4.3" {
+		t.Errorf("Expected patched image, got %s", image)
+	}
+}
+
+// TestPatch_JSONAndMerge covers the other two patch formats.
+func TestPatch_JSONAndMerge(t *testing.T) {
+	client := newCrashLoopClient(t)
+
+	if _, err := client.Patch("deployment", "web", "demo", PatchJSON, `[{"op":"replace","path":"/spec/replicas","value":3}]`); err != nil {
+		t.Fatalf("JSON patch failed: %v", err)
+	}
+	if _, err := client.Patch("pod", "web-1", "demo", PatchMerge, `{"metadata":{"labels":{"triaged":"true"}}}`); err != nil {
+		t.Fatalf("Merge patch failed: %v", err)
+	}
+
+	deploy, err := client.clientset.AppsV1().Deployments("demo").Get(context.TODO(), "web", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Get deployment failed: %v", err)
+	}
+	if *deploy.Spec.Replicas != 3 {
+		t.Errorf("Expected 3 replicas, got %d", *deploy.Spec.Replicas)
+	}
+	pod, err := client.clientset.CoreV1().Pods("demo").Get(context.TODO(), "web-1", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Get pod failed: %v", err)
+	}
+	if pod.Labels["triaged"] != "true" {
+		t.Errorf("Expected merged label, got %v", pod.Labels)
+	}
+
+	if _, err := client.Patch("pod", "missing", "demo", PatchMerge, `{}`); err == nil {
+		t.Error("Expected patch of a missing object to fail")
+	}
+	if _, err := ParsePatchType("yaml"); err == nil {
+		t.Error("Expected unknown patch type to be rejected")
+	}
+}
+
+// TestApply_CreatesAndUpdates checks server-side apply both creates a
+// missing object and updates a live one (with force, since another
+// manager owns the fields), and that a dry-run leaves the cluster
+// untouched.
+func TestApply_CreatesAndUpdates(t *testing.T) {
+	client := newCrashLoopClient(t)
+	newPod := strings.ReplaceAll(fixedWebPod, "web-1", "web-2")
+
+	preview, err := client.DryRunApply(newPod, "", false)
+	if err != nil {
+		t.Fatalf("DryRunApply failed: %v", err)
+	}
+	if preview.LiveExists || len(preview.Changes) == 0 {
+		t.Errorf("Expected additions for a new object, got %+v", preview)
+	}
+	if state, _ := client.GetResourceState("pod", "web-2", "demo"); state.Exists {
+		t.Fatal("Dry-run apply persisted the object")
+	}
+
+	if _, err := client.Apply(newPod, "", false); err != nil {
+		t.Fatalf("Apply (create) failed: %v", err)
+	}
+	if state, _ := client.GetResourceState("pod", "web-2", "demo"); !state.Exists {
+		t.Error("Apply did not create the object")
+	}
+
+	// web-1 came from a plain create, so its image is owned by another
+	// manager: apply must report the conflict until force is set.
+	if _, err := client.DryRunApply(fixedWebPod, "", false); err == nil || !strings.Contains(err.Error(), "conflict") {
+		t.Fatalf("Expected field-manager conflict, got %v", err)
+	}
+	preview, err = client.DryRunApply(fixedWebPod, "", true)
+	if err != nil {
+		t.Fatalf("DryRunApply (update) failed: %v", err)
+	}
+	if !preview.LiveExists || !strings.Contains(preview.Render(), `"example.com/web:1.4.3"`) {
+		t.Errorf("Expected image change against the live pod, got:\n%s", preview.Render())
+	}
+
+	out, err := client.Apply(fixedWebPod, "remediator", true)
+	if err != nil {
+		t.Fatalf("Apply (update) failed: %v", err)
+	}
+	if !strings.Contains(out, "field manager remediator") {
+		t.Errorf("Expected field manager in result, got %q", out)
+	}
+	pod, err := client.clientset.CoreV1().Pods("demo").Get(context.TODO(), "web-1", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Get pod failed: %v", err)
+	}
+	if pod.Spec.Containers[0].Image != "example.com/web:1.4.3" {
+		t.Errorf("Expected applied image, got %s", pod.Spec.Containers[0].Image)
+	}
+}
+
+func deploymentImage(t *testing.T, client *Client) string {
+	t.Helper()
+	deploy, err := client.clientset.AppsV1().Deployments("demo").Get(context.TODO(), "web", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Get deployment failed: %v", err)
+	}
+	return deploy.Spec.Template.Spec.Containers[0].Image
+}
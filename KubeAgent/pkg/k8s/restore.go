@@ -0,0 +1,83 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic"
+)
+
+// restoreStrippedMetadata are identity and bookkeeping fields the API
+// server assigns. A snapshot keeps them for the record, but sending
+// them back would make a re-create fail (uid, resourceVersion) or be
+// silently ignored anyway.
+var restoreStrippedMetadata = []string{
+	"uid", "resourceVersion", "creationTimestamp", "generation",
+	"managedFields", "selfLink", "deletionTimestamp", "deletionGracePeriodSeconds",
+}
+
+// RestoreResource puts a resource back to a previously captured state.
+//
+// snapshot is the object as GetResourceState returned it before the
+// change (Raw.Object), or nil when the object did not exist then:
+//   - nil snapshot: the object is deleted if it exists now.
+//   - live object present: it is updated in place to the snapshot,
+//     carrying over the live resourceVersion.
+//   - live object gone (e.g. the change was a delete): the snapshot is
+//     created again.
+//
+// status is dropped on the way back; it belongs to controllers and the
+// restored object will report its own.
+func (c *Client) RestoreResource(resource, name, namespace string, snapshot map[string]interface{}) (string, error) {
+	mapping, err := c.mappingFor(resource)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve resource '%s': %w", resource, err)
+	}
+
+	var ri dynamic.ResourceInterface
+	if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+		ri = c.dynamicClient.Resource(mapping.Resource).Namespace(namespace)
+	} else {
+		ri = c.dynamicClient.Resource(mapping.Resource)
+	}
+	kind := mapping.GroupVersionKind.Kind
+
+	live, err := ri.Get(context.TODO(), name, metav1.GetOptions{})
+	liveExists := err == nil
+	if err != nil && !isNotFoundError(err) {
+		return "", fmt.Errorf("failed to get live %s/%s: %w", kind, name, err)
+	}
+
+	if snapshot == nil {
+		if !liveExists {
+			return fmt.Sprintf("%s/%s in namespace %s already absent", kind, name, namespace), nil
+		}
+		if err := ri.Delete(context.TODO(), name, metav1.DeleteOptions{}); err != nil {
+			return "", fmt.Errorf("failed to delete %s/%s: %w", kind, name, err)
+		}
+		return fmt.Sprintf("Deleted %s/%s in namespace %s (did not exist before)", kind, name, namespace), nil
+	}
+
+	obj := &unstructured.Unstructured{Object: runtime.DeepCopyJSON(snapshot)}
+	for _, field := range restoreStrippedMetadata {
+		unstructured.RemoveNestedField(obj.Object, "metadata", field)
+	}
+	unstructured.RemoveNestedField(obj.Object, "status")
+
+	if liveExists {
+		obj.SetResourceVersion(live.GetResourceVersion())
+		if _, err := ri.Update(context.TODO(), obj, metav1.UpdateOptions{}); err != nil {
+			return "", fmt.Errorf("failed to restore %s/%s: %w", kind, name, err)
+		}
+		return fmt.Sprintf("Restored %s/%s in namespace %s", kind, name, namespace), nil
+	}
+
+	if _, err := ri.Create(context.TODO(), obj, metav1.CreateOptions{}); err != nil {
+		return "", fmt.Errorf("failed to re-create %s/%s: %w", kind, name, err)
+	}
+	return fmt.Sprintf("Re-created %s/%s in namespace %s", kind, name, namespace), nil
+}
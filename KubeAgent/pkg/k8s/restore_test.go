@@ -0,0 +1,52 @@
+package k8s
+
+import (
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// TestRestoreResource covers the three rollback cases: undo an update,
+// re-create a deleted object and delete an object that did not exist.
+func TestRestoreResource(t *testing.T) {
+	client := newCrashLoopClient(t)
+
+	before, err := client.GetResourceState("deployment", "web", "demo")
+	if err != nil {
+		t.Fatalf("GetResourceState failed: %v", err)
+	}
+	snapshot := runtime.DeepCopyJSON(before.Raw.Object)
+	if _, err := client.Patch("deployment", "web", "demo", PatchStrategic, imagePatch); err != nil {
+		t.Fatalf("Patch failed: %v", err)
+	}
+	if _, err := client.RestoreResource("Deployment", "web", "demo", snapshot); err != nil {
+		t.Fatalf("Restore (update) failed: %v", err)
+	}
+	if image := deploymentImage(t, client); image != "example.com/web:1.4.2" {
+		t.Errorf("Expected original image, got %s", image)
+	}
+
+	pod, _ := client.GetResourceState("pod", "web-1", "demo")
+	podSnapshot := runtime.DeepCopyJSON(pod.Raw.Object)
+	if _, err := client.DeleteResource("pod", "web-1", "demo"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	out, err := client.RestoreResource("Pod", "web-1", "demo", podSnapshot)
+	if err != nil || !strings.HasPrefix(out, "Re-created") {
+		t.Fatalf("Restore (re-create) failed: %q (err %v)", out, err)
+	}
+	if state, _ := client.GetResourceState("pod", "web-1", "demo"); !state.Exists {
+		t.Error("Pod was not re-created")
+	}
+
+	if _, err := client.CreateResource(strings.ReplaceAll(fixedWebPod, "web-1", "web-2")); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if _, err := client.RestoreResource("Pod", "web-2", "demo", nil); err != nil {
+		t.Fatalf("Restore (delete) failed: %v", err)
+	}
+	if state, _ := client.GetResourceState("pod", "web-2", "demo"); state.Exists {
+		t.Error("Pod that did not exist before was not deleted")
+	}
+}
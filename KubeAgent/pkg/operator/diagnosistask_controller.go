@@ -0,0 +1,666 @@
+// Package operator runs the agents in-cluster: a controller-runtime
+// reconciler turns each DiagnosisTask into a coordinator request and
+// mirrors the request's plan, approvals and outcome into the task's
+// status.
+//
+// Requests run on an api.Server, the same engine behind `kubeagent
+// serve` and the chat bots, so planning, plan approval, HumanTool
+// approvals and the final report behave the same everywhere. The
+// reconciler only translates: spec into a SubmitRequest, approval
+// annotations into decisions, and request state into status.
+package operator
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	"kubeagent/pkg/agent"
+	"kubeagent/pkg/agent/harness"
+	"kubeagent/pkg/api"
+	"kubeagent/pkg/operator/v1alpha1"
+)
+
+const (
+	// Finalizer cancels a task's request when the DiagnosisTask is
+	// deleted before it finished. It is removed once the task finishes.
+	Finalizer = "kubeagent.io/cancel-request"
+
+	// DefaultPollInterval is how often an unfinished task's status is
+	// refreshed from its request.
+	DefaultPollInterval = 5 * time.Second
+
+	// operatorApprover decides approvals of tasks without
+	// approvalRequired.
+	operatorApprover = "kubeagent-operator"
+
+	// maxStatusText caps free-form status fields, which an LLM can
+	// make arbitrarily long, well below etcd's object size limit.
+	maxStatusText = 4096
+)
+
+// Backend is what the reconciler needs from the request engine.
+// *api.Server implements it.
+type Backend interface {
+	Submit(submit api.SubmitRequest) (api.RequestRecord, error)
+	Request(id string) (api.RequestRecord, bool)
+	Plan(id string) (*agent.ExecutionPlan, bool)
+	Cancel(id string) (api.RequestRecord, error)
+	PendingApprovals() []harness.ApprovalRecord
+	ResolveApproval(id string, decision harness.ApprovalDecision) (harness.ApprovalRecord, error)
+}
+
+// DiagnosisTaskReconciler drives DiagnosisTasks. A task is submitted
+// once; afterwards each reconcile reads the request's state, applies
+// approval annotations and writes status until the request finishes.
+//
+// The request engine lives in this process, so a restart loses the
+// requests that were running: their tasks fail rather than being
+// silently resubmitted halfway through a remediation. The reason is
+// RequestLost unless the backend still has the request's plan stored.
+type DiagnosisTaskReconciler struct {
+	client       client.Client
+	backend      Backend
+	logger       agent.Logger
+	pollInterval time.Duration
+	policy       harness.Approver
+	events       chan event.GenericEvent
+
+	// started maps task UIDs to their request IDs. A reconcile can
+	// read a task from the cache before its status carries the request
+	// ID; this stops it from being submitted twice.
+	mu      sync.Mutex
+	started map[types.UID]string
+	tasks   map[string]types.NamespacedName
+}
+
+// NewDiagnosisTaskReconciler creates a reconciler reading and writing
+// tasks through c and running them on backend.
+func NewDiagnosisTaskReconciler(c client.Client, backend Backend, logger agent.Logger) *DiagnosisTaskReconciler {
+	return &DiagnosisTaskReconciler{
+		client:       c,
+		backend:      backend,
+		logger:       logger,
+		pollInterval: DefaultPollInterval,
+		events:       make(chan event.GenericEvent, 64),
+		started:      make(map[types.UID]string),
+		tasks:        make(map[string]types.NamespacedName),
+	}
+}
+
+// WithPollInterval sets how often unfinished tasks are refreshed.
+// Non-positive values are ignored.
+func (r *DiagnosisTaskReconciler) WithPollInterval(d time.Duration) *DiagnosisTaskReconciler {
+	if d > 0 {
+		r.pollInterval = d
+	}
+	return r
+}
+
+// WithAutoApprove lets the operator approve the HumanTool changes of
+// tasks without approvalRequired up to maxRisk, like --auto-approve;
+// riskier ones wait in status.pendingApproval for an annotation. Empty
+// leaves every change to an annotation.
+func (r *DiagnosisTaskReconciler) WithAutoApprove(maxRisk harness.Risk) *DiagnosisTaskReconciler {
+	r.policy = nil
+	if maxRisk != "" {
+		r.policy = harness.NewAutoApprover(maxRisk)
+	}
+	return r
+}
+
+// SetupWithManager registers the reconciler for DiagnosisTasks, and for
+// the approvals NotifyApproval reports so they show up in status
+// without waiting for the next poll.
+func (r *DiagnosisTaskReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&v1alpha1.DiagnosisTask{}).
+		WatchesRawSource(source.Channel(r.events, &handler.EnqueueRequestForObject{})).
+		Named("diagnosistask").
+		Complete(r)
+}
+
+// NotifyApproval queues a reconcile of the task a new approval belongs
+// to; register it with api.Server.OnApproval. It never blocks the
+// approver: if the queue is full the next poll catches up.
+func (r *DiagnosisTaskReconciler) NotifyApproval(record harness.ApprovalRecord) {
+	r.mu.Lock()
+	key, ok := r.tasks[record.RequestID]
+	r.mu.Unlock()
+	if !ok {
+		return
+	}
+	task := &v1alpha1.DiagnosisTask{ObjectMeta: metav1.ObjectMeta{Namespace: key.Namespace, Name: key.Name}}
+	select {
+	case r.events <- event.GenericEvent{Object: task}:
+	default:
+	}
+}
+
+// +kubebuilder:rbac:groups=kubeagent.io,resources=diagnosistasks,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=kubeagent.io,resources=diagnosistasks/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=kubeagent.io,resources=diagnosistasks/finalizers,verbs=update
+
+// Reconcile implements reconcile.Reconciler.
+func (r *DiagnosisTaskReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	var task v1alpha1.DiagnosisTask
+	if err := r.client.Get(ctx, req.NamespacedName, &task); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	if !task.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, r.finalize(ctx, &task)
+	}
+	if task.Status.Phase.Finished() {
+		return ctrl.Result{}, r.release(ctx, &task)
+	}
+	if controllerutil.AddFinalizer(&task, Finalizer) {
+		if err := r.client.Update(ctx, &task); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	status := task.Status.DeepCopy()
+	if status.RequestID == "" {
+		r.mu.Lock()
+		status.RequestID = r.started[task.UID]
+		r.mu.Unlock()
+	}
+	if status.RequestID == "" {
+		r.start(&task, status)
+	}
+	if status.RequestID != "" {
+		r.observe(&task, status)
+	}
+
+	if !equality.Semantic.DeepEqual(*status, task.Status) {
+		task.Status = *status
+		if err := r.client.Status().Update(ctx, &task); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+	if status.Phase.Finished() {
+		return ctrl.Result{}, r.release(ctx, &task)
+	}
+	return ctrl.Result{RequeueAfter: r.pollInterval}, nil
+}
+
+// start submits the task's request.
+func (r *DiagnosisTaskReconciler) start(task *v1alpha1.DiagnosisTask, status *v1alpha1.DiagnosisTaskStatus) {
+	now := metav1.Now()
+	status.StartTime = &now
+	submit, err := Submission(task)
+	if err != nil {
+		r.fail(task, status, "InvalidSpec", err.Error())
+		return
+	}
+	record, err := r.backend.Submit(submit)
+	if err != nil {
+		r.fail(task, status, "SubmitFailed", err.Error())
+		return
+	}
+
+	r.mu.Lock()
+	r.started[task.UID] = record.ID
+	r.tasks[record.ID] = types.NamespacedName{Namespace: task.Namespace, Name: task.Name}
+	r.mu.Unlock()
+	status.RequestID = record.ID
+	status.Phase = v1alpha1.PhasePending
+	r.logger.Info("DiagnosisTask submitted", map[string]interface{}{
+		"task":       task.Namespace + "/" + task.Name,
+		"request_id": record.ID,
+		"intent":     submit.Intent,
+	})
+}
+
+// Submission is the request a DiagnosisTask asks for. It uses the same
+// context keys as the CLI and chat front ends, so the coordinator finds
+// the target without asking the LLM.
+func Submission(task *v1alpha1.DiagnosisTask) (api.SubmitRequest, error) {
+	target := task.Spec.Target
+	kind := target.Kind
+	if kind == "" {
+		kind = "Pod"
+	}
+	if target.Name == "" {
+		return api.SubmitRequest{}, fmt.Errorf("spec.target.name is required")
+	}
+	if !task.HasAgent(v1alpha1.AgentDiagnostician) {
+		return api.SubmitRequest{}, fmt.Errorf("spec.agents must include %s", v1alpha1.AgentDiagnostician)
+	}
+	if task.Spec.AutoRemediate && !task.HasAgent(v1alpha1.AgentRemediator) {
+		return api.SubmitRequest{}, fmt.Errorf("autoRemediate needs the %s agent", v1alpha1.AgentRemediator)
+	}
+
+	namespace := task.TargetNamespace()
+	intent, verb := string(agent.TaskTypeDiagnose), "Diagnose"
+	if task.Spec.AutoRemediate {
+		intent, verb = string(agent.TaskTypeRemediate), "Diagnose and fix"
+	}
+	input := fmt.Sprintf("%s %s %q in namespace %q.", verb, strings.ToLower(kind), target.Name, namespace)
+	if task.Spec.Description != "" {
+		input += " " + task.Spec.Description
+	}
+	submitCtx := map[string]interface{}{
+		"namespace":     namespace,
+		"resource_kind": kind,
+		"resource_name": target.Name,
+	}
+	if kind == "Pod" {
+		submitCtx["pod_name"] = target.Name
+	}
+	return api.SubmitRequest{
+		Input:            input,
+		Intent:           intent,
+		Context:          submitCtx,
+		User:             "diagnosistask:" + task.Namespace + "/" + task.Name,
+		ApprovalRequired: task.Spec.ApprovalRequired,
+		DiagnoseOnly:     !task.HasAgent(v1alpha1.AgentRemediator),
+	}, nil
+}
+
+// observe refreshes status from the task's request, deciding whatever
+// approvals the task's annotations or spec already answer.
+func (r *DiagnosisTaskReconciler) observe(task *v1alpha1.DiagnosisTask, status *v1alpha1.DiagnosisTaskStatus) {
+	record, ok := r.backend.Request(status.RequestID)
+	if !ok {
+		r.fail(task, status, "RequestLost", fmt.Sprintf(
+			"request %s is gone, most likely because the operator restarted; delete and recreate the DiagnosisTask to run it again",
+			status.RequestID))
+		return
+	}
+	pending := r.pendingFor(record.ID)
+	if r.decide(task, pending) {
+		record, _ = r.backend.Request(status.RequestID)
+		pending = r.pendingFor(record.ID)
+	}
+
+	plan, _ := r.backend.Plan(record.ID)
+	report := finalReport(record.Response)
+	status.PlanID = record.PlanID
+	status.Tasks = taskStatuses(plan, report)
+	status.RootCause = truncate(rootCause(plan, report), maxStatusText)
+	status.RemediationPlan = truncate(remediationPlan(plan, report), maxStatusText)
+	status.Message = truncate(record.Error, maxStatusText)
+	status.PendingApproval = nil
+	if len(pending) > 0 {
+		status.PendingApproval = &v1alpha1.ApprovalStatus{
+			ID:     pending[0].ID,
+			Kind:   string(pending[0].Kind),
+			Risk:   string(pending[0].Risk),
+			Prompt: truncate(pending[0].Prompt, maxStatusText),
+		}
+	}
+	status.Phase = phaseOf(record.Status, plan, pending)
+	setConditions(task, status, record, report)
+	if status.Phase.Finished() && status.CompletionTime == nil {
+		now := metav1.Now()
+		status.CompletionTime = &now
+	}
+}
+
+// pendingFor lists the request's approvals, oldest first.
+func (r *DiagnosisTaskReconciler) pendingFor(requestID string) []harness.ApprovalRecord {
+	var pending []harness.ApprovalRecord
+	for _, record := range r.backend.PendingApprovals() {
+		if record.RequestID == requestID {
+			pending = append(pending, record)
+		}
+	}
+	return pending
+}
+
+// decide answers the pending approvals the task has a decision for: the
+// one named by the approve or reject annotation and, without
+// approvalRequired, the plan and the changes the risk policy allows. It
+// reports whether it decided any.
+func (r *DiagnosisTaskReconciler) decide(task *v1alpha1.DiagnosisTask, pending []harness.ApprovalRecord) bool {
+	annotations := task.GetAnnotations()
+	approver := annotations[v1alpha1.AnnotationApprover]
+	if approver == "" {
+		approver = "annotation"
+	}
+	decided := false
+	for _, approval := range pending {
+		var decision harness.ApprovalDecision
+		switch {
+		case annotations[v1alpha1.AnnotationApprove] == approval.ID:
+			decision = harness.ApprovalDecision{Approved: true, Approver: approver, Reason: annotations[v1alpha1.AnnotationReason]}
+		case annotations[v1alpha1.AnnotationReject] == approval.ID:
+			decision = harness.ApprovalDecision{Approved: false, Approver: approver, Reason: annotations[v1alpha1.AnnotationReason]}
+		case task.Spec.ApprovalRequired:
+			continue
+		case approval.Kind == harness.ApprovalPlan:
+			decision = harness.ApprovalDecision{Approved: true, Approver: operatorApprover, Reason: "spec.approvalRequired is false"}
+		case r.policy != nil:
+			// A change above the policy's risk waits for a person
+			// instead of being rejected.
+			policy, err := r.policy.Approve(context.Background(), approval.ApprovalRequest)
+			if err != nil || !policy.Approved {
+				continue
+			}
+			decision = policy
+		default:
+			continue
+		}
+		if _, err := r.backend.ResolveApproval(approval.ID, decision); err != nil {
+			r.logger.Warn("Failed to decide approval", map[string]interface{}{
+				"task":        task.Namespace + "/" + task.Name,
+				"approval_id": approval.ID,
+				"error":       err.Error(),
+			})
+			continue
+		}
+		r.logger.Info("Approval decided for DiagnosisTask", map[string]interface{}{
+			"task":        task.Namespace + "/" + task.Name,
+			"approval_id": approval.ID,
+			"approved":    decision.Approved,
+			"approver":    decision.Approver,
+		})
+		decided = true
+	}
+	return decided
+}
+
+// finalize cancels an unfinished request and releases the task.
+func (r *DiagnosisTaskReconciler) finalize(ctx context.Context, task *v1alpha1.DiagnosisTask) error {
+	if !controllerutil.ContainsFinalizer(task, Finalizer) {
+		return nil
+	}
+	requestID := task.Status.RequestID
+	if requestID == "" {
+		r.mu.Lock()
+		requestID = r.started[task.UID]
+		r.mu.Unlock()
+	}
+	if requestID != "" {
+		if record, ok := r.backend.Request(requestID); ok && !record.Status.Finished() {
+			if _, err := r.backend.Cancel(requestID); err != nil {
+				return err
+			}
+			r.logger.Info("Cancelled request of deleted DiagnosisTask", map[string]interface{}{
+				"task":       task.Namespace + "/" + task.Name,
+				"request_id": requestID,
+			})
+		}
+	}
+	r.forget(task)
+	controllerutil.RemoveFinalizer(task, Finalizer)
+	return r.client.Update(ctx, task)
+}
+
+// release drops a finished task's finalizer: there is no request left
+// to cancel, and deleting the task must not depend on the operator.
+func (r *DiagnosisTaskReconciler) release(ctx context.Context, task *v1alpha1.DiagnosisTask) error {
+	r.forget(task)
+	if controllerutil.RemoveFinalizer(task, Finalizer) {
+		return r.client.Update(ctx, task)
+	}
+	return nil
+}
+
+func (r *DiagnosisTaskReconciler) forget(task *v1alpha1.DiagnosisTask) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if id, ok := r.started[task.UID]; ok {
+		delete(r.tasks, id)
+		delete(r.started, task.UID)
+	}
+}
+
+func (r *DiagnosisTaskReconciler) fail(task *v1alpha1.DiagnosisTask, status *v1alpha1.DiagnosisTaskStatus, reason, message string) {
+	now := metav1.Now()
+	status.Phase = v1alpha1.PhaseFailed
+	status.Message = message
+	status.PendingApproval = nil
+	status.CompletionTime = &now
+	meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+		Type:               v1alpha1.ConditionCompleted,
+		Status:             metav1.ConditionFalse,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: task.Generation,
+	})
+}
+
+// phaseOf maps a request's status to a phase. While running, a
+// pending HumanTool approval or a running remediation is what a reader
+// of `kubectl get dt` most wants to know.
+func phaseOf(status api.RequestStatus, plan *agent.ExecutionPlan, pending []harness.ApprovalRecord) v1alpha1.Phase {
+	switch status {
+	case api.RequestQueued:
+		return v1alpha1.PhasePending
+	case api.RequestPlanning:
+		return v1alpha1.PhasePlanning
+	case api.RequestAwaitingApproval:
+		return v1alpha1.PhaseAwaitingApproval
+	case api.RequestRunning:
+		if len(pending) > 0 {
+			return v1alpha1.PhaseAwaitingApproval
+		}
+		if plan != nil {
+			for _, task := range plan.Tasks {
+				if task.Type == agent.TaskTypeRemediate && task.Status == agent.TaskStatusRunning {
+					return v1alpha1.PhaseRemediating
+				}
+			}
+		}
+		return v1alpha1.PhaseDiagnosing
+	case api.RequestCompleted:
+		return v1alpha1.PhaseCompleted
+	case api.RequestRejected:
+		return v1alpha1.PhaseRejected
+	}
+	return v1alpha1.PhaseFailed
+}
+
+func setConditions(task *v1alpha1.DiagnosisTask, status *v1alpha1.DiagnosisTaskStatus, record api.RequestRecord, report *agent.FinalReport) {
+	set := func(conditionType string, value metav1.ConditionStatus, reason, message string) {
+		meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+			Type:               conditionType,
+			Status:             value,
+			Reason:             reason,
+			Message:            truncate(message, maxStatusText),
+			ObservedGeneration: task.Generation,
+		})
+	}
+
+	switch {
+	case record.PlanID != "":
+		set(v1alpha1.ConditionPlanned, metav1.ConditionTrue, "PlanCreated", fmt.Sprintf("plan %s with %d task(s)", record.PlanID, len(status.Tasks)))
+	case record.Status.Finished():
+		set(v1alpha1.ConditionPlanned, metav1.ConditionFalse, "PlanningFailed", record.Error)
+	}
+
+	if record.ApprovalID != "" {
+		switch record.Status {
+		case api.RequestAwaitingApproval:
+			set(v1alpha1.ConditionApproved, metav1.ConditionFalse, "AwaitingApproval",
+				fmt.Sprintf("annotate with %s=%s to approve", v1alpha1.AnnotationApprove, record.ApprovalID))
+		case api.RequestRejected:
+			set(v1alpha1.ConditionApproved, metav1.ConditionFalse, "Rejected", record.Error)
+		case api.RequestCancelled:
+			set(v1alpha1.ConditionApproved, metav1.ConditionFalse, "Cancelled", record.Error)
+		default:
+			set(v1alpha1.ConditionApproved, metav1.ConditionTrue, "Approved", "plan approved")
+		}
+	}
+
+	if value, reason, message, ok := verification(report); ok {
+		set(v1alpha1.ConditionVerified, value, reason, message)
+	}
+
+	switch status.Phase {
+	case v1alpha1.PhaseCompleted:
+		set(v1alpha1.ConditionCompleted, metav1.ConditionTrue, "Completed", record.Result)
+	case v1alpha1.PhaseRejected:
+		set(v1alpha1.ConditionCompleted, metav1.ConditionFalse, "Rejected", record.Error)
+	case v1alpha1.PhaseFailed:
+		reason := "Failed"
+		if record.Status == api.RequestCancelled {
+			reason = "Cancelled"
+		}
+		set(v1alpha1.ConditionCompleted, metav1.ConditionFalse, reason, record.Error)
+	}
+}
+
+// verification summarizes the post-change checks of the report. A
+// remediation that failed verification and was retried is judged by
+// its retry.
+func verification(report *agent.FinalReport) (value metav1.ConditionStatus, reason, message string, ok bool) {
+	if report == nil {
+		return "", "", "", false
+	}
+	value, reason = metav1.ConditionTrue, "VerificationPassed"
+	var summaries []string
+	for _, task := range report.Tasks {
+		v := task.Verification
+		if v == nil || task.RetriedBy != "" {
+			continue
+		}
+		ok = true
+		summaries = append(summaries, fmt.Sprintf("%s: %s", task.ID, defaultString(v.Summary, string(v.Status))))
+		switch {
+		case v.Status == harness.VerificationFailed:
+			value, reason = metav1.ConditionFalse, "VerificationFailed"
+		case v.Status != harness.VerificationPassed && value == metav1.ConditionTrue:
+			value, reason = metav1.ConditionUnknown, "VerificationInconclusive"
+		}
+	}
+	return value, reason, strings.Join(summaries, "; "), ok
+}
+
+// taskStatuses lists the plan's tasks, from the final report once
+// there is one and from the live plan before.
+func taskStatuses(plan *agent.ExecutionPlan, report *agent.FinalReport) []v1alpha1.TaskStatus {
+	var tasks []v1alpha1.TaskStatus
+	if report != nil {
+		for _, task := range report.Tasks {
+			ts := v1alpha1.TaskStatus{
+				ID:     task.ID,
+				Type:   string(task.Type),
+				Agent:  string(task.Agent),
+				Status: string(task.Status),
+				Error:  truncate(defaultString(task.Error, task.SkipReason), maxStatusText),
+			}
+			if v := task.Verification; v != nil {
+				ts.Verification = string(v.Status)
+				ts.VerificationSummary = truncate(v.Summary, maxStatusText)
+			}
+			tasks = append(tasks, ts)
+		}
+		return tasks
+	}
+	if plan == nil {
+		return nil
+	}
+	for _, task := range plan.Tasks {
+		tasks = append(tasks, v1alpha1.TaskStatus{
+			ID:     task.ID,
+			Type:   string(task.Type),
+			Agent:  string(task.AssignedAgent),
+			Status: string(task.Status),
+			Error:  truncate(task.Error, maxStatusText),
+		})
+	}
+	return tasks
+}
+
+// rootCause collects what the diagnoses found.
+func rootCause(plan *agent.ExecutionPlan, report *agent.FinalReport) string {
+	var causes []string
+	add := func(taskType agent.TaskType, output map[string]interface{}) {
+		if taskType != agent.TaskTypeDiagnose {
+			return
+		}
+		if cause, ok := output["root_cause"].(string); ok && cause != "" {
+			causes = append(causes, cause)
+		}
+	}
+	if report != nil {
+		for _, task := range report.Tasks {
+			add(task.Type, task.Output)
+		}
+	} else if plan != nil {
+		for _, task := range plan.Tasks {
+			add(task.Type, task.Output)
+		}
+	}
+	return strings.Join(causes, "\n")
+}
+
+// remediationPlan lists the planned remediations with the actions each
+// took once it ran.
+func remediationPlan(plan *agent.ExecutionPlan, report *agent.FinalReport) string {
+	if plan == nil {
+		return ""
+	}
+	actions := make(map[string][]string)
+	if report != nil {
+		for _, task := range report.Tasks {
+			switch taken := task.Output["actions_taken"].(type) {
+			case []string:
+				actions[task.ID] = taken
+			case []interface{}:
+				for _, action := range taken {
+					actions[task.ID] = append(actions[task.ID], fmt.Sprint(action))
+				}
+			}
+		}
+	}
+	var b strings.Builder
+	for _, task := range plan.Tasks {
+		if task.Type != agent.TaskTypeRemediate {
+			continue
+		}
+		fmt.Fprintf(&b, "- %s: %s\n", task.ID, task.Description)
+		for _, action := range actions[task.ID] {
+			fmt.Fprintf(&b, "  - %s\n", action)
+		}
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// finalReport extracts the coordinator's FinalReport from a response.
+func finalReport(response *agent.Response) *agent.FinalReport {
+	if response == nil {
+		return nil
+	}
+	switch report := response.Data[agent.FinalReportKey].(type) {
+	case *agent.FinalReport:
+		return report
+	case agent.FinalReport:
+		return &report
+	}
+	return nil
+}
+
+// truncate cuts s to at most n bytes without splitting a rune.
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && s[n]&0xC0 == 0x80 {
+		n--
+	}
+	return s[:n] + "…"
+}
+
+func defaultString(s, fallback string) string {
+	if s == "" {
+		return fallback
+	}
+	return s
+}
@@ -0,0 +1,378 @@
+package operator
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	"kubeagent/pkg/agent"
+	"kubeagent/pkg/agent/agenttest"
+	"kubeagent/pkg/agent/harness"
+	"kubeagent/pkg/api"
+	"kubeagent/pkg/operator/v1alpha1"
+)
+
+func newScheme(t *testing.T) *runtime.Scheme {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	if err := v1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	return scheme
+}
+
+func newTask(name string, spec v1alpha1.DiagnosisTaskSpec) *v1alpha1.DiagnosisTask {
+	return &v1alpha1.DiagnosisTask{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "shop", UID: types.UID("uid-" + name)},
+		Spec:       spec,
+	}
+}
+
+type fixture struct {
+	client     client.Client
+	server     *api.Server
+	reconciler *DiagnosisTaskReconciler
+}
+
+func setup(t *testing.T, objects ...client.Object) *fixture {
+	t.Helper()
+	return setupWith(t, &agenttest.FixingCoordinator{}, objects...)
+}
+
+func setupWith(t *testing.T, coordinator agent.CoordinatorAgent, objects ...client.Object) *fixture {
+	t.Helper()
+	c := fake.NewClientBuilder().
+		WithScheme(newScheme(t)).
+		WithObjects(objects...).
+		WithStatusSubresource(&v1alpha1.DiagnosisTask{}).
+		Build()
+	server := api.NewServer(coordinator, agent.NewNoOpLogger()).WithRemediationApproval(false)
+	return &fixture{
+		client:     c,
+		server:     server,
+		reconciler: NewDiagnosisTaskReconciler(c, server, agent.NewNoOpLogger()),
+	}
+}
+
+func (f *fixture) reconcile(t *testing.T, name string) ctrl.Result {
+	t.Helper()
+	result, err := f.reconciler.Reconcile(context.Background(), ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "shop", Name: name}})
+	if err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	return result
+}
+
+func (f *fixture) get(t *testing.T, name string) *v1alpha1.DiagnosisTask {
+	t.Helper()
+	var task v1alpha1.DiagnosisTask
+	if err := f.client.Get(context.Background(), types.NamespacedName{Namespace: "shop", Name: name}, &task); err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	return &task
+}
+
+// reconcileUntil reconciles name until its status satisfies done.
+func (f *fixture) reconcileUntil(t *testing.T, name string, done func(*v1alpha1.DiagnosisTaskStatus) bool) *v1alpha1.DiagnosisTask {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		f.reconcile(t, name)
+		task := f.get(t, name)
+		if done(&task.Status) {
+			return task
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out; last status %+v", task.Status)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func phase(want v1alpha1.Phase) func(*v1alpha1.DiagnosisTaskStatus) bool {
+	return func(s *v1alpha1.DiagnosisTaskStatus) bool { return s.Phase == want }
+}
+
+func condition(t *testing.T, task *v1alpha1.DiagnosisTask, conditionType string) metav1.Condition {
+	t.Helper()
+	c := meta.FindStatusCondition(task.Status.Conditions, conditionType)
+	if c == nil {
+		t.Fatalf("Expected condition %s, got %+v", conditionType, task.Status.Conditions)
+	}
+	return *c
+}
+
+func TestReconcile_ApprovalRequiredWaitsForTheAnnotation(t *testing.T) {
+	f := setup(t, newTask("web-1", v1alpha1.DiagnosisTaskSpec{
+		Target:           v1alpha1.Target{Name: "web-1"},
+		AutoRemediate:    true,
+		ApprovalRequired: true,
+	}))
+
+	task := f.reconcileUntil(t, "web-1", phase(v1alpha1.PhaseAwaitingApproval))
+	if !controllerutil.ContainsFinalizer(task, Finalizer) {
+		t.Error("Expected the finalizer while the request runs")
+	}
+	approval := task.Status.PendingApproval
+	if approval == nil || approval.Kind != string(harness.ApprovalPlan) || !strings.Contains(approval.Prompt, "fix [remediate]") {
+		t.Fatalf("Expected the plan in status.pendingApproval, got %+v", approval)
+	}
+	if c := condition(t, task, v1alpha1.ConditionApproved); c.Status != metav1.ConditionFalse || !strings.Contains(c.Message, approval.ID) {
+		t.Errorf("Expected Approved=False naming the approval, got %+v", c)
+	}
+	if c := condition(t, task, v1alpha1.ConditionPlanned); c.Status != metav1.ConditionTrue {
+		t.Errorf("Expected Planned=True, got %+v", c)
+	}
+	if len(task.Status.Tasks) != 2 || !strings.Contains(task.Status.RemediationPlan, "fix: raise the memory limit") {
+		t.Errorf("Expected the plan in status, got %+v / %q", task.Status.Tasks, task.Status.RemediationPlan)
+	}
+
+	// An annotation for another approval decides nothing.
+	task.Annotations = map[string]string{v1alpha1.AnnotationApprove: "stale-id"}
+	if err := f.client.Update(context.Background(), task); err != nil {
+		t.Fatal(err)
+	}
+	f.reconcile(t, "web-1")
+	if len(f.server.PendingApprovals()) != 1 {
+		t.Fatal("Expected a stale approval annotation to be ignored")
+	}
+
+	task = f.get(t, "web-1")
+	task.Annotations = map[string]string{v1alpha1.AnnotationApprove: approval.ID, v1alpha1.AnnotationApprover: "alice"}
+	if err := f.client.Update(context.Background(), task); err != nil {
+		t.Fatal(err)
+	}
+	task = f.reconcileUntil(t, "web-1", phase(v1alpha1.PhaseCompleted))
+
+	s := task.Status
+	if s.RootCause != "container exceeds its 64Mi memory limit" {
+		t.Errorf("Unexpected root cause %q", s.RootCause)
+	}
+	if !strings.Contains(s.RemediationPlan, "patched deployment web memory limit to 256Mi") {
+		t.Errorf("Expected the actions taken in the remediation plan, got %q", s.RemediationPlan)
+	}
+	if s.PendingApproval != nil || s.CompletionTime == nil {
+		t.Errorf("Expected a finished status, got %+v", s)
+	}
+	if s.Tasks[1].Verification != "passed" || s.Tasks[1].VerificationSummary != "pod is Ready" {
+		t.Errorf("Expected the fix's verification in status, got %+v", s.Tasks[1])
+	}
+	for conditionType, want := range map[string]metav1.ConditionStatus{
+		v1alpha1.ConditionApproved:  metav1.ConditionTrue,
+		v1alpha1.ConditionVerified:  metav1.ConditionTrue,
+		v1alpha1.ConditionCompleted: metav1.ConditionTrue,
+	} {
+		if c := condition(t, task, conditionType); c.Status != want {
+			t.Errorf("Expected %s=%s, got %+v", conditionType, want, c)
+		}
+	}
+	if controllerutil.ContainsFinalizer(task, Finalizer) {
+		t.Error("Expected the finalizer to be dropped once finished")
+	}
+	if result := f.reconcile(t, "web-1"); result.RequeueAfter != 0 {
+		t.Errorf("Expected a finished task not to be requeued, got %+v", result)
+	}
+}
+
+func TestReconcile_RejectAnnotation(t *testing.T) {
+	f := setup(t, newTask("web-2", v1alpha1.DiagnosisTaskSpec{
+		Target:           v1alpha1.Target{Name: "web-2"},
+		AutoRemediate:    true,
+		ApprovalRequired: true,
+	}))
+	task := f.reconcileUntil(t, "web-2", phase(v1alpha1.PhaseAwaitingApproval))
+	task.Annotations = map[string]string{
+		v1alpha1.AnnotationReject: task.Status.PendingApproval.ID,
+		v1alpha1.AnnotationReason: "not during the sale",
+	}
+	if err := f.client.Update(context.Background(), task); err != nil {
+		t.Fatal(err)
+	}
+	task = f.reconcileUntil(t, "web-2", phase(v1alpha1.PhaseRejected))
+	if c := condition(t, task, v1alpha1.ConditionCompleted); c.Status != metav1.ConditionFalse || !strings.Contains(c.Message, "not during the sale") {
+		t.Errorf("Expected Completed=False with the reason, got %+v", c)
+	}
+}
+
+func TestReconcile_WithoutApprovalRequiredTheOperatorApproves(t *testing.T) {
+	f := setup(t, newTask("web-3", v1alpha1.DiagnosisTaskSpec{
+		Target:        v1alpha1.Target{Name: "web-3", Namespace: "staging"},
+		AutoRemediate: true,
+	}))
+	// A server that holds remediation plans on its own still runs them:
+	// the task said no approval is needed.
+	f.server.WithRemediationApproval(true)
+
+	task := f.reconcileUntil(t, "web-3", phase(v1alpha1.PhaseCompleted))
+	if c := condition(t, task, v1alpha1.ConditionApproved); c.Status != metav1.ConditionTrue {
+		t.Errorf("Expected the operator to approve, got %+v", c)
+	}
+	record, _ := f.server.Request(task.Status.RequestID)
+	if !strings.Contains(record.Input, `"staging"`) {
+		t.Errorf("Expected the target namespace in the request, got %q", record.Input)
+	}
+}
+
+// overeagerCoordinator plans a fix whatever the intent, as an LLM
+// planner may.
+type overeagerCoordinator struct {
+	agenttest.FixingCoordinator
+}
+
+func (c *overeagerCoordinator) Plan(ctx *agent.AgentContext, request *agent.Request) (*agent.ExecutionPlan, error) {
+	remediate := *request
+	remediate.Intent = string(agent.TaskTypeRemediate)
+	return c.FixingCoordinator.Plan(ctx, &remediate)
+}
+
+// confirmingCoordinator asks gate to confirm a change of each risk
+// before running the fix, as HumanTool does.
+type confirmingCoordinator struct {
+	agenttest.FixingCoordinator
+	gate      *harness.ApprovalGate
+	risks     []harness.Risk
+	decisions chan harness.ApprovalDecision
+}
+
+func (c *confirmingCoordinator) ExecutePlan(ctx *agent.AgentContext, plan *agent.ExecutionPlan) (*agent.Response, error) {
+	for _, risk := range c.risks {
+		decision := c.gate.Decide(ctx.Context(), harness.ApprovalRequest{
+			Kind: harness.ApprovalAction, RequestID: ctx.RequestID, TaskID: "fix", Prompt: string(risk) + "-risk change", Risk: risk,
+		})
+		c.decisions <- decision
+		if !decision.Approved {
+			return nil, fmt.Errorf("change rejected: %s", decision.Reason)
+		}
+	}
+	return c.FixingCoordinator.ExecutePlan(ctx, plan)
+}
+
+func TestReconcile_WithoutApprovalRequiredChangesFollowThePolicy(t *testing.T) {
+	queue := harness.NewHTTPApprover(nil)
+	coordinator := &confirmingCoordinator{
+		gate:      harness.NewApprovalGate(queue),
+		risks:     []harness.Risk{harness.RiskLow, harness.RiskMedium},
+		decisions: make(chan harness.ApprovalDecision, 2),
+	}
+	f := setupWith(t, coordinator, newTask("web-9", v1alpha1.DiagnosisTaskSpec{
+		Target:        v1alpha1.Target{Name: "web-9"},
+		AutoRemediate: true,
+	}))
+	f.server.WithApprovals(coordinator.gate, queue)
+	f.reconciler.WithAutoApprove(harness.RiskLow)
+
+	// The low-risk change is within the policy; the medium one waits.
+	task := f.reconcileUntil(t, "web-9", phase(v1alpha1.PhaseAwaitingApproval))
+	if decision := <-coordinator.decisions; !decision.Approved || decision.Approver != "auto-policy" {
+		t.Errorf("Expected the policy to approve the low-risk change, got %+v", decision)
+	}
+	approval := task.Status.PendingApproval
+	if approval == nil || approval.Kind != harness.ApprovalAction || approval.Risk != string(harness.RiskMedium) {
+		t.Fatalf("Expected the medium-risk change in status.pendingApproval, got %+v", approval)
+	}
+	f.reconcile(t, "web-9")
+	if len(f.server.PendingApprovals()) != 1 {
+		t.Fatal("Expected the medium-risk change to stay pending")
+	}
+
+	task.Annotations = map[string]string{v1alpha1.AnnotationApprove: approval.ID}
+	if err := f.client.Update(context.Background(), task); err != nil {
+		t.Fatal(err)
+	}
+	f.reconcileUntil(t, "web-9", phase(v1alpha1.PhaseCompleted))
+	if decision := <-coordinator.decisions; !decision.Approved || decision.Approver != "annotation" {
+		t.Errorf("Expected the annotation to approve the medium-risk change, got %+v", decision)
+	}
+}
+
+func TestReconcile_DiagnoseOnly(t *testing.T) {
+	f := setupWith(t, &overeagerCoordinator{}, newTask("web-4", v1alpha1.DiagnosisTaskSpec{Target: v1alpha1.Target{Name: "web-4"}}))
+	task := f.reconcileUntil(t, "web-4", phase(v1alpha1.PhaseCompleted))
+	if len(task.Status.Tasks) != 1 || task.Status.Tasks[0].Type != string(agent.TaskTypeDiagnose) || task.Status.RemediationPlan != "" {
+		t.Errorf("Expected the planned fix to be dropped, got %+v", task.Status)
+	}
+	if meta.FindStatusCondition(task.Status.Conditions, v1alpha1.ConditionVerified) != nil {
+		t.Error("Expected no Verified condition without a remediation")
+	}
+}
+
+func TestReconcile_DeletionCancelsTheRequest(t *testing.T) {
+	f := setup(t, newTask("web-5", v1alpha1.DiagnosisTaskSpec{
+		Target:           v1alpha1.Target{Name: "web-5"},
+		AutoRemediate:    true,
+		ApprovalRequired: true,
+	}))
+	task := f.reconcileUntil(t, "web-5", phase(v1alpha1.PhaseAwaitingApproval))
+	if err := f.client.Delete(context.Background(), task); err != nil {
+		t.Fatal(err)
+	}
+	f.reconcile(t, "web-5")
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		record, _ := f.server.Request(task.Status.RequestID)
+		if record.Status == api.RequestCancelled {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the request to be cancelled, got %s", record.Status)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	var gone v1alpha1.DiagnosisTask
+	if err := f.client.Get(context.Background(), client.ObjectKeyFromObject(task), &gone); err == nil {
+		t.Error("Expected the task to be deleted once its finalizer is removed")
+	}
+}
+
+func TestReconcile_Failures(t *testing.T) {
+	lost := newTask("lost", v1alpha1.DiagnosisTaskSpec{Target: v1alpha1.Target{Name: "web-6"}})
+	lost.Status.RequestID = "from-a-previous-process"
+	invalid := newTask("invalid", v1alpha1.DiagnosisTaskSpec{
+		Target:        v1alpha1.Target{Name: "web-7"},
+		Agents:        []string{v1alpha1.AgentDiagnostician},
+		AutoRemediate: true,
+	})
+	f := setup(t, lost, invalid)
+
+	for name, reason := range map[string]string{"lost": "RequestLost", "invalid": "InvalidSpec"} {
+		f.reconcile(t, name)
+		task := f.get(t, name)
+		if task.Status.Phase != v1alpha1.PhaseFailed {
+			t.Errorf("%s: expected Failed, got %s", name, task.Status.Phase)
+		}
+		if c := condition(t, task, v1alpha1.ConditionCompleted); c.Reason != reason {
+			t.Errorf("%s: expected reason %s, got %+v", name, reason, c)
+		}
+	}
+}
+
+func TestSubmission(t *testing.T) {
+	task := newTask("web-8", v1alpha1.DiagnosisTaskSpec{
+		Target:           v1alpha1.Target{Name: "web-8"},
+		Agents:           []string{v1alpha1.AgentDiagnostician, v1alpha1.AgentRemediator},
+		AutoRemediate:    true,
+		ApprovalRequired: true,
+		Description:      "OOMKilled twice an hour",
+	})
+	submit, err := Submission(task)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if submit.Intent != string(agent.TaskTypeRemediate) || !submit.ApprovalRequired || submit.DiagnoseOnly ||
+		submit.Context["namespace"] != "shop" || submit.Context["pod_name"] != "web-8" ||
+		!strings.HasSuffix(submit.Input, "OOMKilled twice an hour") || submit.User != "diagnosistask:shop/web-8" {
+		t.Errorf("Unexpected submission %+v", submit)
+	}
+}
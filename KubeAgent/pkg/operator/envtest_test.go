@@ -0,0 +1,130 @@
+package operator
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/envtest"
+	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+
+	"kubeagent/pkg/agent"
+	"kubeagent/pkg/agent/agenttest"
+	"kubeagent/pkg/api"
+	"kubeagent/pkg/operator/v1alpha1"
+)
+
+// TestEnvtest runs the controller against a real API server with the
+// generated CRD. It needs the envtest binaries:
+//
+//	go run sigs.k8s.io/controller-runtime/tools/setup-envtest@latest use -p path
+//	KUBEBUILDER_ASSETS=<that path> go test ./pkg/operator/ -run Envtest
+func TestEnvtest(t *testing.T) {
+	if os.Getenv("KUBEBUILDER_ASSETS") == "" {
+		t.Skip("KUBEBUILDER_ASSETS is not set; see setup-envtest")
+	}
+	env := &envtest.Environment{
+		CRDDirectoryPaths:     []string{filepath.Join("..", "..", "..", "deploy", "crd")},
+		ErrorIfCRDPathMissing: true,
+	}
+	config, err := env.Start()
+	if err != nil {
+		t.Fatalf("Starting envtest: %v", err)
+	}
+	defer env.Stop()
+
+	mgr, err := ctrl.NewManager(config, ctrl.Options{
+		Scheme:  newScheme(t),
+		Metrics: metricsserver.Options{BindAddress: "0"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := api.NewServer(&agenttest.FixingCoordinator{}, agent.NewNoOpLogger()).WithRemediationApproval(false)
+	reconciler := NewDiagnosisTaskReconciler(mgr.GetClient(), server, agent.NewNoOpLogger()).
+		WithPollInterval(100 * time.Millisecond)
+	server.OnApproval(reconciler.NotifyApproval)
+	if err := reconciler.SetupWithManager(mgr); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go mgr.Start(ctx)
+
+	c, err := client.New(config, client.Options{Scheme: newScheme(t)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("CRDValidation", func(t *testing.T) {
+		invalid := &v1alpha1.DiagnosisTask{
+			ObjectMeta: metav1.ObjectMeta{Name: "invalid", Namespace: "default"},
+			Spec: v1alpha1.DiagnosisTaskSpec{
+				Target:        v1alpha1.Target{Name: "web-1"},
+				Agents:        []string{v1alpha1.AgentDiagnostician},
+				AutoRemediate: true,
+			},
+		}
+		if err := c.Create(ctx, invalid); err == nil || !strings.Contains(err.Error(), "remediator") {
+			t.Errorf("Expected the CRD to refuse autoRemediate without the remediator, got %v", err)
+		}
+	})
+
+	t.Run("ApproveByAnnotation", func(t *testing.T) {
+		task := &v1alpha1.DiagnosisTask{
+			ObjectMeta: metav1.ObjectMeta{Name: "web-1", Namespace: "default"},
+			Spec: v1alpha1.DiagnosisTaskSpec{
+				Target:           v1alpha1.Target{Name: "web-1"},
+				AutoRemediate:    true,
+				ApprovalRequired: true,
+			},
+		}
+		if err := c.Create(ctx, task); err != nil {
+			t.Fatal(err)
+		}
+		key := client.ObjectKeyFromObject(task)
+		task = waitForPhase(t, c, key, v1alpha1.PhaseAwaitingApproval)
+
+		task.Spec.AutoRemediate = false
+		if err := c.Update(ctx, task); err == nil || !strings.Contains(err.Error(), "immutable") {
+			t.Errorf("Expected the spec to be immutable, got %v", err)
+		}
+
+		// The operator writes status concurrently; retry on conflicts.
+		err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+			task := waitForPhase(t, c, key, v1alpha1.PhaseAwaitingApproval)
+			task.Annotations = map[string]string{v1alpha1.AnnotationApprove: task.Status.PendingApproval.ID}
+			return c.Update(ctx, task)
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		task = waitForPhase(t, c, key, v1alpha1.PhaseCompleted)
+		if task.Status.RootCause == "" || len(task.Status.Tasks) != 2 || task.Status.Tasks[1].Verification != "passed" {
+			t.Errorf("Expected the report in status, got %+v", task.Status)
+		}
+	})
+}
+
+func waitForPhase(t *testing.T, c client.Client, key types.NamespacedName, want v1alpha1.Phase) *v1alpha1.DiagnosisTask {
+	t.Helper()
+	deadline := time.Now().Add(30 * time.Second)
+	for {
+		var task v1alpha1.DiagnosisTask
+		if err := c.Get(context.Background(), key, &task); err == nil && task.Status.Phase == want {
+			return &task
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for %s to reach %s; last phase %q", key, want, task.Status.Phase)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
@@ -0,0 +1,226 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Agents a DiagnosisTask may enlist.
+const (
+	AgentDiagnostician = "diagnostician"
+	AgentRemediator    = "remediator"
+)
+
+// Annotations that decide the approval in status.pendingApproval. The
+// value must be that approval's ID, so an annotation left over from an
+// earlier approval cannot answer a later one:
+//
+//	kubectl annotate diagnosistask web-1 kubeagent.io/approve=<id> --overwrite
+const (
+	AnnotationApprove = "kubeagent.io/approve"
+	AnnotationReject  = "kubeagent.io/reject"
+	// AnnotationApprover names who decided, for the audit log.
+	AnnotationApprover = "kubeagent.io/approver"
+	// AnnotationReason is recorded with the decision.
+	AnnotationReason = "kubeagent.io/reason"
+)
+
+// Condition types set on status.conditions.
+const (
+	// ConditionPlanned is true once the coordinator produced a plan.
+	ConditionPlanned = "Planned"
+	// ConditionApproved tracks the plan approval of tasks with
+	// approvalRequired.
+	ConditionApproved = "Approved"
+	// ConditionVerified is true when every remediation passed its
+	// post-change verification, false when one failed.
+	ConditionVerified = "Verified"
+	// ConditionCompleted is true when the request succeeded and false
+	// once it failed or was rejected.
+	ConditionCompleted = "Completed"
+)
+
+// Phase is where a DiagnosisTask stands.
+// +kubebuilder:validation:Enum=Pending;Planning;AwaitingApproval;Diagnosing;Remediating;Completed;Failed;Rejected
+type Phase string
+
+const (
+	PhasePending          Phase = "Pending"
+	PhasePlanning         Phase = "Planning"
+	PhaseAwaitingApproval Phase = "AwaitingApproval"
+	PhaseDiagnosing       Phase = "Diagnosing"
+	PhaseRemediating      Phase = "Remediating"
+	PhaseCompleted        Phase = "Completed"
+	PhaseFailed           Phase = "Failed"
+	PhaseRejected         Phase = "Rejected"
+)
+
+// Finished reports whether the phase is final.
+func (p Phase) Finished() bool {
+	return p == PhaseCompleted || p == PhaseFailed || p == PhaseRejected
+}
+
+// Target is the workload to diagnose.
+type Target struct {
+	// Kind of the workload. Only Pod is diagnosed directly today.
+	// +kubebuilder:default=Pod
+	// +optional
+	Kind string `json:"kind,omitempty"`
+
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+
+	// Namespace defaults to the DiagnosisTask's own.
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// DiagnosisTaskSpec is what to diagnose and how far the agents may go.
+// It cannot change once created: the request it started would no
+// longer match.
+// +kubebuilder:validation:XValidation:rule="!self.autoRemediate || !has(self.agents) || 'remediator' in self.agents",message="autoRemediate needs the remediator agent"
+type DiagnosisTaskSpec struct {
+	Target Target `json:"target"`
+
+	// Agents that may work on the task. Defaults to the diagnostician,
+	// plus the remediator with autoRemediate. Without the remediator,
+	// fixes the planner adds anyway are dropped from the plan.
+	// +optional
+	// +kubebuilder:validation:items:Enum=diagnostician;remediator
+	Agents []string `json:"agents,omitempty"`
+
+	// AutoRemediate lets the remediator fix what the diagnosis finds.
+	// +optional
+	AutoRemediate bool `json:"autoRemediate,omitempty"`
+
+	// ApprovalRequired holds the plan, and every change the remediator
+	// asks to confirm, until an annotation approves it. Without it the
+	// operator approves the plan, and the changes the operator's
+	// --auto-approve risk allows; the rest still wait for an annotation.
+	// +optional
+	ApprovalRequired bool `json:"approvalRequired,omitempty"`
+
+	// Description adds symptoms or context to the request.
+	// +optional
+	Description string `json:"description,omitempty"`
+}
+
+// ApprovalStatus is an approval waiting for a decision.
+type ApprovalStatus struct {
+	ID string `json:"id"`
+	// Kind is "plan" or "action".
+	Kind string `json:"kind"`
+	// +optional
+	Risk string `json:"risk,omitempty"`
+	// +optional
+	Prompt string `json:"prompt,omitempty"`
+}
+
+// TaskStatus is one task of the plan.
+type TaskStatus struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+	// +optional
+	Agent string `json:"agent,omitempty"`
+	// +optional
+	Status string `json:"status,omitempty"`
+	// +optional
+	Error string `json:"error,omitempty"`
+	// Verification is the post-change check of a remediation: passed,
+	// failed or inconclusive.
+	// +optional
+	Verification string `json:"verification,omitempty"`
+	// +optional
+	VerificationSummary string `json:"verificationSummary,omitempty"`
+}
+
+// DiagnosisTaskStatus is what the operator observed.
+type DiagnosisTaskStatus struct {
+	// +optional
+	Phase Phase `json:"phase,omitempty"`
+
+	// RequestID is the coordinator request working on the task.
+	// +optional
+	RequestID string `json:"requestID,omitempty"`
+	// +optional
+	PlanID string `json:"planID,omitempty"`
+
+	// +optional
+	RootCause string `json:"rootCause,omitempty"`
+	// RemediationPlan lists the planned remediations and, once they
+	// ran, the actions they took.
+	// +optional
+	RemediationPlan string `json:"remediationPlan,omitempty"`
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// PendingApproval is the decision the task waits on, if any.
+	// +optional
+	PendingApproval *ApprovalStatus `json:"pendingApproval,omitempty"`
+
+	// +optional
+	Tasks []TaskStatus `json:"tasks,omitempty"`
+
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// +optional
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+}
+
+// DiagnosisTask asks the agents to diagnose, and optionally fix, one
+// workload.
+//
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:shortName=dt
+// +kubebuilder:printcolumn:name="Target",type=string,JSONPath=`.spec.target.name`
+// +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="Approval",type=string,JSONPath=`.status.pendingApproval.id`,priority=1
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+type DiagnosisTask struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="spec is immutable"
+	Spec   DiagnosisTaskSpec   `json:"spec"`
+	Status DiagnosisTaskStatus `json:"status,omitempty"`
+}
+
+// DiagnosisTaskList is a list of DiagnosisTasks.
+//
+// +kubebuilder:object:root=true
+type DiagnosisTaskList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []DiagnosisTask `json:"items"`
+}
+
+// TargetNamespace is the namespace of the target workload.
+func (t *DiagnosisTask) TargetNamespace() string {
+	if t.Spec.Target.Namespace != "" {
+		return t.Spec.Target.Namespace
+	}
+	return t.Namespace
+}
+
+// HasAgent reports whether the task may use agent, applying the
+// defaults of spec.agents.
+func (t *DiagnosisTask) HasAgent(agent string) bool {
+	if len(t.Spec.Agents) == 0 {
+		return agent == AgentDiagnostician || (agent == AgentRemediator && t.Spec.AutoRemediate)
+	}
+	for _, a := range t.Spec.Agents {
+		if a == agent {
+			return true
+		}
+	}
+	return false
+}
+
+func init() {
+	SchemeBuilder.Register(&DiagnosisTask{}, &DiagnosisTaskList{})
+}
@@ -0,0 +1,22 @@
+// Package v1alpha1 is the kubeagent.io/v1alpha1 API: the DiagnosisTask
+// custom resource the operator turns into coordinator requests.
+//
+// +kubebuilder:object:generate=true
+// +groupName=kubeagent.io
+package v1alpha1
+
+import (
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/scheme"
+)
+
+var (
+	// GroupVersion is the group and version of every type here.
+	GroupVersion = schema.GroupVersion{Group: "kubeagent.io", Version: "v1alpha1"}
+
+	// SchemeBuilder registers the types with a runtime.Scheme.
+	SchemeBuilder = &scheme.Builder{GroupVersion: GroupVersion}
+
+	// AddToScheme adds the types to a scheme.
+	AddToScheme = SchemeBuilder.AddToScheme
+)
@@ -0,0 +1,193 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+
+	"kubeagent/pkg/agent/harness"
+	"kubeagent/pkg/k8s"
+)
+
+// ApplyTool submits a full resource YAML with server-side apply. Unlike
+// CreateTool it works whether or not the object exists, and only the
+// fields in the YAML change owner — fields set by other managers (an
+// HPA's replicas, a mesh sidecar) are left alone unless force is set.
+//
+// Harness and preview wiring mirror CreateTool; the preflight verb is
+// "apply".
+type ApplyTool struct {
+	client       *k8s.Client
+	preflight    *harness.PreflightChain
+	auditor      harness.AuditLogger
+	previews     *ChangePreviews
+	snapshots    SnapshotRecorder
+	fieldManager string
+}
+
+func NewApplyTool(client *k8s.Client) *ApplyTool {
+	return &ApplyTool{
+		client:       client,
+		previews:     NewChangePreviews(),
+		fieldManager: k8s.DefaultFieldManager,
+	}
+}
+
+// WithFieldManager overrides the field manager recorded on applied
+// fields. Empty keeps k8s.DefaultFieldManager.
+func (a *ApplyTool) WithFieldManager(name string) *ApplyTool {
+	if name != "" {
+		a.fieldManager = name
+	}
+	return a
+}
+
+// WithChangePreviews shares a preview board, normally the one passed to
+// HumanTool.WithChangePreviews. Nil keeps the tool's private board.
+func (a *ApplyTool) WithChangePreviews(p *ChangePreviews) *ApplyTool {
+	if p != nil {
+		a.previews = p
+	}
+	return a
+}
+
+// WithPreflight attaches a Guide chain. Nil is tolerated (no-op).
+func (a *ApplyTool) WithPreflight(chain *harness.PreflightChain) *ApplyTool {
+	a.preflight = chain
+	return a
+}
+
+// WithAuditor attaches an audit sink for preflight events. Nil is tolerated.
+func (a *ApplyTool) WithAuditor(auditor harness.AuditLogger) *ApplyTool {
+	a.auditor = auditor
+	return a
+}
+
+// WithSnapshots attaches a rollback snapshot recorder. Nil is tolerated.
+func (a *ApplyTool) WithSnapshots(s SnapshotRecorder) *ApplyTool {
+	a.snapshots = s
+	return a
+}
+
+func (a *ApplyTool) Name() string {
+	return "ApplyTool"
+}
+
+func (a *ApplyTool) Description() string {
+	return "用于以服务端 apply 方式提交完整的资源 YAML（资源不存在则创建，存在则按字段合并更新）。必须先以 dry_run=true 调用查看差异，经 HumanTool 确认后再以相同 YAML 正式执行"
+}
+
+func (a *ApplyTool) ArgsSchema() string {
+	return `{"type":"object","properties":{"yaml":{"type":"string","description":"要 apply 的 K8s 资源 YAML 内容，必须包含 metadata.name"},"force":{"type":"boolean","description":"为 true 时强制接管其他 field manager 拥有的字段，默认 false"},"dry_run":{"type":"boolean","description":"为 true 时只做服务端 dry-run 并返回差异，不会真正修改"}},"required":["yaml"]}`
+}
+
+func (a *ApplyTool) Execute(params map[string]any) (string, error) {
+	return a.ExecuteContext(context.Background(), params)
+}
+
+// ExecuteContext runs the write for the request and task ctx is scoped
+// to; its previews are only visible to that task's HumanTool.
+func (a *ApplyTool) ExecuteContext(ctx context.Context, params map[string]any) (string, error) {
+	yamlContent, ok := params["yaml"].(string)
+	if !ok || yamlContent == "" {
+		return "", fmt.Errorf("yaml is required")
+	}
+	force, _ := params["force"].(bool)
+	// Prefixed so an apply and a create of the same YAML are separate
+	// changes: their diffs differ when the object already exists.
+	key := "apply:" + previewKey(yamlContent)
+	if force {
+		key += ":force"
+	}
+
+	if dryRun, _ := params["dry_run"].(bool); dryRun {
+		result, err := a.preview(ctx, key, yamlContent, force)
+		if err != nil {
+			return "", err
+		}
+		return result.Render() + "\nDry-run only, nothing was changed. Get approval via HumanTool, then call ApplyTool again with the same yaml and dry_run=false.", nil
+	}
+
+	// Preview before preflight, as in CreateTool.
+	previewed, ready, found := a.previews.lookup(ctx, key)
+	if !found {
+		result, err := a.preview(ctx, key, yamlContent, force)
+		if err != nil {
+			return "", err
+		}
+		return "", fmt.Errorf("apply requires a reviewed dry-run first; the diff is:\n%s\nGet approval via HumanTool, then call ApplyTool again with the same yaml", result.Render())
+	}
+	if !ready {
+		return "", fmt.Errorf("apply of %s %s/%s has not been approved: call HumanTool so the operator can review the dry-run diff, then retry",
+			previewed.Kind, previewed.Namespace, previewed.Name)
+	}
+
+	// Same peek-then-guard as CreateTool: decode failures fall through
+	// to the real call so the LLM sees the decoder's error.
+	if a.preflight != nil {
+		if kind, name, ns, peekErr := peekResource(yamlContent); peekErr == nil {
+			req := harness.PreflightRequest{
+				Verb:         "apply",
+				ResourceKind: kind,
+				ResourceName: name,
+				Namespace:    ns,
+				Metadata:     preflightMetadata(previewed, "yaml", yamlContent),
+			}
+			res := a.preflight.Run(ctx, req)
+			a.recordPreflight(ctx, req, res, previewed)
+			if res.Decision == harness.PreflightBlock {
+				return "", fmt.Errorf("apply blocked by preflight: %s", res.Reason)
+			}
+		}
+	}
+
+	if kind, name, ns, peekErr := peekResource(yamlContent); peekErr == nil {
+		if err := captureSnapshot(ctx, a.snapshots, "ApplyTool", kind, name, ns); err != nil {
+			return "", err
+		}
+	}
+
+	out, err := a.client.Apply(yamlContent, a.fieldManager, force)
+	if err == nil {
+		a.previews.consume(ctx, key)
+	}
+	return out, err
+}
+
+func (a *ApplyTool) preview(ctx context.Context, key, yamlContent string, force bool) (*k8s.DryRunResult, error) {
+	result, err := a.client.DryRunApply(yamlContent, a.fieldManager, force)
+	if err != nil {
+		return nil, fmt.Errorf("dry-run failed: %w", err)
+	}
+	a.previews.record(ctx, key, result)
+	auditPreview(ctx, a.auditor, "ApplyTool", "dry_run_apply", result)
+	return result, nil
+}
+
+// recordPreflight emits an audit event; see CreateTool.recordPreflight.
+func (a *ApplyTool) recordPreflight(ctx context.Context, req harness.PreflightRequest, res *harness.PreflightResult, previewed *k8s.DryRunResult) {
+	if a.auditor == nil || res == nil {
+		return
+	}
+	details := map[string]interface{}{}
+	if previewed != nil {
+		details = diffDetails(previewed)
+	}
+	if len(res.Warnings) > 0 {
+		details["warnings"] = res.Warnings
+	}
+	details["field_manager"] = a.fieldManager
+	_ = a.auditor.Record(ctx, harness.AuditEvent{
+		Kind:      harness.AuditPreflight,
+		RequestID: requestID(ctx),
+		Actor:     "ApplyTool",
+		Action:    "apply",
+		Outcome:   string(res.Decision),
+		Reason:    res.Reason,
+		Target: harness.AuditTarget{
+			Kind:      req.ResourceKind,
+			Name:      req.ResourceName,
+			Namespace: req.Namespace,
+		},
+		Details: details,
+	})
+}
@@ -0,0 +1,57 @@
+package tools
+
+import (
+	"strings"
+	"testing"
+
+	"kubeagent/pkg/k8s"
+)
+
+const webDeploymentYAML = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  namespace: demo
+spec:
+  replicas: 1
+  selector:
+    matchLabels: {app: web}
+  template:
+    metadata:
+      labels: {app: web}
+    spec:
+      containers:
+      - name: web
+        image: example.com/web:1.4.3
+`
+
+// TestApplyTool_RequiresDryRun checks ApplyTool shares the mandatory
+// preview, that force is part of the previewed change, and that an
+// apply of a live object's owned fields conflicts until forced.
+func TestApplyTool_RequiresDryRun(t *testing.T) {
+	client := k8s.NewFakeClient(newWebDeployment())
+	tool := NewApplyTool(client)
+
+	_, err := tool.Execute(map[string]any{"yaml": webDeploymentYAML})
+	if err == nil || !strings.Contains(err.Error(), "dry-run failed") || !strings.Contains(err.Error(), "conflict") {
+		t.Fatalf("Expected conflict from the implicit dry-run, got %v", err)
+	}
+
+	out, err := tool.Execute(map[string]any{"yaml": webDeploymentYAML, "force": true, "dry_run": true})
+	if err != nil {
+		t.Fatalf("Forced dry-run failed: %v", err)
+	}
+	if !strings.Contains(out, `"example.com/web:1.4.3"`) {
+		t.Errorf("Expected image change in diff, got %q", out)
+	}
+
+	// The preview was for a forced apply; an unforced one is a
+	// different change and needs its own dry-run.
+	if _, err := tool.Execute(map[string]any{"yaml": webDeploymentYAML}); err == nil {
+		t.Fatal("Unforced apply should not reuse the forced preview")
+	}
+
+	if out, err := tool.Execute(map[string]any{"yaml": webDeploymentYAML, "force": true}); err != nil || !strings.Contains(out, "field manager kubeagent") {
+		t.Fatalf("Forced apply failed: %q (err %v)", out, err)
+	}
+}
@@ -0,0 +1,189 @@
+package tools
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	yamlutil "k8s.io/apimachinery/pkg/runtime/serializer/yaml"
+
+	"kubeagent/pkg/agent/harness"
+	"kubeagent/pkg/k8s"
+)
+
+// ChangePreviews is the hand-off between write tools and HumanTool.
+//
+// A write tool stores the dry-run diff of each change it is about to
+// make; HumanTool shows every not-yet-approved diff above its prompt
+// and records the operator's answer. The write tool then refuses to
+// apply a change whose diff was never approved, so "the human saw
+// exactly this change" is enforced by code rather than by the prompt.
+//
+// Previews are keyed by the normalised object (see previewKey): the LLM
+// may re-indent the YAML between the dry-run and the real call, and
+// that should not count as a different change.
+//
+// One board serves every Remediator task, and under serve or operator
+// several requests at once, so entries are also scoped to the request
+// and task of the calling context (harness.RequestScope). HumanTool
+// only shows, and its answer only settles, the caller's own diffs.
+type ChangePreviews struct {
+	mu              sync.Mutex
+	entries         map[string]*changePreview
+	order           []string
+	requireApproval bool
+}
+
+type changePreview struct {
+	scope    string
+	result   *k8s.DryRunResult
+	approved bool
+}
+
+// NewChangePreviews creates an empty preview board. Until a HumanTool
+// is attached via HumanTool.WithChangePreviews, previews are still
+// mandatory but need no approval.
+func NewChangePreviews() *ChangePreviews {
+	return &ChangePreviews{entries: make(map[string]*changePreview)}
+}
+
+// record stores (or replaces) the preview for key in ctx's scope.
+// Replacing resets approval: a new dry-run may show a different diff.
+func (p *ChangePreviews) record(ctx context.Context, key string, result *k8s.DryRunResult) {
+	scope := previewScope(ctx)
+	key = scope + key
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, ok := p.entries[key]; !ok {
+		p.order = append(p.order, key)
+	}
+	p.entries[key] = &changePreview{scope: scope, result: result}
+}
+
+// lookup returns the preview for key in ctx's scope and whether it may
+// be applied.
+func (p *ChangePreviews) lookup(ctx context.Context, key string) (result *k8s.DryRunResult, ready bool, found bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	entry, ok := p.entries[previewScope(ctx)+key]
+	if !ok {
+		return nil, false, false
+	}
+	return entry.result, entry.approved || !p.requireApproval, true
+}
+
+// consume drops a preview once its change has been applied, so the
+// same approval cannot be replayed for a second write.
+func (p *ChangePreviews) consume(ctx context.Context, key string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.remove(previewScope(ctx) + key)
+}
+
+// pending returns the keys and diffs of ctx's scope still awaiting
+// approval, oldest first. The keys are only meant for resolve.
+func (p *ChangePreviews) pending(ctx context.Context) ([]string, []*k8s.DryRunResult) {
+	scope := previewScope(ctx)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var keys []string
+	var results []*k8s.DryRunResult
+	for _, key := range p.order {
+		if entry := p.entries[key]; entry.scope == scope && !entry.approved {
+			keys = append(keys, key)
+			results = append(results, entry.result)
+		}
+	}
+	return keys, results
+}
+
+// resolve applies the operator's answer to the previews they were
+// shown. A rejection discards them, forcing a fresh dry-run before any
+// retry.
+func (p *ChangePreviews) resolve(keys []string, approved bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, key := range keys {
+		if approved {
+			if entry, ok := p.entries[key]; ok {
+				entry.approved = true
+			}
+		} else {
+			p.remove(key)
+		}
+	}
+}
+
+func (p *ChangePreviews) enableApproval() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.requireApproval = true
+}
+
+// remove must be called with p.mu held.
+func (p *ChangePreviews) remove(key string) {
+	delete(p.entries, key)
+	for i, k := range p.order {
+		if k == key {
+			p.order = append(p.order[:i], p.order[i+1:]...)
+			break
+		}
+	}
+}
+
+// previewScope prefixes board keys with the request and task ctx is
+// scoped to. Unscoped calls (tests, direct tool use) share one scope.
+func previewScope(ctx context.Context) string {
+	requestID, taskID := harness.RequestScope(ctx)
+	return requestID + "/" + taskID + "/"
+}
+
+// requestID is the request ctx is scoped to. Every audit event a tool
+// records carries it, so the per-request feeds (the API's event stream,
+// the final report's audit section) show the tool's previews, preflight
+// verdicts and writes.
+func requestID(ctx context.Context) string {
+	id, _ := harness.RequestScope(ctx)
+	return id
+}
+
+// previewKey identifies a change by its decoded object, so formatting
+// differences in the YAML do not matter. Undecodable input falls back
+// to the raw text; it will fail the real call anyway.
+func previewKey(yamlContent string) string {
+	data := []byte(yamlContent)
+	obj := &unstructured.Unstructured{}
+	dec := yamlutil.NewDecodingSerializer(unstructured.UnstructuredJSONScheme)
+	if _, _, err := dec.Decode(data, nil, obj); err == nil {
+		if normalised, err := json.Marshal(obj.Object); err == nil {
+			data = normalised
+		}
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// auditPreview records a dry-run as a preflight event carrying the diff,
+// so the audit trail shows what each write tool proposed even when the
+// change is later rejected. Nil auditor is a no-op.
+func auditPreview(ctx context.Context, auditor harness.AuditLogger, actor, action string, result *k8s.DryRunResult) {
+	if auditor == nil {
+		return
+	}
+	_ = auditor.Record(ctx, harness.AuditEvent{
+		Kind:      harness.AuditPreflight,
+		RequestID: requestID(ctx),
+		Actor:     actor,
+		Action:    action,
+		Outcome:   "previewed",
+		Target: harness.AuditTarget{
+			Kind:      result.Kind,
+			Name:      result.Name,
+			Namespace: result.Namespace,
+		},
+		Details: diffDetails(result),
+	})
+}
@@ -17,14 +17,31 @@ import (
 // Harness wiring (optional): see DeleteTool for rationale. CreateTool
 // performs a lightweight YAML peek before calling the chain so the
 // Guide can see kind/name/namespace without the full RESTMapping.
+//
+// Every create is previewed first. Calling with dry_run=true runs a
+// server-side dry-run and returns the diff against the live object; a
+// real create whose object was never dry-run is refused with that diff
+// attached, so the LLM cannot skip the step. When the ChangePreviews
+// board is shared with HumanTool, the diff must also have been approved.
 type CreateTool struct {
 	client    *k8s.Client
 	preflight *harness.PreflightChain
 	auditor   harness.AuditLogger
+	previews  *ChangePreviews
+	snapshots SnapshotRecorder
 }
 
 func NewCreateTool(client *k8s.Client) *CreateTool {
-	return &CreateTool{client: client}
+	return &CreateTool{client: client, previews: NewChangePreviews()}
+}
+
+// WithChangePreviews shares a preview board, normally the one passed to
+// HumanTool.WithChangePreviews. Nil keeps the tool's private board.
+func (c *CreateTool) WithChangePreviews(p *ChangePreviews) *CreateTool {
+	if p != nil {
+		c.previews = p
+	}
+	return c
 }
 
 // WithPreflight attaches a Guide chain. Nil is tolerated (no-op).
@@ -39,23 +56,65 @@ func (c *CreateTool) WithAuditor(a harness.AuditLogger) *CreateTool {
 	return c
 }
 
+// WithSnapshots attaches a rollback snapshot recorder. Nil is tolerated.
+// A create snapshots the object's absence, so rollback deletes it.
+func (c *CreateTool) WithSnapshots(s SnapshotRecorder) *CreateTool {
+	c.snapshots = s
+	return c
+}
+
 func (c *CreateTool) Name() string {
 	return "CreateTool"
 }
 
 func (c *CreateTool) Description() string {
-	return "用于在 Kubernetes 集群中创建资源（Pod、Service、Deployment 等），需要提供资源 YAML 内容"
+	return "用于在 Kubernetes 集群中创建资源（Pod、Service、Deployment 等），需要提供资源 YAML 内容。必须先以 dry_run=true 调用查看与线上对象的差异，经 HumanTool 确认后再以相同 YAML 正式创建"
 }
 
 func (c *CreateTool) ArgsSchema() string {
-	return `{"type":"object","properties":{"yaml":{"type":"string","description":"要创建的 K8s 资源的 YAML 内容"}},"required":["yaml"]}`
+	return `{"type":"object","properties":{"yaml":{"type":"string","description":"要创建的 K8s 资源的 YAML 内容"},"dry_run":{"type":"boolean","description":"为 true 时只做服务端 dry-run 并返回与线上对象的差异，不会真正创建"}},"required":["yaml"]}`
 }
 
 func (c *CreateTool) Execute(params map[string]any) (string, error) {
+	return c.ExecuteContext(context.Background(), params)
+}
+
+// ExecuteContext runs the write for the request and task ctx is scoped
+// to; its previews are only visible to that task's HumanTool.
+func (c *CreateTool) ExecuteContext(ctx context.Context, params map[string]any) (string, error) {
 	yamlContent, ok := params["yaml"].(string)
 	if !ok || yamlContent == "" {
 		return "", fmt.Errorf("yaml is required")
 	}
+	key := previewKey(yamlContent)
+
+	// A dry-run mutates nothing, so it bypasses the preflight chain:
+	// ResourceExistsCheck would block exactly the "object already
+	// exists" case whose diff the operator most needs to see.
+	if dryRun, _ := params["dry_run"].(bool); dryRun {
+		result, err := c.preview(ctx, key, yamlContent)
+		if err != nil {
+			return "", err
+		}
+		return result.Render() + "\nDry-run only, nothing was changed. Get approval via HumanTool, then call CreateTool again with the same yaml and dry_run=false.", nil
+	}
+
+	// Mandatory preview: no dry-run on record means run one now and
+	// hand the diff back instead of creating. This comes before
+	// preflight so a create that is refused anyway does not cost a
+	// consensus vote.
+	previewed, ready, found := c.previews.lookup(ctx, key)
+	if !found {
+		result, err := c.preview(ctx, key, yamlContent)
+		if err != nil {
+			return "", err
+		}
+		return "", fmt.Errorf("create requires a reviewed dry-run first; the diff is:\n%s\nGet approval via HumanTool, then call CreateTool again with the same yaml", result.Render())
+	}
+	if !ready {
+		return "", fmt.Errorf("create of %s %s/%s has not been approved: call HumanTool so the operator can review the dry-run diff, then retry",
+			previewed.Kind, previewed.Namespace, previewed.Name)
+	}
 
 	// Preflight guard. Peek at the YAML first so protected-namespace
 	// and other namespace-scoped checks can fire. A decode failure here
@@ -70,16 +129,64 @@ func (c *CreateTool) Execute(params map[string]any) (string, error) {
 				ResourceKind: kind,
 				ResourceName: name,
 				Namespace:    ns,
+				Metadata:     preflightMetadata(previewed, "yaml", yamlContent),
 			}
-			res := c.preflight.Run(context.TODO(), req)
-			c.recordPreflight(req, res)
+			res := c.preflight.Run(ctx, req)
+			c.recordPreflight(ctx, req, res, previewed)
 			if res.Decision == harness.PreflightBlock {
 				return "", fmt.Errorf("create blocked by preflight: %s", res.Reason)
 			}
 		}
 	}
 
-	return c.client.CreateResource(yamlContent)
+	if kind, name, ns, peekErr := peekResource(yamlContent); peekErr == nil {
+		if err := captureSnapshot(ctx, c.snapshots, "CreateTool", kind, name, ns); err != nil {
+			return "", err
+		}
+	}
+
+	out, err := c.client.CreateResource(yamlContent)
+	if err == nil {
+		c.previews.consume(ctx, key)
+	}
+	return out, err
+}
+
+// preview runs the server-side dry-run, stores the result on the
+// preview board and audits it with the diff in the event details.
+func (c *CreateTool) preview(ctx context.Context, key, yamlContent string) (*k8s.DryRunResult, error) {
+	result, err := c.client.DryRunCreate(yamlContent)
+	if err != nil {
+		return nil, fmt.Errorf("dry-run failed: %w", err)
+	}
+	c.previews.record(ctx, key, result)
+	auditPreview(ctx, c.auditor, "CreateTool", "dry_run_create", result)
+	return result, nil
+}
+
+// diffDetails is the audit payload for a preview: the rendered diff for
+// humans reading the log and the structured changes for tooling.
+func diffDetails(result *k8s.DryRunResult) map[string]interface{} {
+	details := map[string]interface{}{
+		"diff":        result.Render(),
+		"changes":     result.Changes,
+		"live_exists": result.LiveExists,
+	}
+	if len(result.Warnings) > 0 {
+		details["dry_run_warnings"] = result.Warnings
+	}
+	return details
+}
+
+// preflightMetadata tells checks what the write would change: the raw
+// change under key, plus the reviewed diff when a dry-run is on record.
+// Consensus reviewers judge the action from it.
+func preflightMetadata(previewed *k8s.DryRunResult, key, change string) map[string]interface{} {
+	metadata := map[string]interface{}{key: change}
+	if previewed != nil {
+		metadata["diff"] = previewed.Render()
+	}
+	return metadata
 }
 
 // peekResource decodes just enough of the YAML to extract (kind, name,
@@ -103,20 +210,27 @@ func peekResource(yamlContent string) (kind, name, namespace string, err error)
 // rationale; this is intentionally a near-duplicate (the two write
 // tools don't yet share a base because Tool is a tiny interface and
 // factoring would add ceremony without removing much code).
-func (c *CreateTool) recordPreflight(req harness.PreflightRequest, res *harness.PreflightResult) {
+//
+// previewed, when set, is the dry-run this create was checked against;
+// its diff goes into the event so the audit trail shows what changed.
+func (c *CreateTool) recordPreflight(ctx context.Context, req harness.PreflightRequest, res *harness.PreflightResult, previewed *k8s.DryRunResult) {
 	if c.auditor == nil || res == nil {
 		return
 	}
 	details := map[string]interface{}{}
+	if previewed != nil {
+		details = diffDetails(previewed)
+	}
 	if len(res.Warnings) > 0 {
 		details["warnings"] = res.Warnings
 	}
-	_ = c.auditor.Record(context.TODO(), harness.AuditEvent{
-		Kind:    harness.AuditPreflight,
-		Actor:   "CreateTool",
-		Action:  "create",
-		Outcome: string(res.Decision),
-		Reason:  res.Reason,
+	_ = c.auditor.Record(ctx, harness.AuditEvent{
+		Kind:      harness.AuditPreflight,
+		RequestID: requestID(ctx),
+		Actor:     "CreateTool",
+		Action:    "create",
+		Outcome:   string(res.Decision),
+		Reason:    res.Reason,
 		Target: harness.AuditTarget{
 			Kind:      req.ResourceKind,
 			Name:      req.ResourceName,
@@ -0,0 +1,195 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"kubeagent/pkg/agent/harness"
+	"kubeagent/pkg/k8s"
+)
+
+const newPodYAML = `apiVersion: v1
+kind: Pod
+metadata:
+  name: web-2
+  namespace: demo
+spec:
+  containers:
+  - name: web
+    image: example.com/web:1.4.3
+`
+
+// The same object as newPodYAML, formatted differently. Approval is
+// tied to the object, not to the LLM's whitespace.
+const newPodYAMLReformatted = `apiVersion: v1
+kind: Pod
+metadata: {name: web-2, namespace: demo}
+spec:
+  containers: [{name: web, image: "example.com/web:1.4.3"}]
+`
+
+// TestCreateTool_RequiresDryRun proves the preview step cannot be
+// skipped: the first real create is refused with the diff attached and
+// nothing reaches the cluster; the retry goes through.
+func TestCreateTool_RequiresDryRun(t *testing.T) {
+	client := k8s.NewFakeClient()
+	tool := NewCreateTool(client)
+
+	_, err := tool.Execute(map[string]any{"yaml": newPodYAML})
+	if err == nil || !strings.Contains(err.Error(), "dry-run") {
+		t.Fatalf("Expected dry-run required error, got %v", err)
+	}
+	if !strings.Contains(err.Error(), `+ spec:`) {
+		t.Errorf("Expected diff in refusal, got %v", err)
+	}
+	if state, _ := client.GetResourceState("pod", "web-2", "demo"); state.Exists {
+		t.Fatal("Create went through without a reviewed dry-run")
+	}
+
+	if _, err := tool.Execute(map[string]any{"yaml": newPodYAMLReformatted}); err != nil {
+		t.Fatalf("Create after dry-run failed: %v", err)
+	}
+	if state, _ := client.GetResourceState("pod", "web-2", "demo"); !state.Exists {
+		t.Error("Pod was not created")
+	}
+}
+
+// TestCreateTool_HumanApprovesDiff runs the full gate: dry_run shows
+// the diff, HumanTool prints that diff above its prompt, and only an
+// approved diff may be applied. The audit trail carries the diff on
+// both the preview and the real create.
+func TestCreateTool_HumanApprovesDiff(t *testing.T) {
+	client := k8s.NewFakeClient()
+	audit := &recordingAuditor{}
+	previews := NewChangePreviews()
+	var prompt bytes.Buffer
+	human := NewHumanTool().WithChangePreviews(previews).WithIO(strings.NewReader("yes\n"), &prompt)
+	tool := NewCreateTool(client).
+		WithChangePreviews(previews).
+		WithPreflight(harness.NewPreflightChain().Add(harness.NewProtectedNamespaceCheck("kube-system"))).
+		WithAuditor(audit)
+
+	out, err := tool.Execute(map[string]any{"yaml": newPodYAML, "dry_run": true})
+	if err != nil {
+		t.Fatalf("Dry-run failed: %v", err)
+	}
+	if !strings.Contains(out, "Pod demo/web-2") {
+		t.Errorf("Expected diff header in dry-run output, got %q", out)
+	}
+	if ev := audit.last(); ev.Action != "dry_run_create" || ev.Details["diff"] == nil {
+		t.Errorf("Expected dry-run audit event with diff, got %+v", ev)
+	}
+
+	if _, err := tool.Execute(map[string]any{"yaml": newPodYAML}); err == nil || !strings.Contains(err.Error(), "not been approved") {
+		t.Fatalf("Expected approval error, got %v", err)
+	}
+
+	answer, err := human.Execute(map[string]any{"prompt": "Create web-2?"})
+	if err != nil || answer != "approved" {
+		t.Fatalf("Expected approval, got %q (err %v)", answer, err)
+	}
+	if !strings.Contains(prompt.String(), "[Dry-run diff]") || !strings.Contains(prompt.String(), "example.com/web:1.4.3") {
+		t.Errorf("HumanTool did not show the diff:\n%s", prompt.String())
+	}
+
+	if _, err := tool.Execute(map[string]any{"yaml": newPodYAML}); err != nil {
+		t.Fatalf("Approved create failed: %v", err)
+	}
+	if ev := audit.last(); ev.Action != "create" || ev.Details["diff"] == nil {
+		t.Errorf("Expected create audit event with diff, got %+v", ev)
+	}
+
+	// Approval is single-use.
+	if _, _, found := previews.lookup(context.Background(), previewKey(newPodYAML)); found {
+		t.Error("Preview should be consumed after the create")
+	}
+}
+
+// TestHumanTool_RejectDiscardsPreview checks a rejected diff cannot be
+// applied later; the LLM has to produce a fresh dry-run.
+func TestHumanTool_RejectDiscardsPreview(t *testing.T) {
+	client := k8s.NewFakeClient()
+	previews := NewChangePreviews()
+	tool := NewCreateTool(client).WithChangePreviews(previews)
+	human := NewHumanTool().WithChangePreviews(previews).WithIO(strings.NewReader("no\n"), &bytes.Buffer{})
+
+	if _, err := tool.Execute(map[string]any{"yaml": newPodYAML, "dry_run": true}); err != nil {
+		t.Fatalf("Dry-run failed: %v", err)
+	}
+	if answer, _ := human.Execute(map[string]any{"prompt": "Create web-2?"}); answer != "rejected" {
+		t.Fatalf("Expected rejection, got %q", answer)
+	}
+	if _, err := tool.Execute(map[string]any{"yaml": newPodYAML}); err == nil || !strings.Contains(err.Error(), "requires a reviewed dry-run") {
+		t.Fatalf("Expected a fresh dry-run to be required, got %v", err)
+	}
+}
+
+// TestHumanTool_PolicyGradesRisk checks an unattended approver sees
+// what the diffs put at stake: creating a new object is low risk,
+// anything without a dry-run (a delete) is high and is rejected with
+// the policy's reason so the LLM can report it.
+func TestHumanTool_PolicyGradesRisk(t *testing.T) {
+	client := k8s.NewFakeClient()
+	previews := NewChangePreviews()
+	tool := NewCreateTool(client).WithChangePreviews(previews)
+	trail := harness.NewAuditTrail(0)
+	gate := harness.NewApprovalGate(harness.NewAutoApprover(harness.RiskLow)).WithAuditor(trail)
+	human := NewHumanTool().WithChangePreviews(previews).WithApprover(gate)
+	ctx := harness.WithRequestScope(context.Background(), "req-1", "task-1")
+
+	if _, err := tool.ExecuteContext(ctx, map[string]any{"yaml": newPodYAML, "dry_run": true}); err != nil {
+		t.Fatalf("Dry-run failed: %v", err)
+	}
+	if answer, _ := human.ExecuteContext(ctx, map[string]any{"prompt": "Create web-2?"}); answer != "approved" {
+		t.Fatalf("Expected a new object to be approved by policy, got %q", answer)
+	}
+	if _, err := tool.ExecuteContext(ctx, map[string]any{"yaml": newPodYAML}); err != nil {
+		t.Fatalf("Expected the approved create to go through, got %v", err)
+	}
+
+	answer, _ := human.ExecuteContext(ctx, map[string]any{"prompt": "Delete web-1?"})
+	if !strings.HasPrefix(answer, "rejected: ") || !strings.Contains(answer, "high risk") {
+		t.Errorf("Expected a policy rejection with its reason, got %q", answer)
+	}
+
+	events := trail.Since(time.Time{}, "req-1")
+	if len(events) != 2 || events[0].Kind != harness.AuditDecision || events[0].Target.Name != "web-2" || events[1].Outcome != "rejected" {
+		t.Errorf("Expected both decisions audited against the request, got %+v", events)
+	}
+	if events[0].Details["task_id"] != "task-1" {
+		t.Errorf("Expected the task from the request scope, got %+v", events[0].Details)
+	}
+}
+
+// TestHumanTool_ApprovesOnlyItsOwnTask checks that a shared preview
+// board keeps concurrent requests apart: approving one task's prompt
+// neither shows nor approves another task's diff.
+func TestHumanTool_ApprovesOnlyItsOwnTask(t *testing.T) {
+	client := k8s.NewFakeClient()
+	previews := NewChangePreviews()
+	tool := NewCreateTool(client).WithChangePreviews(previews)
+	var prompt bytes.Buffer
+	human := NewHumanTool().WithChangePreviews(previews).WithIO(strings.NewReader("yes\n"), &prompt)
+	mine := harness.WithRequestScope(context.Background(), "req-1", "task-1")
+	theirs := harness.WithRequestScope(context.Background(), "req-2", "task-1")
+
+	if _, err := tool.ExecuteContext(theirs, map[string]any{"yaml": newPodYAML, "dry_run": true}); err != nil {
+		t.Fatalf("Dry-run failed: %v", err)
+	}
+	answer, _ := human.ExecuteContext(mine, map[string]any{"prompt": "Go ahead?"})
+	if answer != "approved" {
+		t.Fatalf("Expected approval, got %q", answer)
+	}
+	if strings.Contains(prompt.String(), "example.com/web:1.4.3") {
+		t.Errorf("Another request's diff was shown:\n%s", prompt.String())
+	}
+	if _, err := tool.ExecuteContext(theirs, map[string]any{"yaml": newPodYAML}); err == nil || !strings.Contains(err.Error(), "not been approved") {
+		t.Errorf("Expected the other request's create to still need approval, got %v", err)
+	}
+	if _, err := tool.ExecuteContext(mine, map[string]any{"yaml": newPodYAML}); err == nil || !strings.Contains(err.Error(), "requires a reviewed dry-run") {
+		t.Errorf("Expected no preview in this task's scope, got %v", err)
+	}
+}
@@ -18,12 +18,15 @@ import (
 //     tool-use loop surfaces back as a tool_result the model can reason about.
 //   - WithAuditor installs an AuditLogger sink; a preflight event is emitted
 //     on every call regardless of decision, so the audit trail captures blocks.
+//   - WithSnapshots installs a SnapshotRecorder; the object is captured
+//     just before deletion so a failed remediation can re-create it.
 //
 // Both are optional — an unwired DeleteTool behaves exactly as before.
 type DeleteTool struct {
 	client    *k8s.Client
 	preflight *harness.PreflightChain
 	auditor   harness.AuditLogger
+	snapshots SnapshotRecorder
 }
 
 func NewDeleteTool(client *k8s.Client) *DeleteTool {
@@ -42,6 +45,12 @@ func (d *DeleteTool) WithAuditor(a harness.AuditLogger) *DeleteTool {
 	return d
 }
 
+// WithSnapshots attaches a rollback snapshot recorder. Nil is tolerated.
+func (d *DeleteTool) WithSnapshots(s SnapshotRecorder) *DeleteTool {
+	d.snapshots = s
+	return d
+}
+
 func (d *DeleteTool) Name() string {
 	return "DeleteTool"
 }
@@ -55,6 +64,12 @@ func (d *DeleteTool) ArgsSchema() string {
 }
 
 func (d *DeleteTool) Execute(params map[string]any) (string, error) {
+	return d.ExecuteContext(context.Background(), params)
+}
+
+// ExecuteContext deletes on behalf of the request and task ctx is
+// scoped to, which is where the rollback snapshot is filed.
+func (d *DeleteTool) ExecuteContext(ctx context.Context, params map[string]any) (string, error) {
 	resource, ok := params["resource"].(string)
 	if !ok || resource == "" {
 		return "", fmt.Errorf("resource is required")
@@ -80,22 +95,25 @@ func (d *DeleteTool) Execute(params map[string]any) (string, error) {
 			ResourceName: name,
 			Namespace:    namespace,
 		}
-		res := d.preflight.Run(context.TODO(), req)
+		res := d.preflight.Run(ctx, req)
 
-		d.recordPreflight(req, res)
+		d.recordPreflight(ctx, req, res)
 
 		if res.Decision == harness.PreflightBlock {
 			return "", fmt.Errorf("delete blocked by preflight: %s", res.Reason)
 		}
 	}
 
+	if err := captureSnapshot(ctx, d.snapshots, "DeleteTool", resource, name, namespace); err != nil {
+		return "", err
+	}
 	return d.client.DeleteResource(resource, name, namespace)
 }
 
 // recordPreflight emits an audit event for the guard decision. Errors
 // from the sink are swallowed — an audit failure must never break a
 // tool call. Nil auditor is a no-op.
-func (d *DeleteTool) recordPreflight(req harness.PreflightRequest, res *harness.PreflightResult) {
+func (d *DeleteTool) recordPreflight(ctx context.Context, req harness.PreflightRequest, res *harness.PreflightResult) {
 	if d.auditor == nil || res == nil {
 		return
 	}
@@ -104,12 +122,13 @@ func (d *DeleteTool) recordPreflight(req harness.PreflightRequest, res *harness.
 	if len(res.Warnings) > 0 {
 		details["warnings"] = res.Warnings
 	}
-	_ = d.auditor.Record(context.TODO(), harness.AuditEvent{
-		Kind:    harness.AuditPreflight,
-		Actor:   "DeleteTool",
-		Action:  "delete",
-		Outcome: outcome,
-		Reason:  res.Reason,
+	_ = d.auditor.Record(ctx, harness.AuditEvent{
+		Kind:      harness.AuditPreflight,
+		RequestID: requestID(ctx),
+		Actor:     "DeleteTool",
+		Action:    "delete",
+		Outcome:   outcome,
+		Reason:    res.Reason,
 		Target: harness.AuditTarget{
 			Kind:      req.ResourceKind,
 			Name:      req.ResourceName,
@@ -1,25 +1,66 @@
 package tools
 
 import (
-	"bufio"
+	"context"
 	"fmt"
+	"io"
 	"os"
-	"strings"
+	"sync"
+
+	"kubeagent/pkg/agent/harness"
+	"kubeagent/pkg/k8s"
 )
 
 // HumanTool asks a human for confirmation before performing dangerous operations.
-type HumanTool struct{}
+//
+// With WithChangePreviews, every dry-run diff the calling task still
+// has awaiting approval is sent along with the prompt, and the answer
+// approves (or discards) those exact diffs. The LLM-written prompt describes the intent; the diff is
+// what the operator actually signs off on.
+//
+// Who answers is up to the harness.Approver behind the tool: a terminal
+// for `fix` and `chat`, the HTTP approval queue for `serve`, a risk
+// policy for unattended runs. Without WithApprover it asks on
+// stdin/stdout.
+type HumanTool struct {
+	gate     *harness.ApprovalGate
+	once     sync.Once
+	previews *ChangePreviews
+}
 
 func NewHumanTool() *HumanTool {
 	return &HumanTool{}
 }
 
+// WithChangePreviews shares the preview board with the write tools and
+// makes approval of their diffs mandatory.
+func (h *HumanTool) WithChangePreviews(p *ChangePreviews) *HumanTool {
+	h.previews = p
+	if p != nil {
+		p.enableApproval()
+	}
+	return h
+}
+
+// WithApprover routes every confirmation through gate. Nil keeps the
+// default terminal approver.
+func (h *HumanTool) WithApprover(gate *harness.ApprovalGate) *HumanTool {
+	h.gate = gate
+	return h
+}
+
+// WithIO replaces stdin/stdout, for tests and terminal-like front ends.
+func (h *HumanTool) WithIO(in io.Reader, out io.Writer) *HumanTool {
+	h.gate = harness.NewApprovalGate(harness.NewTerminalApprover(harness.NewLineReader(in), out))
+	return h
+}
+
 func (h *HumanTool) Name() string {
 	return "HumanTool"
 }
 
 func (h *HumanTool) Description() string {
-	return "当需要执行不可逆的危险操作（如删除资源）时，先向用户寻求确认。用户输入 yes/y 表示确认，其他输入表示拒绝。"
+	return "当需要执行不可逆的危险操作（如删除资源）时，先向用户寻求确认。返回 approved 表示确认，rejected 表示拒绝（可能附带原因）。PatchTool / ApplyTool / CreateTool dry-run 产生的差异会一并展示给用户确认。"
 }
 
 func (h *HumanTool) ArgsSchema() string {
@@ -27,22 +68,75 @@ func (h *HumanTool) ArgsSchema() string {
 }
 
 func (h *HumanTool) Execute(params map[string]any) (string, error) {
+	return h.ExecuteContext(context.Background(), params)
+}
+
+// ExecuteContext asks for approval on behalf of the request and task
+// ctx is scoped to. It returns "approved", or "rejected" followed by
+// the reason when the approver gave one (a timeout, a policy, a
+// reviewer's note) so the LLM can tell the operator why.
+func (h *HumanTool) ExecuteContext(ctx context.Context, params map[string]any) (string, error) {
 	prompt, ok := params["prompt"].(string)
 	if !ok || prompt == "" {
 		return "", fmt.Errorf("prompt is required")
 	}
 
-	fmt.Printf("\n[Human Approval Required] %s\n请输入 yes/y 确认，其他内容取消: ", prompt)
-
-	reader := bufio.NewReader(os.Stdin)
-	input, err := reader.ReadString('\n')
-	if err != nil {
-		return "", fmt.Errorf("failed to read user input: %w", err)
+	var pendingKeys []string
+	var results []*k8s.DryRunResult
+	if h.previews != nil {
+		pendingKeys, results = h.previews.pending(ctx)
+	}
+	requestID, taskID := harness.RequestScope(ctx)
+	req := harness.ApprovalRequest{
+		Kind:      harness.ApprovalAction,
+		RequestID: requestID,
+		TaskID:    taskID,
+		Actor:     "HumanTool",
+		Prompt:    prompt,
+		Risk:      previewRisk(results),
+	}
+	for _, result := range results {
+		req.Diffs = append(req.Diffs, result.Render())
+	}
+	if len(results) == 1 {
+		req.Target = harness.AuditTarget{Kind: results[0].Kind, Name: results[0].Name, Namespace: results[0].Namespace}
 	}
 
-	input = strings.TrimSpace(strings.ToLower(input))
-	if input == "yes" || input == "y" {
+	decision := h.approvalGate().Decide(ctx, req)
+	if h.previews != nil {
+		h.previews.resolve(pendingKeys, decision.Approved)
+	}
+	switch {
+	case decision.Approved:
 		return "approved", nil
+	case decision.Reason != "":
+		return "rejected: " + decision.Reason, nil
 	}
 	return "rejected", nil
 }
+
+func (h *HumanTool) approvalGate() *harness.ApprovalGate {
+	h.once.Do(func() {
+		if h.gate == nil {
+			h.gate = harness.NewApprovalGate(harness.NewTerminalApprover(harness.NewLineReader(os.Stdin), os.Stdout))
+		}
+	})
+	return h.gate
+}
+
+// previewRisk grades a confirmation by the diffs behind it. Without a
+// diff the LLM is asking about something no dry-run covered, such as a
+// delete, so it is high. Changing live objects is medium; only creating
+// new ones is low. Previews with warnings count as medium at least.
+func previewRisk(results []*k8s.DryRunResult) harness.Risk {
+	if len(results) == 0 {
+		return harness.RiskHigh
+	}
+	risk := harness.RiskLow
+	for _, result := range results {
+		if result.LiveExists || len(result.Warnings) > 0 {
+			risk = harness.RiskMedium
+		}
+	}
+	return risk
+}
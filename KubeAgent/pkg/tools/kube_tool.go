@@ -15,7 +15,7 @@ import (
 // "not allowed", the LLM tends to retry the same command a few more
 // times before giving up, which burns tool-loop iterations. Naming
 // the replacement tool in the error body steers the LLM back to
-// CreateTool / DeleteTool in one shot.
+// PatchTool / ApplyTool / CreateTool / DeleteTool in one shot.
 var allowedKubeCommands = map[string]bool{
 	"get":          true,
 	"describe":     true,
@@ -30,17 +30,17 @@ var allowedKubeCommands = map[string]bool{
 // Mapped to a short hint so the LLM sees the right alternative in
 // the failure message.
 var kubeWriteRedirects = map[string]string{
-	"patch":    "use DeleteTool to let the controller recreate the resource, or CreateTool to submit a replacement YAML",
-	"apply":    "use CreateTool with the full YAML",
+	"patch":    "use PatchTool with a strategic, merge or json patch",
+	"apply":    "use ApplyTool with the full YAML (server-side apply)",
 	"create":   "use CreateTool with the full YAML",
-	"edit":     "use CreateTool to submit the new YAML (after HumanTool approval)",
+	"edit":     "use PatchTool for a targeted change, or ApplyTool with the full YAML",
 	"delete":   "use DeleteTool (after HumanTool approval)",
-	"scale":    "use CreateTool to submit a patched Deployment YAML",
+	"scale":    "use PatchTool, e.g. {\"spec\":{\"replicas\":3}} on the Deployment",
 	"rollout":  "use DeleteTool on the target Pod to trigger a controller-managed restart",
-	"label":    "use CreateTool to submit the updated YAML",
-	"annotate": "use CreateTool to submit the updated YAML",
-	"replace":  "use CreateTool with the full YAML",
-	"set":      "use CreateTool with the full updated YAML",
+	"label":    "use PatchTool with a metadata.labels patch",
+	"annotate": "use PatchTool with a metadata.annotations patch",
+	"replace":  "use ApplyTool with the full YAML",
+	"set":      "use PatchTool to change the field in place (e.g. a container image)",
 }
 
 type KubeTool struct{}
@@ -61,14 +61,14 @@ func (k *KubeTool) Description() string {
 	// correct write tool to use for mutations.
 	return "只读的 kubectl 命令执行器。仅允许：get / describe / logs / top / explain / version / cluster-info。" +
 		"禁止写操作（patch / apply / edit / delete / create / scale / rollout / label / annotate / replace / set）——" +
-		"删除资源请使用 DeleteTool，创建或修改资源请使用 CreateTool（需先通过 HumanTool 获得确认）。"
+		"删除资源请使用 DeleteTool，原地修改字段请使用 PatchTool，提交完整 YAML 请使用 ApplyTool 或 CreateTool（需先通过 HumanTool 获得确认）。"
 }
 
 func (k *KubeTool) ArgsSchema() string {
 	// The schema's description mirrors the redirect hint so a model
 	// that reads the schema but skims the tool description still sees
 	// it.
-	return `{"type":"object","properties":{"command":{"type":"string","description":"要运行的只读 kubectl 命令，例如 'kubectl get pods -n default'、'kubectl describe pod foo'。写操作（patch/apply/delete/...）不支持，请改用 DeleteTool 或 CreateTool。"}},"required":["command"]}`
+	return `{"type":"object","properties":{"command":{"type":"string","description":"要运行的只读 kubectl 命令，例如 'kubectl get pods -n default'、'kubectl describe pod foo'。写操作（patch/apply/delete/...）不支持，请改用 PatchTool、ApplyTool、DeleteTool 或 CreateTool。"}},"required":["command"]}`
 }
 
 func (k *KubeTool) Execute(params map[string]any) (string, error) {
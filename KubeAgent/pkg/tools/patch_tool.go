@@ -0,0 +1,227 @@
+package tools
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"kubeagent/pkg/agent/harness"
+	"kubeagent/pkg/k8s"
+)
+
+// PatchTool changes fields of a live K8s resource in place — an image
+// tag, a memory limit, a replica count — without the delete-then-create
+// dance CreateTool needs for existing objects. The controller then rolls
+// the change out, which is usually what a remediation wants.
+//
+// Harness and preview wiring mirror CreateTool: the preflight chain sees
+// verb "patch" (so ResourceExistsCheck rejects patches to missing
+// objects), and every patch must be previewed with dry_run=true first.
+type PatchTool struct {
+	client    *k8s.Client
+	preflight *harness.PreflightChain
+	auditor   harness.AuditLogger
+	previews  *ChangePreviews
+	snapshots SnapshotRecorder
+}
+
+func NewPatchTool(client *k8s.Client) *PatchTool {
+	return &PatchTool{client: client, previews: NewChangePreviews()}
+}
+
+// WithChangePreviews shares a preview board, normally the one passed to
+// HumanTool.WithChangePreviews. Nil keeps the tool's private board.
+func (p *PatchTool) WithChangePreviews(previews *ChangePreviews) *PatchTool {
+	if previews != nil {
+		p.previews = previews
+	}
+	return p
+}
+
+// WithPreflight attaches a Guide chain. Nil is tolerated (no-op).
+func (p *PatchTool) WithPreflight(chain *harness.PreflightChain) *PatchTool {
+	p.preflight = chain
+	return p
+}
+
+// WithAuditor attaches an audit sink for preflight events. Nil is tolerated.
+func (p *PatchTool) WithAuditor(a harness.AuditLogger) *PatchTool {
+	p.auditor = a
+	return p
+}
+
+// WithSnapshots attaches a rollback snapshot recorder. Nil is tolerated.
+func (p *PatchTool) WithSnapshots(s SnapshotRecorder) *PatchTool {
+	p.snapshots = s
+	return p
+}
+
+func (p *PatchTool) Name() string {
+	return "PatchTool"
+}
+
+func (p *PatchTool) Description() string {
+	return "用于原地修改 Kubernetes 集群中已有资源的字段（如镜像、资源限制、副本数、标签），支持 strategic / merge / json 三种补丁类型。必须先以 dry_run=true 调用查看差异，经 HumanTool 确认后再以相同参数正式执行"
+}
+
+func (p *PatchTool) ArgsSchema() string {
+	return `{"type":"object","properties":{"resource":{"type":"string","description":"K8s 资源类型，例如 deployment、pod"},"name":{"type":"string","description":"资源实例的名称"},"namespace":{"type":"string","description":"资源所在命名空间"},"patch_type":{"type":"string","enum":["strategic","merge","json"],"description":"补丁类型，默认 strategic；json 为 RFC 6902 操作列表"},"patch":{"type":"string","description":"JSON 格式的补丁内容，例如 {\"spec\":{\"replicas\":3}}"},"dry_run":{"type":"boolean","description":"为 true 时只做服务端 dry-run 并返回差异，不会真正修改"}},"required":["resource","name","namespace","patch"]}`
+}
+
+func (p *PatchTool) Execute(params map[string]any) (string, error) {
+	return p.ExecuteContext(context.Background(), params)
+}
+
+// ExecuteContext runs the write for the request and task ctx is scoped
+// to; its previews are only visible to that task's HumanTool.
+func (p *PatchTool) ExecuteContext(ctx context.Context, params map[string]any) (string, error) {
+	resource, ok := params["resource"].(string)
+	if !ok || resource == "" {
+		return "", fmt.Errorf("resource is required")
+	}
+	name, ok := params["name"].(string)
+	if !ok || name == "" {
+		return "", fmt.Errorf("name is required")
+	}
+	namespace, ok := params["namespace"].(string)
+	if !ok || namespace == "" {
+		namespace = "default"
+	}
+	typeName, _ := params["patch_type"].(string)
+	patchType, err := k8s.ParsePatchType(strings.ToLower(typeName))
+	if err != nil {
+		return "", err
+	}
+	patch, err := patchBody(params["patch"])
+	if err != nil {
+		return "", err
+	}
+
+	resource = strings.ToLower(resource)
+	key := patchKey(resource, name, namespace, patchType, patch)
+
+	if dryRun, _ := params["dry_run"].(bool); dryRun {
+		result, err := p.preview(ctx, key, resource, name, namespace, patchType, patch)
+		if err != nil {
+			return "", err
+		}
+		return result.Render() + "\nDry-run only, nothing was changed. Get approval via HumanTool, then call PatchTool again with the same arguments and dry_run=false.", nil
+	}
+
+	// Preview before preflight, as in CreateTool.
+	previewed, ready, found := p.previews.lookup(ctx, key)
+	if !found {
+		result, err := p.preview(ctx, key, resource, name, namespace, patchType, patch)
+		if err != nil {
+			return "", err
+		}
+		return "", fmt.Errorf("patch requires a reviewed dry-run first; the diff is:\n%s\nGet approval via HumanTool, then call PatchTool again with the same arguments", result.Render())
+	}
+	if !ready {
+		return "", fmt.Errorf("patch of %s %s/%s has not been approved: call HumanTool so the operator can review the dry-run diff, then retry",
+			previewed.Kind, previewed.Namespace, previewed.Name)
+	}
+
+	if p.preflight != nil {
+		req := harness.PreflightRequest{
+			Verb:         "patch",
+			ResourceKind: resource,
+			ResourceName: name,
+			Namespace:    namespace,
+			Metadata:     preflightMetadata(previewed, "patch", patch),
+		}
+		res := p.preflight.Run(ctx, req)
+		p.recordPreflight(ctx, req, res, previewed)
+		if res.Decision == harness.PreflightBlock {
+			return "", fmt.Errorf("patch blocked by preflight: %s", res.Reason)
+		}
+	}
+
+	if err := captureSnapshot(ctx, p.snapshots, "PatchTool", resource, name, namespace); err != nil {
+		return "", err
+	}
+
+	out, err := p.client.Patch(resource, name, namespace, patchType, patch)
+	if err == nil {
+		p.previews.consume(ctx, key)
+	}
+	return out, err
+}
+
+func (p *PatchTool) preview(ctx context.Context, key, resource, name, namespace string, patchType k8s.PatchType, patch string) (*k8s.DryRunResult, error) {
+	result, err := p.client.DryRunPatch(resource, name, namespace, patchType, patch)
+	if err != nil {
+		return nil, fmt.Errorf("dry-run failed: %w", err)
+	}
+	p.previews.record(ctx, key, result)
+	auditPreview(ctx, p.auditor, "PatchTool", "dry_run_patch", result)
+	return result, nil
+}
+
+// recordPreflight emits an audit event; see CreateTool.recordPreflight.
+func (p *PatchTool) recordPreflight(ctx context.Context, req harness.PreflightRequest, res *harness.PreflightResult, previewed *k8s.DryRunResult) {
+	if p.auditor == nil || res == nil {
+		return
+	}
+	details := map[string]interface{}{}
+	if previewed != nil {
+		details = diffDetails(previewed)
+	}
+	if len(res.Warnings) > 0 {
+		details["warnings"] = res.Warnings
+	}
+	_ = p.auditor.Record(ctx, harness.AuditEvent{
+		Kind:      harness.AuditPreflight,
+		RequestID: requestID(ctx),
+		Actor:     "PatchTool",
+		Action:    "patch",
+		Outcome:   string(res.Decision),
+		Reason:    res.Reason,
+		Target: harness.AuditTarget{
+			Kind:      req.ResourceKind,
+			Name:      req.ResourceName,
+			Namespace: req.Namespace,
+		},
+		Details: details,
+	})
+}
+
+// patchBody accepts the patch as a JSON string or, because models often
+// send structured arguments, as an already-decoded object or list.
+func patchBody(v any) (string, error) {
+	switch body := v.(type) {
+	case nil:
+		return "", fmt.Errorf("patch is required")
+	case string:
+		if strings.TrimSpace(body) == "" {
+			return "", fmt.Errorf("patch is required")
+		}
+		if !json.Valid([]byte(body)) {
+			return "", fmt.Errorf("patch must be valid JSON")
+		}
+		return body, nil
+	default:
+		data, err := json.Marshal(body)
+		if err != nil {
+			return "", fmt.Errorf("patch must be valid JSON: %w", err)
+		}
+		return string(data), nil
+	}
+}
+
+// patchKey identifies a patch by its target and re-marshalled body, so
+// whitespace and key order in the JSON do not make a new change.
+func patchKey(resource, name, namespace string, patchType k8s.PatchType, patch string) string {
+	body := []byte(patch)
+	var decoded interface{}
+	if err := json.Unmarshal(body, &decoded); err == nil {
+		if normalised, err := json.Marshal(decoded); err == nil {
+			body = normalised
+		}
+	}
+	sum := sha256.Sum256([]byte(strings.Join([]string{"patch", resource, namespace, name, string(patchType), string(body)}, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,128 @@
+package tools
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"kubeagent/pkg/agent/harness"
+	"kubeagent/pkg/k8s"
+)
+
+func newWebDeployment() *appsv1.Deployment {
+	replicas := int32(1)
+	labels := map[string]string{"app": "web"}
+	return &appsv1.Deployment{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "apps/v1", Kind: "Deployment"},
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "demo"},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{MatchLabels: labels},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec: corev1.PodSpec{Containers: []corev1.Container{
+					{Name: "web", Image: "example.com/web:1.4.2"},
+				}},
+			},
+		},
+	}
+}
+
+// TestPatchTool_HumanApprovesDiff runs the same gate as CreateTool: the
+// patch is refused until its dry-run diff has been approved, and an
+// equivalent JSON body (different key order, structured argument)
+// counts as the same change.
+func TestPatchTool_HumanApprovesDiff(t *testing.T) {
+	client := k8s.NewFakeClient(newWebDeployment())
+	audit := &recordingAuditor{}
+	previews := NewChangePreviews()
+	var prompt bytes.Buffer
+	human := NewHumanTool().WithChangePreviews(previews).WithIO(strings.NewReader("y\n"), &prompt)
+	tool := NewPatchTool(client).
+		WithChangePreviews(previews).
+		WithPreflight(harness.NewPreflightChain().Add(harness.NewResourceExistsCheck(client))).
+		WithAuditor(audit)
+
+	args := map[string]any{
+		"resource":  "Deployment",
+		"name":      "web",
+		"namespace": "demo",
+		"patch":     `{"spec": {"replicas": 3}}`,
+		"dry_run":   true,
+	}
+	out, err := tool.Execute(args)
+	if err != nil {
+		t.Fatalf("Dry-run failed: %v", err)
+	}
+	if !strings.Contains(out, "~ spec.replicas: 1 -> 3") {
+		t.Errorf("Expected replica change in diff, got %q", out)
+	}
+	if ev := audit.last(); ev.Action != "dry_run_patch" || ev.Details["diff"] == nil {
+		t.Errorf("Expected dry-run audit event with diff, got %+v", ev)
+	}
+
+	args["dry_run"] = false
+	args["patch"] = map[string]any{"spec": map[string]any{"replicas": 3}}
+	if _, err := tool.Execute(args); err == nil || !strings.Contains(err.Error(), "not been approved") {
+		t.Fatalf("Expected approval error, got %v", err)
+	}
+
+	if answer, _ := human.Execute(map[string]any{"prompt": "Scale web to 3?"}); answer != "approved" {
+		t.Fatalf("Expected approval, got %q", answer)
+	}
+	if !strings.Contains(prompt.String(), "spec.replicas") {
+		t.Errorf("HumanTool did not show the diff:\n%s", prompt.String())
+	}
+
+	if _, err := tool.Execute(args); err != nil {
+		t.Fatalf("Approved patch failed: %v", err)
+	}
+	if ev := audit.last(); ev.Action != "patch" || ev.Details["diff"] == nil {
+		t.Errorf("Expected patch audit event with diff, got %+v", ev)
+	}
+
+	// Re-previewing the same patch shows it has landed.
+	if out, err := tool.Execute(map[string]any{
+		"resource": "deployment", "name": "web", "namespace": "demo",
+		"patch": `{"spec":{"replicas":3}}`, "dry_run": true,
+	}); err != nil || !strings.Contains(out, "(no changes)") {
+		t.Errorf("Expected patch to be applied, got %q (err %v)", out, err)
+	}
+}
+
+// TestPatchTool_PreflightBlocksMissing checks the "patch" verb reaches
+// ResourceExistsCheck, so an object deleted between the dry-run and
+// the patch is a policy block rather than an API error.
+func TestPatchTool_PreflightBlocksMissing(t *testing.T) {
+	client := k8s.NewFakeClient(newWebDeployment())
+	tool := NewPatchTool(client).
+		WithPreflight(harness.NewPreflightChain().Add(harness.NewResourceExistsCheck(client)))
+	args := map[string]any{
+		"resource":  "deployment",
+		"name":      "web",
+		"namespace": "demo",
+		"patch":     `{"spec":{"replicas":3}}`,
+	}
+	if _, err := tool.Execute(args); err == nil || !strings.Contains(err.Error(), "requires a reviewed dry-run") {
+		t.Fatalf("Expected the patch to need a dry-run, got %v", err)
+	}
+	if _, err := client.DeleteResource("deployment", "web", "demo"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+
+	_, err := tool.Execute(args)
+	if err == nil || !strings.Contains(err.Error(), "blocked by preflight") {
+		t.Fatalf("Expected preflight block, got %v", err)
+	}
+
+	if _, err := tool.Execute(map[string]any{
+		"resource": "deployment", "name": "web", "namespace": "demo",
+		"patch": "{", "dry_run": true,
+	}); err == nil || !strings.Contains(err.Error(), "valid JSON") {
+		t.Errorf("Expected invalid JSON to be rejected, got %v", err)
+	}
+}
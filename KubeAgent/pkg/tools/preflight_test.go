@@ -7,6 +7,7 @@ import (
 	"testing"
 
 	"kubeagent/pkg/agent/harness"
+	"kubeagent/pkg/k8s"
 )
 
 // recordingAuditor captures the last event so tests can assert what
@@ -46,7 +47,8 @@ func TestDeleteTool_PreflightBlocksProtectedNamespace(t *testing.T) {
 		WithPreflight(chain).
 		WithAuditor(audit)
 
-	out, err := tool.Execute(map[string]any{
+	ctx := harness.WithRequestScope(context.Background(), "req-1", "fix")
+	out, err := tool.ExecuteContext(ctx, map[string]any{
 		"resource":  "pod",
 		"name":      "coredns-abc",
 		"namespace": "kube-system",
@@ -68,18 +70,22 @@ func TestDeleteTool_PreflightBlocksProtectedNamespace(t *testing.T) {
 	if ev.Target.Namespace != "kube-system" {
 		t.Fatalf("expected namespace on audit target, got %+v", ev.Target)
 	}
+	if ev.RequestID != "req-1" {
+		t.Fatalf("expected the request from the call's scope, got %q", ev.RequestID)
+	}
 }
 
 // TestCreateTool_PreflightPeeksYAML proves the YAML peek surfaces the
 // right namespace/kind to the chain, and a protected-namespace YAML
-// is blocked without touching the real client.
+// is blocked even once its dry-run is on record.
 func TestCreateTool_PreflightPeeksYAML(t *testing.T) {
 	chain := harness.NewPreflightChain().
 		Add(harness.NewProtectedNamespaceCheck("kube-system"))
 
 	audit := &recordingAuditor{}
 
-	tool := NewCreateTool(nil).
+	client := k8s.NewFakeClient()
+	tool := NewCreateTool(client).
 		WithPreflight(chain).
 		WithAuditor(audit)
 
@@ -94,10 +100,16 @@ spec:
     image: nginx
 `
 
+	if _, err := tool.Execute(map[string]any{"yaml": yaml, "dry_run": true}); err != nil {
+		t.Fatalf("dry-run failed: %v", err)
+	}
 	_, err := tool.Execute(map[string]any{"yaml": yaml})
 	if err == nil {
 		t.Fatalf("expected preflight block for kube-system create")
 	}
+	if state, _ := client.GetResourceState("pod", "rogue", "kube-system"); state.Exists {
+		t.Fatal("blocked create reached the cluster")
+	}
 	if !strings.Contains(err.Error(), "preflight") {
 		t.Fatalf("expected preflight error, got %v", err)
 	}
@@ -135,3 +147,35 @@ func TestCreateTool_DecodeFailureSkipsPreflight(t *testing.T) {
 
 	_, _ = tool.Execute(map[string]any{"yaml": "not a valid yaml ::: {{{"})
 }
+
+// countingCheck counts how often the chain reached it, standing in for
+// a consensus vote whose every run costs LLM calls.
+type countingCheck struct{ runs int }
+
+func (c *countingCheck) Name() string { return "counting" }
+
+func (c *countingCheck) Check(context.Context, harness.PreflightRequest) (*harness.PreflightResult, error) {
+	c.runs++
+	return &harness.PreflightResult{Decision: harness.PreflightAllow}, nil
+}
+
+// TestCreateTool_PreviewBeforePreflight checks a create refused for
+// lacking a dry-run or an approval never runs the preflight chain.
+func TestCreateTool_PreviewBeforePreflight(t *testing.T) {
+	check := &countingCheck{}
+	previews := NewChangePreviews()
+	previews.enableApproval()
+	tool := NewCreateTool(k8s.NewFakeClient()).
+		WithChangePreviews(previews).
+		WithPreflight(harness.NewPreflightChain().Add(check))
+
+	if _, err := tool.Execute(map[string]any{"yaml": newPodYAML}); err == nil {
+		t.Fatal("Expected the create to need a dry-run")
+	}
+	if _, err := tool.Execute(map[string]any{"yaml": newPodYAML}); err == nil {
+		t.Fatal("Expected the create to need an approval")
+	}
+	if check.runs != 0 {
+		t.Errorf("Expected no preflight run before approval, got %d", check.runs)
+	}
+}
@@ -0,0 +1,28 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+)
+
+// SnapshotRecorder captures the state of an object right before a
+// write tool changes it, so a remediation that fails verification can
+// be rolled back. The remediator's Snapshotter is the implementation;
+// tools only know this one method so they stay free of agent state.
+// ctx carries the request and task the write is made for.
+type SnapshotRecorder interface {
+	Capture(ctx context.Context, tool, resource, name, namespace string) error
+}
+
+// captureSnapshot is called after every guard has passed and just
+// before the real write. A capture failure aborts the write: a change
+// with no restore point is exactly what rollback exists to prevent.
+func captureSnapshot(ctx context.Context, recorder SnapshotRecorder, tool, resource, name, namespace string) error {
+	if recorder == nil {
+		return nil
+	}
+	if err := recorder.Capture(ctx, tool, resource, name, namespace); err != nil {
+		return fmt.Errorf("refusing to write %s %s/%s without a rollback snapshot: %w", resource, namespace, name, err)
+	}
+	return nil
+}
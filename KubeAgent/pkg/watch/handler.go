@@ -0,0 +1,122 @@
+package watch
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"kubeagent/pkg/agent"
+)
+
+// WatchUser is the Request.User of requests the watcher submits, so
+// audit trails and reports tell them from an operator's.
+const WatchUser = "kubeagent-watch"
+
+// CoordinatorHandler submits each incident to a coordinator as a
+// Request: diagnose only, or diagnose and remediate when the
+// escalation policy says so.
+type CoordinatorHandler struct {
+	coordinator agent.CoordinatorAgent
+	logger      agent.Logger
+	setup       func(ctx *agent.AgentContext)
+	onResult    func(incident Incident, response *agent.Response, err error)
+}
+
+// NewCoordinatorHandler builds a handler for coordinator.
+func NewCoordinatorHandler(coordinator agent.CoordinatorAgent, logger agent.Logger) *CoordinatorHandler {
+	return &CoordinatorHandler{coordinator: coordinator, logger: logger}
+}
+
+// WithContextSetup runs setup on each request's context before it is
+// planned, e.g. to attach a usage ledger or a stream handler. Nil is
+// tolerated.
+func (h *CoordinatorHandler) WithContextSetup(setup func(ctx *agent.AgentContext)) *CoordinatorHandler {
+	h.setup = setup
+	return h
+}
+
+// WithResultHandler receives every incident's response, or the error
+// that stopped it. Nil is tolerated.
+func (h *CoordinatorHandler) WithResultHandler(onResult func(incident Incident, response *agent.Response, err error)) *CoordinatorHandler {
+	h.onResult = onResult
+	return h
+}
+
+// Handle implements Handler.
+func (h *CoordinatorHandler) Handle(ctx context.Context, incident Incident, remediate bool) {
+	response, err := h.handle(ctx, incident, remediate)
+	if err != nil {
+		h.logger.Error("Incident handling failed", map[string]interface{}{
+			"incident": incident.String(),
+			"error":    err.Error(),
+		})
+	}
+	if h.onResult != nil {
+		h.onResult(incident, response, err)
+	}
+}
+
+func (h *CoordinatorHandler) handle(ctx context.Context, incident Incident, remediate bool) (*agent.Response, error) {
+	request := IncidentRequest(incident, remediate)
+	agentCtx := agent.NewAgentContext(ctx, request.ID, WatchUser, uuid.New().String())
+	if h.setup != nil {
+		h.setup(agentCtx)
+	}
+
+	plan, err := h.coordinator.Plan(agentCtx, request)
+	if err != nil {
+		return nil, fmt.Errorf("planning failed: %w", err)
+	}
+	if !remediate {
+		// The planner may add a fix the policy does not allow; dropping
+		// it also drops whatever depends on it.
+		plan.DropRemediation()
+		if len(plan.Tasks) == 0 {
+			return nil, fmt.Errorf("plan %s has no diagnosis to run", plan.ID)
+		}
+	}
+	return h.coordinator.ExecutePlan(agentCtx, plan)
+}
+
+// IncidentRequest is the request submitted for incident. The intent is
+// fixed so the planner does not have to guess it, and the incident's
+// fields go in Context as the fallback task input.
+func IncidentRequest(incident Incident, remediate bool) *agent.Request {
+	intent := string(agent.TaskTypeDiagnose)
+	verb := "Diagnose"
+	if remediate {
+		intent = string(agent.TaskTypeRemediate)
+		verb = "Diagnose and remediate"
+	}
+	input := fmt.Sprintf("%s the %s failure of %s %q in namespace %q.",
+		verb, incident.Reason, incident.Kind, incident.Name, incident.Namespace)
+	if incident.Container != "" {
+		input += fmt.Sprintf(" The failing container is %q.", incident.Container)
+	}
+	if incident.Message != "" {
+		input += " Kubernetes reports: " + incident.Message
+	}
+
+	context := map[string]interface{}{
+		"namespace":     incident.Namespace,
+		"resource_kind": incident.Kind,
+		"resource_name": incident.Name,
+		"reason":        incident.Reason,
+	}
+	if incident.Kind == "Pod" {
+		context["pod_name"] = incident.Name
+	}
+	if incident.Container != "" {
+		context["container"] = incident.Container
+	}
+	return &agent.Request{
+		ID:        uuid.New().String(),
+		User:      WatchUser,
+		Input:     input,
+		Intent:    intent,
+		Context:   context,
+		Metadata:  map[string]string{"incident": incident.Key()},
+		CreatedAt: incident.DetectedAt,
+	}
+}
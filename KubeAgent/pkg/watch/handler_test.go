@@ -0,0 +1,56 @@
+package watch
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"kubeagent/pkg/agent"
+)
+
+// planningCoordinator plans a fixed diagnose → remediate → audit chain
+// and records what it was asked to execute.
+type planningCoordinator struct {
+	agent.CoordinatorAgent
+	request  *agent.Request
+	executed *agent.ExecutionPlan
+}
+
+func (c *planningCoordinator) Plan(ctx *agent.AgentContext, request *agent.Request) (*agent.ExecutionPlan, error) {
+	c.request = request
+	return &agent.ExecutionPlan{ID: "p", Tasks: []*agent.Task{
+		{ID: "d", Type: agent.TaskTypeDiagnose},
+		{ID: "r", Type: agent.TaskTypeRemediate, Dependencies: []string{"d"}},
+		{ID: "a", Type: agent.TaskTypeAudit, Dependencies: []string{"r"}},
+	}}, nil
+}
+
+func (c *planningCoordinator) ExecutePlan(ctx *agent.AgentContext, plan *agent.ExecutionPlan) (*agent.Response, error) {
+	c.executed = plan
+	return &agent.Response{Status: agent.TaskStatusCompleted}, nil
+}
+
+func TestCoordinatorHandler_DiagnosesWithoutEscalation(t *testing.T) {
+	coordinator := &planningCoordinator{}
+	var result *agent.Response
+	handler := NewCoordinatorHandler(coordinator, agent.NewNoOpLogger()).
+		WithResultHandler(func(incident Incident, response *agent.Response, err error) { result = response })
+	incident := Incident{Kind: "Pod", Namespace: "demo", Name: "web-1", Reason: "OOMKilled", Container: "app"}
+
+	handler.Handle(context.Background(), incident, false)
+	if coordinator.request.Intent != "diagnose" || coordinator.request.Context["pod_name"] != "web-1" ||
+		!strings.Contains(coordinator.request.Input, `"web-1"`) {
+		t.Errorf("Unexpected request %+v", coordinator.request)
+	}
+	if tasks := coordinator.executed.Tasks; len(tasks) != 1 || tasks[0].ID != "d" {
+		t.Errorf("Expected only the diagnosis to run, got %d task(s)", len(tasks))
+	}
+	if result == nil {
+		t.Error("Expected the result handler to see the response")
+	}
+
+	handler.Handle(context.Background(), incident, true)
+	if coordinator.request.Intent != "remediate" || len(coordinator.executed.Tasks) != 3 {
+		t.Errorf("Expected the full plan when escalating, got %d task(s)", len(coordinator.executed.Tasks))
+	}
+}
@@ -0,0 +1,189 @@
+// Package watch notices failing workloads and hands each incident to
+// the coordinator, so KubeAgent can diagnose a CrashLoopBackOff without
+// someone typing into a REPL.
+package watch
+
+import (
+	"fmt"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+
+	"kubeagent/pkg/agent/harness"
+)
+
+// Incident is one failure the watcher noticed on one object.
+type Incident struct {
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Reason    string `json:"reason"`
+	Message   string `json:"message,omitempty"`
+
+	// Container is set for pod incidents caused by one container.
+	Container  string    `json:"container,omitempty"`
+	DetectedAt time.Time `json:"detected_at"`
+}
+
+// Key identifies the incident for de-duplication: the same object
+// failing for the same reason is the same incident.
+func (i Incident) Key() string {
+	return fmt.Sprintf("%s/%s/%s/%s", i.Kind, i.Namespace, i.Name, i.Reason)
+}
+
+// String is the incident as a log line.
+func (i Incident) String() string {
+	s := fmt.Sprintf("%s %s/%s: %s", i.Kind, i.Namespace, i.Name, i.Reason)
+	if i.Container != "" {
+		s += " (container " + i.Container + ")"
+	}
+	return s
+}
+
+// PodIncidents returns the failures pod is currently in: a container
+// waiting for a reason harness.IsTerminalFailure knows, a container
+// that has just been killed for running out of memory, an eviction, or
+// a pod the scheduler cannot place. An OOM kill the container already
+// restarted from is only news next to the pod before it, see
+// incidentsSince.
+func PodIncidents(pod *corev1.Pod) []Incident {
+	if pod == nil {
+		return nil
+	}
+	incident := func(reason, message, container string) Incident {
+		return Incident{Kind: "Pod", Namespace: pod.Namespace, Name: pod.Name, Reason: reason, Message: message, Container: container}
+	}
+
+	var out []Incident
+	if pod.Status.Reason == "Evicted" {
+		out = append(out, incident("Evicted", pod.Status.Message, ""))
+	}
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == corev1.PodScheduled && cond.Status == corev1.ConditionFalse && cond.Reason == corev1.PodReasonUnschedulable {
+			out = append(out, incident("FailedScheduling", cond.Message, ""))
+		}
+	}
+	for _, cs := range podContainerStatuses(pod) {
+		if w := cs.State.Waiting; w != nil && harness.IsTerminalFailure(w.Reason) {
+			out = append(out, incident(w.Reason, w.Message, cs.Name))
+		}
+		if t := cs.State.Terminated; t != nil && t.Reason == "OOMKilled" {
+			out = append(out, oomIncident(pod, cs))
+		}
+	}
+	return out
+}
+
+// oomRestarts returns an OOMKilled incident for every container of cur
+// that restarted since prev after running out of memory.
+// LastTerminationState keeps the reason of a kill long past, so it only
+// counts together with a higher RestartCount.
+func oomRestarts(prev, cur *corev1.Pod) []Incident {
+	restarts := make(map[string]int32)
+	for _, cs := range podContainerStatuses(prev) {
+		restarts[cs.Name] = cs.RestartCount
+	}
+	var out []Incident
+	for _, cs := range podContainerStatuses(cur) {
+		before, known := restarts[cs.Name]
+		if t := cs.LastTerminationState.Terminated; t != nil && t.Reason == "OOMKilled" && known && cs.RestartCount > before {
+			out = append(out, oomIncident(cur, cs))
+		}
+	}
+	return out
+}
+
+func oomIncident(pod *corev1.Pod, cs corev1.ContainerStatus) Incident {
+	return Incident{
+		Kind: "Pod", Namespace: pod.Namespace, Name: pod.Name, Reason: "OOMKilled",
+		Message: fmt.Sprintf("restarted %d time(s)", cs.RestartCount), Container: cs.Name,
+	}
+}
+
+func podContainerStatuses(pod *corev1.Pod) []corev1.ContainerStatus {
+	return append(append([]corev1.ContainerStatus(nil), pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...)
+}
+
+// DeploymentIncidents returns the failures deployment is currently in:
+// a rollout past its progress deadline, or a ReplicaSet that cannot
+// create pods (quota, admission).
+func DeploymentIncidents(deployment *appsv1.Deployment) []Incident {
+	if deployment == nil {
+		return nil
+	}
+	var out []Incident
+	for _, cond := range deployment.Status.Conditions {
+		switch {
+		case cond.Type == appsv1.DeploymentProgressing && cond.Status == corev1.ConditionFalse && cond.Reason == "ProgressDeadlineExceeded",
+			cond.Type == appsv1.DeploymentReplicaFailure && cond.Status == corev1.ConditionTrue:
+			out = append(out, Incident{
+				Kind: "Deployment", Namespace: deployment.Namespace, Name: deployment.Name,
+				Reason: cond.Reason, Message: cond.Message,
+			})
+		}
+	}
+	return out
+}
+
+// JobIncidents returns the job's failure, e.g. BackoffLimitExceeded or
+// DeadlineExceeded.
+func JobIncidents(job *batchv1.Job) []Incident {
+	if job == nil {
+		return nil
+	}
+	for _, cond := range job.Status.Conditions {
+		if cond.Type == batchv1.JobFailed && cond.Status == corev1.ConditionTrue {
+			reason := cond.Reason
+			if reason == "" {
+				reason = "Failed"
+			}
+			return []Incident{{Kind: "Job", Namespace: job.Namespace, Name: job.Name, Reason: reason, Message: cond.Message}}
+		}
+	}
+	return nil
+}
+
+// incidentsOf dispatches on the informer object's type.
+func incidentsOf(obj interface{}) []Incident {
+	switch o := obj.(type) {
+	case *corev1.Pod:
+		return PodIncidents(o)
+	case *appsv1.Deployment:
+		return DeploymentIncidents(o)
+	case *batchv1.Job:
+		return JobIncidents(o)
+	}
+	return nil
+}
+
+// incidentsSince is incidentsOf(cur) plus, for a pod, the containers
+// OOM-killed since prev: a container that restarted no longer shows the
+// kill in its current state.
+func incidentsSince(prev, cur interface{}) []Incident {
+	out := incidentsOf(cur)
+	if prevPod, ok := prev.(*corev1.Pod); ok {
+		if curPod, ok := cur.(*corev1.Pod); ok {
+			out = append(out, oomRestarts(prevPod, curPod)...)
+		}
+	}
+	return out
+}
+
+// transitions returns the incidents in cur that were not in prev: the
+// object has just started failing for that reason. A pod that stays in
+// CrashLoopBackOff across updates is reported once.
+func transitions(prev, cur []Incident) []Incident {
+	before := make(map[string]bool, len(prev))
+	for _, incident := range prev {
+		before[incident.Key()] = true
+	}
+	var out []Incident
+	for _, incident := range cur {
+		if !before[incident.Key()] {
+			out = append(out, incident)
+		}
+	}
+	return out
+}
@@ -0,0 +1,134 @@
+package watch
+
+import (
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func crashingPod(name, reason string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "demo"},
+		Status: corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{{
+			Name:  "app",
+			State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: reason}},
+		}}},
+	}
+}
+
+func reasons(incidents []Incident) []string {
+	var out []string
+	for _, incident := range incidents {
+		out = append(out, incident.Reason)
+	}
+	return out
+}
+
+func TestPodIncidents(t *testing.T) {
+	oom := crashingPod("web-1", "CrashLoopBackOff")
+	oom.Status.ContainerStatuses[0].LastTerminationState.Terminated = &corev1.ContainerStateTerminated{Reason: "OOMKilled"}
+
+	killed := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web-6", Namespace: "demo"},
+		Status: corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{{
+			Name:  "app",
+			State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Reason: "OOMKilled"}},
+		}}}}
+
+	evicted := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web-2", Namespace: "demo"},
+		Status: corev1.PodStatus{Reason: "Evicted", Message: "The node was low on resource: memory."}}
+
+	pending := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web-3", Namespace: "demo"},
+		Status: corev1.PodStatus{Conditions: []corev1.PodCondition{{
+			Type: corev1.PodScheduled, Status: corev1.ConditionFalse, Reason: corev1.PodReasonUnschedulable,
+			Message: "0/3 nodes are available: 3 Insufficient cpu.",
+		}}}}
+
+	cases := []struct {
+		name string
+		pod  *corev1.Pod
+		want []string
+	}{
+		{"crash loop after an earlier OOM", oom, []string{"CrashLoopBackOff"}},
+		{"OOM-killed now", killed, []string{"OOMKilled"}},
+		{"image pull", crashingPod("web-4", "ImagePullBackOff"), []string{"ImagePullBackOff"}},
+		{"starting up", crashingPod("web-5", "ContainerCreating"), nil},
+		{"evicted", evicted, []string{"Evicted"}},
+		{"unschedulable", pending, []string{"FailedScheduling"}},
+	}
+	for _, tc := range cases {
+		got := reasons(PodIncidents(tc.pod))
+		if len(got) != len(tc.want) {
+			t.Errorf("%s: expected %v, got %v", tc.name, tc.want, got)
+			continue
+		}
+		for i := range got {
+			if got[i] != tc.want[i] {
+				t.Errorf("%s: expected %v, got %v", tc.name, tc.want, got)
+			}
+		}
+	}
+
+	incident := PodIncidents(oom)[0]
+	if incident.Container != "app" || incident.Key() != "Pod/demo/web-1/CrashLoopBackOff" {
+		t.Errorf("Unexpected incident %+v", incident)
+	}
+}
+
+func TestDeploymentAndJobIncidents(t *testing.T) {
+	deployment := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "demo"},
+		Status: appsv1.DeploymentStatus{Conditions: []appsv1.DeploymentCondition{
+			{Type: appsv1.DeploymentProgressing, Status: corev1.ConditionFalse, Reason: "ProgressDeadlineExceeded"},
+			{Type: appsv1.DeploymentAvailable, Status: corev1.ConditionFalse, Reason: "MinimumReplicasUnavailable"},
+		}}}
+	if got := reasons(DeploymentIncidents(deployment)); len(got) != 1 || got[0] != "ProgressDeadlineExceeded" {
+		t.Errorf("Expected a stalled rollout, got %v", got)
+	}
+
+	job := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "migrate", Namespace: "demo"},
+		Status: batchv1.JobStatus{Conditions: []batchv1.JobCondition{
+			{Type: batchv1.JobFailed, Status: corev1.ConditionTrue, Reason: "BackoffLimitExceeded"},
+		}}}
+	if got := reasons(JobIncidents(job)); len(got) != 1 || got[0] != "BackoffLimitExceeded" {
+		t.Errorf("Expected a failed job, got %v", got)
+	}
+}
+
+// TestIncidentsSince_ReportsNewOOMKills checks an OOM kill in a
+// container's last state is only news when the container restarted
+// since the previous version of the pod.
+func TestIncidentsSince_ReportsNewOOMKills(t *testing.T) {
+	running := func(restarts int32) *corev1.Pod {
+		return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web-1", Namespace: "demo"},
+			Status: corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{{
+				Name:                 "app",
+				State:                corev1.ContainerState{Running: &corev1.ContainerStateRunning{}},
+				LastTerminationState: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Reason: "OOMKilled"}},
+				RestartCount:         restarts,
+			}}}}
+	}
+
+	if got := incidentsSince(nil, running(1)); len(got) != 0 {
+		t.Errorf("Expected a pod that ran out of memory long ago to be no news when first seen, got %v", got)
+	}
+	if got := transitions(incidentsOf(running(1)), incidentsSince(running(1), running(1))); len(got) != 0 {
+		t.Errorf("Expected an update without a restart to be no news, got %v", got)
+	}
+	got := transitions(incidentsOf(running(1)), incidentsSince(running(1), running(2)))
+	if len(got) != 1 || got[0].Reason != "OOMKilled" || got[0].Container != "app" {
+		t.Errorf("Expected the restart after an OOM kill to be reported, got %v", got)
+	}
+}
+
+func TestTransitionsReportOnlyNewFailures(t *testing.T) {
+	before := PodIncidents(crashingPod("web-1", "CrashLoopBackOff"))
+	after := PodIncidents(crashingPod("web-1", "CrashLoopBackOff"))
+	if got := transitions(before, after); len(got) != 0 {
+		t.Errorf("Expected a pod still crash-looping to be no news, got %v", got)
+	}
+	if got := transitions(PodIncidents(crashingPod("web-1", "ContainerCreating")), after); len(got) != 1 {
+		t.Errorf("Expected the first crash to be reported, got %v", got)
+	}
+}
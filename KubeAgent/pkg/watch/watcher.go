@@ -0,0 +1,241 @@
+package watch
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+
+	"kubeagent/pkg/agent"
+)
+
+// Defaults for Config.
+const (
+	DefaultDedupWindow = 30 * time.Minute
+	DefaultMinInterval = time.Minute
+	DefaultQueueSize   = 100
+)
+
+// Config controls what the Watcher watches and how fast it hands
+// incidents on.
+type Config struct {
+	// Namespaces to watch. Empty means all namespaces.
+	Namespaces []string
+
+	// DedupWindow is how long an incident is not reported again after
+	// it was queued. A pod that recovers and fails for the same reason
+	// within the window is not a new incident.
+	DedupWindow time.Duration
+
+	// MinInterval is the least time between two incidents handed to
+	// the handler, so a node failure that breaks fifty pods does not
+	// become fifty concurrent LLM sessions.
+	MinInterval time.Duration
+
+	// QueueSize bounds the incidents waiting for the handler. Incidents
+	// beyond it are dropped and logged.
+	QueueSize int
+
+	// Escalation decides which incidents are remediated, not only
+	// diagnosed.
+	Escalation EscalationPolicy
+}
+
+// DefaultConfig watches every namespace, diagnoses only, and hands on
+// at most one incident a minute.
+func DefaultConfig() Config {
+	return Config{
+		DedupWindow: DefaultDedupWindow,
+		MinInterval: DefaultMinInterval,
+		QueueSize:   DefaultQueueSize,
+	}
+}
+
+// EscalationPolicy says which incidents go on to remediation. The zero
+// value never escalates.
+type EscalationPolicy struct {
+	// Reasons to remediate, e.g. "OOMKilled". "*" matches every reason.
+	Reasons []string
+
+	// Namespaces remediation is allowed in. Empty means every watched
+	// namespace.
+	Namespaces []string
+}
+
+// Remediate reports whether incident should be fixed, not only
+// diagnosed.
+func (p EscalationPolicy) Remediate(incident Incident) bool {
+	if len(p.Namespaces) > 0 && !containsString(p.Namespaces, incident.Namespace) {
+		return false
+	}
+	return containsString(p.Reasons, "*") || containsString(p.Reasons, incident.Reason)
+}
+
+// Handler acts on an incident. remediate is the escalation policy's
+// verdict. Handle is called from one goroutine, one incident at a time.
+type Handler interface {
+	Handle(ctx context.Context, incident Incident, remediate bool)
+}
+
+// HandlerFunc adapts a function to Handler.
+type HandlerFunc func(ctx context.Context, incident Incident, remediate bool)
+
+// Handle implements Handler.
+func (f HandlerFunc) Handle(ctx context.Context, incident Incident, remediate bool) {
+	f(ctx, incident, remediate)
+}
+
+// Watcher watches Pods, Deployments and Jobs through shared informers
+// and hands each new failure to a Handler: de-duplicated, rate-limited
+// and one at a time.
+type Watcher struct {
+	clientset kubernetes.Interface
+	handler   Handler
+	logger    agent.Logger
+	config    Config
+
+	mu    sync.Mutex
+	seen  map[string]time.Time
+	queue chan Incident
+	now   func() time.Time
+}
+
+// NewWatcher builds a watcher with DefaultConfig.
+func NewWatcher(clientset kubernetes.Interface, handler Handler, logger agent.Logger) *Watcher {
+	return &Watcher{
+		clientset: clientset,
+		handler:   handler,
+		logger:    logger,
+		config:    DefaultConfig(),
+		seen:      make(map[string]time.Time),
+		now:       time.Now,
+	}
+}
+
+// WithConfig replaces the configuration. Zero durations and sizes take
+// the defaults; a negative MinInterval means no rate limit.
+func (w *Watcher) WithConfig(c Config) *Watcher {
+	if c.DedupWindow == 0 {
+		c.DedupWindow = DefaultDedupWindow
+	}
+	if c.MinInterval == 0 {
+		c.MinInterval = DefaultMinInterval
+	}
+	if c.QueueSize <= 0 {
+		c.QueueSize = DefaultQueueSize
+	}
+	w.config = c
+	return w
+}
+
+// Run watches until ctx is done. Objects already failing when the
+// watch starts are reported too: they are as new to the watcher as a
+// failure that happens later.
+func (w *Watcher) Run(ctx context.Context) error {
+	w.queue = make(chan Incident, w.config.QueueSize)
+
+	namespaces := w.config.Namespaces
+	if len(namespaces) == 0 {
+		namespaces = []string{metav1.NamespaceAll}
+	}
+	handlers := cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			w.observe(nil, incidentsOf(obj))
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			w.observe(incidentsOf(oldObj), incidentsSince(oldObj, newObj))
+		},
+	}
+	var synced []cache.InformerSynced
+	for _, namespace := range namespaces {
+		factory := informers.NewSharedInformerFactoryWithOptions(w.clientset, 0, informers.WithNamespace(namespace))
+		for _, informer := range []cache.SharedIndexInformer{
+			factory.Core().V1().Pods().Informer(),
+			factory.Apps().V1().Deployments().Informer(),
+			factory.Batch().V1().Jobs().Informer(),
+		} {
+			if _, err := informer.AddEventHandler(handlers); err != nil {
+				return fmt.Errorf("failed to watch: %w", err)
+			}
+			synced = append(synced, informer.HasSynced)
+		}
+		factory.Start(ctx.Done())
+	}
+	if !cache.WaitForCacheSync(ctx.Done(), synced...) {
+		return ctx.Err()
+	}
+	w.logger.Info("Watching for failing workloads", map[string]interface{}{
+		"namespaces": namespaces,
+	})
+
+	return w.process(ctx)
+}
+
+// observe queues the incidents that are new between two versions of
+// an object and have not been seen within the dedup window.
+func (w *Watcher) observe(prev, cur []Incident) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	now := w.now()
+	for key, at := range w.seen {
+		if now.Sub(at) >= w.config.DedupWindow {
+			delete(w.seen, key)
+		}
+	}
+	for _, incident := range transitions(prev, cur) {
+		key := incident.Key()
+		if _, dup := w.seen[key]; dup {
+			continue
+		}
+		incident.DetectedAt = now
+		select {
+		case w.queue <- incident:
+			w.seen[key] = now
+			w.logger.Info("Incident detected", map[string]interface{}{
+				"incident": incident.String(),
+			})
+		default:
+			// Not marked seen, so a later update can queue it again.
+			w.logger.Warn("Incident queue full; dropping", map[string]interface{}{
+				"incident": incident.String(),
+			})
+		}
+	}
+}
+
+// process hands queued incidents to the handler, at most one per
+// MinInterval.
+func (w *Watcher) process(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case incident := <-w.queue:
+			w.handler.Handle(ctx, incident, w.config.Escalation.Remediate(incident))
+		}
+		if w.config.MinInterval <= 0 {
+			continue
+		}
+		timer := time.NewTimer(w.config.MinInterval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		case <-timer.C:
+		}
+	}
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
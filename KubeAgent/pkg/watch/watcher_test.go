@@ -0,0 +1,111 @@
+package watch
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"kubeagent/pkg/agent"
+)
+
+func TestWatcher_DeduplicatesWithinWindow(t *testing.T) {
+	w := NewWatcher(fake.NewSimpleClientset(), nil, agent.NewNoOpLogger()).
+		WithConfig(Config{DedupWindow: 10 * time.Minute, QueueSize: 10})
+	w.queue = make(chan Incident, w.config.QueueSize)
+	now := time.Now()
+	w.now = func() time.Time { return now }
+
+	crashing := PodIncidents(crashingPod("web-1", "CrashLoopBackOff"))
+	w.observe(nil, crashing)
+	// The pod recovers and crashes again within the window.
+	w.observe(nil, crashing)
+	if len(w.queue) != 1 {
+		t.Fatalf("Expected one queued incident, got %d", len(w.queue))
+	}
+
+	now = now.Add(11 * time.Minute)
+	w.observe(nil, crashing)
+	if len(w.queue) != 2 {
+		t.Errorf("Expected the incident again after the window, got %d queued", len(w.queue))
+	}
+}
+
+func TestWatcher_DropsWhenQueueFull(t *testing.T) {
+	w := NewWatcher(fake.NewSimpleClientset(), nil, agent.NewNoOpLogger()).
+		WithConfig(Config{QueueSize: 1})
+	w.queue = make(chan Incident, w.config.QueueSize)
+
+	w.observe(nil, PodIncidents(crashingPod("web-1", "CrashLoopBackOff")))
+	w.observe(nil, PodIncidents(crashingPod("web-2", "CrashLoopBackOff")))
+	if len(w.queue) != 1 {
+		t.Fatalf("Expected the queue to stay bounded, got %d", len(w.queue))
+	}
+	if _, seen := w.seen["Pod/demo/web-2/CrashLoopBackOff"]; seen {
+		t.Error("Expected a dropped incident to be reportable later")
+	}
+}
+
+func TestWatcher_RunHandsIncidentsToHandler(t *testing.T) {
+	clientset := fake.NewSimpleClientset(crashingPod("web-1", "CrashLoopBackOff"))
+	type handled struct {
+		incident  Incident
+		remediate bool
+	}
+	got := make(chan handled, 4)
+	handler := HandlerFunc(func(ctx context.Context, incident Incident, remediate bool) {
+		got <- handled{incident, remediate}
+	})
+	w := NewWatcher(clientset, handler, agent.NewNoOpLogger()).WithConfig(Config{
+		Namespaces:  []string{"demo"},
+		MinInterval: -1,
+		Escalation:  EscalationPolicy{Reasons: []string{"ImagePullBackOff"}},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- w.Run(ctx) }()
+
+	select {
+	case h := <-got:
+		if h.incident.Name != "web-1" || h.remediate {
+			t.Errorf("Expected web-1 diagnosed only, got %+v", h)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the pod already failing to be reported")
+	}
+
+	pod := crashingPod("web-2", "ImagePullBackOff")
+	if _, err := clientset.CoreV1().Pods("demo").Create(ctx, pod, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	select {
+	case h := <-got:
+		if h.incident.Name != "web-2" || !h.remediate {
+			t.Errorf("Expected web-2 escalated, got %+v", h)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the new failure to be reported")
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Expected a clean stop, got %v", err)
+	}
+}
+
+func TestEscalationPolicy(t *testing.T) {
+	oom := Incident{Namespace: "demo", Reason: "OOMKilled"}
+	if (EscalationPolicy{}).Remediate(oom) {
+		t.Error("Expected the zero policy to never remediate")
+	}
+	if !(EscalationPolicy{Reasons: []string{"*"}}).Remediate(oom) {
+		t.Error("Expected * to match every reason")
+	}
+	if (EscalationPolicy{Reasons: []string{"OOMKilled"}, Namespaces: []string{"staging"}}).Remediate(oom) {
+		t.Error("Expected remediation to stay in its namespaces")
+	}
+}